@@ -0,0 +1,100 @@
+package agentclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// After breakerThreshold attempts in a row fail to reach an agent, calls fail
+// fast for breakerCooldown. Then one call is let through: if it reaches the
+// agent the breaker closes, if not it stays open for another cooldown.
+const (
+	breakerThreshold = 5
+	breakerCooldown  = 30 * time.Second
+)
+
+type breaker struct {
+	failures  int
+	openUntil time.Time
+	probing   bool // The call let through after the cooldown is in flight
+}
+
+var breakers = struct {
+	sync.Mutex
+	hosts map[string]*breaker
+}{hosts: make(map[string]*breaker)}
+
+// allow returns ErrCircuitOpen while the host's breaker is open
+func allow(host string) error {
+	breakers.Lock()
+	defer breakers.Unlock()
+
+	b := breakers.hosts[host]
+	if b == nil || b.failures < breakerThreshold {
+		return nil
+	}
+
+	now := time.Now()
+	if now.Before(b.openUntil) {
+		return &requestError{
+			kind: ErrCircuitOpen,
+			err:  fmt.Errorf("%d failed attempts in a row, retrying in %s", b.failures, b.openUntil.Sub(now).Round(time.Second)),
+		}
+	}
+	if b.probing {
+		return &requestError{
+			kind: ErrCircuitOpen,
+			err:  errors.New("waiting for a test request to finish"),
+		}
+	}
+	b.probing = true
+	return nil
+}
+
+// report records how an attempt went. An answer from the agent, even an
+// error, means it is up; calls cancelled by the caller or never sent say
+// nothing about it.
+func report(ctx context.Context, host string, err error) {
+	breakers.Lock()
+	defer breakers.Unlock()
+
+	b := breakers.hosts[host]
+	var agentErr *AgentError
+	switch {
+	case err == nil || errors.As(err, &agentErr) || errors.Is(err, ErrInvalidResponse):
+		if b != nil && b.failures >= breakerThreshold {
+			log.Printf("🔌 Agent %s is reachable again, circuit closed", host)
+		}
+		delete(breakers.hosts, host)
+
+	case errors.Is(err, ErrUnreachable) && ctx.Err() != context.Canceled:
+		if b == nil {
+			b = &breaker{}
+			breakers.hosts[host] = b
+		}
+		b.failures++
+		b.probing = false
+		if b.failures >= breakerThreshold {
+			if b.failures == breakerThreshold {
+				log.Printf("🔌 Agent %s failed %d times in a row, circuit open for %s", host, b.failures, breakerCooldown)
+			}
+			b.openUntil = time.Now().Add(breakerCooldown)
+		}
+
+	default:
+		if b != nil {
+			b.probing = false
+		}
+	}
+}
+
+// Reset closes a host's breaker, e.g. after its endpoint changed
+func Reset(host string) {
+	breakers.Lock()
+	delete(breakers.hosts, host)
+	breakers.Unlock()
+}
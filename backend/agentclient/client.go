@@ -0,0 +1,242 @@
+// Package agentclient is how the backend calls the /client API of agents.
+// It looks up the device, authenticates with its token, bounds every call
+// with a timeout, retries reads that failed to reach the agent and stops
+// calling agents that keep failing until they had time to come back.
+package agentclient
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+// Timeouts per attempt. Reads are retried, so a read can take up to
+// maxAttempts times readTimeout plus the backoff.
+const (
+	readTimeout   = 10 * time.Second
+	actionTimeout = 30 * time.Second
+	longTimeout   = 2 * time.Minute // Cleanups that walk the disk
+)
+
+const (
+	maxAttempts  = 3
+	retryBackoff = 250 * time.Millisecond // Doubled after each attempt
+	maxBody      = 16 << 20
+)
+
+var (
+	// ErrDeviceNotFound means the host is not a registered device
+	ErrDeviceNotFound = errors.New("device not registered")
+	// ErrUnreachable means the request did not get an answer from the agent
+	ErrUnreachable = errors.New("agent unreachable")
+	// ErrCircuitOpen means the agent failed too often and is not called
+	// until its cooldown is over
+	ErrCircuitOpen = errors.New("agent unavailable")
+	// ErrInvalidResponse means the agent answered with something we can't parse
+	ErrInvalidResponse = errors.New("invalid agent response")
+
+	errDatabase = errors.New("database error")
+)
+
+// AgentError is an error the agent answered with
+type AgentError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *AgentError) Error() string {
+	return e.Message
+}
+
+// requestError tags the cause of a failed call with one of the errors above
+// while keeping the cause's message
+type requestError struct {
+	kind error
+	err  error
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+func (e *requestError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// Agent is a registered device that can be called
+type Agent struct {
+	Host  string
+	OS    string
+	token string
+}
+
+// Open looks up a registered device
+func Open(ctx context.Context, queries *serverdb.Queries, host string) (*Agent, error) {
+	device, err := queries.GetServerDeviceByIP(ctx, host)
+	if err == sql.ErrNoRows {
+		return nil, ErrDeviceNotFound
+	} else if err != nil {
+		return nil, &requestError{kind: errDatabase, err: err}
+	}
+	return NewAgent(device.Ip, device.Os, device.AccessToken), nil
+}
+
+// NewAgent is for callers that already loaded the device
+func NewAgent(host, osType, accessToken string) *Agent {
+	return &Agent{Host: host, OS: osType, token: accessToken}
+}
+
+// call describes one request to an agent endpoint
+type call struct {
+	method     string
+	path       string
+	query      url.Values
+	body       interface{} // Sent as JSON when not nil
+	timeout    time.Duration
+	idempotent bool // Safe to send again when the agent was not reached
+}
+
+func (a *Agent) get(ctx context.Context, path string, out interface{}) error {
+	return a.do(ctx, call{method: http.MethodGet, path: path, timeout: readTimeout, idempotent: true}, out)
+}
+
+func (a *Agent) post(ctx context.Context, path string, body, out interface{}) error {
+	return a.do(ctx, call{method: http.MethodPost, path: path, body: body, timeout: actionTimeout}, out)
+}
+
+func (a *Agent) do(ctx context.Context, c call, out interface{}) error {
+	var payload []byte
+	if c.body != nil {
+		var err error
+		if payload, err = json.Marshal(c.body); err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	attempts := 1
+	if c.idempotent {
+		attempts = maxAttempts
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			backoff := retryBackoff << (attempt - 1)
+			backoff += time.Duration(rand.Int63n(int64(backoff) / 2)) // Spread out concurrent retries
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(backoff):
+			}
+		}
+
+		if err = allow(a.Host); err != nil {
+			return err
+		}
+		err = a.send(ctx, c, payload, out)
+		report(ctx, a.Host, err)
+		if err == nil || !errors.Is(err, ErrUnreachable) || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// send makes one attempt
+func (a *Agent) send(ctx context.Context, c call, payload []byte, out interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := a.request(ctx, c, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	if err != nil {
+		return &requestError{kind: ErrUnreachable, err: err}
+	}
+
+	// Handle client error responses
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp.StatusCode, respBody)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return &requestError{kind: ErrInvalidResponse, err: err}
+	}
+	return nil
+}
+
+// request sends c with body and returns the agent's response, whatever its
+// status
+func (a *Agent) request(ctx context.Context, c call, body io.Reader) (*http.Response, error) {
+	clientURL := config.GetClientURL(a.Host, c.path)
+	if len(c.query) > 0 {
+		clientURL += "?" + c.query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, c.method, clientURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := config.AgentClient.Do(req)
+	if err != nil {
+		return nil, &requestError{kind: ErrUnreachable, err: err}
+	}
+	return resp, nil
+}
+
+// responseError turns an answer other than 200 into an AgentError
+func responseError(statusCode int, body []byte) error {
+	var envelope struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &envelope) == nil && envelope.Status == "failed" {
+		return &AgentError{StatusCode: statusCode, Message: envelope.Message}
+	}
+	return &AgentError{StatusCode: statusCode, Message: string(bytes.TrimSpace(body))}
+}
+
+// Describe turns an error from this package into the message and status
+// the API answers with
+func Describe(err error) (string, int) {
+	var agentErr *AgentError
+	switch {
+	case errors.Is(err, ErrDeviceNotFound):
+		return "Device not registered", http.StatusNotFound
+	case errors.Is(err, ErrCircuitOpen):
+		return "Client unavailable: " + err.Error(), http.StatusServiceUnavailable
+	case errors.As(err, &agentErr):
+		return "Client error: " + agentErr.Message, http.StatusBadGateway
+	case errors.Is(err, ErrUnreachable) && errors.Is(err, context.DeadlineExceeded):
+		return "Client timed out: " + err.Error(), http.StatusGatewayTimeout
+	case errors.Is(err, ErrUnreachable):
+		return "Failed to reach client: " + err.Error(), http.StatusBadGateway
+	case errors.Is(err, ErrInvalidResponse):
+		return "Invalid client response: " + err.Error(), http.StatusBadGateway
+	case errors.Is(err, errDatabase):
+		return "Database error: " + err.Error(), http.StatusInternalServerError
+	default:
+		return "Failed to prepare request: " + err.Error(), http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,218 @@
+package agentclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+)
+
+// newTestAgent points an Agent at a local server standing in for the agent
+func newTestAgent(t *testing.T, handler http.Handler) *Agent {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := config.AppConfig
+	config.AppConfig = &config.AppConfiguration{ClientPort: port, ClientProtocol: "http"}
+	Reset(host)
+	t.Cleanup(func() {
+		server.Close()
+		config.AppConfig = previous
+		Reset(host)
+	})
+	return NewAgent(host, "linux", "token")
+}
+
+// dropConnection makes the request fail without an answer
+func dropConnection(w http.ResponseWriter) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err == nil {
+		conn.Close()
+	}
+}
+
+// flakyHandler drops the first failures requests and answers the rest
+func flakyHandler(failures int32, calls *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			dropConnection(w)
+			return
+		}
+		w.Write([]byte(`{"status":"ok"}`))
+	})
+}
+
+func TestReadsAreRetried(t *testing.T) {
+	var calls atomic.Int32
+	a := newTestAgent(t, flakyHandler(maxAttempts-1, &calls))
+
+	if err := a.get(context.Background(), "/client/x", nil); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got := calls.Load(); got != maxAttempts {
+		t.Errorf("agent saw %d requests, want %d", got, maxAttempts)
+	}
+}
+
+func TestReadsGiveUpAfterMaxAttempts(t *testing.T) {
+	var calls atomic.Int32
+	a := newTestAgent(t, flakyHandler(maxAttempts, &calls))
+
+	err := a.get(context.Background(), "/client/x", nil)
+	if !errors.Is(err, ErrUnreachable) {
+		t.Fatalf("get error = %v, want ErrUnreachable", err)
+	}
+	if got := calls.Load(); got != maxAttempts {
+		t.Errorf("agent saw %d requests, want %d", got, maxAttempts)
+	}
+}
+
+func TestActionsAreNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	a := newTestAgent(t, flakyHandler(1, &calls))
+
+	err := a.post(context.Background(), "/client/x", map[string]string{"a": "b"}, nil)
+	if !errors.Is(err, ErrUnreachable) {
+		t.Fatalf("post error = %v, want ErrUnreachable", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("agent saw %d requests, want 1", got)
+	}
+}
+
+func TestAgentErrorsAreNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	a := newTestAgent(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"status":"failed","message":"disk full"}`))
+	}))
+
+	err := a.get(context.Background(), "/client/x", nil)
+	var agentErr *AgentError
+	if !errors.As(err, &agentErr) || agentErr.Message != "disk full" || agentErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("get error = %v, want the agent's message", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("agent saw %d requests, want 1", got)
+	}
+}
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	var calls atomic.Int32
+	var failing atomic.Bool
+	failing.Store(true)
+	a := newTestAgent(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if failing.Load() {
+			dropConnection(w)
+			return
+		}
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	post := func() error {
+		return a.post(context.Background(), "/client/x", nil, nil)
+	}
+
+	for i := 0; i < breakerThreshold; i++ {
+		if err := post(); !errors.Is(err, ErrUnreachable) {
+			t.Fatalf("attempt %d error = %v, want ErrUnreachable", i+1, err)
+		}
+	}
+	if err := post(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("error after %d failures = %v, want ErrCircuitOpen", breakerThreshold, err)
+	}
+	if got := calls.Load(); got != breakerThreshold {
+		t.Errorf("agent saw %d requests, want %d", got, breakerThreshold)
+	}
+
+	// After the cooldown one request is let through, and an answer closes
+	// the breaker
+	breakers.Lock()
+	breakers.hosts[a.Host].openUntil = time.Now().Add(-time.Second)
+	breakers.Unlock()
+	failing.Store(false)
+
+	if err := post(); err != nil {
+		t.Fatalf("probe after cooldown: %v", err)
+	}
+	breakers.Lock()
+	_, open := breakers.hosts[a.Host]
+	breakers.Unlock()
+	if open {
+		t.Error("breaker still tracked after the agent answered")
+	}
+}
+
+func TestBreakerLetsOneProbeThrough(t *testing.T) {
+	const host = "192.0.2.1"
+	Reset(host)
+	t.Cleanup(func() { Reset(host) })
+
+	breakers.Lock()
+	breakers.hosts[host] = &breaker{failures: breakerThreshold, openUntil: time.Now().Add(-time.Second)}
+	breakers.Unlock()
+
+	if err := allow(host); err != nil {
+		t.Fatalf("first call after cooldown: %v", err)
+	}
+	if err := allow(host); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("second call while probing = %v, want ErrCircuitOpen", err)
+	}
+
+	// A failed probe opens the breaker for another cooldown
+	report(context.Background(), host, &requestError{kind: ErrUnreachable, err: errors.New("refused")})
+	if err := allow(host); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("call after a failed probe = %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestReportIgnoresCancelledCalls(t *testing.T) {
+	const host = "192.0.2.2"
+	Reset(host)
+	t.Cleanup(func() { Reset(host) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < breakerThreshold; i++ {
+		report(ctx, host, &requestError{kind: ErrUnreachable, err: context.Canceled})
+	}
+	if err := allow(host); err != nil {
+		t.Errorf("calls cancelled by the caller opened the breaker: %v", err)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"not registered", ErrDeviceNotFound, http.StatusNotFound},
+		{"circuit open", &requestError{kind: ErrCircuitOpen, err: errors.New("x")}, http.StatusServiceUnavailable},
+		{"agent error", &AgentError{StatusCode: 400, Message: "bad"}, http.StatusBadGateway},
+		{"timeout", &requestError{kind: ErrUnreachable, err: context.DeadlineExceeded}, http.StatusGatewayTimeout},
+		{"unreachable", &requestError{kind: ErrUnreachable, err: errors.New("refused")}, http.StatusBadGateway},
+		{"invalid response", &requestError{kind: ErrInvalidResponse, err: errors.New("x")}, http.StatusBadGateway},
+		{"database", &requestError{kind: errDatabase, err: errors.New("x")}, http.StatusInternalServerError},
+		{"other", errors.New("x"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := Describe(tt.err); got != tt.want {
+				t.Errorf("Describe(%v) status = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package agentclient
+
+import "context"
+
+// StatusResponse is what most agent actions answer with
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+// MessageResponse is a status with an explanation
+type MessageResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// BasicInfo is the hostname and timezone of a device
+type BasicInfo struct {
+	Hostname string `json:"hostname"`
+	Timezone string `json:"timezone"`
+}
+
+// PasswordChange sets the password of a local user
+type PasswordChange struct {
+	Username string `json:"username"`
+	New      string `json:"new"`
+}
+
+// CommandResult is the output of a shell command
+type CommandResult struct {
+	Status string `json:"status"`
+	Output string `json:"output"`
+}
+
+// BasicInfo reads the hostname and timezone
+func (a *Agent) BasicInfo(ctx context.Context) (BasicInfo, error) {
+	var info BasicInfo
+	err := a.get(ctx, "/client/config1/basic", &info)
+	return info, err
+}
+
+// UpdateBasic sets the hostname and timezone
+func (a *Agent) UpdateBasic(ctx context.Context, info BasicInfo) (StatusResponse, error) {
+	var resp StatusResponse
+	err := a.post(ctx, "/client/config1/basic_update", info, &resp)
+	return resp, err
+}
+
+// Uptime reads how long the device has been up
+func (a *Agent) Uptime(ctx context.Context) (string, error) {
+	var resp struct {
+		Uptime string `json:"uptime"`
+	}
+	err := a.get(ctx, "/client/config1/uptime", &resp)
+	return resp.Uptime, err
+}
+
+// AddSSHKey authorizes a public key for SSH logins
+func (a *Agent) AddSSHKey(ctx context.Context, key string) (MessageResponse, error) {
+	var resp MessageResponse
+	err := a.post(ctx, "/client/config1/ssh", map[string]string{"key": key}, &resp)
+	return resp, err
+}
+
+// ChangePassword sets a local user's password
+func (a *Agent) ChangePassword(ctx context.Context, change PasswordChange) (StatusResponse, error) {
+	var resp StatusResponse
+	err := a.post(ctx, "/client/config1/pass", change, &resp)
+	return resp, err
+}
+
+// RunCommand runs a shell command and returns its output
+func (a *Agent) RunCommand(ctx context.Context, command string) (CommandResult, error) {
+	var resp CommandResult
+	err := a.post(ctx, "/client/config1/cmd", map[string]string{"command": command}, &resp)
+	return resp, err
+}
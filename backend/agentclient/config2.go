@@ -0,0 +1,107 @@
+package agentclient
+
+import "context"
+
+// NetworkUpdate switches the primary interface between dhcp and static
+type NetworkUpdate struct {
+	Method  string `json:"method"`
+	IP      string `json:"ip"`
+	Subnet  string `json:"subnet"`
+	Gateway string `json:"gateway"`
+	DNS     string `json:"dns"`
+}
+
+// InterfaceUpdate brings an interface up or down
+type InterfaceUpdate struct {
+	Interface string `json:"interface"`
+	Status    string `json:"status"`
+}
+
+// RouteUpdate adds or deletes a route
+type RouteUpdate struct {
+	Action      string `json:"action"`
+	Destination string `json:"destination"`
+	Gateway     string `json:"gateway"`
+	Interface   string `json:"interface,omitempty"`
+	Metric      string `json:"metric,omitempty"`
+}
+
+// FirewallUpdate adds or deletes a firewall rule. Linux agents read the
+// first group of fields, Windows agents the second.
+type FirewallUpdate struct {
+	Action      string `json:"action"`
+	Rule        string `json:"rule,omitempty"`
+	Protocol    string `json:"protocol,omitempty"`
+	Port        string `json:"port,omitempty"`
+	Source      string `json:"source,omitempty"`
+	Destination string `json:"destination,omitempty"`
+
+	Name          string `json:"name,omitempty"`
+	DisplayName   string `json:"displayName,omitempty"`
+	Direction     string `json:"direction,omitempty"`
+	ActionType    string `json:"actionType,omitempty"`
+	Enabled       string `json:"enabled,omitempty"`
+	Profile       string `json:"profile,omitempty"`
+	LocalPort     string `json:"localPort,omitempty"`
+	RemotePort    string `json:"remotePort,omitempty"`
+	LocalAddress  string `json:"localAddress,omitempty"`
+	RemoteAddress string `json:"remoteAddress,omitempty"`
+	Program       string `json:"program,omitempty"`
+	Service       string `json:"service,omitempty"`
+}
+
+// Network reads the interfaces and their addresses
+func (a *Agent) Network(ctx context.Context) (interface{}, error) {
+	var resp interface{}
+	err := a.get(ctx, "/client/config2/network", &resp)
+	return resp, err
+}
+
+// Routes reads the routing table
+func (a *Agent) Routes(ctx context.Context) (interface{}, error) {
+	var resp interface{}
+	err := a.get(ctx, "/client/config2/route", &resp)
+	return resp, err
+}
+
+// Firewall reads the firewall rules
+func (a *Agent) Firewall(ctx context.Context) (interface{}, error) {
+	var resp interface{}
+	err := a.get(ctx, "/client/config2/firewall", &resp)
+	return resp, err
+}
+
+// UpdateNetwork changes how the device gets its address
+func (a *Agent) UpdateNetwork(ctx context.Context, update NetworkUpdate) (StatusResponse, error) {
+	var resp StatusResponse
+	err := a.post(ctx, "/client/config2/updatenetwork", update, &resp)
+	return resp, err
+}
+
+// UpdateInterface brings an interface up or down
+func (a *Agent) UpdateInterface(ctx context.Context, update InterfaceUpdate) (StatusResponse, error) {
+	var resp StatusResponse
+	err := a.post(ctx, "/client/config2/updateinterface", update, &resp)
+	return resp, err
+}
+
+// RestartInterfaces restarts networking
+func (a *Agent) RestartInterfaces(ctx context.Context) (interface{}, error) {
+	var resp interface{}
+	err := a.post(ctx, "/client/config2/restartinterface", struct{}{}, &resp)
+	return resp, err
+}
+
+// UpdateRoute adds or deletes a route
+func (a *Agent) UpdateRoute(ctx context.Context, update RouteUpdate) (interface{}, error) {
+	var resp interface{}
+	err := a.post(ctx, "/client/config2/updateroute", update, &resp)
+	return resp, err
+}
+
+// UpdateFirewall adds or deletes a firewall rule
+func (a *Agent) UpdateFirewall(ctx context.Context, update FirewallUpdate) (interface{}, error) {
+	var resp interface{}
+	err := a.post(ctx, "/client/config2/updatefirewall", update, &resp)
+	return resp, err
+}
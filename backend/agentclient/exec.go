@@ -0,0 +1,86 @@
+package agentclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// States of a command started with StartExec
+const (
+	ExecRunning   = "running"
+	ExecExited    = "exited"
+	ExecCancelled = "cancelled"
+	ExecTimedOut  = "timeout"
+)
+
+// ExecStart starts a command that keeps running on the agent after the call.
+// Starting again with the same ID returns the command already running.
+type ExecStart struct {
+	ID         string `json:"id"`
+	Command    string `json:"command"`
+	TimeoutSec int    `json:"timeout_sec,omitempty"` // Agent default is an hour
+}
+
+// ExecStatus is a command's state and its output after the offsets asked for
+type ExecStatus struct {
+	ID            string     `json:"id"`
+	State         string     `json:"state"`
+	ExitCode      *int       `json:"exit_code"`
+	Error         string     `json:"error"`
+	StartedAt     time.Time  `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+	Stdout        string     `json:"stdout"`
+	Stderr        string     `json:"stderr"`
+	StdoutOffset  int64      `json:"stdout_offset"`
+	StderrOffset  int64      `json:"stderr_offset"`
+	StdoutDropped int64      `json:"stdout_dropped"`
+	StderrDropped int64      `json:"stderr_dropped"`
+}
+
+// StartExec starts a command in the background. It is safe to retry because
+// the agent deduplicates on the ID.
+func (a *Agent) StartExec(ctx context.Context, start ExecStart) (ExecStatus, error) {
+	var resp ExecStatus
+	err := a.do(ctx, call{
+		method:     http.MethodPost,
+		path:       "/client/config1/exec/start",
+		body:       start,
+		timeout:    actionTimeout,
+		idempotent: true,
+	}, &resp)
+	return resp, err
+}
+
+// ExecStatus reads a background command's state and the output written since
+// the given offsets
+func (a *Agent) ExecStatus(ctx context.Context, id string, stdoutOffset, stderrOffset int64) (ExecStatus, error) {
+	var resp ExecStatus
+	err := a.do(ctx, call{
+		method: http.MethodGet,
+		path:   "/client/config1/exec/status",
+		query: url.Values{
+			"id":            {id},
+			"stdout_offset": {strconv.FormatInt(stdoutOffset, 10)},
+			"stderr_offset": {strconv.FormatInt(stderrOffset, 10)},
+		},
+		timeout:    readTimeout,
+		idempotent: true,
+	}, &resp)
+	return resp, err
+}
+
+// CancelExec kills a background command and everything it started
+func (a *Agent) CancelExec(ctx context.Context, id string) (ExecStatus, error) {
+	var resp ExecStatus
+	err := a.do(ctx, call{
+		method:     http.MethodPost,
+		path:       "/client/config1/exec/cancel",
+		body:       map[string]string{"id": id},
+		timeout:    actionTimeout,
+		idempotent: true,
+	}, &resp)
+	return resp, err
+}
@@ -0,0 +1,89 @@
+package agentclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// LogQuery selects log lines. Date and time filters make the agent return
+// entries from that point on.
+type LogQuery struct {
+	Lines int
+	Date  string // YYYY-MM-DD
+	Time  string // HH:MM:SS
+}
+
+// OptimizeResult is the outcome of a cleanup, which can be "partial"
+type OptimizeResult struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// Health reads CPU, memory, disk and network usage
+func (a *Agent) Health(ctx context.Context) (interface{}, error) {
+	var resp interface{}
+	err := a.get(ctx, "/client/health", &resp)
+	return resp, err
+}
+
+// Logs reads system log lines
+func (a *Agent) Logs(ctx context.Context, query LogQuery) (interface{}, error) {
+	lines := query.Lines
+	if lines <= 0 {
+		lines = 100
+	}
+	c := call{
+		method:     http.MethodGet,
+		path:       "/client/log",
+		query:      url.Values{"lines": {strconv.Itoa(lines)}},
+		timeout:    actionTimeout,
+		idempotent: true,
+	}
+	// Filters go in a POST body
+	if query.Date != "" || query.Time != "" {
+		c.method = http.MethodPost
+		c.body = struct {
+			Date string `json:"date,omitempty"`
+			Time string `json:"time,omitempty"`
+		}{query.Date, query.Time}
+	}
+
+	var resp interface{}
+	err := a.do(ctx, c, &resp)
+	return resp, err
+}
+
+// CleanInfo reads how much space a cleanup would free
+func (a *Agent) CleanInfo(ctx context.Context) (interface{}, error) {
+	var resp interface{}
+	err := a.get(ctx, "/client/resource/cleaninfo", &resp)
+	return resp, err
+}
+
+// Optimize removes temporary files and caches
+func (a *Agent) Optimize(ctx context.Context) (OptimizeResult, error) {
+	var resp OptimizeResult
+	err := a.do(ctx, call{
+		method:  http.MethodPost,
+		path:    "/client/resource/optimize",
+		body:    struct{}{},
+		timeout: longTimeout,
+	}, &resp)
+	return resp, err
+}
+
+// Services lists the system services
+func (a *Agent) Services(ctx context.Context) (interface{}, error) {
+	var resp interface{}
+	err := a.get(ctx, "/client/resource/service", &resp)
+	return resp, err
+}
+
+// RestartService restarts a system service
+func (a *Agent) RestartService(ctx context.Context, service string) (interface{}, error) {
+	var resp interface{}
+	err := a.post(ctx, "/client/resource/restartservice", map[string]string{"service": service}, &resp)
+	return resp, err
+}
@@ -0,0 +1,104 @@
+package agentclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// CommandEvent is one event of a streamed command: a chunk of stdout or
+// stderr, and last its exit code
+type CommandEvent struct {
+	Type     string    `json:"type"` // stdout, stderr or exit
+	Data     string    `json:"data,omitempty"`
+	ExitCode *int      `json:"exit_code,omitempty"`
+	State    string    `json:"state,omitempty"` // exited or timeout, on the exit event
+	Error    string    `json:"error,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// CommandStream reads the events of a running command. Closing it before
+// the exit event kills the command on the agent.
+type CommandStream struct {
+	body   io.ReadCloser
+	dec    *json.Decoder
+	cancel context.CancelFunc
+}
+
+// Next waits for the next event. It returns io.EOF after the last one.
+func (s *CommandStream) Next() (CommandEvent, error) {
+	var ev CommandEvent
+	err := s.dec.Decode(&ev)
+	var syntaxErr *json.SyntaxError
+	switch {
+	case err == nil || err == io.EOF:
+	case errors.As(err, &syntaxErr):
+		err = &requestError{kind: ErrInvalidResponse, err: err}
+	default:
+		err = &requestError{kind: ErrUnreachable, err: err}
+	}
+	return ev, err
+}
+
+func (s *CommandStream) Close() error {
+	s.cancel()
+	return s.body.Close()
+}
+
+// StreamCommand runs a shell command and returns its output as the agent
+// writes it. The agent kills the command after timeoutSec (0 for its default
+// of an hour) or when ctx ends.
+func (a *Agent) StreamCommand(ctx context.Context, command string, timeoutSec int) (*CommandStream, error) {
+	c := call{method: http.MethodPost, path: "/client/config1/cmd/stream", timeout: actionTimeout}
+	payload, err := json.Marshal(struct {
+		Command    string `json:"command"`
+		TimeoutSec int    `json:"timeout_sec,omitempty"`
+	}{command, timeoutSec})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	// Not retried: the command may already be running
+	resp, cancel, err := a.openStream(ctx, c, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	return &CommandStream{body: resp.Body, dec: json.NewDecoder(resp.Body), cancel: cancel}, nil
+}
+
+// openStream sends c once and returns the response as soon as the agent
+// answers. c.timeout only covers waiting for the answer; the stream is open
+// until cancel is called or ctx ends.
+func (a *Agent) openStream(ctx context.Context, c call, body io.Reader) (*http.Response, context.CancelFunc, error) {
+	if err := allow(a.Host); err != nil {
+		return nil, nil, err
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	timer := time.AfterFunc(c.timeout, cancel)
+	resp, err := a.request(streamCtx, c, body)
+	// A timer that already fired has cancelled streamCtx, so headers that
+	// raced it in are no use: the body is dead
+	if !timer.Stop() {
+		if err == nil {
+			resp.Body.Close()
+		}
+		err = &requestError{kind: ErrUnreachable, err: fmt.Errorf("no answer within %s: %w", c.timeout, context.DeadlineExceeded)}
+	}
+	if err == nil && resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+		resp.Body.Close()
+		err = responseError(resp.StatusCode, respBody)
+	}
+	report(ctx, a.Host, err)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return resp, cancel, nil
+}
@@ -0,0 +1,127 @@
+package agentclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// streamHandler waits headerDelay before answering, then writes events with
+// eventDelay before each
+func streamHandler(headerDelay, eventDelay time.Duration, events ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(headerDelay):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+
+		for _, ev := range events {
+			select {
+			case <-time.After(eventDelay):
+			case <-r.Context().Done():
+				return
+			}
+			io.WriteString(w, ev+"\n")
+			w.(http.Flusher).Flush()
+		}
+	})
+}
+
+func streamCall(timeout time.Duration) call {
+	return call{method: http.MethodPost, path: "/client/config1/cmd/stream", timeout: timeout}
+}
+
+func openTestStream(a *Agent, timeout time.Duration) (*CommandStream, error) {
+	resp, cancel, err := a.openStream(context.Background(), streamCall(timeout), strings.NewReader(`{}`))
+	if err != nil {
+		return nil, err
+	}
+	return &CommandStream{body: resp.Body, dec: json.NewDecoder(resp.Body), cancel: cancel}, nil
+}
+
+func TestStreamReadsEvents(t *testing.T) {
+	a := newTestAgent(t, streamHandler(0, 0,
+		`{"type":"stdout","data":"hello\n"}`,
+		`{"type":"exit","exit_code":0,"state":"exited"}`))
+
+	stream, err := openTestStream(a, time.Second)
+	if err != nil {
+		t.Fatalf("openStream: %v", err)
+	}
+	defer stream.Close()
+
+	ev, err := stream.Next()
+	if err != nil || ev.Type != "stdout" || ev.Data != "hello\n" {
+		t.Fatalf("first event = %+v, %v", ev, err)
+	}
+	ev, err = stream.Next()
+	if err != nil || ev.Type != "exit" || ev.ExitCode == nil || *ev.ExitCode != 0 {
+		t.Fatalf("exit event = %+v, %v", ev, err)
+	}
+	if _, err := stream.Next(); err != io.EOF {
+		t.Errorf("after the exit event got %v, want io.EOF", err)
+	}
+}
+
+func TestStreamOutlivesHeaderTimeout(t *testing.T) {
+	a := newTestAgent(t, streamHandler(0, 200*time.Millisecond, `{"type":"stdout","data":"late"}`))
+
+	stream, err := openTestStream(a, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("openStream: %v", err)
+	}
+	defer stream.Close()
+
+	// The timeout only covers the wait for headers
+	if ev, err := stream.Next(); err != nil || ev.Data != "late" {
+		t.Errorf("event after the header timeout = %+v, %v", ev, err)
+	}
+}
+
+func TestStreamHeaderTimeout(t *testing.T) {
+	a := newTestAgent(t, streamHandler(time.Second, 0))
+
+	_, err := openTestStream(a, 50*time.Millisecond)
+	if !errors.Is(err, ErrUnreachable) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("openStream error = %v, want a timeout", err)
+	}
+	if _, status := Describe(err); status != http.StatusGatewayTimeout {
+		t.Errorf("timeout described as %d, want %d", status, http.StatusGatewayTimeout)
+	}
+}
+
+func TestStreamAgentError(t *testing.T) {
+	a := newTestAgent(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"status":"failed","message":"command is required"}`)
+	}))
+
+	_, err := openTestStream(a, time.Second)
+	var agentErr *AgentError
+	if !errors.As(err, &agentErr) || agentErr.Message != "command is required" {
+		t.Fatalf("openStream error = %v, want the agent's message", err)
+	}
+}
+
+func TestStreamInvalidEvent(t *testing.T) {
+	a := newTestAgent(t, streamHandler(0, 0, `not json`))
+
+	stream, err := openTestStream(a, time.Second)
+	if err != nil {
+		t.Fatalf("openStream: %v", err)
+	}
+	defer stream.Close()
+
+	if _, err := stream.Next(); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("Next error = %v, want ErrInvalidResponse", err)
+	}
+}
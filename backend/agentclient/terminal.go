@@ -0,0 +1,106 @@
+package agentclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+// TerminalEvent is what the agent sends on a terminal: output, and last the
+// exit code of the shell
+type TerminalEvent struct {
+	Type     string `json:"type"` // output or exit
+	Data     []byte `json:"data,omitempty"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// terminalInput is what is sent to the agent: keystrokes or a new size
+type terminalInput struct {
+	Type string `json:"type"` // input or resize
+	Data []byte `json:"data,omitempty"`
+	Cols int    `json:"cols,omitempty"`
+	Rows int    `json:"rows,omitempty"`
+}
+
+// Terminal is an interactive shell in a pseudo-terminal on the agent. The
+// shell is hung up when the terminal is closed.
+type Terminal struct {
+	mu     sync.Mutex
+	input  *io.PipeWriter
+	enc    *json.Encoder
+	body   io.ReadCloser
+	dec    *json.Decoder
+	cancel context.CancelFunc
+}
+
+// OpenTerminal starts a login shell on the agent with a terminal of the
+// given size
+func (a *Agent) OpenTerminal(ctx context.Context, cols, rows int) (*Terminal, error) {
+	c := call{
+		method:  http.MethodPost,
+		path:    "/client/config1/terminal",
+		query:   url.Values{"cols": {strconv.Itoa(cols)}, "rows": {strconv.Itoa(rows)}},
+		timeout: actionTimeout,
+	}
+
+	// Keystrokes are written to the request body while the response is read
+	pr, pw := io.Pipe()
+	resp, cancel, err := a.openStream(ctx, c, pr)
+	if err != nil {
+		pw.Close()
+		return nil, err
+	}
+
+	return &Terminal{
+		input:  pw,
+		enc:    json.NewEncoder(pw),
+		body:   resp.Body,
+		dec:    json.NewDecoder(resp.Body),
+		cancel: cancel,
+	}, nil
+}
+
+// Input types into the terminal
+func (t *Terminal) Input(p []byte) error {
+	return t.send(terminalInput{Type: "input", Data: p})
+}
+
+func (t *Terminal) Resize(cols, rows int) error {
+	return t.send(terminalInput{Type: "resize", Cols: cols, Rows: rows})
+}
+
+func (t *Terminal) send(in terminalInput) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.enc.Encode(in); err != nil {
+		return &requestError{kind: ErrUnreachable, err: err}
+	}
+	return nil
+}
+
+// Next waits for the next event. It returns io.EOF after the last one.
+func (t *Terminal) Next() (TerminalEvent, error) {
+	var ev TerminalEvent
+	err := t.dec.Decode(&ev)
+	var syntaxErr *json.SyntaxError
+	switch {
+	case err == nil || err == io.EOF:
+	case errors.As(err, &syntaxErr):
+		err = &requestError{kind: ErrInvalidResponse, err: err}
+	default:
+		err = &requestError{kind: ErrUnreachable, err: err}
+	}
+	return ev, err
+}
+
+func (t *Terminal) Close() error {
+	t.input.Close()
+	t.cancel()
+	return t.body.Close()
+}
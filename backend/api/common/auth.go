@@ -10,5 +10,29 @@ func RegisterAuthRoutes(mux *http.ServeMux, queries *generaldb.Queries) {
 	mux.HandleFunc("/api/auth/login", auth.HandleLogin(queries))
 	mux.HandleFunc("/api/auth/refresh", auth.HandleRefresh(queries))
 	mux.HandleFunc("/api/auth/verify", auth.HandleVerify(queries))
+	mux.HandleFunc("/api/auth/logout", auth.HandleLogout(queries))
+	mux.HandleFunc("/api/auth/jwks", auth.HandleJWKS)
+
+	// Session management for the logged-in user
+	mux.HandleFunc("/api/auth/sessions", auth.HandleListSessions(queries))
+	mux.HandleFunc("/api/auth/sessions/revoke", auth.HandleRevokeSession(queries))
+	mux.HandleFunc("/api/auth/sessions/revokeall", auth.HandleRevokeAllSessions(queries))
+
+	// Password change and email reset
+	mux.HandleFunc("/api/auth/password/policy", auth.HandlePasswordPolicy(queries))
+	mux.HandleFunc("/api/auth/password/change", auth.HandleChangePassword(queries))
+	mux.HandleFunc("/api/auth/password/forgot", auth.HandleForgotPassword(queries))
+	mux.HandleFunc("/api/auth/password/reset", auth.HandleResetPassword(queries))
+
+	// Two-factor authentication
+	mux.HandleFunc("/api/auth/login/mfa", auth.HandleMFALogin(queries))
+	mux.HandleFunc("/api/auth/mfa/enroll", auth.HandleMFAEnroll(queries))
+	mux.HandleFunc("/api/auth/mfa/verify", auth.HandleMFAVerify(queries))
+	mux.HandleFunc("/api/auth/mfa/disable", auth.HandleMFADisable(queries))
+	mux.HandleFunc("/api/auth/mfa/status", auth.HandleMFAStatus(queries))
+
+	// Single sign-on (OpenID Connect)
+	mux.HandleFunc("/api/auth/oidc/login", auth.HandleOIDCLogin)
+	mux.HandleFunc("/api/auth/oidc/callback", auth.HandleOIDCCallback(queries))
 
 }
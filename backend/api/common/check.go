@@ -7,7 +7,11 @@ import (
 	"net/http"
 )
 
-func RegisterCheckRoutes(mux *http.ServeMux, queries *serverdb.Queries) {
+func RegisterCheckRoutes(mux *http.ServeMux, queries *serverdb.Queries, authz *config.Authorizer) {
 	mux.HandleFunc("/api/server/check", config.HandleCheck(queries))
-	mux.HandleFunc("/api/server/config1/device", config1.HandleGetAllServers(queries))
+	mux.HandleFunc("/api/server/config1/device", config1.HandleGetAllServers(queries, authz))
+}
+
+func RegisterPermissionRoutes(mux *http.ServeMux, authz *config.Authorizer) {
+	mux.HandleFunc("/api/server/permissions", config.HandleMyPermissions(authz))
 }
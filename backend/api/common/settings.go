@@ -1,14 +1,41 @@
 package common
 
 import (
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
 	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
 	"github.com/kishore-001/ServerManagementSuite/backend/logic/settings"
 	"net/http"
 )
 
-func RegisterSettingsRoutes(mux *http.ServeMux, queries *generaldb.Queries) {
+func RegisterSettingsRoutes(mux *http.ServeMux, queries *generaldb.Queries, authz *config.Authorizer) {
 	mux.HandleFunc("/api/admin/settings/adduser", settings.HandleAddUser(queries))
 	mux.HandleFunc("/api/admin/settings/removeuser", settings.HandleRemoveUser(queries))
 	mux.HandleFunc("/api/admin/settings/listuser", settings.HandleListUsers(queries))
+	mux.HandleFunc("/api/admin/settings/mfa/policy", settings.HandleMFAPolicy(queries))
+	mux.HandleFunc("/api/admin/settings/mfa/reset", settings.HandleResetUserMFA(queries))
+	mux.HandleFunc("/api/admin/settings/sessions", settings.HandleListUserSessions(queries))
+	mux.HandleFunc("/api/admin/settings/sessions/revoke", settings.HandleRevokeUserSessions(queries))
+	mux.HandleFunc("/api/admin/settings/lockout/policy", settings.HandleLockoutPolicy(queries))
+	mux.HandleFunc("/api/admin/settings/lockout/list", settings.HandleListLockouts(queries))
+	mux.HandleFunc("/api/admin/settings/lockout/unlock", settings.HandleUnlock(queries))
+	mux.HandleFunc("/api/admin/settings/password/policy", settings.HandlePasswordPolicy(queries))
+
+	// Roles and permissions
+	mux.HandleFunc("/api/admin/settings/roles", settings.HandleListRoles(queries))
+	mux.HandleFunc("/api/admin/settings/roles/save", settings.HandleSaveRole(queries, authz))
+	mux.HandleFunc("/api/admin/settings/roles/delete", settings.HandleDeleteRole(queries, authz))
+	mux.HandleFunc("/api/admin/settings/roles/setprimary", settings.HandleUpdateUserRole(queries, authz))
+	mux.HandleFunc("/api/admin/settings/roles/assignments", settings.HandleListRoleAssignments(queries))
+	mux.HandleFunc("/api/admin/settings/roles/assign", settings.HandleAssignRole(queries, authz))
+	mux.HandleFunc("/api/admin/settings/roles/unassign", settings.HandleUnassignRole(queries, authz))
+
+	// Personal API tokens (any user, for their own tokens)
+	mux.HandleFunc("/api/admin/settings/apitokens", settings.HandleListAPITokens(queries, authz))
+	mux.HandleFunc("/api/admin/settings/apitokens/create", settings.HandleCreateAPIToken(queries))
+	mux.HandleFunc("/api/admin/settings/apitokens/revoke", settings.HandleRevokeAPIToken(queries, authz))
+
+	// Audit log of every /api/admin/ call
+	mux.HandleFunc("/api/admin/settings/audit", settings.HandleListAudit(queries))
+	mux.HandleFunc("/api/admin/settings/audit/export", settings.HandleExportAudit(queries))
 
 }
@@ -0,0 +1,13 @@
+package server
+
+import (
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/agenttls"
+	"net/http"
+)
+
+// Register agent certificate routes (admin)
+func RegisterAgentTLSRoutes(mux *http.ServeMux, queries *serverdb.Queries) {
+	mux.HandleFunc("/api/admin/server/tls/provision", agenttls.HandleProvision(queries))
+	mux.HandleFunc("/api/admin/server/tls/ca", agenttls.HandleCACertificate())
+}
@@ -1,18 +1,19 @@
 package server
 
 import (
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
 	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
 	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/alert"
 	"net/http"
 )
 
 // Register alert routes
-func RegisterAlertRoutes(mux *http.ServeMux, queries *serverdb.Queries) {
+func RegisterAlertRoutes(mux *http.ServeMux, queries *serverdb.Queries, authz *config.Authorizer) {
 	// Existing route
-	mux.HandleFunc("/api/server/alerts", alert.HandleListAlerts(queries))
+	mux.HandleFunc("/api/server/alerts", alert.HandleListAlerts(queries, authz))
 
 	// New routes for status management
-	mux.HandleFunc("/api/server/alerts/markseen", alert.HandleMarkAlertsAsSeen(queries))
-	mux.HandleFunc("/api/server/alerts/marksingleseen", alert.HandleMarkSingleAlertAsSeen(queries))
-	mux.HandleFunc("/api/server/alerts/delete", alert.HandleDeleteAlerts(queries))
+	mux.HandleFunc("/api/server/alerts/markseen", alert.HandleMarkAlertsAsSeen(queries, authz))
+	mux.HandleFunc("/api/server/alerts/marksingleseen", alert.HandleMarkSingleAlertAsSeen(queries, authz))
+	mux.HandleFunc("/api/server/alerts/delete", alert.HandleDeleteAlerts(queries, authz))
 }
@@ -1,17 +1,20 @@
 package server
 
 import (
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
 	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
 	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/config1"
 	"net/http"
 )
 
-func RegisterConfig1Routes(mux *http.ServeMux, queries *serverdb.Queries) {
+func RegisterConfig1Routes(mux *http.ServeMux, queries *serverdb.Queries, authz *config.Authorizer) {
 	mux.HandleFunc("/api/admin/server/config1/basic", config1.HandleBasic(queries))
 	mux.HandleFunc("/api/admin/server/config1/basic_update", config1.HandleBasicChange(queries))
-	mux.HandleFunc("/api/admin/server/config1/create", config1.HandleCreateServer(queries))
+	mux.HandleFunc("/api/admin/server/config1/create", config1.HandleCreateServer(queries, authz))
 	mux.HandleFunc("/api/admin/server/config1/delete", config1.HandleDeleteServer(queries))
+	mux.HandleFunc("/api/admin/server/config1/update", config1.HandleUpdateDevice(queries, authz))
 	mux.HandleFunc("/api/admin/server/config1/cmd", config1.HandleCommand(queries))
+	mux.HandleFunc("/api/admin/server/config1/cmd/stream", config1.HandleCommandStream(queries))
 	mux.HandleFunc("/api/admin/server/config1/pass", config1.HandlePasswordChange(queries))
 	mux.HandleFunc("/api/admin/server/config1/ssh", config1.HandleSSHKeyManagement(queries))
 	mux.HandleFunc("/api/admin/server/config1/overview", config1.HandleServerOverview(queries))
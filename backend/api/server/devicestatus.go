@@ -0,0 +1,13 @@
+package server
+
+import (
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/devicestatus"
+	"net/http"
+)
+
+// Register device status routes (protected)
+func RegisterDeviceStatusRoutes(mux *http.ServeMux, queries *serverdb.Queries, authz *config.Authorizer) {
+	mux.HandleFunc("/api/server/status/history", devicestatus.HandleHistory(queries, authz))
+}
@@ -0,0 +1,14 @@
+package server
+
+import (
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/devicetoken"
+	"net/http"
+)
+
+// Register device access token rotation and revocation routes (admin)
+func RegisterDeviceTokenRoutes(mux *http.ServeMux, queries *serverdb.Queries) {
+	mux.HandleFunc("/api/admin/server/token/rotate", devicetoken.HandleRotate(queries))
+	mux.HandleFunc("/api/admin/server/token/revoke", devicetoken.HandleRevoke(queries))
+	mux.HandleFunc("/api/admin/server/token/revoked", devicetoken.HandleListRevoked(queries))
+}
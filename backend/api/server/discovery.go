@@ -0,0 +1,15 @@
+package server
+
+import (
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/discovery"
+	"net/http"
+)
+
+// Register subnet discovery routes (admin)
+func RegisterDiscoveryRoutes(mux *http.ServeMux, queries *serverdb.Queries) {
+	mux.HandleFunc("/api/admin/server/discovery/scan", discovery.HandleStartScan(queries))
+	mux.HandleFunc("/api/admin/server/discovery/jobs", discovery.HandleListJobs(queries))
+	mux.HandleFunc("/api/admin/server/discovery/job", discovery.HandleGetJob(queries))
+	mux.HandleFunc("/api/admin/server/discovery/cancel", discovery.HandleCancelJob(queries))
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/enroll"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/reverse"
+	"net/http"
+)
+
+// Register enrollment code and approval routes (admin)
+func RegisterEnrollRoutes(mux *http.ServeMux, queries *serverdb.Queries) {
+	mux.HandleFunc("/api/admin/server/enroll/codes", enroll.HandleListCodes(queries))
+	mux.HandleFunc("/api/admin/server/enroll/codes/create", enroll.HandleCreateCode(queries))
+	mux.HandleFunc("/api/admin/server/enroll/codes/delete", enroll.HandleDeleteCode(queries))
+	mux.HandleFunc("/api/admin/server/enroll/requests", enroll.HandleListEnrollments(queries))
+	mux.HandleFunc("/api/admin/server/enroll/approve", enroll.HandleApprove(queries))
+	mux.HandleFunc("/api/admin/server/enroll/deny", enroll.HandleDeny(queries))
+}
+
+// Register routes called by agents; they authenticate with their own credentials
+func RegisterAgentRoutes(mux *http.ServeMux, queries *serverdb.Queries) {
+	mux.HandleFunc("/api/agent/enroll", enroll.HandleEnroll(queries))
+	mux.HandleFunc("/api/agent/tunnel", reverse.HandleTunnel(queries))
+}
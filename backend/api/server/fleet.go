@@ -0,0 +1,14 @@
+package server
+
+import (
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/fleet"
+	"net/http"
+)
+
+// Register operations that run on many hosts at once (admin)
+func RegisterFleetRoutes(mux *http.ServeMux, queries *serverdb.Queries, authz *config.Authorizer) {
+	mux.HandleFunc("/api/admin/server/fleet/run", fleet.HandleRun(queries, authz))
+	mux.HandleFunc("/api/admin/server/fleet/operations", fleet.HandleListOperations())
+}
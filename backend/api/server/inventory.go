@@ -0,0 +1,15 @@
+package server
+
+import (
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/inventory"
+	"net/http"
+)
+
+// Register bulk import and export of the device inventory (admin)
+func RegisterInventoryRoutes(mux *http.ServeMux, queries *serverdb.Queries, general *generaldb.Queries, authz *config.Authorizer) {
+	mux.HandleFunc("/api/admin/server/inventory/import", inventory.HandleImport(queries, authz))
+	mux.HandleFunc("/api/admin/server/inventory/export", inventory.HandleExport(queries, general, authz))
+}
@@ -0,0 +1,17 @@
+package server
+
+import (
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/jobs"
+	"net/http"
+)
+
+// Register background job routes (admin)
+func RegisterJobRoutes(mux *http.ServeMux, queries *serverdb.Queries, authz *config.Authorizer) {
+	mux.HandleFunc("/api/admin/server/jobs/submit", jobs.HandleSubmit(queries, authz))
+	mux.HandleFunc("/api/admin/server/jobs/list", jobs.HandleList(queries, authz))
+	mux.HandleFunc("/api/admin/server/jobs/get", jobs.HandleGet(queries, authz))
+	mux.HandleFunc("/api/admin/server/jobs/cancel", jobs.HandleCancel(queries, authz))
+	mux.HandleFunc("/api/admin/server/jobs/operations", jobs.HandleListOperations())
+}
@@ -0,0 +1,19 @@
+package server
+
+import (
+	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/mac"
+	"net/http"
+)
+
+// Register MAC whitelist/blacklist routes
+func RegisterMACRoutes(mux *http.ServeMux, queries *generaldb.Queries) {
+	mux.HandleFunc("/api/admin/server/mac/list", mac.HandleListEntries(queries))
+	mux.HandleFunc("/api/admin/server/mac/create", mac.HandleCreateEntry(queries))
+	mux.HandleFunc("/api/admin/server/mac/update", mac.HandleUpdateEntry(queries))
+	mux.HandleFunc("/api/admin/server/mac/delete", mac.HandleDeleteEntry(queries))
+
+	// MACs reported by agents and the segments where unknown MACs raise alerts
+	mux.HandleFunc("/api/admin/server/mac/sightings", mac.HandleListSightings(queries))
+	mux.HandleFunc("/api/admin/server/mac/segments", mac.HandleSegments(queries))
+}
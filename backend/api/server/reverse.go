@@ -0,0 +1,13 @@
+package server
+
+import (
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/reverse"
+	"net/http"
+)
+
+// Register reverse-connect routes (admin)
+func RegisterReverseRoutes(mux *http.ServeMux, queries *serverdb.Queries) {
+	mux.HandleFunc("/api/admin/server/reverse/set", reverse.HandleSetReverse(queries))
+	mux.HandleFunc("/api/admin/server/reverse/list", reverse.HandleListTunnels(queries))
+}
@@ -0,0 +1,19 @@
+package server
+
+import (
+	"database/sql"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/terminal"
+	"net/http"
+)
+
+// Register web terminal routes. Sessions are opened and managed on the admin
+// mux; the browser then connects its WebSocket on the terminal mux with the
+// one-time ticket it was given.
+func RegisterTerminalRoutes(adminMux, terminalMux *http.ServeMux, db *sql.DB, queries *serverdb.Queries) {
+	adminMux.HandleFunc("/api/admin/server/terminal/open", terminal.HandleOpen(db, queries))
+	adminMux.HandleFunc("/api/admin/server/terminal/sessions", terminal.HandleList(queries))
+	adminMux.HandleFunc("/api/admin/server/terminal/close", terminal.HandleClose(queries))
+
+	terminalMux.HandleFunc("/api/terminal/connect", terminal.HandleConnect(queries))
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	db "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+)
+
+// APITokenPrefix marks personal access tokens so they are never mistaken for JWTs
+const APITokenPrefix = "sms_"
+
+const apiTokenDisplayLength = 12 // Characters kept in token_prefix for listings
+
+// APITokenIdentity is the owner and scope of a validated personal access token
+type APITokenIdentity struct {
+	ID       int32
+	Username string
+	Role     string
+	Scopes   []string // Empty means every permission of the owner
+}
+
+// GenerateAPIToken creates a new personal access token and the short prefix
+// shown in listings
+func GenerateAPIToken() (string, string, error) {
+	bytes := make([]byte, 24)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", err
+	}
+	token := APITokenPrefix + hex.EncodeToString(bytes)
+	return token, token[:apiTokenDisplayLength], nil
+}
+
+// IsAPIToken reports whether a bearer token looks like a personal access token
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// ValidateAPIToken looks up a personal access token, rejects expired ones and
+// records when and from where it was used
+func ValidateAPIToken(ctx context.Context, dbQueries *db.Queries, token, ip string) (*APITokenIdentity, error) {
+	row, err := dbQueries.GetAPITokenByHash(ctx, HashToken(token))
+	if err != nil {
+		return nil, errors.New("invalid API token")
+	}
+
+	if row.ExpiresAt.Valid && row.ExpiresAt.Time.Before(time.Now()) {
+		return nil, errors.New("API token expired")
+	}
+
+	err = dbQueries.TouchAPIToken(ctx, db.TouchAPITokenParams{
+		Ip: ip,
+		ID: row.ID,
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to record API token use for %s: %v", row.Username, err)
+	}
+
+	return &APITokenIdentity{
+		ID:       row.ID,
+		Username: row.Username,
+		Role:     row.Role,
+		Scopes:   row.Scopes,
+	}, nil
+}
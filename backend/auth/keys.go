@@ -0,0 +1,271 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyConfig describes where the token signing keys come from
+type KeyConfig struct {
+	Secret    string // HMAC secret for the active key when no key file provides it
+	KeyID     string // kid of the key new tokens are signed with
+	Algorithm string // HS256, RS256 or EdDSA
+	KeysDir   string // Optional directory of <kid>.key (HMAC) and <kid>.pem (RSA/Ed25519) files
+}
+
+// signingKey is one entry of the key set. Keys without private material
+// (public-only PEM files) can verify tokens but never sign them.
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+type keySet struct {
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+var (
+	keysMu sync.RWMutex
+	keys   *keySet
+)
+
+// ConfigureKeys loads the signing keys. Keeping old keys in KeysDir after
+// switching KeyID lets tokens signed with them stay valid until they expire.
+func ConfigureKeys(cfg KeyConfig) error {
+	if cfg.KeyID == "" {
+		cfg.KeyID = "default"
+	}
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = "HS256"
+	}
+
+	set := &keySet{keys: make(map[string]*signingKey)}
+
+	if cfg.KeysDir != "" {
+		if err := loadKeysDir(set, cfg.KeysDir); err != nil {
+			return err
+		}
+	}
+
+	if _, ok := set.keys[cfg.KeyID]; !ok && cfg.Secret != "" {
+		set.keys[cfg.KeyID] = &signingKey{
+			id:      cfg.KeyID,
+			method:  jwt.SigningMethodHS256,
+			private: []byte(cfg.Secret),
+			public:  []byte(cfg.Secret),
+		}
+	}
+
+	active, ok := set.keys[cfg.KeyID]
+	if !ok {
+		return fmt.Errorf("no key found for active key id %q", cfg.KeyID)
+	}
+	if active.private == nil {
+		return fmt.Errorf("active key %q has no private key", cfg.KeyID)
+	}
+	if active.method.Alg() != cfg.Algorithm {
+		return fmt.Errorf("active key %q is %s, but JWT algorithm is %s", cfg.KeyID, active.method.Alg(), cfg.Algorithm)
+	}
+	set.active = active
+
+	keysMu.Lock()
+	keys = set
+	keysMu.Unlock()
+	return nil
+}
+
+// loadKeysDir reads every <kid>.key and <kid>.pem file in dir
+func loadKeysDir(set *keySet, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read keys directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		ext := filepath.Ext(entry.Name())
+		kid := strings.TrimSuffix(entry.Name(), ext)
+		if ext != ".key" && ext != ".pem" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read key %s: %w", entry.Name(), err)
+		}
+
+		var key *signingKey
+		if ext == ".key" {
+			secret := []byte(strings.TrimSpace(string(data)))
+			key = &signingKey{id: kid, method: jwt.SigningMethodHS256, private: secret, public: secret}
+		} else {
+			key, err = parsePEMKey(kid, data)
+			if err != nil {
+				return fmt.Errorf("failed to parse key %s: %w", entry.Name(), err)
+			}
+		}
+
+		if _, exists := set.keys[kid]; exists {
+			return fmt.Errorf("duplicate key id %q", kid)
+		}
+		set.keys[kid] = key
+	}
+	return nil
+}
+
+// parsePEMKey accepts a PKCS#8 / PKCS#1 private key or a PKIX public key
+func parsePEMKey(kid string, data []byte) (*signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &signingKey{id: kid, method: jwt.SigningMethodRS256, private: k, public: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &signingKey{id: kid, method: jwt.SigningMethodRS256, public: k}, nil
+	case ed25519.PrivateKey:
+		return &signingKey{id: kid, method: jwt.SigningMethodEdDSA, private: k, public: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &signingKey{id: kid, method: jwt.SigningMethodEdDSA, public: k}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+}
+
+// currentKeys returns the loaded key set
+func currentKeys() (*keySet, error) {
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+	if keys == nil {
+		return nil, errors.New("signing keys not configured")
+	}
+	return keys, nil
+}
+
+// signToken signs claims with the active key and stamps its kid in the header
+func signToken(claims jwt.Claims) (string, error) {
+	set, err := currentKeys()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(set.active.method, claims)
+	token.Header["kid"] = set.active.id
+	return token.SignedString(set.active.private)
+}
+
+// verificationKey picks the key named by the token's kid and makes sure the
+// token's algorithm matches it, so an RSA public key is never used as an
+// HMAC secret
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	set, err := currentKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	key := set.active
+	if kid, ok := token.Header["kid"].(string); ok {
+		if key, ok = set.keys[kid]; !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.public, nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// HandleJWKS publishes the public keys so other services can validate
+// access tokens. HMAC keys are secret and never listed.
+func HandleJWKS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	set, err := currentKeys()
+	if err != nil {
+		http.Error(w, "Keys not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	jwks := make([]jwk, 0, len(set.keys))
+	for _, key := range set.keys {
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwks = append(jwks, jwk{
+				Kty: "RSA",
+				Kid: key.id,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks = append(jwks, jwk{
+				Kty: "OKP",
+				Kid: key.id,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	sort.Slice(jwks, func(i, j int) bool { return jwks[i].Kid < jwks[j].Kid })
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": jwks})
+}
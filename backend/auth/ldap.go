@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	db "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+)
+
+const ldapTimeout = 10 * time.Second
+
+// errLDAPInvalidCredentials means the directory rejected the user or password,
+// as opposed to the directory being unreachable
+var errLDAPInvalidCredentials = errors.New("invalid credentials")
+
+// LDAPConfig holds the directory login settings
+type LDAPConfig struct {
+	URL                string // ldap://host:389 or ldaps://host:636
+	StartTLS           bool   // Upgrade ldap:// connections before binding
+	CACertFile         string // Optional PEM bundle for the directory certificate
+	InsecureSkipVerify bool
+
+	BindDN       string // Service account used for the user search; empty binds anonymously
+	BindPassword string
+	BaseDN       string
+	UserFilter   string // %s is replaced by the escaped username, e.g. (uid=%s)
+
+	UsernameAttribute string // uid, or sAMAccountName on Active Directory
+	EmailAttribute    string
+	GroupAttribute    string // memberOf
+
+	// Optional group search for directories without memberOf; %s is the user DN
+	GroupBaseDN string
+	GroupFilter string
+
+	RoleMap     []RoleMapping // Group CN or full DN to role
+	DefaultRole string        // Role for users in no mapped group; empty refuses them
+}
+
+// ldapIdentity is what a successful directory login tells us about the user
+type ldapIdentity struct {
+	username string
+	email    string
+	role     string
+}
+
+type ldapDirectory struct {
+	cfg       LDAPConfig
+	tlsConfig *tls.Config
+}
+
+var ldapDir *ldapDirectory
+
+// ConfigureLDAP enables directory logins. Local accounts keep using their
+// stored password so they work even when the directory is down.
+func ConfigureLDAP(cfg LDAPConfig) error {
+	if cfg.URL == "" || cfg.BaseDN == "" {
+		return errors.New("LDAP URL and base DN are required")
+	}
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(uid=%s)"
+	}
+	if !strings.Contains(cfg.UserFilter, "%s") {
+		return errors.New("LDAP user filter must contain %s")
+	}
+	if cfg.UsernameAttribute == "" {
+		cfg.UsernameAttribute = "uid"
+	}
+	if cfg.EmailAttribute == "" {
+		cfg.EmailAttribute = "mail"
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = "memberOf"
+	}
+	if strings.HasPrefix(strings.ToLower(cfg.URL), "ldaps://") && cfg.StartTLS {
+		return errors.New("StartTLS cannot be used with ldaps://")
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if cfg.CACertFile != "" {
+		pem, err := os.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return fmt.Errorf("read LDAP CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", cfg.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	ldapDir = &ldapDirectory{cfg: cfg, tlsConfig: tlsConfig}
+	return nil
+}
+
+// LDAPEnabled reports whether directory logins are configured
+func LDAPEnabled() bool {
+	return ldapDir != nil
+}
+
+// connect dials the directory, upgrading to TLS when configured
+func (d *ldapDirectory) connect() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(d.cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: ldapTimeout}),
+		ldap.DialWithTLSConfig(d.tlsConfig),
+	)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(ldapTimeout)
+
+	if d.cfg.StartTLS {
+		if err := conn.StartTLS(d.tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("StartTLS: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// authenticate finds the user with the service account, then binds as them
+// to check the password
+func (d *ldapDirectory) authenticate(username, password string) (*ldapIdentity, error) {
+	// An empty password would be an unauthenticated bind, which many
+	// directories accept for any DN
+	if username == "" || password == "" {
+		return nil, errLDAPInvalidCredentials
+	}
+
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if d.cfg.BindDN != "" {
+		err = conn.Bind(d.cfg.BindDN, d.cfg.BindPassword)
+	} else {
+		err = conn.UnauthenticatedBind("")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("service bind: %w", err)
+	}
+
+	attributes := []string{d.cfg.UsernameAttribute, d.cfg.EmailAttribute, d.cfg.GroupAttribute}
+	result, err := conn.Search(ldap.NewSearchRequest(
+		d.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(ldapTimeout.Seconds()), false,
+		fmt.Sprintf(d.cfg.UserFilter, ldap.EscapeFilter(username)),
+		attributes, nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("user search: %w", err)
+	}
+	if result == nil || len(result.Entries) != 1 {
+		// Missing or ambiguous users are both a failed login
+		return nil, errLDAPInvalidCredentials
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, errLDAPInvalidCredentials
+		}
+		return nil, fmt.Errorf("user bind: %w", err)
+	}
+
+	groups := entry.GetAttributeValues(d.cfg.GroupAttribute)
+	if d.cfg.GroupFilter != "" {
+		// Search with the service account again, the user may not be allowed to
+		if d.cfg.BindDN != "" {
+			if err := conn.Bind(d.cfg.BindDN, d.cfg.BindPassword); err != nil {
+				return nil, fmt.Errorf("service bind: %w", err)
+			}
+		}
+		groupBase := d.cfg.GroupBaseDN
+		if groupBase == "" {
+			groupBase = d.cfg.BaseDN
+		}
+		groupResult, err := conn.Search(ldap.NewSearchRequest(
+			groupBase, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, int(ldapTimeout.Seconds()), false,
+			fmt.Sprintf(d.cfg.GroupFilter, ldap.EscapeFilter(entry.DN)),
+			[]string{"dn"}, nil,
+		))
+		if err != nil {
+			return nil, fmt.Errorf("group search: %w", err)
+		}
+		for _, g := range groupResult.Entries {
+			groups = append(groups, g.DN)
+		}
+	}
+
+	canonical := entry.GetAttributeValue(d.cfg.UsernameAttribute)
+	if canonical == "" {
+		canonical = username
+	}
+
+	return &ldapIdentity{
+		username: canonical,
+		email:    entry.GetAttributeValue(d.cfg.EmailAttribute),
+		role:     mapRole(groupNames(groups), d.cfg.RoleMap, d.cfg.DefaultRole),
+	}, nil
+}
+
+// groupNames returns each group DN together with its CN so role maps can use
+// either "CN=SMS Admins,OU=Groups,DC=corp,DC=local" or just "SMS Admins"
+func groupNames(groupDNs []string) []string {
+	names := make([]string, 0, len(groupDNs)*2)
+	for _, groupDN := range groupDNs {
+		names = append(names, groupDN)
+		parsed, err := ldap.ParseDN(groupDN)
+		if err != nil || len(parsed.RDNs) == 0 {
+			continue
+		}
+		for _, attr := range parsed.RDNs[0].Attributes {
+			if strings.EqualFold(attr.Type, "cn") {
+				names = append(names, attr.Value)
+			}
+		}
+	}
+	return names
+}
+
+// errLDAPNoRole means the password was right but no group maps to a role
+var errLDAPNoRole = errors.New("no role is mapped to your directory groups")
+
+// loginWithLDAP checks the password against the directory and creates or
+// refreshes the matching account
+func loginWithLDAP(ctx context.Context, dbQueries *db.Queries, username, password string) (db.User, error) {
+	identity, err := ldapDir.authenticate(username, password)
+	if err != nil {
+		return db.User{}, err
+	}
+	if identity.role == "" {
+		return db.User{}, errLDAPNoRole
+	}
+	return provisionExternalUser(ctx, dbQueries, AuthProviderLDAP, identity.username, identity.email, identity.role)
+}
@@ -0,0 +1,345 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAP protocol operations used by the fake directory (RFC 4511)
+const (
+	ldapBindRequest    = 0
+	ldapBindResponse   = 1
+	ldapUnbindRequest  = 2
+	ldapSearchRequest  = 3
+	ldapSearchEntry    = 4
+	ldapSearchDone     = 5
+	ldapResultSuccess  = 0
+	ldapResultBadLogin = 49
+)
+
+type fakeEntry struct {
+	dn         string
+	attributes map[string][]string
+}
+
+// fakeDirectory is a minimal LDAP server: binds are checked against
+// passwords by DN and searches are answered by their exact filter
+type fakeDirectory struct {
+	passwords map[string]string      // DN -> password; "" binds anonymously
+	results   map[string][]fakeEntry // Filter -> entries
+
+	mu       sync.Mutex
+	binds    []string // DNs of successful binds, in order
+	searches []string // Filters searched, in order
+}
+
+func (d *fakeDirectory) serve(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go d.handle(conn)
+		}
+	}()
+	return "ldap://" + listener.Addr().String()
+}
+
+func (d *fakeDirectory) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case ldapBindRequest:
+			dn := op.Children[1].Value.(string)
+			password := op.Children[2].Data.String()
+			code := int64(ldapResultBadLogin)
+			if want, ok := d.passwords[dn]; (ok && want == password) || (dn == "" && password == "") {
+				code = ldapResultSuccess
+				d.mu.Lock()
+				d.binds = append(d.binds, dn)
+				d.mu.Unlock()
+			}
+			conn.Write(ldapMessage(id, ldapResult(ldapBindResponse, code)).Bytes())
+
+		case ldapSearchRequest:
+			filter, err := ldap.DecompileFilter(op.Children[6])
+			if err != nil {
+				return
+			}
+			d.mu.Lock()
+			d.searches = append(d.searches, filter)
+			d.mu.Unlock()
+
+			for _, entry := range d.results[filter] {
+				conn.Write(ldapMessage(id, searchEntry(entry)).Bytes())
+			}
+			conn.Write(ldapMessage(id, ldapResult(ldapSearchDone, ldapResultSuccess)).Bytes())
+
+		case ldapUnbindRequest:
+			return
+		}
+	}
+}
+
+// seen returns the successful binds and the searches so far
+func (d *fakeDirectory) seen() (binds, searches []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.binds...), append([]string(nil), d.searches...)
+}
+
+func ldapMessage(id int64, op *ber.Packet) *ber.Packet {
+	message := ber.NewSequence("LDAP message")
+	message.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "message ID"))
+	message.AppendChild(op)
+	return message
+}
+
+func ldapResult(tag ber.Tag, code int64) *ber.Packet {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "result")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "result code"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matched DN"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnostic message"))
+	return result
+}
+
+func searchEntry(entry fakeEntry) *ber.Packet {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldapSearchEntry, nil, "search entry")
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "object name"))
+	attributes := ber.NewSequence("attributes")
+	for name, values := range entry.attributes {
+		attribute := ber.NewSequence("attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "value"))
+		}
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+	result.AppendChild(attributes)
+	return result
+}
+
+const (
+	serviceDN = "cn=sms,ou=services,dc=corp,dc=local"
+	aliceDN   = "uid=alice,ou=people,dc=corp,dc=local"
+)
+
+var alice = fakeEntry{dn: aliceDN, attributes: map[string][]string{
+	"uid":      {"alice"},
+	"mail":     {"alice@corp.local"},
+	"memberOf": {"cn=SMS Ops,ou=groups,dc=corp,dc=local", "cn=Staff,ou=groups,dc=corp,dc=local"},
+}}
+
+func newFakeDirectory() *fakeDirectory {
+	return &fakeDirectory{
+		passwords: map[string]string{serviceDN: "service-pass", aliceDN: "alice-pass"},
+		results: map[string][]fakeEntry{
+			"(uid=alice)": {alice},
+			"(uid=Alice)": {alice},
+			"(uid=bob)": {
+				{dn: "uid=bob,ou=people,dc=corp,dc=local"},
+				{dn: "uid=bob,ou=contractors,dc=corp,dc=local"},
+			},
+			"(member=" + aliceDN + ")": {{dn: "cn=Auditors,ou=groups,dc=corp,dc=local"}},
+		},
+	}
+}
+
+// useTestLDAP configures directory logins against dir for the duration of the test
+func useTestLDAP(t *testing.T, dir *fakeDirectory, cfg LDAPConfig) *ldapDirectory {
+	t.Helper()
+	cfg.URL = dir.serve(t)
+	cfg.BaseDN = "dc=corp,dc=local"
+	if cfg.RoleMap == nil {
+		cfg.RoleMap = []RoleMapping{{Group: "SMS Admins", Role: "admin"}, {Group: "sms ops", Role: "operator"}}
+	}
+	previous := ldapDir
+	if err := ConfigureLDAP(cfg); err != nil {
+		t.Fatalf("ConfigureLDAP: %v", err)
+	}
+	t.Cleanup(func() { ldapDir = previous })
+	return ldapDir
+}
+
+func TestLDAPAuthenticate(t *testing.T) {
+	dir := newFakeDirectory()
+	d := useTestLDAP(t, dir, LDAPConfig{BindDN: serviceDN, BindPassword: "service-pass"})
+
+	identity, err := d.authenticate("Alice", "alice-pass")
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	want := ldapIdentity{username: "alice", email: "alice@corp.local", role: "operator"}
+	if *identity != want {
+		t.Errorf("identity = %+v, want %+v", *identity, want)
+	}
+	// The password is checked by binding as the DN the search found
+	if binds, _ := dir.seen(); !reflect.DeepEqual(binds, []string{serviceDN, aliceDN}) {
+		t.Errorf("binds = %v, want the service account, then %s", binds, aliceDN)
+	}
+}
+
+func TestLDAPAuthenticateRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		password string
+	}{
+		{"wrong password", "alice", "guess"},
+		{"unknown user", "carol", "alice-pass"},
+		{"ambiguous user", "bob", "alice-pass"},
+		{"empty password", "alice", ""},
+		{"empty username", "", "alice-pass"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := useTestLDAP(t, newFakeDirectory(), LDAPConfig{BindDN: serviceDN, BindPassword: "service-pass"})
+			if _, err := d.authenticate(tt.username, tt.password); !errors.Is(err, errLDAPInvalidCredentials) {
+				t.Errorf("authenticate error = %v, want errLDAPInvalidCredentials", err)
+			}
+		})
+	}
+}
+
+func TestLDAPEmptyPasswordNeverBinds(t *testing.T) {
+	dir := newFakeDirectory()
+	d := useTestLDAP(t, dir, LDAPConfig{BindDN: serviceDN, BindPassword: "service-pass"})
+	d.authenticate("alice", "")
+	if binds, searches := dir.seen(); len(binds) != 0 || len(searches) != 0 {
+		t.Errorf("empty password reached the directory: binds %v, searches %v", binds, searches)
+	}
+}
+
+func TestLDAPFilterIsEscaped(t *testing.T) {
+	dir := newFakeDirectory()
+	d := useTestLDAP(t, dir, LDAPConfig{BindDN: serviceDN, BindPassword: "service-pass"})
+
+	// Unescaped, this would match every user and pick alice
+	if _, err := d.authenticate("*", "alice-pass"); !errors.Is(err, errLDAPInvalidCredentials) {
+		t.Errorf("authenticate(*) error = %v, want errLDAPInvalidCredentials", err)
+	}
+	d.authenticate("a)(uid=alice", "alice-pass")
+
+	want := []string{`(uid=\2a)`, `(uid=a\29\28uid=alice)`}
+	if _, searches := dir.seen(); !reflect.DeepEqual(searches, want) {
+		t.Errorf("searches = %v, want %v", searches, want)
+	}
+}
+
+func TestLDAPServiceBindFailure(t *testing.T) {
+	d := useTestLDAP(t, newFakeDirectory(), LDAPConfig{BindDN: serviceDN, BindPassword: "rotated"})
+	_, err := d.authenticate("alice", "alice-pass")
+	if err == nil || errors.Is(err, errLDAPInvalidCredentials) {
+		t.Errorf("authenticate error = %v, want a directory error", err)
+	}
+}
+
+func TestLDAPAnonymousSearch(t *testing.T) {
+	dir := newFakeDirectory()
+	d := useTestLDAP(t, dir, LDAPConfig{})
+	if _, err := d.authenticate("alice", "alice-pass"); err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if binds, _ := dir.seen(); !reflect.DeepEqual(binds, []string{"", aliceDN}) {
+		t.Errorf("binds = %v, want an anonymous bind, then %s", binds, aliceDN)
+	}
+}
+
+func TestLDAPGroupSearch(t *testing.T) {
+	dir := newFakeDirectory()
+	d := useTestLDAP(t, dir, LDAPConfig{
+		BindDN:       serviceDN,
+		BindPassword: "service-pass",
+		GroupFilter:  "(member=%s)",
+		RoleMap:      []RoleMapping{{Group: "Auditors", Role: "viewer"}},
+	})
+
+	identity, err := d.authenticate("alice", "alice-pass")
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if identity.role != "viewer" {
+		t.Errorf("role = %q, want viewer", identity.role)
+	}
+	// The group search runs as the service account again
+	if binds, _ := dir.seen(); !reflect.DeepEqual(binds, []string{serviceDN, aliceDN, serviceDN}) {
+		t.Errorf("binds = %v, want the service account around %s", binds, aliceDN)
+	}
+}
+
+func TestLDAPLoginWithoutRole(t *testing.T) {
+	useTestLDAP(t, newFakeDirectory(), LDAPConfig{
+		BindDN:       serviceDN,
+		BindPassword: "service-pass",
+		RoleMap:      []RoleMapping{{Group: "SMS Admins", Role: "admin"}},
+	})
+
+	if _, err := loginWithLDAP(context.Background(), nil, "alice", "alice-pass"); !errors.Is(err, errLDAPNoRole) {
+		t.Errorf("loginWithLDAP error = %v, want errLDAPNoRole", err)
+	}
+}
+
+func TestConfigureLDAPRejects(t *testing.T) {
+	previous := ldapDir
+	t.Cleanup(func() { ldapDir = previous })
+
+	tests := []struct {
+		name string
+		cfg  LDAPConfig
+	}{
+		{"no URL", LDAPConfig{BaseDN: "dc=corp"}},
+		{"no base DN", LDAPConfig{URL: "ldap://dir"}},
+		{"filter without placeholder", LDAPConfig{URL: "ldap://dir", BaseDN: "dc=corp", UserFilter: "(uid=admin)"}},
+		{"StartTLS on ldaps", LDAPConfig{URL: "LDAPS://dir", BaseDN: "dc=corp", StartTLS: true}},
+		{"missing CA file", LDAPConfig{URL: "ldap://dir", BaseDN: "dc=corp", CACertFile: "/nonexistent/ca.pem"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ConfigureLDAP(tt.cfg); err == nil {
+				t.Error("ConfigureLDAP accepted the config")
+			}
+		})
+	}
+}
+
+func TestGroupNames(t *testing.T) {
+	got := groupNames([]string{
+		"CN=SMS Admins,OU=Groups,DC=corp,DC=local",
+		"ou=not-a-cn,dc=corp",
+		"not a DN",
+	})
+	want := []string{
+		"CN=SMS Admins,OU=Groups,DC=corp,DC=local", "SMS Admins",
+		"ou=not-a-cn,dc=corp",
+		"not a DN",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("groupNames = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strconv"
+	"time"
+
+	db "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+)
+
+// app_settings keys for the lockout policy
+const (
+	SettingLockoutThreshold   = "lockout_threshold"        // Failed logins per username before lockout
+	SettingLockoutIPThreshold = "lockout_ip_threshold"     // Failed logins per source IP before lockout
+	SettingLockoutDuration    = "lockout_duration_minutes" // How long a lockout lasts
+)
+
+// Failure counter scopes
+const (
+	LockoutScopeUser = "user"
+	LockoutScopeIP   = "ip"
+)
+
+const (
+	failureWindow      = 1 * time.Hour   // Counters restart after this long without failures
+	backoffFreeAttempt = 2               // Failures allowed before backoff starts
+	maxBackoff         = 5 * time.Minute // Upper bound for the exponential delay
+	lockoutSweepEvery  = 1 * time.Hour   // How often finished counters are deleted
+)
+
+// LockoutPolicy controls when repeated login failures lock an account or IP
+type LockoutPolicy struct {
+	Threshold       int `json:"threshold"`
+	IPThreshold     int `json:"ip_threshold"`
+	DurationMinutes int `json:"duration_minutes"`
+}
+
+// DefaultLockoutPolicy applies until an admin changes the settings
+var DefaultLockoutPolicy = LockoutPolicy{
+	Threshold:       5,
+	IPThreshold:     20,
+	DurationMinutes: 15,
+}
+
+// LockoutEvent describes a username or IP that just crossed its threshold
+type LockoutEvent struct {
+	Scope       string
+	Username    string
+	IP          string
+	Failures    int
+	LockedUntil time.Time
+}
+
+var lockoutNotifier func(LockoutEvent)
+
+// SetLockoutNotifier registers the callback used to raise alerts on lockouts
+func SetLockoutNotifier(notify func(LockoutEvent)) {
+	lockoutNotifier = notify
+}
+
+// LoadLockoutPolicy reads the lockout policy, falling back to the defaults
+func LoadLockoutPolicy(ctx context.Context, dbQueries *db.Queries) (LockoutPolicy, error) {
+	policy := DefaultLockoutPolicy
+	settings := []struct {
+		key   string
+		value *int
+	}{
+		{SettingLockoutThreshold, &policy.Threshold},
+		{SettingLockoutIPThreshold, &policy.IPThreshold},
+		{SettingLockoutDuration, &policy.DurationMinutes},
+	}
+
+	for _, s := range settings {
+		value, err := dbQueries.GetAppSetting(ctx, s.key)
+		if err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			return policy, err
+		}
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			*s.value = n
+		}
+	}
+	return policy, nil
+}
+
+// loginRetryAfter returns how long the caller must wait before trying again,
+// or zero when neither the username nor the source IP is locked
+func loginRetryAfter(ctx context.Context, dbQueries *db.Queries, username, ip string) (time.Duration, error) {
+	var wait time.Duration
+	for _, key := range []db.GetLoginLockParams{
+		{Scope: LockoutScopeUser, Key: username},
+		{Scope: LockoutScopeIP, Key: ip},
+	} {
+		lockedUntil, err := dbQueries.GetLoginLock(ctx, key)
+		if err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		if remaining := time.Until(lockedUntil.Time); lockedUntil.Valid && remaining > wait {
+			wait = remaining
+		}
+	}
+	return wait, nil
+}
+
+// recordLoginFailure bumps the username and IP counters, applies backoff or
+// lockout, and raises an alert the moment a threshold is crossed
+func recordLoginFailure(ctx context.Context, dbQueries *db.Queries, username, ip string) {
+	policy, err := LoadLockoutPolicy(ctx, dbQueries)
+	if err != nil {
+		log.Printf("⚠️ Failed to load lockout policy, using defaults: %v", err)
+	}
+
+	counters := []struct {
+		scope     string
+		key       string
+		threshold int
+	}{
+		{LockoutScopeUser, username, policy.Threshold},
+		{LockoutScopeIP, ip, policy.IPThreshold},
+	}
+
+	for _, c := range counters {
+		failures, err := dbQueries.RecordLoginFailure(ctx, db.RecordLoginFailureParams{
+			Scope:       c.scope,
+			Key:         c.key,
+			ResetBefore: time.Now().Add(-failureWindow),
+		})
+		if err != nil {
+			log.Printf("❌ Failed to record login failure for %s %s: %v", c.scope, c.key, err)
+			continue
+		}
+
+		delay, crossed := failureDelay(int(failures), c.threshold, policy)
+		if delay == 0 {
+			continue
+		}
+
+		lockedUntil := time.Now().Add(delay)
+		err = dbQueries.SetLoginLock(ctx, db.SetLoginLockParams{
+			Scope:       c.scope,
+			Key:         c.key,
+			LockedUntil: sql.NullTime{Time: lockedUntil, Valid: true},
+		})
+		if err != nil {
+			log.Printf("❌ Failed to lock %s %s: %v", c.scope, c.key, err)
+			continue
+		}
+
+		if crossed {
+			log.Printf("🔒 Login locked for %s %s after %d failures (user %s, ip %s)", c.scope, c.key, failures, username, ip)
+			if lockoutNotifier != nil {
+				go lockoutNotifier(LockoutEvent{
+					Scope:       c.scope,
+					Username:    username,
+					IP:          ip,
+					Failures:    int(failures),
+					LockedUntil: lockedUntil,
+				})
+			}
+		}
+	}
+}
+
+// clearLoginFailures resets the username counter after a successful login.
+// The IP counter is left alone so a stuffing run cannot reset itself with
+// one valid credential.
+func clearLoginFailures(ctx context.Context, dbQueries *db.Queries, username string) {
+	_, err := dbQueries.ClearLoginFailures(ctx, db.ClearLoginFailuresParams{
+		Scope: LockoutScopeUser,
+		Key:   username,
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to clear login failures for %s: %v", username, err)
+	}
+}
+
+// StartLockoutSweep deletes failure counters whose window and lockout are
+// both over, so addresses that tried once don't stay in the table forever.
+// A deleted counter starts again at one, as it would have anyway.
+func StartLockoutSweep(dbQueries *db.Queries) {
+	go func() {
+		ticker := time.NewTicker(lockoutSweepEvery)
+		defer ticker.Stop()
+		for range ticker.C {
+			deleted, err := dbQueries.DeleteExpiredLoginFailures(context.Background(), time.Now().Add(-failureWindow))
+			if err != nil {
+				log.Printf("❌ Failed to delete expired login failures: %v", err)
+			} else if deleted > 0 {
+				log.Printf("🧹 Deleted %d expired login failure counter(s)", deleted)
+			}
+		}
+	}()
+}
+
+// failureDelay is how long a counter at failures stays locked, and whether
+// this failure is the one that crossed threshold
+func failureDelay(failures, threshold int, policy LockoutPolicy) (time.Duration, bool) {
+	if failures >= threshold {
+		return time.Duration(policy.DurationMinutes) * time.Minute, failures == threshold
+	}
+	return backoffDelay(failures), false
+}
+
+// backoffDelay doubles the wait for every failure past the free attempts
+func backoffDelay(failures int) time.Duration {
+	if failures <= backoffFreeAttempt {
+		return 0
+	}
+	shift := failures - backoffFreeAttempt - 1
+	if shift > 16 {
+		return maxBackoff
+	}
+	delay := time.Second << uint(shift)
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{10, 128 * time.Second},
+		{11, 256 * time.Second},
+		{12, maxBackoff},
+		{19, maxBackoff},
+		{20, maxBackoff},
+		{1000, maxBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := backoffDelay(tt.failures); got != tt.want {
+			t.Errorf("backoffDelay(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestFailureDelay(t *testing.T) {
+	policy := LockoutPolicy{Threshold: 5, IPThreshold: 20, DurationMinutes: 15}
+	lockout := 15 * time.Minute
+
+	tests := []struct {
+		name        string
+		failures    int
+		threshold   int
+		wantDelay   time.Duration
+		wantCrossed bool
+	}{
+		{"free attempt", 1, policy.Threshold, 0, false},
+		{"backoff below threshold", 4, policy.Threshold, 2 * time.Second, false},
+		{"reaches user threshold", 5, policy.Threshold, lockout, true},
+		{"past user threshold", 6, policy.Threshold, lockout, false},
+		{"ip below threshold", 5, policy.IPThreshold, 4 * time.Second, false},
+		{"ip backoff capped", 19, policy.IPThreshold, maxBackoff, false},
+		{"reaches ip threshold", 20, policy.IPThreshold, lockout, true},
+		{"threshold of one", 1, 1, lockout, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, crossed := failureDelay(tt.failures, tt.threshold, policy)
+			if delay != tt.wantDelay || crossed != tt.wantCrossed {
+				t.Errorf("failureDelay(%d, %d) = %s, %v; want %s, %v",
+					tt.failures, tt.threshold, delay, crossed, tt.wantDelay, tt.wantCrossed)
+			}
+		})
+	}
+}
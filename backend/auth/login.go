@@ -4,9 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	db "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
 	"golang.org/x/crypto/bcrypt"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -18,7 +23,8 @@ type loginRequest struct {
 type loginResponse struct {
 	Status      string `json:"status"`
 	AccessToken string `json:"access_token,omitempty"`
-	Message     string `json:"message,omitempty"` // Optional for errors
+	MFAToken    string `json:"mfa_token,omitempty"` // Set when a second login step is needed
+	Message     string `json:"message,omitempty"`   // Optional for errors
 }
 
 func writeJSON(w http.ResponseWriter, status int, response loginResponse) {
@@ -27,6 +33,16 @@ func writeJSON(w http.ResponseWriter, status int, response loginResponse) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// writeLockedOut tells the client how long to wait before the next attempt
+func writeLockedOut(w http.ResponseWriter, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	writeJSON(w, http.StatusTooManyRequests, loginResponse{
+		Status:  "error",
+		Message: fmt.Sprintf("Too many failed login attempts, try again in %d seconds", seconds),
+	})
+}
+
 func HandleLogin(dbQueries *db.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -40,62 +56,75 @@ func HandleLogin(dbQueries *db.Queries) http.HandlerFunc {
 			return
 		}
 
-		user, err := dbQueries.GetUserByName(context.Background(), req.Username)
-		if err == sql.ErrNoRows {
-			writeJSON(w, http.StatusUnauthorized, loginResponse{Status: "error", Message: "Invalid credentials"})
-			return
-		} else if err != nil {
+		// Refuse early while the username or source IP is backing off
+		clientIP := ClientIP(r)
+		wait, err := loginRetryAfter(r.Context(), dbQueries, req.Username, clientIP)
+		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Database error"})
 			return
 		}
-
-		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-			writeJSON(w, http.StatusUnauthorized, loginResponse{Status: "error", Message: "Invalid credentials"})
+		if wait > 0 {
+			writeLockedOut(w, wait)
 			return
 		}
 
-		accessToken, err := GenerateAccessToken(req.Username, user.Role)
-		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Failed to generate access token"})
+		user, err := dbQueries.GetUserByName(context.Background(), req.Username)
+		if err != nil && err != sql.ErrNoRows {
+			writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Database error"})
 			return
 		}
+		known := err == nil
 
-		var refreshToken string
-		existingSession, err := dbQueries.GetValidRefreshTokenByUser(context.Background(), req.Username)
-
-		if err == sql.ErrNoRows {
-			refreshToken, err = GenerateRefreshToken()
-			if err != nil {
-				writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Failed to generate refresh token"})
+		switch {
+		case known && user.AuthProvider == AuthProviderLocal:
+			// Local accounts never depend on the directory (break-glass access)
+			if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+				recordLoginFailure(r.Context(), dbQueries, req.Username, clientIP)
+				writeJSON(w, http.StatusUnauthorized, loginResponse{Status: "error", Message: "Invalid credentials"})
 				return
 			}
 
-			err = dbQueries.SaveRefreshToken(context.Background(), db.SaveRefreshTokenParams{
-				Username:     req.Username,
-				RefreshToken: refreshToken,
-				ExpiresAt:    time.Now().Add(7 * 24 * time.Hour),
-			})
-			if err != nil {
-				writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Failed to save session"})
+		case LDAPEnabled() && (!known || user.AuthProvider == AuthProviderLDAP):
+			user, err = loginWithLDAP(r.Context(), dbQueries, req.Username, req.Password)
+			if errors.Is(err, errLDAPInvalidCredentials) {
+				recordLoginFailure(r.Context(), dbQueries, req.Username, clientIP)
+				writeJSON(w, http.StatusUnauthorized, loginResponse{Status: "error", Message: "Invalid credentials"})
+				return
+			} else if errors.Is(err, errLDAPNoRole) || errors.Is(err, errProvisionRefused) {
+				log.Printf("⚠️ LDAP login for %s refused: %v", req.Username, err)
+				writeJSON(w, http.StatusForbidden, loginResponse{Status: "error", Message: err.Error()})
+				return
+			} else if err != nil {
+				log.Printf("❌ LDAP login for %s failed: %v", req.Username, err)
+				writeJSON(w, http.StatusServiceUnavailable, loginResponse{Status: "error", Message: "Directory login unavailable"})
 				return
 			}
-		} else if err != nil {
-			writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Database error"})
+
+		default:
+			// Unknown names are counted too, so lockouts don't reveal which accounts exist
+			recordLoginFailure(r.Context(), dbQueries, req.Username, clientIP)
+			writeJSON(w, http.StatusUnauthorized, loginResponse{Status: "error", Message: "Invalid credentials"})
 			return
-		} else {
-			refreshToken = existingSession.RefreshToken
 		}
 
-		// Set refresh token in HttpOnly cookie
-		http.SetCookie(w, &http.Cookie{
-			Name:     "refresh_token",
-			Value:    refreshToken,
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   false,
-			SameSite: http.SameSiteLaxMode,
-			MaxAge:   7 * 24 * 60 * 60,
-		})
+		// Second factor: users with MFA finish login at /api/auth/login/mfa
+		challenge, err := mfaChallenge(r.Context(), dbQueries, user)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: err.Error()})
+			return
+		}
+		if challenge != nil {
+			writeJSON(w, http.StatusOK, *challenge)
+			return
+		}
+
+		clearLoginFailures(r.Context(), dbQueries, req.Username)
+
+		accessToken, err := startSession(w, r, dbQueries, user.Name, user.Role)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: err.Error()})
+			return
+		}
 
 		// Final successful login response
 		writeJSON(w, http.StatusOK, loginResponse{
@@ -104,3 +133,40 @@ func HandleLogin(dbQueries *db.Queries) http.HandlerFunc {
 		})
 	}
 }
+
+// mfaChallenge runs after the first factor succeeded, for every login method.
+// It returns the MFA step for users with MFA enabled, the enrollment step
+// when the admin policy demands MFA, or nil when a session can start.
+func mfaChallenge(ctx context.Context, dbQueries *db.Queries, user db.User) (*loginResponse, error) {
+	mfaState, err := dbQueries.GetUserMFA(ctx, user.Name)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, errors.New("Database error")
+	}
+
+	if err == nil && mfaState.Enabled {
+		mfaToken, err := GenerateMFAToken(user.Name, user.Role, PurposeMFALogin)
+		if err != nil {
+			return nil, errors.New("Failed to generate MFA token")
+		}
+		return &loginResponse{Status: "mfa_required", MFAToken: mfaToken}, nil
+	}
+
+	// Admins without MFA must enroll first when the policy demands it
+	required, err := MFARequiredFor(ctx, dbQueries, user.Name, user.Role)
+	if err != nil {
+		return nil, errors.New("Database error")
+	}
+	if !required {
+		return nil, nil
+	}
+
+	setupToken, err := GenerateMFAToken(user.Name, user.Role, PurposeMFASetup)
+	if err != nil {
+		return nil, errors.New("Failed to generate MFA token")
+	}
+	return &loginResponse{
+		Status:   "mfa_setup_required",
+		MFAToken: setupToken,
+		Message:  "MFA enrollment is required for admin accounts",
+	}, nil
+}
@@ -0,0 +1,521 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SettingRequireAdminMFA is the app_settings key for the admin MFA policy
+const SettingRequireAdminMFA = "require_admin_mfa"
+
+const recoveryCodeCount = 10
+
+type mfaLoginRequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+type mfaVerifyRequest struct {
+	Code string `json:"code"`
+}
+
+type mfaDisableRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+type mfaResponse struct {
+	Status        string   `json:"status"`
+	Message       string   `json:"message,omitempty"`
+	Secret        string   `json:"secret,omitempty"`
+	OTPAuthURL    string   `json:"otpauth_url,omitempty"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	AccessToken   string   `json:"access_token,omitempty"` // Set when enrollment completes a login
+}
+
+func writeMFAJSON(w http.ResponseWriter, status int, response mfaResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// adminCheck decides who the admin MFA policy applies to. main wires it to
+// the role-based authorizer; without it the primary role decides.
+var adminCheck func(ctx context.Context, username string) (bool, error)
+
+// SetAdminCheck registers how to tell whether a user has admin power
+func SetAdminCheck(check func(ctx context.Context, username string) (bool, error)) {
+	adminCheck = check
+}
+
+// MFARequiredFor reports whether the admin MFA policy is on and applies to
+// the user, judged by their effective permissions rather than the role column
+func MFARequiredFor(ctx context.Context, dbQueries *db.Queries, username, role string) (bool, error) {
+	required, err := AdminMFARequired(ctx, dbQueries)
+	if err != nil || !required {
+		return false, err
+	}
+	if adminCheck != nil {
+		return adminCheck(ctx, username)
+	}
+	return role == "admin", nil
+}
+
+// AdminMFARequired reports whether the admin MFA policy is switched on
+func AdminMFARequired(ctx context.Context, dbQueries *db.Queries) (bool, error) {
+	value, err := dbQueries.GetAppSetting(ctx, SettingRequireAdminMFA)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return value == "true", nil
+}
+
+// HandleMFALogin completes a login for users with MFA enabled
+func HandleMFALogin(dbQueries *db.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, loginResponse{Status: "error", Message: "Method not allowed"})
+			return
+		}
+
+		var req mfaLoginRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MFAToken == "" {
+			writeJSON(w, http.StatusBadRequest, loginResponse{Status: "error", Message: "Invalid request payload"})
+			return
+		}
+
+		claims, err := ValidateMFAToken(req.MFAToken, PurposeMFALogin)
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, loginResponse{Status: "error", Message: "Invalid or expired MFA token"})
+			return
+		}
+
+		// Code guessing counts against the same lockout as password guessing
+		clientIP := ClientIP(r)
+		wait, err := loginRetryAfter(r.Context(), dbQueries, claims.Username, clientIP)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Database error"})
+			return
+		}
+		if wait > 0 {
+			writeLockedOut(w, wait)
+			return
+		}
+
+		if err := checkSecondFactor(r.Context(), dbQueries, claims.Username, req.Code, req.RecoveryCode); err != nil {
+			recordLoginFailure(r.Context(), dbQueries, claims.Username, clientIP)
+			writeJSON(w, http.StatusUnauthorized, loginResponse{Status: "error", Message: err.Error()})
+			return
+		}
+		clearLoginFailures(r.Context(), dbQueries, claims.Username)
+
+		accessToken, err := startSession(w, r, dbQueries, claims.Username, claims.Role)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: err.Error()})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, loginResponse{
+			Status:      "ok",
+			AccessToken: accessToken,
+		})
+	}
+}
+
+// HandleMFAEnroll creates a new (not yet active) TOTP secret for the caller
+func HandleMFAEnroll(dbQueries *db.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeMFAJSON(w, http.StatusMethodNotAllowed, mfaResponse{Status: "error", Message: "Method not allowed"})
+			return
+		}
+
+		claims, err := mfaSubject(r)
+		if err != nil {
+			writeMFAJSON(w, http.StatusUnauthorized, mfaResponse{Status: "error", Message: "Invalid or expired token"})
+			return
+		}
+
+		existing, err := dbQueries.GetUserMFA(r.Context(), claims.Username)
+		if err != nil && err != sql.ErrNoRows {
+			writeMFAJSON(w, http.StatusInternalServerError, mfaResponse{Status: "error", Message: "Database error"})
+			return
+		}
+		if err == nil && existing.Enabled {
+			writeMFAJSON(w, http.StatusConflict, mfaResponse{Status: "error", Message: "MFA is already enabled"})
+			return
+		}
+
+		secret, err := GenerateTOTPSecret()
+		if err != nil {
+			writeMFAJSON(w, http.StatusInternalServerError, mfaResponse{Status: "error", Message: "Failed to generate secret"})
+			return
+		}
+
+		err = dbQueries.UpsertUserMFASecret(r.Context(), db.UpsertUserMFASecretParams{
+			Username:   claims.Username,
+			TotpSecret: secret,
+		})
+		if err != nil {
+			writeMFAJSON(w, http.StatusInternalServerError, mfaResponse{Status: "error", Message: "Failed to save secret"})
+			return
+		}
+
+		writeMFAJSON(w, http.StatusOK, mfaResponse{
+			Status:     "ok",
+			Secret:     secret,
+			OTPAuthURL: TOTPProvisioningURI(secret, claims.Username),
+		})
+	}
+}
+
+// HandleMFAVerify activates a pending secret once the caller proves they can
+// generate codes, and returns a fresh set of recovery codes. When called with
+// a setup token it also finishes the interrupted login.
+func HandleMFAVerify(dbQueries *db.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeMFAJSON(w, http.StatusMethodNotAllowed, mfaResponse{Status: "error", Message: "Method not allowed"})
+			return
+		}
+
+		claims, err := mfaSubject(r)
+		if err != nil {
+			writeMFAJSON(w, http.StatusUnauthorized, mfaResponse{Status: "error", Message: "Invalid or expired token"})
+			return
+		}
+
+		var req mfaVerifyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+			writeMFAJSON(w, http.StatusBadRequest, mfaResponse{Status: "error", Message: "Code is required"})
+			return
+		}
+
+		pending, err := dbQueries.GetUserMFA(r.Context(), claims.Username)
+		if err == sql.ErrNoRows {
+			writeMFAJSON(w, http.StatusBadRequest, mfaResponse{Status: "error", Message: "No MFA enrollment in progress"})
+			return
+		} else if err != nil {
+			writeMFAJSON(w, http.StatusInternalServerError, mfaResponse{Status: "error", Message: "Database error"})
+			return
+		}
+		if pending.Enabled {
+			writeMFAJSON(w, http.StatusConflict, mfaResponse{Status: "error", Message: "MFA is already enabled"})
+			return
+		}
+
+		step, ok := ValidateTOTP(pending.TotpSecret, req.Code, time.Now())
+		if !ok {
+			writeMFAJSON(w, http.StatusUnauthorized, mfaResponse{Status: "error", Message: "Invalid code"})
+			return
+		}
+
+		err = dbQueries.EnableUserMFA(r.Context(), db.EnableUserMFAParams{
+			Username:     claims.Username,
+			LastUsedStep: step,
+		})
+		if err != nil {
+			writeMFAJSON(w, http.StatusInternalServerError, mfaResponse{Status: "error", Message: "Failed to enable MFA"})
+			return
+		}
+
+		codes, err := replaceRecoveryCodes(r.Context(), dbQueries, claims.Username)
+		if err != nil {
+			writeMFAJSON(w, http.StatusInternalServerError, mfaResponse{Status: "error", Message: "Failed to create recovery codes"})
+			return
+		}
+
+		response := mfaResponse{
+			Status:        "ok",
+			Message:       "MFA enabled. Store the recovery codes somewhere safe, they are shown only once.",
+			RecoveryCodes: codes,
+		}
+
+		// Forced enrollment during login: hand out the session now
+		if claims.Purpose == PurposeMFASetup {
+			accessToken, err := startSession(w, r, dbQueries, claims.Username, claims.Role)
+			if err != nil {
+				writeMFAJSON(w, http.StatusInternalServerError, mfaResponse{Status: "error", Message: err.Error()})
+				return
+			}
+			response.AccessToken = accessToken
+		}
+
+		writeMFAJSON(w, http.StatusOK, response)
+	}
+}
+
+// HandleMFADisable turns MFA off after checking the password and a second factor
+func HandleMFADisable(dbQueries *db.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeMFAJSON(w, http.StatusMethodNotAllowed, mfaResponse{Status: "error", Message: "Method not allowed"})
+			return
+		}
+
+		claims, err := ValidateAccessToken(bearerToken(r))
+		if err != nil {
+			writeMFAJSON(w, http.StatusUnauthorized, mfaResponse{Status: "error", Message: "Invalid or expired token"})
+			return
+		}
+
+		var req mfaDisableRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeMFAJSON(w, http.StatusBadRequest, mfaResponse{Status: "error", Message: "Invalid request body"})
+			return
+		}
+
+		user, err := dbQueries.GetUserByName(r.Context(), claims.Username)
+		if err != nil {
+			writeMFAJSON(w, http.StatusUnauthorized, mfaResponse{Status: "error", Message: "User not found"})
+			return
+		}
+
+		required, err := MFARequiredFor(r.Context(), dbQueries, user.Name, user.Role)
+		if err != nil {
+			writeMFAJSON(w, http.StatusInternalServerError, mfaResponse{Status: "error", Message: "Database error"})
+			return
+		}
+		if required {
+			writeMFAJSON(w, http.StatusForbidden, mfaResponse{Status: "error", Message: "MFA is required for admin accounts"})
+			return
+		}
+
+		state, err := dbQueries.GetUserMFA(r.Context(), claims.Username)
+		if err != nil || !state.Enabled {
+			writeMFAJSON(w, http.StatusBadRequest, mfaResponse{Status: "error", Message: "MFA is not enabled for this account"})
+			return
+		}
+
+		// Same lockout and identity providers as every other re-authentication
+		err = ConfirmIdentity(r.Context(), dbQueries, claims.Username, req.Password, req.Code, req.RecoveryCode, ClientIP(r))
+		if errors.Is(err, ErrReauthFailed) {
+			writeMFAJSON(w, http.StatusUnauthorized, mfaResponse{Status: "error", Message: err.Error()})
+			return
+		} else if err != nil {
+			writeMFAJSON(w, http.StatusInternalServerError, mfaResponse{Status: "error", Message: "Database error"})
+			return
+		}
+
+		if err := dbQueries.DeleteUserMFA(r.Context(), claims.Username); err != nil {
+			writeMFAJSON(w, http.StatusInternalServerError, mfaResponse{Status: "error", Message: "Failed to disable MFA"})
+			return
+		}
+		if err := dbQueries.DeleteRecoveryCodes(r.Context(), claims.Username); err != nil {
+			writeMFAJSON(w, http.StatusInternalServerError, mfaResponse{Status: "error", Message: "Failed to remove recovery codes"})
+			return
+		}
+
+		writeMFAJSON(w, http.StatusOK, mfaResponse{Status: "ok", Message: "MFA disabled"})
+	}
+}
+
+// HandleMFAStatus reports the caller's MFA state
+func HandleMFAStatus(dbQueries *db.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMFAJSON(w, http.StatusMethodNotAllowed, mfaResponse{Status: "error", Message: "Method not allowed"})
+			return
+		}
+
+		claims, err := mfaSubject(r)
+		if err != nil {
+			writeMFAJSON(w, http.StatusUnauthorized, mfaResponse{Status: "error", Message: "Invalid or expired token"})
+			return
+		}
+
+		enabled := false
+		state, err := dbQueries.GetUserMFA(r.Context(), claims.Username)
+		if err == nil {
+			enabled = state.Enabled
+		} else if err != sql.ErrNoRows {
+			writeMFAJSON(w, http.StatusInternalServerError, mfaResponse{Status: "error", Message: "Database error"})
+			return
+		}
+
+		remaining, err := dbQueries.CountUnusedRecoveryCodes(r.Context(), claims.Username)
+		if err != nil {
+			writeMFAJSON(w, http.StatusInternalServerError, mfaResponse{Status: "error", Message: "Database error"})
+			return
+		}
+
+		required, err := MFARequiredFor(r.Context(), dbQueries, claims.Username, claims.Role)
+		if err != nil {
+			writeMFAJSON(w, http.StatusInternalServerError, mfaResponse{Status: "error", Message: "Database error"})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":                   "ok",
+			"enabled":                  enabled,
+			"required":                 required,
+			"recovery_codes_remaining": remaining,
+		})
+	}
+}
+
+// checkSecondFactor accepts either a TOTP code or an unused recovery code.
+// TOTP steps are recorded so the same code cannot be replayed.
+func checkSecondFactor(ctx context.Context, dbQueries *db.Queries, username, code, recoveryCode string) error {
+	state, err := dbQueries.GetUserMFA(ctx, username)
+	if err != nil || !state.Enabled {
+		return errors.New("MFA is not enabled for this account")
+	}
+
+	if code != "" {
+		step, ok := ValidateTOTP(state.TotpSecret, code, time.Now())
+		if !ok {
+			return errors.New("Invalid code")
+		}
+		updated, err := dbQueries.UpdateMFALastUsedStep(ctx, db.UpdateMFALastUsedStepParams{
+			Username:     username,
+			LastUsedStep: step,
+		})
+		if err != nil || updated == 0 {
+			return errors.New("Code already used")
+		}
+		return nil
+	}
+
+	if recoveryCode != "" {
+		used, err := dbQueries.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+			Username: username,
+			CodeHash: HashRecoveryCode(recoveryCode),
+		})
+		if err != nil || used == 0 {
+			return errors.New("Invalid recovery code")
+		}
+		return nil
+	}
+
+	return errors.New("Code or recovery code is required")
+}
+
+// ErrReauthFailed is returned by ConfirmIdentity when the password or code
+// is wrong or missing
+var ErrReauthFailed = errors.New("re-authentication failed")
+
+// ConfirmIdentity is the fresh login step in front of especially sensitive
+// actions. Local and LDAP accounts give their password, and a TOTP or
+// recovery code as well when MFA is enabled. SSO accounts have no password
+// here, so they need MFA. Failures count against the login lockout.
+func ConfirmIdentity(ctx context.Context, dbQueries *db.Queries, username, password, code, recoveryCode, ip string) error {
+	wait, err := loginRetryAfter(ctx, dbQueries, username, ip)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return fmt.Errorf("%w: too many failed attempts, try again in %d seconds", ErrReauthFailed, int(wait.Seconds())+1)
+	}
+
+	user, err := dbQueries.GetUserByName(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	mfaEnabled := false
+	if state, err := dbQueries.GetUserMFA(ctx, username); err == nil {
+		mfaEnabled = state.Enabled
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+
+	fail := func(reason string) error {
+		recordLoginFailure(ctx, dbQueries, username, ip)
+		return fmt.Errorf("%w: %s", ErrReauthFailed, reason)
+	}
+
+	switch user.AuthProvider {
+	case AuthProviderLocal:
+		if password == "" || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+			return fail("invalid password")
+		}
+	case AuthProviderLDAP:
+		if password == "" || !LDAPEnabled() {
+			return fail("invalid password")
+		}
+		if _, err := ldapDir.authenticate(username, password); errors.Is(err, errLDAPInvalidCredentials) {
+			return fail("invalid password")
+		} else if err != nil {
+			return err
+		}
+	default:
+		if !mfaEnabled {
+			return fmt.Errorf("%w: enable MFA to confirm your identity", ErrReauthFailed)
+		}
+	}
+
+	if mfaEnabled {
+		if code == "" && recoveryCode == "" {
+			return fmt.Errorf("%w: MFA code is required", ErrReauthFailed)
+		}
+		if err := checkSecondFactor(ctx, dbQueries, username, code, recoveryCode); err != nil {
+			return fail(err.Error())
+		}
+	}
+
+	clearLoginFailures(ctx, dbQueries, username)
+	return nil
+}
+
+// replaceRecoveryCodes discards old recovery codes and stores a new set
+func replaceRecoveryCodes(ctx context.Context, dbQueries *db.Queries, username string) ([]string, error) {
+	codes, err := GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dbQueries.DeleteRecoveryCodes(ctx, username); err != nil {
+		return nil, err
+	}
+
+	for _, code := range codes {
+		err := dbQueries.CreateRecoveryCode(ctx, db.CreateRecoveryCodeParams{
+			Username: username,
+			CodeHash: HashRecoveryCode(code),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
+
+// mfaSubject resolves the caller of an MFA management endpoint. A regular
+// access token works, and so does the setup token handed out when an admin
+// has to enroll before finishing login.
+func mfaSubject(r *http.Request) (*Claims, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, errors.New("missing authorization header")
+	}
+
+	if claims, err := ValidateAccessToken(token); err == nil {
+		return claims, nil
+	}
+	return ValidateMFAToken(token, PurposeMFASetup)
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header
+func bearerToken(r *http.Request) string {
+	authHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
+}
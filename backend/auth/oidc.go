@@ -0,0 +1,324 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/golang-jwt/jwt/v5"
+	db "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+	"golang.org/x/oauth2"
+)
+
+const (
+	oidcStateCookie = "oidc_state"
+	oidcFlowTTL     = 10 * time.Minute
+	purposeOIDCFlow = "oidc_flow" // Never accepted as an access or MFA token
+)
+
+// OIDCConfig holds the single sign-on settings
+type OIDCConfig struct {
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string   // Backend callback, e.g. http://sms:8000/api/auth/oidc/callback
+	FrontendURL   string   // Where the browser lands after login
+	Scopes        []string // Extra scopes besides openid
+	UsernameClaim string   // Claim used as the SMS username
+	RoleClaim     string   // Claim holding groups or roles (string or list)
+	RoleMap       []RoleMapping
+	DefaultRole   string // Role for users matching no mapping; empty refuses them
+}
+
+// oidcFlow is the per-login state kept between the redirect and the
+// callback. It travels in a signed cookie, so any backend instance can finish
+// the login and a restart does not lose logins in progress.
+type oidcFlow struct {
+	State    string `json:"state"`
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+	Purpose  string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+type oidcClient struct {
+	cfg OIDCConfig
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+var oidcSSO *oidcClient
+
+// ConfigureOIDC enables single sign-on. Provider discovery happens on first
+// use so the backend still starts while the IdP is unreachable.
+func ConfigureOIDC(cfg OIDCConfig) {
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = "preferred_username"
+	}
+	if cfg.RoleClaim == "" {
+		cfg.RoleClaim = "groups"
+	}
+	if cfg.FrontendURL == "" {
+		cfg.FrontendURL = "/"
+	}
+	oidcSSO = &oidcClient{cfg: cfg}
+}
+
+// OIDCEnabled reports whether single sign-on is configured
+func OIDCEnabled() bool {
+	return oidcSSO != nil
+}
+
+// setup returns the discovered provider and the OAuth2 client config
+func (c *oidcClient) setup(ctx context.Context) (*oidc.Provider, *oauth2.Config, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.provider == nil {
+		provider, err := oidc.NewProvider(ctx, c.cfg.Issuer)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.provider = provider
+	}
+
+	return c.provider, &oauth2.Config{
+		ClientID:     c.cfg.ClientID,
+		ClientSecret: c.cfg.ClientSecret,
+		RedirectURL:  c.cfg.RedirectURL,
+		Endpoint:     c.provider.Endpoint(),
+		Scopes:       append([]string{oidc.ScopeOpenID}, c.cfg.Scopes...),
+	}, nil
+}
+
+// startFlow creates the PKCE verifier, nonce and state for a new login and
+// signs them into the value of the state cookie
+func startFlow() (oidcFlow, string, error) {
+	state, err := randomHex(16)
+	if err != nil {
+		return oidcFlow{}, "", err
+	}
+	nonce, err := randomHex(16)
+	if err != nil {
+		return oidcFlow{}, "", err
+	}
+	flow := oidcFlow{
+		State:    state,
+		Verifier: oauth2.GenerateVerifier(),
+		Nonce:    nonce,
+		Purpose:  purposeOIDCFlow,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(oidcFlowTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    "snsms-backend",
+		},
+	}
+
+	signed, err := signToken(flow)
+	if err != nil {
+		return oidcFlow{}, "", err
+	}
+	return flow, signed, nil
+}
+
+// finishFlow checks the signed cookie value and that it belongs to state
+func finishFlow(cookieValue, state string) (oidcFlow, bool) {
+	var flow oidcFlow
+	token, err := jwt.ParseWithClaims(cookieValue, &flow, verificationKey)
+	if err != nil || !token.Valid || flow.Purpose != purposeOIDCFlow || flow.State != state {
+		return oidcFlow{}, false
+	}
+	return flow, true
+}
+
+// HandleOIDCLogin redirects the browser to the identity provider
+func HandleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if oidcSSO == nil {
+		http.Error(w, "Single sign-on is not configured", http.StatusNotFound)
+		return
+	}
+
+	_, oauthConfig, err := oidcSSO.setup(r.Context())
+	if err != nil {
+		log.Printf("❌ OIDC discovery failed: %v", err)
+		http.Error(w, "Identity provider unavailable", http.StatusBadGateway)
+		return
+	}
+
+	flow, signedFlow, err := startFlow()
+	if err != nil {
+		http.Error(w, "Failed to start login", http.StatusInternalServerError)
+		return
+	}
+
+	// Binds the callback to this browser
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    signedFlow,
+		Path:     "/api/auth/oidc/",
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(oidcFlowTTL.Seconds()),
+	})
+
+	authURL := oauthConfig.AuthCodeURL(flow.State,
+		oidc.Nonce(flow.Nonce),
+		oauth2.S256ChallengeOption(flow.Verifier),
+	)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// HandleOIDCCallback completes the authorization-code flow, provisions the
+// user and starts a normal session (access token via /api/auth/refresh)
+func HandleOIDCCallback(dbQueries *db.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if oidcSSO == nil {
+			http.Error(w, "Single sign-on is not configured", http.StatusNotFound)
+			return
+		}
+
+		if errParam := r.URL.Query().Get("error"); errParam != "" {
+			http.Error(w, "Login failed at identity provider: "+errParam, http.StatusUnauthorized)
+			return
+		}
+
+		state := r.URL.Query().Get("state")
+		cookie, err := r.Cookie(oidcStateCookie)
+		if err != nil || state == "" {
+			http.Error(w, "Invalid login state", http.StatusBadRequest)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Value: "", Path: "/api/auth/oidc/", MaxAge: -1})
+
+		flow, ok := finishFlow(cookie.Value, state)
+		if !ok {
+			http.Error(w, "Login expired or invalid, please try again", http.StatusBadRequest)
+			return
+		}
+
+		provider, oauthConfig, err := oidcSSO.setup(r.Context())
+		if err != nil {
+			http.Error(w, "Identity provider unavailable", http.StatusBadGateway)
+			return
+		}
+
+		token, err := oauthConfig.Exchange(r.Context(), r.URL.Query().Get("code"), oauth2.VerifierOption(flow.Verifier))
+		if err != nil {
+			log.Printf("❌ OIDC code exchange failed: %v", err)
+			http.Error(w, "Failed to exchange authorization code", http.StatusUnauthorized)
+			return
+		}
+
+		rawIDToken, ok := token.Extra("id_token").(string)
+		if !ok {
+			http.Error(w, "Identity provider returned no ID token", http.StatusUnauthorized)
+			return
+		}
+
+		idToken, err := provider.Verifier(&oidc.Config{ClientID: oidcSSO.cfg.ClientID}).Verify(r.Context(), rawIDToken)
+		if err != nil {
+			log.Printf("❌ OIDC ID token rejected: %v", err)
+			http.Error(w, "Invalid ID token", http.StatusUnauthorized)
+			return
+		}
+		if idToken.Nonce != flow.Nonce {
+			http.Error(w, "Invalid ID token nonce", http.StatusUnauthorized)
+			return
+		}
+
+		var claims map[string]interface{}
+		if err := idToken.Claims(&claims); err != nil {
+			http.Error(w, "Invalid ID token claims", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := provisionOIDCUser(r.Context(), dbQueries, claims)
+		if err != nil {
+			log.Printf("⚠️ OIDC login refused: %v", err)
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		// Same second factor and admin MFA policy as password logins. The
+		// frontend reads the step from the URL fragment and finishes it at
+		// /api/auth/login/mfa or the MFA enrollment endpoints.
+		challenge, err := mfaChallenge(r.Context(), dbQueries, user)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if challenge != nil {
+			fragment := url.Values{"status": {challenge.Status}, "mfa_token": {challenge.MFAToken}}
+			log.Printf("🔑 OIDC login for %s needs %s", user.Name, challenge.Status)
+			http.Redirect(w, r, oidcSSO.cfg.FrontendURL+"#"+fragment.Encode(), http.StatusFound)
+			return
+		}
+
+		if _, err := startSession(w, r, dbQueries, user.Name, user.Role); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("🔑 OIDC login for %s (role %s)", user.Name, user.Role)
+		http.Redirect(w, r, oidcSSO.cfg.FrontendURL, http.StatusFound)
+	}
+}
+
+// provisionOIDCUser maps the ID token claims to an account
+func provisionOIDCUser(ctx context.Context, dbQueries *db.Queries, claims map[string]interface{}) (db.User, error) {
+	username, _ := claims[oidcSSO.cfg.UsernameClaim].(string)
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return db.User{}, fmt.Errorf("ID token has no %s claim", oidcSSO.cfg.UsernameClaim)
+	}
+
+	role := mapOIDCRole(claims[oidcSSO.cfg.RoleClaim])
+	if role == "" {
+		return db.User{}, fmt.Errorf("user %s has no role mapping", username)
+	}
+
+	email, _ := claims["email"].(string)
+	return provisionExternalUser(ctx, dbQueries, AuthProviderOIDC, username, email, role)
+}
+
+// mapOIDCRole picks the role for a claim that may be a string or a list
+func mapOIDCRole(claim interface{}) string {
+	var values []string
+	switch v := claim.(type) {
+	case string:
+		values = append(values, v)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	return mapRole(values, oidcSSO.cfg.RoleMap, oidcSSO.cfg.DefaultRole)
+}
+
+func randomHex(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// useTestKeys signs tokens with an HMAC test key for the duration of the test
+func useTestKeys(t *testing.T, secret string) {
+	t.Helper()
+	keysMu.RLock()
+	previous := keys
+	keysMu.RUnlock()
+	if err := ConfigureKeys(KeyConfig{Secret: secret}); err != nil {
+		t.Fatalf("ConfigureKeys: %v", err)
+	}
+	t.Cleanup(func() {
+		keysMu.Lock()
+		keys = previous
+		keysMu.Unlock()
+	})
+}
+
+// useTestOIDC enables single sign-on for the duration of the test
+func useTestOIDC(t *testing.T, cfg OIDCConfig) {
+	t.Helper()
+	previous := oidcSSO
+	ConfigureOIDC(cfg)
+	t.Cleanup(func() { oidcSSO = previous })
+}
+
+func TestFlowRoundTrip(t *testing.T) {
+	useTestKeys(t, "test-secret")
+
+	flow, cookie, err := startFlow()
+	if err != nil {
+		t.Fatalf("startFlow: %v", err)
+	}
+	if flow.State == "" || flow.Nonce == "" || flow.Verifier == "" || flow.State == flow.Nonce {
+		t.Errorf("flow = %+v", flow)
+	}
+
+	got, ok := finishFlow(cookie, flow.State)
+	if !ok {
+		t.Fatal("finishFlow rejected its own cookie")
+	}
+	if got.State != flow.State || got.Nonce != flow.Nonce || got.Verifier != flow.Verifier {
+		t.Errorf("finishFlow = %+v, want %+v", got, flow)
+	}
+
+	other, _, _ := startFlow()
+	if other.State == flow.State || other.Verifier == flow.Verifier {
+		t.Error("two logins share their state")
+	}
+}
+
+func TestFinishFlowRejects(t *testing.T) {
+	useTestKeys(t, "test-secret")
+	flow, cookie, err := startFlow()
+	if err != nil {
+		t.Fatalf("startFlow: %v", err)
+	}
+
+	expired := flow
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	expiredCookie, _ := signToken(expired)
+
+	otherPurpose := flow
+	otherPurpose.Purpose = "mfa"
+	otherPurposeCookie, _ := signToken(otherPurpose)
+
+	// Change one character inside the signature; the last one may only
+	// carry padding bits
+	i := len(cookie) - 5
+	tampered := cookie[:i] + "A" + cookie[i+1:]
+	if cookie[i] == 'A' {
+		tampered = cookie[:i] + "B" + cookie[i+1:]
+	}
+
+	tests := []struct {
+		name   string
+		cookie string
+		state  string
+	}{
+		{"state from another login", cookie, "0123456789abcdef0123456789abcdef"},
+		{"empty state", cookie, ""},
+		{"tampered cookie", tampered, flow.State},
+		{"expired", expiredCookie, flow.State},
+		{"other purpose", otherPurposeCookie, flow.State},
+		{"not a token", "garbage", flow.State},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := finishFlow(tt.cookie, tt.state); ok {
+				t.Error("finishFlow accepted the cookie")
+			}
+		})
+	}
+
+	// A cookie signed by another backend's key is refused
+	useTestKeys(t, "other-secret")
+	if _, ok := finishFlow(cookie, flow.State); ok {
+		t.Error("finishFlow accepted a cookie signed with another key")
+	}
+}
+
+func TestCallbackRejectsBeforeTheProvider(t *testing.T) {
+	useTestKeys(t, "test-secret")
+	useTestOIDC(t, OIDCConfig{Issuer: "http://idp.invalid"})
+	flow, cookie, err := startFlow()
+	if err != nil {
+		t.Fatalf("startFlow: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		cookie string
+		want   int
+	}{
+		{"error from the provider", "?error=access_denied", cookie, http.StatusUnauthorized},
+		{"no state cookie", "?state=" + flow.State + "&code=x", "", http.StatusBadRequest},
+		{"no state", "?code=x", cookie, http.StatusBadRequest},
+		{"state mismatch", "?state=other&code=x", cookie, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback"+tt.query, nil)
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+			HandleOIDCCallback(nil)(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+
+	// The state cookie is single use even when the login fails
+	r := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?state=other&code=x", nil)
+	r.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: cookie})
+	w := httptest.NewRecorder()
+	HandleOIDCCallback(nil)(w, r)
+	cleared := false
+	for _, c := range w.Result().Cookies() {
+		if c.Name == oidcStateCookie && c.MaxAge < 0 {
+			cleared = true
+		}
+	}
+	if !cleared {
+		t.Error("state cookie not cleared")
+	}
+}
+
+func TestOIDCNotConfigured(t *testing.T) {
+	previous := oidcSSO
+	oidcSSO = nil
+	t.Cleanup(func() { oidcSSO = previous })
+
+	for name, handler := range map[string]http.HandlerFunc{
+		"login":    HandleOIDCLogin,
+		"callback": HandleOIDCCallback(nil),
+	} {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/"+name, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s status = %d, want %d", name, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestMapOIDCRole(t *testing.T) {
+	useTestOIDC(t, OIDCConfig{
+		RoleMap:     []RoleMapping{{Group: "sms-admins", Role: "admin"}, {Group: "sms-ops", Role: "operator"}},
+		DefaultRole: "viewer",
+	})
+
+	tests := []struct {
+		name  string
+		claim interface{}
+		want  string
+	}{
+		{"string claim", "SMS-Ops", "operator"},
+		{"first mapping wins", []interface{}{"sms-ops", "sms-admins"}, "admin"},
+		{"non-string items skipped", []interface{}{42, "sms-ops"}, "operator"},
+		{"no match", []interface{}{"staff"}, "viewer"},
+		{"missing claim", nil, "viewer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mapOIDCRole(tt.claim); got != tt.want {
+				t.Errorf("mapOIDCRole(%v) = %q, want %q", tt.claim, got, tt.want)
+			}
+		})
+	}
+
+	oidcSSO.cfg.DefaultRole = ""
+	if got := mapOIDCRole("staff"); got != "" {
+		t.Errorf("unmapped user got role %q without a default", got)
+	}
+}
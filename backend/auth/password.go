@@ -0,0 +1,461 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	db "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SettingPasswordPolicy is the app_settings key holding the policy as JSON
+const SettingPasswordPolicy = "password_policy"
+
+const (
+	passwordResetTTL         = 30 * time.Minute
+	passwordResetWorkTimeout = time.Minute // Lookups and sending for one forgot-password request
+	maxResetsPerHour         = 3           // Reset emails per user per hour
+	maxPasswordLength        = 72          // bcrypt ignores anything longer
+	minPasswordMinLength     = 6
+)
+
+// PasswordPolicy is enforced whenever a local password is set
+type PasswordPolicy struct {
+	MinLength        int  `json:"min_length"`
+	RequireUpper     bool `json:"require_upper"`
+	RequireLower     bool `json:"require_lower"`
+	RequireDigit     bool `json:"require_digit"`
+	RequireSymbol    bool `json:"require_symbol"`
+	DisallowUsername bool `json:"disallow_username"` // Reject passwords containing the username
+}
+
+// DefaultPasswordPolicy applies until an admin changes the settings
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:        8,
+	RequireUpper:     true,
+	RequireLower:     true,
+	RequireDigit:     true,
+	RequireSymbol:    false,
+	DisallowUsername: true,
+}
+
+// LoadPasswordPolicy reads the password policy, falling back to the defaults
+func LoadPasswordPolicy(ctx context.Context, dbQueries *db.Queries) (PasswordPolicy, error) {
+	policy := DefaultPasswordPolicy
+	value, err := dbQueries.GetAppSetting(ctx, SettingPasswordPolicy)
+	if err == sql.ErrNoRows {
+		return policy, nil
+	} else if err != nil {
+		return policy, err
+	}
+	if err := json.Unmarshal([]byte(value), &policy); err != nil {
+		log.Printf("⚠️ Invalid stored password policy, using defaults: %v", err)
+		return DefaultPasswordPolicy, nil
+	}
+	return policy, nil
+}
+
+// SavePasswordPolicy validates and stores the password policy
+func SavePasswordPolicy(ctx context.Context, dbQueries *db.Queries, policy PasswordPolicy) error {
+	if policy.MinLength < minPasswordMinLength || policy.MinLength > maxPasswordLength {
+		return fmt.Errorf("min_length must be between %d and %d", minPasswordMinLength, maxPasswordLength)
+	}
+	value, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	return dbQueries.UpsertAppSetting(ctx, db.UpsertAppSettingParams{
+		Key:   SettingPasswordPolicy,
+		Value: string(value),
+	})
+}
+
+// Validate returns a user-facing reason when the password breaks the policy
+func (p PasswordPolicy) Validate(username, password string) error {
+	if len(password) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters", p.MinLength)
+	}
+	if len(password) > maxPasswordLength {
+		return fmt.Errorf("password must be at most %d bytes", maxPasswordLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsDigit(c):
+			digit = true
+		case unicode.IsPunct(c) || unicode.IsSymbol(c) || unicode.IsSpace(c):
+			symbol = true
+		}
+	}
+
+	var missing []string
+	if p.RequireUpper && !upper {
+		missing = append(missing, "an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		missing = append(missing, "a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		missing = append(missing, "a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		missing = append(missing, "a symbol")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("password must contain %s", strings.Join(missing, ", "))
+	}
+
+	if p.DisallowUsername && username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		return fmt.Errorf("password must not contain the username")
+	}
+	return nil
+}
+
+// ValidatePassword checks a new password against the stored policy
+func ValidatePassword(ctx context.Context, dbQueries *db.Queries, username, password string) error {
+	policy, err := LoadPasswordPolicy(ctx, dbQueries)
+	if err != nil {
+		return err
+	}
+	return policy.Validate(username, password)
+}
+
+// PasswordResetMail is handed to the mailer registered with SetPasswordResetMailer
+type PasswordResetMail struct {
+	Username  string
+	Email     string
+	Token     string
+	ExpiresAt time.Time
+}
+
+var passwordResetMailer func(PasswordResetMail) error
+
+// SetPasswordResetMailer registers the callback that emails reset tokens
+func SetPasswordResetMailer(send func(PasswordResetMail) error) {
+	passwordResetMailer = send
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+	KeepAPITokens   bool   `json:"keep_api_tokens"` // Leave personal access tokens working
+}
+
+type forgotPasswordRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// HandlePasswordPolicy lets the login and settings pages show the rules
+func HandlePasswordPolicy(dbQueries *db.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, loginResponse{Status: "error", Message: "Method not allowed"})
+			return
+		}
+
+		policy, err := LoadPasswordPolicy(r.Context(), dbQueries)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Database error"})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "ok",
+			"policy": policy,
+		})
+	}
+}
+
+// HandleChangePassword changes the caller's own password and ends their
+// other sessions. Personal access tokens are deleted too unless the request
+// sets keep_api_tokens.
+func HandleChangePassword(dbQueries *db.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, loginResponse{Status: "error", Message: "Method not allowed"})
+			return
+		}
+
+		claims, err := ValidateAccessToken(bearerToken(r))
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, loginResponse{Status: "error", Message: "Invalid or expired token"})
+			return
+		}
+
+		var req changePasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CurrentPassword == "" || req.NewPassword == "" {
+			writeJSON(w, http.StatusBadRequest, loginResponse{Status: "error", Message: "Current and new password are required"})
+			return
+		}
+
+		// The current password is guessable here too, so it shares the login lockout
+		clientIP := ClientIP(r)
+		wait, err := loginRetryAfter(r.Context(), dbQueries, claims.Username, clientIP)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Database error"})
+			return
+		}
+		if wait > 0 {
+			writeLockedOut(w, wait)
+			return
+		}
+
+		user, err := dbQueries.GetUserByName(r.Context(), claims.Username)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Database error"})
+			return
+		}
+		if user.AuthProvider != AuthProviderLocal {
+			writeJSON(w, http.StatusBadRequest, loginResponse{Status: "error", Message: "Password is managed by your identity provider"})
+			return
+		}
+
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+			recordLoginFailure(r.Context(), dbQueries, claims.Username, clientIP)
+			writeJSON(w, http.StatusUnauthorized, loginResponse{Status: "error", Message: "Current password is incorrect"})
+			return
+		}
+
+		if req.NewPassword == req.CurrentPassword {
+			writeJSON(w, http.StatusBadRequest, loginResponse{Status: "error", Message: "New password must differ from the current one"})
+			return
+		}
+		if err := ValidatePassword(r.Context(), dbQueries, user.Name, req.NewPassword); err != nil {
+			writeJSON(w, http.StatusBadRequest, loginResponse{Status: "error", Message: err.Error()})
+			return
+		}
+
+		if err := setPassword(r.Context(), dbQueries, user.Name, req.NewPassword); err != nil {
+			writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Failed to update password"})
+			return
+		}
+
+		// Keep the session that made the change, end the rest along with
+		// the access tokens they issued
+		var ended []int32
+		if claims.Session != 0 {
+			ended, err = dbQueries.DeleteOtherSessionsByUser(r.Context(), db.DeleteOtherSessionsByUserParams{
+				Username: user.Name,
+				ID:       claims.Session,
+			})
+		} else {
+			ended, err = dbQueries.DeleteSessionsByUser(r.Context(), user.Name)
+		}
+		if err != nil {
+			log.Printf("⚠️ Failed to end sessions after password change for %s: %v", user.Name, err)
+		}
+		if err := RevokeSessionTokens(r.Context(), dbQueries, user.Name, ended); err != nil {
+			log.Printf("⚠️ Failed to revoke access tokens after password change for %s: %v", user.Name, err)
+		}
+		message := "Password changed, other sessions have been signed out"
+		if !req.KeepAPITokens {
+			deleteAPITokens(r.Context(), dbQueries, user.Name, "password change")
+			message = "Password changed, other sessions and API tokens have been revoked"
+		}
+		clearLoginFailures(r.Context(), dbQueries, user.Name)
+
+		log.Printf("🔑 Password changed for %s", user.Name)
+		writeJSON(w, http.StatusOK, loginResponse{Status: "ok", Message: message})
+	}
+}
+
+// HandleForgotPassword emails a single-use reset token. The response is the
+// same whether or not the account exists.
+func HandleForgotPassword(dbQueries *db.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, loginResponse{Status: "error", Message: "Method not allowed"})
+			return
+		}
+
+		var req forgotPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, loginResponse{Status: "error", Message: "Invalid request payload"})
+			return
+		}
+		req.Username = strings.TrimSpace(req.Username)
+		req.Email = strings.TrimSpace(req.Email)
+		if req.Username == "" && req.Email == "" {
+			writeJSON(w, http.StatusBadRequest, loginResponse{Status: "error", Message: "Username or email is required"})
+			return
+		}
+
+		// All of the work, lookups included, happens after the response so
+		// its timing is the same whether or not the account exists
+		go func(username, email string) {
+			ctx, cancel := context.WithTimeout(context.Background(), passwordResetWorkTimeout)
+			defer cancel()
+			if err := sendPasswordReset(ctx, dbQueries, username, email); err != nil {
+				log.Printf("❌ Password reset request failed: %v", err)
+			}
+		}(req.Username, req.Email)
+
+		writeJSON(w, http.StatusOK, loginResponse{
+			Status:  "ok",
+			Message: "If the account exists, a reset link has been sent to its email address",
+		})
+	}
+}
+
+// sendPasswordReset creates a token for a local account and hands it to the
+// mailer. It runs after the response has gone out.
+func sendPasswordReset(ctx context.Context, dbQueries *db.Queries, username, email string) error {
+	var user db.User
+	var err error
+	if username != "" {
+		user, err = dbQueries.GetUserByName(ctx, username)
+	} else {
+		user, err = dbQueries.GetUserByEmail(ctx, email)
+	}
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if user.AuthProvider != AuthProviderLocal || user.Email == "" {
+		return nil
+	}
+	if passwordResetMailer == nil {
+		return fmt.Errorf("no mailer configured, cannot reset password for %s", user.Name)
+	}
+
+	recent, err := dbQueries.CountRecentPasswordResets(ctx, db.CountRecentPasswordResetsParams{
+		Username:  user.Name,
+		CreatedAt: time.Now().Add(-time.Hour),
+	})
+	if err != nil {
+		return err
+	}
+	if recent >= maxResetsPerHour {
+		log.Printf("⚠️ Password reset limit reached for %s", user.Name)
+		return nil
+	}
+
+	token, err := randomHex(32)
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(passwordResetTTL)
+	err = dbQueries.CreatePasswordReset(ctx, db.CreatePasswordResetParams{
+		Username:  user.Name,
+		TokenHash: HashToken(token),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	mail := PasswordResetMail{Username: user.Name, Email: user.Email, Token: token, ExpiresAt: expiresAt}
+	if err := passwordResetMailer(mail); err != nil {
+		return fmt.Errorf("failed to send password reset email to %s: %w", user.Name, err)
+	}
+	return nil
+}
+
+// HandleResetPassword sets a new password using an emailed token, signs the
+// user out everywhere and deletes their personal access tokens
+func HandleResetPassword(dbQueries *db.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, loginResponse{Status: "error", Message: "Method not allowed"})
+			return
+		}
+
+		var req resetPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" || req.NewPassword == "" {
+			writeJSON(w, http.StatusBadRequest, loginResponse{Status: "error", Message: "Token and new password are required"})
+			return
+		}
+
+		tokenHash := HashToken(req.Token)
+		username, err := dbQueries.GetPasswordReset(r.Context(), tokenHash)
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusBadRequest, loginResponse{Status: "error", Message: "Reset link is invalid or has expired"})
+			return
+		} else if err != nil {
+			writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Database error"})
+			return
+		}
+
+		// Check the policy before burning the token so the user can retry
+		if err := ValidatePassword(r.Context(), dbQueries, username, req.NewPassword); err != nil {
+			writeJSON(w, http.StatusBadRequest, loginResponse{Status: "error", Message: err.Error()})
+			return
+		}
+
+		// Consuming is atomic, so a token can't be redeemed twice
+		if _, err := dbQueries.ConsumePasswordReset(r.Context(), tokenHash); err == sql.ErrNoRows {
+			writeJSON(w, http.StatusBadRequest, loginResponse{Status: "error", Message: "Reset link is invalid or has expired"})
+			return
+		} else if err != nil {
+			writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Database error"})
+			return
+		}
+
+		if err := setPassword(r.Context(), dbQueries, username, req.NewPassword); err != nil {
+			writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Failed to update password"})
+			return
+		}
+
+		if _, err := dbQueries.DeleteSessionsByUser(r.Context(), username); err != nil {
+			log.Printf("⚠️ Failed to end sessions after password reset for %s: %v", username, err)
+		}
+		if err := RevokeUserTokens(r.Context(), dbQueries, username); err != nil {
+			log.Printf("⚠️ Failed to revoke access tokens after password reset for %s: %v", username, err)
+		}
+		deleteAPITokens(r.Context(), dbQueries, username, "password reset")
+		if err := dbQueries.DeletePasswordResetsByUser(r.Context(), username); err != nil {
+			log.Printf("⚠️ Failed to clear reset tokens for %s: %v", username, err)
+		}
+		clearLoginFailures(r.Context(), dbQueries, username)
+		clearRefreshCookie(w)
+
+		log.Printf("🔑 Password reset for %s", username)
+		writeJSON(w, http.StatusOK, loginResponse{Status: "ok", Message: "Password reset, please log in with your new password"})
+	}
+}
+
+// deleteAPITokens removes every personal access token of a user whose
+// password was replaced, since any of them may be in the wrong hands
+func deleteAPITokens(ctx context.Context, dbQueries *db.Queries, username, reason string) {
+	deleted, err := dbQueries.DeleteAPITokensByUser(ctx, username)
+	if err != nil {
+		log.Printf("⚠️ Failed to delete API tokens after %s for %s: %v", reason, username, err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("🔑 Deleted %d API token(s) of %s after %s", deleted, username, reason)
+	}
+}
+
+// setPassword stores the bcrypt hash of a new local password
+func setPassword(ctx context.Context, dbQueries *db.Queries, username, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return dbQueries.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+		Name:         username,
+		PasswordHash: string(hash),
+	})
+}
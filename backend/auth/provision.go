@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	db "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+)
+
+// Where an account's credentials live (users.auth_provider)
+const (
+	AuthProviderLocal = "local" // bcrypt hash in users.password_hash
+	AuthProviderOIDC  = "oidc"  // OpenID Connect single sign-on
+	AuthProviderLDAP  = "ldap"  // LDAP / Active Directory bind
+)
+
+// errProvisionRefused wraps the reasons an external login cannot be mapped to
+// an account; the message is safe to show to the user
+var errProvisionRefused = errors.New("login refused")
+
+// unusablePasswordHash is stored for external accounts; no password matches it
+const unusablePasswordHash = "!sso"
+
+// RoleMapping maps one IdP group or claim value to an SMS role. The first
+// matching entry wins, so list the most privileged mapping first.
+type RoleMapping struct {
+	Group string
+	Role  string
+}
+
+// ParseRoleMap parses "group=role,group2=role2"
+func ParseRoleMap(value string) ([]RoleMapping, error) {
+	var mappings []RoleMapping
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("invalid role mapping %q", pair)
+		}
+		mappings = append(mappings, RoleMapping{
+			Group: strings.TrimSpace(parts[0]),
+			Role:  strings.TrimSpace(parts[1]),
+		})
+	}
+	return mappings, nil
+}
+
+// mapRole returns the role of the first mapping matching one of the groups
+// (case-insensitive), or defaultRole
+func mapRole(groups []string, mappings []RoleMapping, defaultRole string) string {
+	for _, m := range mappings {
+		for _, g := range groups {
+			if strings.EqualFold(g, m.Group) {
+				return m.Role
+			}
+		}
+	}
+	return defaultRole
+}
+
+// provisionExternalUser creates the account on first login and keeps its role
+// and email in sync with the identity provider afterwards. Accounts owned by
+// another provider (including local break-glass accounts) are never taken over.
+func provisionExternalUser(ctx context.Context, dbQueries *db.Queries, provider, username, email, role string) (db.User, error) {
+	if _, err := dbQueries.GetRole(ctx, role); err == sql.ErrNoRows {
+		return db.User{}, fmt.Errorf("%w: mapped role %q does not exist", errProvisionRefused, role)
+	} else if err != nil {
+		return db.User{}, err
+	}
+
+	if email == "" {
+		email = username + "@sso.local"
+	}
+
+	existing, err := dbQueries.GetUserByName(ctx, username)
+	if err == sql.ErrNoRows {
+		return dbQueries.CreateProvisionedUser(ctx, db.CreateProvisionedUserParams{
+			Name:         username,
+			Role:         role,
+			Email:        email,
+			PasswordHash: unusablePasswordHash,
+			AuthProvider: provider,
+		})
+	} else if err != nil {
+		return db.User{}, err
+	}
+
+	if existing.AuthProvider != provider {
+		return db.User{}, fmt.Errorf("%w: account %s already exists with %s login", errProvisionRefused, username, existing.AuthProvider)
+	}
+
+	err = dbQueries.UpdateProvisionedUser(ctx, db.UpdateProvisionedUserParams{
+		Name:         username,
+		Role:         role,
+		Email:        email,
+		AuthProvider: provider,
+	})
+	if err != nil {
+		return db.User{}, err
+	}
+	if existing.Role != role {
+		// Tokens from earlier logins still carry the old role
+		if err := RevokeUserTokens(ctx, dbQueries, username); err != nil {
+			return db.User{}, err
+		}
+	}
+	existing.Role = role
+	existing.Email = email
+	return existing, nil
+}
@@ -3,10 +3,16 @@ package auth
 import (
 	db "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"log"
 	"net/http"
+	"time"
 )
 
+// HandleRefresh exchanges the refresh cookie for a new access token. The
+// refresh token is rotated on every use; presenting an already rotated token
+// outside the grace period is treated as theft and ends the session.
 func HandleRefresh(dbQueries *db.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -15,16 +21,20 @@ func HandleRefresh(dbQueries *db.Queries) http.HandlerFunc {
 		}
 
 		// Get refresh token from cookie
-		cookie, err := r.Cookie("refresh_token")
-		if err != nil {
+		cookie, err := r.Cookie(refreshCookieName)
+		if err != nil || cookie.Value == "" {
 			http.Error(w, "No refresh token", http.StatusUnauthorized)
 			return
 		}
+		tokenHash := HashToken(cookie.Value)
 
 		// Validate refresh token in database
-		session, err := dbQueries.GetRefreshToken(context.Background(), cookie.Value)
-		if err != nil {
-			http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		session, err := dbQueries.GetSessionByTokenHash(context.Background(), tokenHash)
+		if err == sql.ErrNoRows {
+			handleRotatedToken(w, dbQueries, tokenHash)
+			return
+		} else if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
 			return
 		}
 
@@ -35,13 +45,39 @@ func HandleRefresh(dbQueries *db.Queries) http.HandlerFunc {
 			return
 		}
 
+		// Rotate the refresh token and slide the session expiry forward
+		newRefreshToken, err := GenerateRefreshToken()
+		if err != nil {
+			http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+			return
+		}
+
+		rotated, err := dbQueries.RotateSessionToken(context.Background(), db.RotateSessionTokenParams{
+			NewTokenHash: HashToken(newRefreshToken),
+			IpAddress:    ClientIP(r),
+			UserAgent:    userAgent(r),
+			ExpiresAt:    time.Now().Add(refreshTokenTTL),
+			ID:           session.ID,
+			OldTokenHash: tokenHash,
+		})
+		if err != nil {
+			http.Error(w, "Failed to rotate session", http.StatusInternalServerError)
+			return
+		}
+		if rotated == 0 {
+			// Another request rotated this token first
+			handleRotatedToken(w, dbQueries, tokenHash)
+			return
+		}
+
 		// Generate new access token
-		accessToken, err := GenerateAccessToken(user.Name, user.Role)
+		accessToken, err := GenerateAccessToken(user.Name, user.Role, session.ID)
 		if err != nil {
 			http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 			return
 		}
 
+		setRefreshCookie(w, newRefreshToken)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":       "ok",
@@ -49,3 +85,45 @@ func HandleRefresh(dbQueries *db.Queries) http.HandlerFunc {
 		})
 	}
 }
+
+// handleRotatedToken deals with a refresh token that is no longer current.
+// Within the grace period an access token is still issued (the concurrent
+// request already set the new cookie); after that the session is revoked.
+func handleRotatedToken(w http.ResponseWriter, dbQueries *db.Queries, tokenHash string) {
+	session, err := dbQueries.GetSessionByPreviousTokenHash(context.Background(), tokenHash)
+	if err != nil {
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	if time.Since(session.LastUsedAt) > rotationGracePeriod {
+		log.Printf("⚠️ Refresh token reuse detected for user %s, revoking session %d", session.Username, session.ID)
+		if err := dbQueries.DeleteSessionByID(context.Background(), session.ID); err != nil {
+			log.Printf("❌ Failed to revoke session %d: %v", session.ID, err)
+		}
+		if err := RevokeSessionTokens(context.Background(), dbQueries, session.Username, []int32{session.ID}); err != nil {
+			log.Printf("❌ Failed to revoke access tokens of session %d: %v", session.ID, err)
+		}
+		clearRefreshCookie(w)
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := dbQueries.GetUserByName(context.Background(), session.Username)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	accessToken, err := GenerateAccessToken(user.Name, user.Role, session.ID)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":       "ok",
+		"access_token": accessToken,
+	})
+}
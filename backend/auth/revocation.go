@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	db "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+)
+
+// The revocation list is checked on every API call, so it is served from
+// memory. Revocations are written to Postgres first and the memory copy is
+// reloaded periodically to pick up revocations made by other instances.
+const revocationSyncInterval = 30 * time.Second
+
+var errTokenRevoked = errors.New("token has been revoked")
+
+type revocationList struct {
+	mu       sync.RWMutex
+	tokens   map[string]time.Time // jti -> token expiry
+	users    map[string]time.Time // username -> tokens issued before this are revoked
+	sessions map[int32]time.Time  // sid -> when the session was ended
+}
+
+var revocations = &revocationList{
+	tokens:   make(map[string]time.Time),
+	users:    make(map[string]time.Time),
+	sessions: make(map[int32]time.Time),
+}
+
+// StartRevocationSync loads the revocation list and keeps it in sync with the
+// database until the process exits
+func StartRevocationSync(dbQueries *db.Queries) error {
+	if err := revocations.load(context.Background(), dbQueries); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(revocationSyncInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			ctx := context.Background()
+			if err := revocations.load(ctx, dbQueries); err != nil {
+				log.Printf("❌ Failed to reload token revocations: %v", err)
+				continue
+			}
+			revocations.prune(ctx, dbQueries)
+		}
+	}()
+	return nil
+}
+
+// load merges the database rows into the memory copy and drops entries that
+// can no longer match an unexpired token. Revocations are never lifted, so
+// merging cannot resurrect a token.
+func (l *revocationList) load(ctx context.Context, dbQueries *db.Queries) error {
+	oldest := time.Now().Add(-accessTokenTTL)
+
+	revokedTokens, err := dbQueries.ListRevokedTokens(ctx)
+	if err != nil {
+		return err
+	}
+	userRevocations, err := dbQueries.ListUserTokenRevocations(ctx, oldest)
+	if err != nil {
+		return err
+	}
+	revokedSessions, err := dbQueries.ListRevokedSessions(ctx, oldest)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, t := range revokedTokens {
+		l.tokens[t.Jti] = t.ExpiresAt
+	}
+	for _, u := range userRevocations {
+		if u.RevokedBefore.After(l.users[u.Username]) {
+			l.users[u.Username] = u.RevokedBefore
+		}
+	}
+	for _, sess := range revokedSessions {
+		l.sessions[sess.SessionID] = sess.RevokedAt
+	}
+
+	now := time.Now()
+	for jti, expiresAt := range l.tokens {
+		if expiresAt.Before(now) {
+			delete(l.tokens, jti)
+		}
+	}
+	for username, cutoff := range l.users {
+		if cutoff.Before(oldest) {
+			delete(l.users, username)
+		}
+	}
+	// No token of a session outlives its end by more than accessTokenTTL
+	for sid, revokedAt := range l.sessions {
+		if revokedAt.Before(oldest) {
+			delete(l.sessions, sid)
+		}
+	}
+	return nil
+}
+
+// prune drops rows that can no longer match an unexpired token
+func (l *revocationList) prune(ctx context.Context, dbQueries *db.Queries) {
+	if _, err := dbQueries.DeleteExpiredRevokedTokens(ctx); err != nil {
+		log.Printf("❌ Failed to prune revoked tokens: %v", err)
+	}
+	if _, err := dbQueries.DeleteStaleUserTokenRevocations(ctx, time.Now().Add(-accessTokenTTL)); err != nil {
+		log.Printf("❌ Failed to prune user token revocations: %v", err)
+	}
+	if _, err := dbQueries.DeleteStaleRevokedSessions(ctx, time.Now().Add(-accessTokenTTL)); err != nil {
+		log.Printf("❌ Failed to prune revoked sessions: %v", err)
+	}
+}
+
+// isRevoked reports whether the token's jti or refresh session was revoked,
+// or it was issued before its user's tokens were invalidated
+func (l *revocationList) isRevoked(claims *Claims) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if claims.ID != "" {
+		if _, ok := l.tokens[claims.ID]; ok {
+			return true
+		}
+	}
+	if claims.Session != 0 {
+		if _, ok := l.sessions[claims.Session]; ok {
+			return true
+		}
+	}
+	if cutoff, ok := l.users[claims.Username]; ok {
+		// Tokens without iat cannot prove they are newer than the cutoff
+		if claims.IssuedAt == nil || claims.IssuedAt.Time.Before(cutoff) {
+			return true
+		}
+	}
+	return false
+}
+
+// RevokeAccessToken puts a single access token on the revocation list until
+// it expires
+func RevokeAccessToken(ctx context.Context, dbQueries *db.Queries, claims *Claims) error {
+	if claims.ID == "" {
+		return errors.New("token has no jti")
+	}
+
+	expiresAt := time.Now().Add(accessTokenTTL)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	err := dbQueries.RevokeToken(ctx, db.RevokeTokenParams{
+		Jti:       claims.ID,
+		Username:  claims.Username,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	revocations.mu.Lock()
+	revocations.tokens[claims.ID] = expiresAt
+	revocations.mu.Unlock()
+	return nil
+}
+
+// RevokeUserTokens invalidates every access token issued to username so far.
+// Call it when a user is deleted or their role changes; tokens issued
+// afterwards (for example by a refresh) carry the new role and stay valid.
+func RevokeUserTokens(ctx context.Context, dbQueries *db.Queries, username string) error {
+	// Match the millisecond precision of iat so a token issued right after
+	// this call (e.g. by the login that changed the role) is not caught
+	cutoff := time.Now().Truncate(time.Millisecond)
+
+	err := dbQueries.RevokeUserTokens(ctx, db.RevokeUserTokensParams{
+		Username:      username,
+		RevokedBefore: cutoff,
+	})
+	if err != nil {
+		return err
+	}
+
+	revocations.mu.Lock()
+	if cutoff.After(revocations.users[username]) {
+		revocations.users[username] = cutoff
+	}
+	revocations.mu.Unlock()
+	return nil
+}
+
+// RevokeSessionTokens invalidates the access tokens issued for the given
+// refresh sessions. Call it whenever sessions are ended, so "log out
+// everywhere" also cuts off access tokens that were already handed out.
+func RevokeSessionTokens(ctx context.Context, dbQueries *db.Queries, username string, sessionIDs []int32) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+
+	err := dbQueries.RevokeSessions(ctx, db.RevokeSessionsParams{
+		SessionIds: sessionIDs,
+		Username:   username,
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	revocations.mu.Lock()
+	for _, sid := range sessionIDs {
+		revocations.sessions[sid] = now
+	}
+	revocations.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	db "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+)
+
+const (
+	refreshCookieName = "refresh_token"
+	refreshTokenTTL   = 7 * 24 * time.Hour
+
+	// A rotated-out refresh token is still honoured for this long, so two
+	// tabs refreshing at the same moment do not log each other out
+	rotationGracePeriod = 30 * time.Second
+
+	maxUserAgentLength = 255
+)
+
+// startSession issues the access token and refresh cookie for a fully
+// authenticated user. Every login gets its own session row, so a user can
+// stay signed in on several devices at once.
+func startSession(w http.ResponseWriter, r *http.Request, dbQueries *db.Queries, username, role string) (string, error) {
+	refreshToken, err := GenerateRefreshToken()
+	if err != nil {
+		return "", errors.New("Failed to generate refresh token")
+	}
+
+	sessionID, err := dbQueries.CreateSession(context.Background(), db.CreateSessionParams{
+		Username:  username,
+		TokenHash: HashToken(refreshToken),
+		UserAgent: userAgent(r),
+		IpAddress: ClientIP(r),
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	})
+	if err != nil {
+		return "", errors.New("Failed to save session")
+	}
+
+	accessToken, err := GenerateAccessToken(username, role, sessionID)
+	if err != nil {
+		return "", errors.New("Failed to generate access token")
+	}
+
+	setRefreshCookie(w, refreshToken)
+	return accessToken, nil
+}
+
+// setRefreshCookie stores the refresh token in an HttpOnly cookie
+func setRefreshCookie(w http.ResponseWriter, refreshToken string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    refreshToken,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(refreshTokenTTL.Seconds()),
+	})
+}
+
+// clearRefreshCookie removes the refresh cookie from the browser
+func clearRefreshCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+}
+
+// ClientIP returns the address the request came from, without the port
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// userAgent returns the request's User-Agent, trimmed to fit the sessions table
+func userAgent(r *http.Request) string {
+	ua := r.UserAgent()
+	if len(ua) > maxUserAgentLength {
+		ua = ua[:maxUserAgentLength]
+	}
+	return ua
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	db "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+)
+
+type revokeSessionRequest struct {
+	ID int32 `json:"id"`
+}
+
+type revokeAllSessionsRequest struct {
+	KeepCurrent bool `json:"keep_current"`
+}
+
+// HandleLogout ends the session behind the refresh cookie, revokes the access
+// token sent in the Authorization header (if any) and clears the cookie
+func HandleLogout(dbQueries *db.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, loginResponse{Status: "error", Message: "Method not allowed"})
+			return
+		}
+
+		if cookie, err := r.Cookie(refreshCookieName); err == nil && cookie.Value != "" {
+			session, err := dbQueries.DeleteSessionByTokenHash(context.Background(), HashToken(cookie.Value))
+			if err != nil && err != sql.ErrNoRows {
+				writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Failed to end session"})
+				return
+			}
+			// Other access tokens of the session (other tabs) stop working too
+			if err == nil {
+				if err := RevokeSessionTokens(context.Background(), dbQueries, session.Username, []int32{session.ID}); err != nil {
+					writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Failed to revoke access tokens"})
+					return
+				}
+			}
+		}
+
+		// An expired or already revoked token needs no revoking
+		if claims, err := ValidateAccessToken(bearerToken(r)); err == nil {
+			if claims.Session != 0 {
+				// Clients without the cookie still end the session the token belongs to
+				if err := dbQueries.DeleteSessionByID(context.Background(), claims.Session); err != nil {
+					writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Failed to end session"})
+					return
+				}
+				if err := RevokeSessionTokens(context.Background(), dbQueries, claims.Username, []int32{claims.Session}); err != nil {
+					writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Failed to revoke access tokens"})
+					return
+				}
+			}
+			if claims.ID != "" {
+				if err := RevokeAccessToken(context.Background(), dbQueries, claims); err != nil {
+					writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Failed to revoke access token"})
+					return
+				}
+			}
+		}
+
+		clearRefreshCookie(w)
+		writeJSON(w, http.StatusOK, loginResponse{Status: "ok", Message: "Logged out"})
+	}
+}
+
+// HandleListSessions lists the caller's active sessions
+func HandleListSessions(dbQueries *db.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, loginResponse{Status: "error", Message: "Method not allowed"})
+			return
+		}
+
+		claims, err := ValidateAccessToken(bearerToken(r))
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, loginResponse{Status: "error", Message: "Invalid or expired token"})
+			return
+		}
+
+		sessions, err := dbQueries.ListSessionsByUser(r.Context(), claims.Username)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Database error"})
+			return
+		}
+
+		result := make([]map[string]interface{}, 0, len(sessions))
+		for _, s := range sessions {
+			result = append(result, map[string]interface{}{
+				"id":           s.ID,
+				"user_agent":   s.UserAgent,
+				"ip_address":   s.IpAddress,
+				"created_at":   s.CreatedAt,
+				"last_used_at": s.LastUsedAt,
+				"expires_at":   s.ExpiresAt,
+				"current":      s.ID == claims.Session,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":   "ok",
+			"sessions": result,
+		})
+	}
+}
+
+// HandleRevokeSession ends one of the caller's sessions by id
+func HandleRevokeSession(dbQueries *db.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, loginResponse{Status: "error", Message: "Method not allowed"})
+			return
+		}
+
+		claims, err := ValidateAccessToken(bearerToken(r))
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, loginResponse{Status: "error", Message: "Invalid or expired token"})
+			return
+		}
+
+		var req revokeSessionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == 0 {
+			writeJSON(w, http.StatusBadRequest, loginResponse{Status: "error", Message: "Session id is required"})
+			return
+		}
+
+		// Scoped to the caller so one user cannot end another's session
+		deleted, err := dbQueries.DeleteUserSession(r.Context(), db.DeleteUserSessionParams{
+			ID:       req.ID,
+			Username: claims.Username,
+		})
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Failed to revoke session"})
+			return
+		}
+		if deleted == 0 {
+			writeJSON(w, http.StatusNotFound, loginResponse{Status: "error", Message: "Session not found"})
+			return
+		}
+		if err := RevokeSessionTokens(r.Context(), dbQueries, claims.Username, []int32{req.ID}); err != nil {
+			writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Failed to revoke access tokens"})
+			return
+		}
+
+		if req.ID == claims.Session {
+			clearRefreshCookie(w)
+		}
+
+		writeJSON(w, http.StatusOK, loginResponse{Status: "ok", Message: "Session revoked"})
+	}
+}
+
+// HandleRevokeAllSessions ends all of the caller's sessions, optionally
+// keeping the one making the request
+func HandleRevokeAllSessions(dbQueries *db.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, loginResponse{Status: "error", Message: "Method not allowed"})
+			return
+		}
+
+		claims, err := ValidateAccessToken(bearerToken(r))
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, loginResponse{Status: "error", Message: "Invalid or expired token"})
+			return
+		}
+
+		// An empty body means "revoke everything"
+		var req revokeAllSessionsRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		var ended []int32
+		if req.KeepCurrent && claims.Session != 0 {
+			ended, err = dbQueries.DeleteOtherSessionsByUser(r.Context(), db.DeleteOtherSessionsByUserParams{
+				Username: claims.Username,
+				ID:       claims.Session,
+			})
+		} else {
+			ended, err = dbQueries.DeleteSessionsByUser(r.Context(), claims.Username)
+			clearRefreshCookie(w)
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Failed to revoke sessions"})
+			return
+		}
+		if err := RevokeSessionTokens(r.Context(), dbQueries, claims.Username, ended); err != nil {
+			writeJSON(w, http.StatusInternalServerError, loginResponse{Status: "error", Message: "Failed to revoke access tokens"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, loginResponse{Status: "ok", Message: "Sessions revoked"})
+	}
+}
@@ -2,34 +2,73 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
 	"time"
 )
 
-var jwtSecret = []byte("your-super-secret-key") // Move to .env in production
+// accessTokenTTL is the lifetime of API access tokens
+const accessTokenTTL = 60 * time.Minute
+
+func init() {
+	// Millisecond iat lets RevokeUserTokens tell tokens issued just before a
+	// revocation from those issued right after it
+	jwt.TimePrecision = time.Millisecond
+}
 
 type Claims struct {
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	Purpose  string `json:"purpose,omitempty"` // Set only on restricted MFA tokens
+	Session  int32  `json:"sid,omitempty"`     // Refresh session the token was issued for
 	jwt.RegisteredClaims
 }
 
-// GenerateAccessToken creates a short-lived JWT token (15 minutes)
-func GenerateAccessToken(username, role string) (string, error) {
+// Token purposes for the short-lived tokens handed out between login steps
+const (
+	PurposeMFALogin = "mfa_login" // Password accepted, waiting for a TOTP or recovery code
+	PurposeMFASetup = "mfa_setup" // Password accepted, MFA enrollment required before login
+)
+
+// GenerateAccessToken creates a short-lived JWT token (60 minutes) bound to a refresh session
+func GenerateAccessToken(username, role string, sessionID int32) (string, error) {
+	// The jti lets a single token be revoked on logout
+	jti, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+
+	claims := Claims{
+		Username: username,
+		Role:     role,
+		Session:  sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(accessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    "snsms-backend",
+		},
+	}
+
+	return signToken(claims)
+}
+
+// GenerateMFAToken creates a 5 minute token that only unlocks the MFA endpoints
+func GenerateMFAToken(username, role, purpose string) (string, error) {
 	claims := Claims{
 		Username: username,
 		Role:     role,
+		Purpose:  purpose,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(60 * time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(5 * time.Minute)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    "snsms-backend",
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return signToken(claims)
 }
 
 // GenerateRefreshToken creates a random refresh token (7 days)
@@ -41,20 +80,53 @@ func GenerateRefreshToken() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
+// HashToken returns the SHA-256 hex digest stored in place of a refresh token
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
 // ValidateAccessToken verifies and decodes JWT token
 func ValidateAccessToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return jwtSecret, nil
-	})
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	// MFA step tokens must never reach the API
+	if claims.Purpose != "" {
+		return nil, fmt.Errorf("token not valid for API access")
+	}
+
+	return claims, nil
+}
+
+// ValidateMFAToken verifies a token issued by GenerateMFAToken for the given purpose
+func ValidateMFAToken(tokenString, purpose string) (*Claims, error) {
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Purpose != purpose {
+		return nil, fmt.Errorf("token not valid for this step")
+	}
+
+	return claims, nil
+}
+
+// parseToken checks the signature and expiry of a token and returns its claims
+func parseToken(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, verificationKey)
 
 	if err != nil {
 		return nil, err
 	}
 
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		if revocations.isRevoked(claims) {
+			return nil, errTokenRevoked
+		}
 		return claims, nil
 	}
 
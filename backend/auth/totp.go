@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	totpIssuer  = "SNSMS"
+	totpDigits  = 6
+	totpModulo  = 1000000
+	totpPeriod  = 30 // seconds per step
+	totpSkew    = 1  // accept one step of clock drift either way
+	secretBytes = 20
+)
+
+var base32NoPad = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret creates a random base32 secret for authenticator apps
+func GenerateTOTPSecret() (string, error) {
+	bytes := make([]byte, secretBytes)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base32NoPad.EncodeToString(bytes), nil
+}
+
+// TOTPProvisioningURI builds the otpauth:// URI shown as a QR code during enrollment
+func TOTPProvisioningURI(secret, username string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", totpIssuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", strconv.Itoa(totpDigits))
+	params.Set("period", strconv.Itoa(totpPeriod))
+
+	label := url.PathEscape(totpIssuer + ":" + username)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// ValidateTOTP checks a code against the secret and returns the matched time
+// step, so callers can refuse a code that was already used.
+func ValidateTOTP(secret, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	key, err := base32NoPad.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for offset := -totpSkew; offset <= totpSkew; offset++ {
+		step := current + int64(offset)
+		if hmac.Equal([]byte(totpCode(key, step)), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode computes the RFC 6238 code for a single time step
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%totpModulo)
+}
+
+// GenerateRecoveryCodes creates single-use backup codes in xxxx-xxxx-xxxx-xxxx form
+func GenerateRecoveryCodes(count int) ([]string, error) {
+	codes := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		bytes := make([]byte, 10)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, err
+		}
+		encoded := strings.ToLower(base32NoPad.EncodeToString(bytes))
+		codes = append(codes, encoded[0:4]+"-"+encoded[4:8]+"-"+encoded[8:12]+"-"+encoded[12:16])
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode normalizes a recovery code and returns its SHA-256 hex digest
+func HashRecoveryCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code))
+	hash := sha256.Sum256([]byte(strings.ToLower(normalized)))
+	return hex.EncodeToString(hash[:])
+}
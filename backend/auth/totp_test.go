@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// Base32 of the RFC 6238 SHA-1 test secret "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTPRFC6238Vectors(t *testing.T) {
+	// RFC 6238 appendix B, SHA-1, truncated to our six digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		step, ok := ValidateTOTP(rfcSecret, tt.code, time.Unix(tt.unix, 0))
+		if !ok {
+			t.Errorf("ValidateTOTP(%s) at %d rejected", tt.code, tt.unix)
+			continue
+		}
+		if want := tt.unix / totpPeriod; step != want {
+			t.Errorf("ValidateTOTP(%s) at %d matched step %d, want %d", tt.code, tt.unix, step, want)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	// "050471" belongs to the step containing 1111111111
+	const code = "050471"
+	issued := time.Unix(1111111111, 0)
+
+	tests := []struct {
+		name   string
+		offset time.Duration
+		want   bool
+	}{
+		{"same step", 0, true},
+		{"one step early", -totpPeriod * time.Second, true},
+		{"one step late", totpPeriod * time.Second, true},
+		{"two steps early", -2 * totpPeriod * time.Second, false},
+		{"two steps late", 2 * totpPeriod * time.Second, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfcSecret, code, issued.Add(tt.offset))
+			if ok != tt.want {
+				t.Fatalf("ValidateTOTP ok = %v, want %v", ok, tt.want)
+			}
+			if ok && step != issued.Unix()/totpPeriod {
+				t.Errorf("matched step %d, want %d", step, issued.Unix()/totpPeriod)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPReplay(t *testing.T) {
+	// checkSecondFactor only accepts a step above the last used one
+	// (UpdateMFALastUsedStep); this mirrors that rule
+	var lastUsed int64
+	use := func(code string, at time.Time) bool {
+		step, ok := ValidateTOTP(rfcSecret, code, at)
+		if !ok || step <= lastUsed {
+			return false
+		}
+		lastUsed = step
+		return true
+	}
+
+	issued := time.Unix(1111111111, 0)
+	if !use("050471", issued) {
+		t.Fatal("first use of the code rejected")
+	}
+	if use("050471", issued) {
+		t.Error("same code accepted twice in its step")
+	}
+	if use("050471", issued.Add(totpPeriod*time.Second)) {
+		t.Error("same code accepted again inside the skew window")
+	}
+	if use("081804", issued) {
+		t.Error("code of an earlier step accepted after a later one was used")
+	}
+}
+
+func TestValidateTOTPMalformed(t *testing.T) {
+	at := time.Unix(59, 0)
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		want   bool
+	}{
+		{"lowercase secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "287082", true},
+		{"surrounding spaces", rfcSecret, " 287082 ", true},
+		{"too short", rfcSecret, "28708", false},
+		{"too long", rfcSecret, "2870820", false},
+		{"wrong code", rfcSecret, "287083", false},
+		{"invalid secret", "not base32!", "287082", false},
+		{"empty", rfcSecret, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(tt.secret, tt.code, at); ok != tt.want {
+				t.Errorf("ValidateTOTP ok = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
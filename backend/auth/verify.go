@@ -25,7 +25,7 @@ func HandleVerify(dbQueries *db.Queries) http.HandlerFunc {
 		}
 
 		// Get refresh token from cookie
-		cookie, err := r.Cookie("refresh_token")
+		cookie, err := r.Cookie(refreshCookieName)
 		if err != nil {
 			// No refresh token cookie found
 			w.Header().Set("Content-Type", "application/json")
@@ -43,7 +43,7 @@ func HandleVerify(dbQueries *db.Queries) http.HandlerFunc {
 		}
 
 		// Check if refresh token exists in database
-		session, err := dbQueries.GetRefreshToken(context.Background(), refreshToken)
+		session, err := dbQueries.GetSessionByTokenHash(context.Background(), HashToken(refreshToken))
 		if err == sql.ErrNoRows {
 			// Token not found in database
 			w.Header().Set("Content-Type", "application/json")
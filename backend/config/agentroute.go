@@ -0,0 +1,340 @@
+// config/agentroute.go
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/pki"
+	"github.com/kishore-001/ServerManagementSuite/backend/tunnel"
+)
+
+// Agents that were issued a certificate are reached over HTTPS with their
+// certificate pinned, reverse-connected agents through their tunnel, and the
+// others keep using CLIENT_PROTOCOL. Any device can override the port,
+// scheme and path and bring its own CA or pin. Routes are served from memory
+// and reloaded to pick up changes from other instances.
+const agentRouteSyncInterval = 30 * time.Second
+
+// AgentEndpoint holds the per-device overrides for reaching an agent
+type AgentEndpoint struct {
+	Scheme      string // http or https, empty for CLIENT_PROTOCOL
+	Port        int    // 0 for CLIENT_PORT
+	BasePath    string // Prefix added by a reverse proxy in front of the agent
+	CACert      string // PEM CA the endpoint certificate must chain to
+	Fingerprint string // SHA-256 the endpoint certificate is pinned to
+}
+
+type agentRoute struct {
+	fingerprint string // SHA-256 of the agent certificate issued by our CA
+	reverse     bool
+	endpoint    AgentEndpoint
+	roots       *x509.CertPool // Parsed endpoint.CACert
+}
+
+var agentRoutes = struct {
+	sync.RWMutex
+	hosts map[string]agentRoute
+}{hosts: make(map[string]agentRoute)}
+
+var directAgentTransport = newAgentTransport()
+
+// AgentTransport must be used for every request to an agent so pinned
+// devices get mutual TLS and reverse-connected ones go through their tunnel
+var AgentTransport http.RoundTripper = &agentRoundTripper{direct: directAgentTransport}
+
+// AgentClient is the agent-aware replacement for http.DefaultClient
+var AgentClient = &http.Client{Transport: AgentTransport}
+
+// StartAgentRouteSync loads how each agent is reached and keeps it in sync
+// with the database until the process exits
+func StartAgentRouteSync(queries *serverdb.Queries) error {
+	if err := loadAgentRoutes(queries); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(agentRouteSyncInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := loadAgentRoutes(queries); err != nil {
+				log.Printf("❌ Failed to reload agent routes: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+func loadAgentRoutes(queries *serverdb.Queries) error {
+	rows, err := queries.ListAgentRoutes(context.Background())
+	if err != nil {
+		return err
+	}
+
+	hosts := make(map[string]agentRoute, len(rows))
+	for _, r := range rows {
+		route := agentRoute{fingerprint: r.TlsFingerprint, reverse: r.ReverseConnect}
+		route.endpoint = AgentEndpoint{
+			Scheme:      r.AgentScheme,
+			Port:        int(r.AgentPort),
+			BasePath:    r.AgentBasePath,
+			CACert:      r.AgentCaCert,
+			Fingerprint: r.AgentFingerprint,
+		}
+		if route.roots, err = parseAgentCA(r.AgentCaCert); err != nil {
+			log.Printf("⚠️ Ignoring invalid agent CA for %s: %v", r.Ip, err)
+		}
+		hosts[r.Ip] = route
+	}
+
+	agentRoutes.Lock()
+	agentRoutes.hosts = hosts
+	agentRoutes.Unlock()
+	return nil
+}
+
+// SetAgentPin records the certificate a host now serves; an empty fingerprint
+// sends the host back to CLIENT_PROTOCOL. Save it to the database first.
+func SetAgentPin(host, fingerprint string) {
+	agentRoutes.Lock()
+	defer agentRoutes.Unlock()
+
+	route := agentRoutes.hosts[host]
+	route.fingerprint = fingerprint
+	setAgentRoute(host, route)
+}
+
+// SetAgentReverse records whether a host is reached through its tunnel. Save
+// it to the database first.
+func SetAgentReverse(host string, reverse bool) {
+	agentRoutes.Lock()
+	defer agentRoutes.Unlock()
+
+	route := agentRoutes.hosts[host]
+	route.reverse = reverse
+	setAgentRoute(host, route)
+}
+
+// SetAgentEndpoint records the endpoint overrides of a host and drops idle
+// connections made with the old settings. Validate the endpoint with
+// NormalizeAgentEndpoint and save it to the database first.
+func SetAgentEndpoint(host string, endpoint AgentEndpoint) error {
+	roots, err := parseAgentCA(endpoint.CACert)
+	if err != nil {
+		return err
+	}
+
+	agentRoutes.Lock()
+	route := agentRoutes.hosts[host]
+	route.endpoint = endpoint
+	route.roots = roots
+	setAgentRoute(host, route)
+	agentRoutes.Unlock()
+
+	directAgentTransport.CloseIdleConnections()
+	return nil
+}
+
+// NormalizeAgentEndpoint validates the overrides an admin entered and returns
+// them in the form they are stored
+func NormalizeAgentEndpoint(endpoint AgentEndpoint, tlsPinned bool) (AgentEndpoint, error) {
+	endpoint.Scheme = strings.ToLower(strings.TrimSpace(endpoint.Scheme))
+	if endpoint.Scheme != "" && endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return endpoint, errors.New("scheme must be http or https")
+	}
+	if tlsPinned && endpoint.Scheme == "http" {
+		return endpoint, errors.New("scheme cannot be http for a device with an agent certificate")
+	}
+	if endpoint.Port < 0 || endpoint.Port > 65535 {
+		return endpoint, errors.New("port must be between 1 and 65535, or 0 for the default")
+	}
+
+	endpoint.BasePath = strings.Trim(strings.TrimSpace(endpoint.BasePath), "/")
+	if endpoint.BasePath != "" {
+		endpoint.BasePath = "/" + endpoint.BasePath
+	}
+	if len(endpoint.BasePath) > 255 || strings.ContainsAny(endpoint.BasePath, "?# ") {
+		return endpoint, errors.New("base_path must be a plain URL path of at most 255 characters")
+	}
+
+	endpoint.CACert = strings.TrimSpace(endpoint.CACert)
+	if _, err := parseAgentCA(endpoint.CACert); err != nil {
+		return endpoint, err
+	}
+
+	// Accept the colon-separated form most tools print
+	endpoint.Fingerprint = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(endpoint.Fingerprint), ":", ""))
+	if endpoint.Fingerprint != "" {
+		if decoded, err := hex.DecodeString(endpoint.Fingerprint); err != nil || len(decoded) != 32 {
+			return endpoint, errors.New("fingerprint must be a SHA-256 digest in hex")
+		}
+	}
+
+	if (endpoint.CACert != "" || endpoint.Fingerprint != "") && endpoint.Scheme == "http" {
+		return endpoint, errors.New("ca_cert and fingerprint need the https scheme")
+	}
+	return endpoint, nil
+}
+
+func parseAgentCA(pemData string) (*x509.CertPool, error) {
+	if pemData == "" {
+		return nil, nil
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(pemData)) {
+		return nil, errors.New("ca_cert must contain at least one PEM certificate")
+	}
+	return pool, nil
+}
+
+// RemoveAgentRoute forgets a deleted or revoked device and drops its tunnel
+func RemoveAgentRoute(host string) {
+	agentRoutes.Lock()
+	delete(agentRoutes.hosts, host)
+	agentRoutes.Unlock()
+
+	tunnel.Disconnect(host)
+}
+
+// IsReverseConnected reports whether host is reached through its tunnel
+func IsReverseConnected(host string) bool {
+	return lookupAgentRoute(host).reverse
+}
+
+func setAgentRoute(host string, route agentRoute) {
+	if route.fingerprint == "" && !route.reverse && route.endpoint == (AgentEndpoint{}) {
+		delete(agentRoutes.hosts, host)
+	} else {
+		agentRoutes.hosts[host] = route
+	}
+}
+
+func lookupAgentRoute(host string) agentRoute {
+	agentRoutes.RLock()
+	defer agentRoutes.RUnlock()
+	return agentRoutes.hosts[host]
+}
+
+// agentRoundTripper sends requests for reverse-connected hosts through their
+// tunnel and dials every other host directly
+type agentRoundTripper struct {
+	direct *http.Transport
+}
+
+func (t *agentRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Hostname()
+	if IsReverseConnected(host) {
+		return tunnel.RoundTrip(host, req)
+	}
+	return t.direct.RoundTrip(req)
+}
+
+func newAgentTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialTLSContext = dialAgentTLS
+	return transport
+}
+
+// dialAgentTLS verifies the agent the way its route asks for: our CA and pin
+// with mutual TLS, a pin or CA set by an admin, or the system roots
+func dialAgentTLS(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	route := lookupAgentRoute(host)
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: host}
+	if pki.Enabled() {
+		// Only agents from our CA ask for it; proxies in front of others may
+		tlsConfig.GetClientCertificate = pki.ClientCertificate
+	}
+
+	switch {
+	case route.fingerprint != "":
+		// Agent certificates are checked against our CA and the pin, not
+		// against host names
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return pki.VerifyAgent(rawCerts, route.fingerprint)
+		}
+	case route.endpoint.Fingerprint != "":
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 || pki.Fingerprint(rawCerts[0]) != route.endpoint.Fingerprint {
+				return fmt.Errorf("certificate of %s does not match the pinned fingerprint", host)
+			}
+			return nil
+		}
+	case route.roots != nil:
+		// Devices are addressed by IP, which private CAs rarely put in the
+		// certificate, so only the chain is checked
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyChain(rawCerts, route.roots)
+		}
+	}
+
+	dialer := &tls.Dialer{Config: tlsConfig}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+func verifyChain(rawCerts [][]byte, roots *x509.CertPool) error {
+	if len(rawCerts) == 0 {
+		return errors.New("agent presented no certificate")
+	}
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		certs = append(certs, cert)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	return err
+}
+
+// agentURL builds the URL of endpoint on host from its route
+func agentURL(host, endpoint string) string {
+	route := lookupAgentRoute(host)
+
+	scheme := AppConfig.ClientProtocol
+	port := AppConfig.ClientPort
+	switch {
+	case route.reverse:
+		// The tunnel speaks plain HTTP/2 inside the agent's own connection
+		return "http://" + net.JoinHostPort(host, port) + endpoint
+	case route.fingerprint != "":
+		// Agents holding a certificate from our CA only serve HTTPS, and an
+		// endpoint override must not downgrade them to plain HTTP
+		scheme = "https"
+	case route.endpoint.Scheme != "":
+		scheme = route.endpoint.Scheme
+	}
+	if route.endpoint.Port != 0 {
+		port = strconv.Itoa(route.endpoint.Port)
+	}
+	return scheme + "://" + net.JoinHostPort(host, port) + route.endpoint.BasePath + endpoint
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// withAgentRoutes replaces the route table and configuration for one test
+func withAgentRoutes(t *testing.T, routes map[string]agentRoute) {
+	t.Helper()
+
+	previousConfig := AppConfig
+	AppConfig = &AppConfiguration{ClientPort: "2210", ClientProtocol: "http"}
+
+	agentRoutes.Lock()
+	previousRoutes := agentRoutes.hosts
+	agentRoutes.hosts = routes
+	agentRoutes.Unlock()
+
+	t.Cleanup(func() {
+		AppConfig = previousConfig
+		agentRoutes.Lock()
+		agentRoutes.hosts = previousRoutes
+		agentRoutes.Unlock()
+	})
+}
+
+func TestAgentURL(t *testing.T) {
+	withAgentRoutes(t, map[string]agentRoute{
+		"10.0.0.1": {fingerprint: "ab"},
+		"10.0.0.2": {fingerprint: "ab", endpoint: AgentEndpoint{Scheme: "http", Port: 8443, BasePath: "/agent"}},
+		"10.0.0.3": {endpoint: AgentEndpoint{Scheme: "https", Port: 443}},
+		"10.0.0.4": {reverse: true, fingerprint: "ab"},
+	})
+
+	tests := []struct {
+		name string
+		host string
+		want string
+	}{
+		{"default route", "10.0.0.9", "http://10.0.0.9:2210/client/x"},
+		{"pinned agent", "10.0.0.1", "https://10.0.0.1:2210/client/x"},
+		{"pinned agent keeps https over an http override", "10.0.0.2", "https://10.0.0.2:8443/agent/client/x"},
+		{"endpoint override", "10.0.0.3", "https://10.0.0.3:443/client/x"},
+		{"reverse-connected agent", "10.0.0.4", "http://10.0.0.4:2210/client/x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := agentURL(tt.host, "/client/x"); got != tt.want {
+				t.Errorf("agentURL(%s) = %s, want %s", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeAgentEndpoint(t *testing.T) {
+	fingerprint := strings.Repeat("ab", 32)
+
+	tests := []struct {
+		name      string
+		endpoint  AgentEndpoint
+		tlsPinned bool
+		want      AgentEndpoint
+		wantErr   bool
+	}{
+		{"defaults", AgentEndpoint{}, false, AgentEndpoint{}, false},
+		{"cleans up input", AgentEndpoint{Scheme: " HTTPS ", BasePath: "agent/", Port: 8443}, false,
+			AgentEndpoint{Scheme: "https", BasePath: "/agent", Port: 8443}, false},
+		{"colon fingerprint", AgentEndpoint{Scheme: "https", Fingerprint: strings.ToUpper(strings.Repeat("ab:", 31) + "ab")}, false,
+			AgentEndpoint{Scheme: "https", Fingerprint: fingerprint}, false},
+		{"http for an unpinned device", AgentEndpoint{Scheme: "http"}, false, AgentEndpoint{Scheme: "http"}, false},
+		{"http for a pinned device", AgentEndpoint{Scheme: "http"}, true, AgentEndpoint{}, true},
+		{"https for a pinned device", AgentEndpoint{Scheme: "https"}, true, AgentEndpoint{Scheme: "https"}, false},
+		{"unknown scheme", AgentEndpoint{Scheme: "ftp"}, false, AgentEndpoint{}, true},
+		{"port out of range", AgentEndpoint{Port: 70000}, false, AgentEndpoint{}, true},
+		{"query in base path", AgentEndpoint{BasePath: "/a?b"}, false, AgentEndpoint{}, true},
+		{"short fingerprint", AgentEndpoint{Scheme: "https", Fingerprint: "abcd"}, false, AgentEndpoint{}, true},
+		{"pin over http", AgentEndpoint{Scheme: "http", Fingerprint: fingerprint}, false, AgentEndpoint{}, true},
+		{"invalid CA", AgentEndpoint{Scheme: "https", CACert: "not a certificate"}, false, AgentEndpoint{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeAgentEndpoint(tt.endpoint, tt.tlsPinned)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("NormalizeAgentEndpoint = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
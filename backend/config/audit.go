@@ -0,0 +1,195 @@
+// config/audit.go
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/auth"
+	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+)
+
+// Audit outcomes
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure" // Handler returned an error status
+	AuditDenied  = "denied"  // Permission check refused the call
+)
+
+const (
+	auditPayloadLimit = 16 << 10 // Bytes of request body kept per entry
+	auditRedacted     = "[REDACTED]"
+)
+
+// auditSecretKeys are JSON fields whose values never reach the audit log.
+// Keys containing "password", "secret" or "token" are redacted as well.
+var auditSecretKeys = map[string]bool{
+	"new":         true, // Device password change
+	"code":        true, // MFA codes
+	"private_key": true,
+}
+
+// auditRecorder remembers the status code written by the handler
+type auditRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *auditRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *auditRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the real writer (flush, hijack)
+func (r *auditRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Audit records every call that passes through it: who, from where, against
+// which device, what was sent (with secrets redacted), the outcome and how
+// long it took. It must sit inside JWTMiddleware so the caller is known.
+func (a *Authorizer) Audit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		payload := auditPayload(r)
+		host, _ := requestHost(r)
+
+		recorder := &auditRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		outcome := AuditSuccess
+		if status == http.StatusForbidden {
+			outcome = AuditDenied
+		} else if status >= 400 {
+			outcome = AuditFailure
+		}
+
+		user, _ := GetUserFromContext(r)
+		entry := generaldb.CreateAuditEntryParams{
+			Actor:      user.Username,
+			SourceIp:   auth.ClientIP(r),
+			Method:     r.Method,
+			Action:     strings.TrimPrefix(r.URL.Path, "/api/admin/"),
+			TargetHost: host,
+			Payload:    payload,
+			StatusCode: int32(status),
+			Outcome:    outcome,
+			DurationMs: int32(time.Since(started).Milliseconds()),
+		}
+		if user.ViaAPIToken() {
+			entry.ApiTokenID = sql.NullInt32{Int32: user.APITokenID, Valid: true}
+		}
+
+		// The request context may already be cancelled by the client
+		if err := a.general.CreateAuditEntry(context.Background(), entry); err != nil {
+			log.Printf("❌ Failed to write audit entry for %s %s: %v", user.Username, r.URL.Path, err)
+		}
+	})
+}
+
+// auditPayload returns the redacted request body (or query string for GETs)
+// and restores the body for the handler
+func auditPayload(r *http.Request) string {
+	if r.Method == http.MethodGet {
+		return redactQuery(r.URL.RawQuery)
+	}
+	if r.Body == nil {
+		return ""
+	}
+
+	body, err := peekBody(r)
+	if err != nil || len(body) == 0 {
+		return ""
+	}
+	if len(body) == maxPeekBody {
+		return "[body of 1 MB or more omitted]"
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		// Only JSON can be redacted safely, so other bodies are not stored
+		return "[non-JSON body omitted]"
+	}
+
+	redacted, err := json.Marshal(redactSecrets(decoded))
+	if err != nil {
+		return ""
+	}
+	if len(redacted) > auditPayloadLimit {
+		return string(redacted[:auditPayloadLimit]) + "...[truncated]"
+	}
+	return string(redacted)
+}
+
+// redactQuery replaces the values of secret-looking query parameters
+func redactQuery(rawQuery string) string {
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "[unparsable query omitted]"
+	}
+	redacted := false
+	for key := range values {
+		if isSecretKey(key) {
+			values[key] = []string{auditRedacted}
+			redacted = true
+		}
+	}
+	if !redacted {
+		return rawQuery
+	}
+	return values.Encode()
+}
+
+// redactSecrets replaces the values of secret-looking keys at any depth
+func redactSecrets(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range v {
+			if isSecretKey(key) {
+				v[key] = auditRedacted
+			} else {
+				v[key] = redactSecrets(inner)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, inner := range v {
+			v[i] = redactSecrets(inner)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	if auditSecretKeys[key] {
+		return true
+	}
+	for _, word := range []string{"password", "secret", "token"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
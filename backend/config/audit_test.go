@@ -0,0 +1,133 @@
+package config
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestIsSecretKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"password", true},
+		{"NewPassword", true},
+		{"client_secret", true},
+		{"access_token", true},
+		{"new", true},
+		{"code", true},
+		{"private_key", true},
+		{"host", false},
+		{"command", false},
+		{"public_key", false},
+		{"recovery", false},
+	}
+
+	for _, tt := range tests {
+		if got := isSecretKey(tt.key); got != tt.want {
+			t.Errorf("isSecretKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestAuditPayloadRedactsJSON(t *testing.T) {
+	body := `{"host":"10.0.0.1","password":"hunter2","users":[{"name":"bob","new":"s3cret"}],` +
+		`"config":{"smtp":{"Secret":"x","port":25}},"code":123456}`
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/x", strings.NewReader(body))
+
+	payload := auditPayload(r)
+	for _, secret := range []string{"hunter2", "s3cret", `"x"`, "123456"} {
+		if strings.Contains(payload, secret) {
+			t.Errorf("payload %s contains %s", payload, secret)
+		}
+	}
+
+	var decoded struct {
+		Host     string `json:"host"`
+		Password string `json:"password"`
+		Users    []struct {
+			Name string `json:"name"`
+			New  string `json:"new"`
+		} `json:"users"`
+		Config struct {
+			SMTP map[string]interface{} `json:"smtp"`
+		} `json:"config"`
+	}
+	if err := json.Unmarshal([]byte(payload), &decoded); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	if decoded.Host != "10.0.0.1" || decoded.Password != auditRedacted || decoded.Users[0].Name != "bob" ||
+		decoded.Users[0].New != auditRedacted || decoded.Config.SMTP["Secret"] != auditRedacted ||
+		decoded.Config.SMTP["port"] != float64(25) {
+		t.Errorf("payload = %s", payload)
+	}
+
+	// The handler still gets the original body
+	if restored, _ := io.ReadAll(r.Body); string(restored) != body {
+		t.Errorf("handler body = %s", restored)
+	}
+}
+
+func TestAuditPayloadRedactsQuery(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/admin/x?host=10.0.0.1&token=abc&lines=50", nil)
+	payload := auditPayload(r)
+	values, err := url.ParseQuery(payload)
+	if err != nil {
+		t.Fatalf("payload %q: %v", payload, err)
+	}
+	if values.Get("host") != "10.0.0.1" || values.Get("lines") != "50" || values.Get("token") != auditRedacted {
+		t.Errorf("payload = %q", payload)
+	}
+
+	// Queries without secrets are kept as sent
+	r = httptest.NewRequest(http.MethodGet, "/api/admin/x?lines=50&host=10.0.0.1", nil)
+	if payload := auditPayload(r); payload != "lines=50&host=10.0.0.1" {
+		t.Errorf("payload = %q", payload)
+	}
+}
+
+func TestAuditPayloadOmitsBodies(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"empty", "", ""},
+		{"not JSON", "password=hunter2", "[non-JSON body omitted]"},
+		{"too large to read", `"` + strings.Repeat("a", maxPeekBody) + `"`, "[body of 1 MB or more omitted]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/admin/x", strings.NewReader(tt.body))
+			if got := auditPayload(r); got != tt.want {
+				t.Errorf("auditPayload = %.60q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/x", strings.NewReader(`["`+strings.Repeat("a", auditPayloadLimit)+`"]`))
+	if got := auditPayload(r); len(got) != auditPayloadLimit+len("...[truncated]") || !strings.HasSuffix(got, "...[truncated]") {
+		t.Errorf("long payload kept %d bytes", len(got))
+	}
+}
+
+func TestAuditRecorderKeepsFirstStatus(t *testing.T) {
+	rec := &auditRecorder{ResponseWriter: httptest.NewRecorder()}
+	rec.Write([]byte("ok"))
+	rec.WriteHeader(http.StatusInternalServerError)
+	if rec.status != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.status, http.StatusOK)
+	}
+
+	rec = &auditRecorder{ResponseWriter: httptest.NewRecorder()}
+	rec.WriteHeader(http.StatusForbidden)
+	if rec.status != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.status, http.StatusForbidden)
+	}
+}
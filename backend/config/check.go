@@ -69,7 +69,8 @@ func HandleCheck(queries *serverdb.Queries) http.HandlerFunc {
 
 		// Create HTTP client with timeout
 		client := &http.Client{
-			Timeout: 10 * time.Second,
+			Transport: AgentTransport,
+			Timeout:   10 * time.Second,
 		}
 
 		// Create request to client
@@ -9,6 +9,10 @@ import (
 	"log"
 )
 
+// serverDB is the pool behind ServerQueries, kept for handlers that need a
+// transaction
+var serverDB *sql.DB
+
 func GeneralQueries() *generaldb.Queries {
 	// Use the loaded configuration
 	if AppConfig == nil {
@@ -44,5 +48,11 @@ func ServerQueries() *serverdb.Queries {
 	}
 
 	log.Println("✅ Connected to Server database")
+	serverDB = dbConn
 	return serverdb.New(dbConn)
 }
+
+// ServerDB returns the connection pool opened by ServerQueries
+func ServerDB() *sql.DB {
+	return serverDB
+}
@@ -6,8 +6,11 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
+	"github.com/kishore-001/ServerManagementSuite/backend/auth"
+	"github.com/kishore-001/ServerManagementSuite/backend/pki"
 )
 
 type AppConfiguration struct {
@@ -15,6 +18,9 @@ type AppConfiguration struct {
 	ClientProtocol string
 	DatabaseURL    string
 	JWTSecret      string
+	JWTAlgorithm   string // HS256, RS256 or EdDSA
+	JWTKeyID       string // kid of the key used to sign new tokens
+	JWTKeysDir     string // Optional directory holding rotated keys
 	ServerPort     string
 	LogLevel       string
 
@@ -24,6 +30,53 @@ type AppConfiguration struct {
 	SMTPUsername string
 	SMTPPassword string
 	SMTPFrom     string
+
+	// Frontend page that accepts ?token= for password resets
+	PasswordResetURL string
+
+	// Single sign-on (disabled unless OIDC_ISSUER is set)
+	OIDCIssuer        string
+	OIDCClientID      string
+	OIDCClientSecret  string
+	OIDCRedirectURL   string
+	OIDCFrontendURL   string
+	OIDCScopes        string
+	OIDCUsernameClaim string
+	OIDCRoleClaim     string
+	OIDCRoleMap       string // "idp-group=role,other-group=role"
+	OIDCDefaultRole   string
+
+	// Directory logins (disabled unless LDAP_URL is set)
+	LDAPURL                string
+	LDAPStartTLS           bool
+	LDAPCACert             string
+	LDAPInsecureSkipVerify bool
+	LDAPBindDN             string
+	LDAPBindPassword       string
+	LDAPBaseDN             string
+	LDAPUserFilter         string
+	LDAPUsernameAttribute  string
+	LDAPEmailAttribute     string
+	LDAPGroupAttribute     string
+	LDAPGroupBaseDN        string
+	LDAPGroupFilter        string
+	LDAPRoleMap            string // "CN or DN=role,..."
+	LDAPDefaultRole        string
+
+	// Device access tokens
+	DeviceTokenRotationDays   int // Rotate tokens older than this; 0 disables scheduled rotation
+	DeviceTokenOverlapMinutes int // How long an agent keeps accepting the replaced token
+
+	// Mutual TLS with the agents
+	AgentTLS   string // "auto" issues certificates to agents that support it, "off" keeps plain CLIENT_PROTOCOL
+	AgentCADir string // Holds the CA certificate and key, shared by every backend instance
+
+	// Background jobs
+	JobWorkers int // Jobs this instance runs at once
+
+	// Web terminal
+	TerminalMaxSessions int // Terminals a user may have open at once
+	TerminalIdleMinutes int // Terminals without input for this long are closed
 }
 
 var AppConfig *AppConfiguration
@@ -46,11 +99,45 @@ func LoadConfig() {
 		smtpPort = 587
 	}
 
+	// Parse device token rotation settings
+	rotationDays, err := strconv.Atoi(getEnv("DEVICE_TOKEN_ROTATION_DAYS", "30"))
+	if err != nil || rotationDays < 0 {
+		log.Printf("⚠️ Invalid DEVICE_TOKEN_ROTATION_DAYS, using default 30")
+		rotationDays = 30
+	}
+	overlapMinutes, err := strconv.Atoi(getEnv("DEVICE_TOKEN_OVERLAP_MINUTES", "10"))
+	if err != nil || overlapMinutes < 1 {
+		log.Printf("⚠️ Invalid DEVICE_TOKEN_OVERLAP_MINUTES, using default 10")
+		overlapMinutes = 10
+	}
+
+	// Parse background job settings
+	jobWorkers, err := strconv.Atoi(getEnv("JOB_WORKERS", "4"))
+	if err != nil || jobWorkers < 1 {
+		log.Printf("⚠️ Invalid JOB_WORKERS, using default 4")
+		jobWorkers = 4
+	}
+
+	// Parse web terminal settings
+	terminalMaxSessions, err := strconv.Atoi(getEnv("TERMINAL_MAX_SESSIONS", "3"))
+	if err != nil || terminalMaxSessions < 1 {
+		log.Printf("⚠️ Invalid TERMINAL_MAX_SESSIONS, using default 3")
+		terminalMaxSessions = 3
+	}
+	terminalIdleMinutes, err := strconv.Atoi(getEnv("TERMINAL_IDLE_MINUTES", "15"))
+	if err != nil || terminalIdleMinutes < 1 {
+		log.Printf("⚠️ Invalid TERMINAL_IDLE_MINUTES, using default 15")
+		terminalIdleMinutes = 15
+	}
+
 	AppConfig = &AppConfiguration{
-		ClientPort:     getEnv("CLIENT_PORT", "8080"),
+		ClientPort:     getEnv("CLIENT_PORT", "2210"),
 		ClientProtocol: getEnv("CLIENT_PROTOCOL", "http"),
 		DatabaseURL:    getEnv("DATABASE_URL", ""),
 		JWTSecret:      getEnv("JWT_SECRET", ""),
+		JWTAlgorithm:   getEnv("JWT_ALGORITHM", "HS256"),
+		JWTKeyID:       getEnv("JWT_KEY_ID", "default"),
+		JWTKeysDir:     getEnv("JWT_KEYS_DIR", ""),
 		ServerPort:     getEnv("SERVER_PORT", "8000"),
 		LogLevel:       getEnv("LOG_LEVEL", "info"),
 
@@ -60,11 +147,51 @@ func LoadConfig() {
 		SMTPUsername: getEnv("SMTP_USERNAME", ""),
 		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
 		SMTPFrom:     getEnv("SMTP_FROM", ""),
+
+		PasswordResetURL: getEnv("PASSWORD_RESET_URL", ""),
+
+		OIDCIssuer:        getEnv("OIDC_ISSUER", ""),
+		OIDCClientID:      getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:  getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:   getEnv("OIDC_REDIRECT_URL", ""),
+		OIDCFrontendURL:   getEnv("OIDC_FRONTEND_URL", "/"),
+		OIDCScopes:        getEnv("OIDC_SCOPES", "profile email"),
+		OIDCUsernameClaim: getEnv("OIDC_USERNAME_CLAIM", "preferred_username"),
+		OIDCRoleClaim:     getEnv("OIDC_ROLE_CLAIM", "groups"),
+		OIDCRoleMap:       getEnv("OIDC_ROLE_MAP", ""),
+		OIDCDefaultRole:   getEnv("OIDC_DEFAULT_ROLE", ""),
+
+		LDAPURL:                getEnv("LDAP_URL", ""),
+		LDAPStartTLS:           getEnv("LDAP_START_TLS", "false") == "true",
+		LDAPCACert:             getEnv("LDAP_CA_CERT", ""),
+		LDAPInsecureSkipVerify: getEnv("LDAP_INSECURE_SKIP_VERIFY", "false") == "true",
+		LDAPBindDN:             getEnv("LDAP_BIND_DN", ""),
+		LDAPBindPassword:       getEnv("LDAP_BIND_PASSWORD", ""),
+		LDAPBaseDN:             getEnv("LDAP_BASE_DN", ""),
+		LDAPUserFilter:         getEnv("LDAP_USER_FILTER", "(uid=%s)"),
+		LDAPUsernameAttribute:  getEnv("LDAP_USERNAME_ATTRIBUTE", "uid"),
+		LDAPEmailAttribute:     getEnv("LDAP_EMAIL_ATTRIBUTE", "mail"),
+		LDAPGroupAttribute:     getEnv("LDAP_GROUP_ATTRIBUTE", "memberOf"),
+		LDAPGroupBaseDN:        getEnv("LDAP_GROUP_BASE_DN", ""),
+		LDAPGroupFilter:        getEnv("LDAP_GROUP_FILTER", ""),
+		LDAPRoleMap:            getEnv("LDAP_ROLE_MAP", ""),
+		LDAPDefaultRole:        getEnv("LDAP_DEFAULT_ROLE", ""),
+
+		DeviceTokenRotationDays:   rotationDays,
+		DeviceTokenOverlapMinutes: overlapMinutes,
+
+		AgentTLS:   strings.ToLower(getEnv("AGENT_TLS", "auto")),
+		AgentCADir: getEnv("AGENT_CA_DIR", "./agent-ca"),
+
+		JobWorkers: jobWorkers,
+
+		TerminalMaxSessions: terminalMaxSessions,
+		TerminalIdleMinutes: terminalIdleMinutes,
 	}
 
 	// Validate required fields
-	if AppConfig.JWTSecret == "" {
-		log.Fatal("❌ JWT_SECRET environment variable is required")
+	if AppConfig.JWTSecret == "" && AppConfig.JWTKeysDir == "" {
+		log.Fatal("❌ JWT_SECRET (or JWT_KEYS_DIR) environment variable is required")
 	}
 	if AppConfig.DatabaseURL == "" {
 		log.Fatal("❌ DATABASE_URL environment variable is required")
@@ -73,6 +200,83 @@ func LoadConfig() {
 		log.Printf("⚠️ Warning: SMTP credentials not configured, email alerts will be disabled")
 	}
 
+	// Token signing keys
+	err = auth.ConfigureKeys(auth.KeyConfig{
+		Secret:    AppConfig.JWTSecret,
+		KeyID:     AppConfig.JWTKeyID,
+		Algorithm: AppConfig.JWTAlgorithm,
+		KeysDir:   AppConfig.JWTKeysDir,
+	})
+	if err != nil {
+		log.Fatalf("❌ Failed to load JWT signing keys: %v", err)
+	}
+
+	// Certificate authority for agent TLS
+	switch AppConfig.AgentTLS {
+	case "auto":
+		if err := pki.Configure(AppConfig.AgentCADir); err != nil {
+			log.Fatalf("❌ Failed to load agent CA: %v", err)
+		}
+		log.Printf("🔐 Agent TLS enabled (CA in %s)", AppConfig.AgentCADir)
+	case "off":
+		log.Printf("⚠️ Warning: AGENT_TLS=off, agents are reached over %s", AppConfig.ClientProtocol)
+	default:
+		log.Fatalf("❌ AGENT_TLS must be auto or off")
+	}
+
+	// Single sign-on
+	if AppConfig.OIDCIssuer != "" {
+		if AppConfig.OIDCClientID == "" || AppConfig.OIDCRedirectURL == "" {
+			log.Fatal("❌ OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required when OIDC_ISSUER is set")
+		}
+		roleMap, err := auth.ParseRoleMap(AppConfig.OIDCRoleMap)
+		if err != nil {
+			log.Fatalf("❌ Invalid OIDC_ROLE_MAP: %v", err)
+		}
+		auth.ConfigureOIDC(auth.OIDCConfig{
+			Issuer:        AppConfig.OIDCIssuer,
+			ClientID:      AppConfig.OIDCClientID,
+			ClientSecret:  AppConfig.OIDCClientSecret,
+			RedirectURL:   AppConfig.OIDCRedirectURL,
+			FrontendURL:   AppConfig.OIDCFrontendURL,
+			Scopes:        strings.Fields(AppConfig.OIDCScopes),
+			UsernameClaim: AppConfig.OIDCUsernameClaim,
+			RoleClaim:     AppConfig.OIDCRoleClaim,
+			RoleMap:       roleMap,
+			DefaultRole:   AppConfig.OIDCDefaultRole,
+		})
+		log.Printf("🔑 OIDC single sign-on enabled (issuer %s)", AppConfig.OIDCIssuer)
+	}
+
+	// Directory logins
+	if AppConfig.LDAPURL != "" {
+		roleMap, err := auth.ParseRoleMap(AppConfig.LDAPRoleMap)
+		if err != nil {
+			log.Fatalf("❌ Invalid LDAP_ROLE_MAP: %v", err)
+		}
+		err = auth.ConfigureLDAP(auth.LDAPConfig{
+			URL:                AppConfig.LDAPURL,
+			StartTLS:           AppConfig.LDAPStartTLS,
+			CACertFile:         AppConfig.LDAPCACert,
+			InsecureSkipVerify: AppConfig.LDAPInsecureSkipVerify,
+			BindDN:             AppConfig.LDAPBindDN,
+			BindPassword:       AppConfig.LDAPBindPassword,
+			BaseDN:             AppConfig.LDAPBaseDN,
+			UserFilter:         AppConfig.LDAPUserFilter,
+			UsernameAttribute:  AppConfig.LDAPUsernameAttribute,
+			EmailAttribute:     AppConfig.LDAPEmailAttribute,
+			GroupAttribute:     AppConfig.LDAPGroupAttribute,
+			GroupBaseDN:        AppConfig.LDAPGroupBaseDN,
+			GroupFilter:        AppConfig.LDAPGroupFilter,
+			RoleMap:            roleMap,
+			DefaultRole:        AppConfig.LDAPDefaultRole,
+		})
+		if err != nil {
+			log.Fatalf("❌ Invalid LDAP configuration: %v", err)
+		}
+		log.Printf("📒 LDAP login enabled (%s)", AppConfig.LDAPURL)
+	}
+
 	log.Printf("✅ Configuration loaded - Server Port: %s, Client Port: %s, SMTP: %s:%d",
 		AppConfig.ServerPort, AppConfig.ClientPort, AppConfig.SMTPHost, AppConfig.SMTPPort)
 }
@@ -84,6 +288,8 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// GetClientURL returns the URL of an agent endpoint, honouring the port,
+// scheme and base path configured for the device
 func GetClientURL(host string, endpoint string) string {
-	return AppConfig.ClientProtocol + "://" + host + ":" + AppConfig.ClientPort + endpoint
+	return agentURL(host, endpoint)
 }
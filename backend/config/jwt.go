@@ -2,6 +2,7 @@ package config
 
 import (
 	"github.com/kishore-001/ServerManagementSuite/backend/auth"
+	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
 	"context"
 	"net/http"
 	"strings"
@@ -14,10 +15,20 @@ const UserContextKey contextKey = "user"
 type UserInfo struct {
 	Username string `json:"username"`
 	Role     string `json:"role"`
+
+	// Set when the caller authenticated with a personal API token
+	APITokenID int32    `json:"api_token_id,omitempty"`
+	Scopes     []string `json:"scopes,omitempty"`
+}
+
+// ViaAPIToken reports whether the request was authenticated with an API token
+func (u *UserInfo) ViaAPIToken() bool {
+	return u.APITokenID != 0
 }
 
-// JWTMiddleware validates access tokens on protected routes
-func JWTMiddleware(next http.Handler) http.Handler {
+// JWTMiddleware validates access tokens on protected routes. Personal API
+// tokens (sms_...) are accepted in the same Authorization header.
+func JWTMiddleware(next http.Handler, queries *generaldb.Queries) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Get token from Authorization header
 		authHeader := r.Header.Get("Authorization")
@@ -33,6 +44,24 @@ func JWTMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		if auth.IsAPIToken(tokenParts[1]) {
+			identity, err := auth.ValidateAPIToken(r.Context(), queries, tokenParts[1], auth.ClientIP(r))
+			if err != nil {
+				http.Error(w, "Invalid or expired API token", http.StatusUnauthorized)
+				return
+			}
+
+			userInfo := UserInfo{
+				Username:   identity.Username,
+				Role:       identity.Role,
+				APITokenID: identity.ID,
+				Scopes:     identity.Scopes,
+			}
+			ctx := context.WithValue(r.Context(), UserContextKey, userInfo)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
 		// Validate the access token
 		claims, err := auth.ValidateAccessToken(tokenParts[1])
 		if err != nil {
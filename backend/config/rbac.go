@@ -0,0 +1,523 @@
+// config/rbac.go
+package config
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+// Permissions checked by Authorizer.Authorize
+const (
+	PermAll            = "*"
+	PermDevicesRead    = "devices.read"    // Health, logs, device list
+	PermDevicesManage  = "devices.manage"  // Register and remove devices
+	PermConfigRead     = "config.read"     // Read device configuration, firewall, routes, services
+	PermConfigWrite    = "config.write"    // Hostname, timezone, passwords, SSH keys
+	PermCmdExec        = "cmd.exec"        // Run arbitrary commands
+	PermTerminal       = "terminal.open"   // Interactive shell on a device
+	PermNetworkWrite   = "network.write"   // Interfaces and routes
+	PermFirewallWrite  = "firewall.write"  // Firewall rules
+	PermServiceRestart = "service.restart" // Restart services
+	PermResourceClean  = "resource.clean"  // Disk cleanup
+	PermAlertsRead     = "alerts.read"
+	PermAlertsAck      = "alerts.ack"
+	PermAlertsDelete   = "alerts.delete"
+	PermUsersManage    = "users.manage" // Everything under /api/admin/settings/
+	PermAuditRead      = "audit.read"   // Query and export the audit log
+	PermMACManage      = "mac.manage"   // Edit the MAC whitelist, blacklist and monitored segments
+)
+
+// AllPermissions lists every permission a role can be given
+var AllPermissions = []string{
+	PermDevicesRead, PermDevicesManage, PermConfigRead, PermConfigWrite,
+	PermCmdExec, PermTerminal, PermNetworkWrite, PermFirewallWrite,
+	PermServiceRestart, PermResourceClean, PermAlertsRead, PermAlertsAck,
+	PermAlertsDelete, PermUsersManage, PermAuditRead, PermMACManage,
+}
+
+// builtinRoles are recreated at startup so the original two roles keep working
+var builtinRoles = []struct {
+	name        string
+	description string
+	permissions []string
+}{
+	{"admin", "Full access", []string{PermAll}},
+	{"viewer", "Read-only monitoring", []string{PermDevicesRead, PermAlertsRead, PermAlertsAck}},
+}
+
+// permAuthenticated marks routes any logged-in user may call
+const permAuthenticated = ""
+
+// routePermissions maps each protected route to the permission it needs.
+// Entries ending in "/" cover every path below them. Routes missing from
+// this table are admin-only.
+var routePermissions = map[string]string{
+	"/api/server/health":                PermDevicesRead,
+	"/api/server/log":                   PermDevicesRead,
+	"/api/server/check":                 PermDevicesRead,
+	"/api/server/config1/device":        PermDevicesRead,
+	"/api/server/status/history":        PermDevicesRead,
+	"/api/server/permissions":           permAuthenticated,
+	"/api/server/alerts":                PermAlertsRead,
+	"/api/server/alerts/markseen":       PermAlertsAck,
+	"/api/server/alerts/marksingleseen": PermAlertsAck,
+	"/api/server/alerts/delete":         PermAlertsDelete,
+
+	"/api/admin/server/config1/basic":        PermConfigRead,
+	"/api/admin/server/config1/overview":     PermConfigRead,
+	"/api/admin/server/config1/basic_update": PermConfigWrite,
+	"/api/admin/server/config1/pass":         PermConfigWrite,
+	"/api/admin/server/config1/ssh":          PermConfigWrite,
+	"/api/admin/server/config1/create":       PermDevicesManage,
+	"/api/admin/server/config1/delete":       PermDevicesManage,
+	"/api/admin/server/config1/update":       PermDevicesManage,
+	"/api/admin/server/config1/cmd":          PermCmdExec,
+	"/api/admin/server/config1/cmd/stream":   PermCmdExec,
+
+	"/api/admin/server/enroll/codes":        PermDevicesManage,
+	"/api/admin/server/enroll/codes/create": PermDevicesManage,
+	"/api/admin/server/enroll/codes/delete": PermDevicesManage,
+	"/api/admin/server/enroll/requests":     PermDevicesManage,
+	"/api/admin/server/enroll/approve":      PermDevicesManage,
+	"/api/admin/server/enroll/deny":         PermDevicesManage,
+
+	"/api/admin/server/token/rotate":  PermDevicesManage,
+	"/api/admin/server/token/revoke":  PermDevicesManage,
+	"/api/admin/server/token/revoked": PermDevicesManage,
+
+	"/api/admin/server/tls/provision": PermDevicesManage,
+	"/api/admin/server/tls/ca":        PermDevicesRead,
+
+	"/api/admin/server/reverse/set":  PermDevicesManage,
+	"/api/admin/server/reverse/list": PermDevicesRead,
+
+	"/api/admin/server/discovery/scan":   PermDevicesManage,
+	"/api/admin/server/discovery/cancel": PermDevicesManage,
+	"/api/admin/server/discovery/jobs":   PermDevicesRead,
+	"/api/admin/server/discovery/job":    PermDevicesRead,
+
+	"/api/admin/server/inventory/import": PermDevicesManage,
+	"/api/admin/server/inventory/export": PermDevicesRead,
+
+	// The operation's own permission (and an API token's scopes) is checked
+	// by the handler for each host through UserTagScope
+	"/api/admin/server/fleet/run":        permAuthenticated,
+	"/api/admin/server/fleet/operations": permAuthenticated,
+
+	// Submit and cancel check the operation's permission (and an API token's
+	// scopes) on the job's host through UserAllowed
+	"/api/admin/server/jobs/submit":     permAuthenticated,
+	"/api/admin/server/jobs/cancel":     permAuthenticated,
+	"/api/admin/server/jobs/operations": permAuthenticated,
+	"/api/admin/server/jobs/list":       PermDevicesRead,
+	"/api/admin/server/jobs/get":        PermDevicesRead,
+
+	// Listing and force-closing other users' terminals stays admin-only
+	"/api/admin/server/terminal/open": PermTerminal,
+
+	"/api/admin/server/config2/getfirewall":          PermConfigRead,
+	"/api/admin/server/config2/getnetworkbasics":     PermConfigRead,
+	"/api/admin/server/config2/getroute":             PermConfigRead,
+	"/api/admin/server/config2/postinterface":        PermNetworkWrite,
+	"/api/admin/server/config2/postnetwork":          PermNetworkWrite,
+	"/api/admin/server/config2/postrestartinterface": PermNetworkWrite,
+	"/api/admin/server/config2/postupdateroute":      PermNetworkWrite,
+	"/api/admin/server/config2/postupdatefirewall":   PermFirewallWrite,
+
+	"/api/admin/server/mac/list":      PermConfigRead,
+	"/api/admin/server/mac/sightings": PermConfigRead,
+	"/api/admin/server/mac/create":    PermMACManage,
+	"/api/admin/server/mac/update":    PermMACManage,
+	"/api/admin/server/mac/delete":    PermMACManage,
+	"/api/admin/server/mac/segments":  PermMACManage,
+
+	"/api/admin/server/resource/cleaninfo":      PermConfigRead,
+	"/api/admin/server/resource/service":        PermConfigRead,
+	"/api/admin/server/resource/optimize":       PermResourceClean,
+	"/api/admin/server/resource/restartservice": PermServiceRestart,
+
+	"/api/admin/settings/":                 PermUsersManage,
+	"/api/admin/settings/apitokens":        permAuthenticated,
+	"/api/admin/settings/apitokens/create": permAuthenticated,
+	"/api/admin/settings/apitokens/revoke": permAuthenticated,
+	"/api/admin/settings/audit":            PermAuditRead,
+	"/api/admin/settings/audit/export":     PermAuditRead,
+}
+
+// multiDeviceRoutes do not target a single device. Any grant of the route's
+// permission lets the caller in, and the handler limits what is listed or
+// changed to the caller's tags (see HostFilter and UserTagScope).
+var multiDeviceRoutes = map[string]bool{
+	"/api/server/config1/device":        true,
+	"/api/server/status/history":        true,
+	"/api/server/alerts":                true,
+	"/api/server/alerts/markseen":       true,
+	"/api/server/alerts/marksingleseen": true,
+	"/api/server/alerts/delete":         true,
+
+	"/api/admin/server/config1/create":   true, // Checks the new device's tag
+	"/api/admin/server/tls/ca":           true, // Public CA certificate, no device data
+	"/api/admin/server/inventory/import": true,
+	"/api/admin/server/inventory/export": true,
+	"/api/admin/server/jobs/list":        true,
+	"/api/admin/server/jobs/get":         true, // Checks the job's host
+}
+
+// permissionForPath returns the permission needed for a path and whether the
+// path is listed at all
+func permissionForPath(path string) (string, bool) {
+	if perm, ok := routePermissions[path]; ok {
+		return perm, true
+	}
+
+	best := ""
+	for prefix := range routePermissions {
+		if strings.HasSuffix(prefix, "/") && strings.HasPrefix(path, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return "", false
+	}
+	return routePermissions[best], true
+}
+
+// Grant is one permission held by a user, limited to devices with the given
+// tags (no tags means every device)
+type Grant struct {
+	Permission string   `json:"permission"`
+	DeviceTags []string `json:"device_tags"`
+}
+
+type cachedGrants struct {
+	grants  []Grant
+	expires time.Time
+}
+
+const grantCacheTTL = 30 * time.Second
+
+// Authorizer resolves a user's roles into permissions and checks them
+// against the device a request targets
+type Authorizer struct {
+	general *generaldb.Queries
+	server  *serverdb.Queries
+
+	mu    sync.RWMutex
+	cache map[string]cachedGrants
+}
+
+func NewAuthorizer(general *generaldb.Queries, server *serverdb.Queries) *Authorizer {
+	return &Authorizer{
+		general: general,
+		server:  server,
+		cache:   make(map[string]cachedGrants),
+	}
+}
+
+// EnsureBuiltinRoles creates the admin and viewer roles if they are missing
+func (a *Authorizer) EnsureBuiltinRoles(ctx context.Context) error {
+	for _, role := range builtinRoles {
+		err := a.general.UpsertRole(ctx, generaldb.UpsertRoleParams{
+			Name:        role.name,
+			Description: role.description,
+			Builtin:     true,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := a.general.DeleteRolePermissions(ctx, role.name); err != nil {
+			return err
+		}
+		for _, perm := range role.permissions {
+			err := a.general.AddRolePermission(ctx, generaldb.AddRolePermissionParams{
+				Role:       role.name,
+				Permission: perm,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Invalidate drops cached grants after roles or assignments change
+func (a *Authorizer) Invalidate() {
+	a.mu.Lock()
+	a.cache = make(map[string]cachedGrants)
+	a.mu.Unlock()
+}
+
+// Grants returns the permissions a user holds through all their roles
+func (a *Authorizer) Grants(ctx context.Context, username string) ([]Grant, error) {
+	a.mu.RLock()
+	cached, ok := a.cache[username]
+	a.mu.RUnlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.grants, nil
+	}
+
+	rows, err := a.general.GetUserGrants(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	grants := make([]Grant, 0, len(rows))
+	for _, row := range rows {
+		grants = append(grants, Grant{Permission: row.Permission, DeviceTags: row.DeviceTags})
+	}
+
+	a.mu.Lock()
+	a.cache[username] = cachedGrants{grants: grants, expires: time.Now().Add(grantCacheTTL)}
+	a.mu.Unlock()
+	return grants, nil
+}
+
+// deviceScoped reports whether grants of perm can be limited to device tags.
+// User management, the MAC lists and unlisted routes are not tied to a
+// device, so scoped grants never cover them.
+func deviceScoped(perm string) bool {
+	return perm != PermUsersManage && perm != PermMACManage && perm != PermAll
+}
+
+// Allowed reports whether the user holds perm for the given device. An empty
+// host means the device is unknown, which only unscoped grants cover.
+func (a *Authorizer) Allowed(ctx context.Context, username, perm, host string) (bool, error) {
+	grants, err := a.Grants(ctx, username)
+	if err != nil {
+		return false, err
+	}
+	return grantsAllow(grants, perm, host, func(host string) (string, error) {
+		return a.deviceTag(ctx, host)
+	})
+}
+
+// grantsAllow is the decision behind Allowed. deviceTag is only called when a
+// scoped grant could cover the host.
+func grantsAllow(grants []Grant, perm, host string, deviceTag func(host string) (string, error)) (bool, error) {
+	var scoped []Grant
+	for _, g := range grants {
+		if g.Permission != perm && g.Permission != PermAll {
+			continue
+		}
+		if len(g.DeviceTags) == 0 {
+			return true, nil
+		}
+		if deviceScoped(perm) {
+			scoped = append(scoped, g)
+		}
+	}
+
+	// Scoped grants fail closed when the target cannot be resolved
+	if len(scoped) == 0 || host == "" {
+		return false, nil
+	}
+
+	tag, err := deviceTag(host)
+	if err != nil {
+		return false, err
+	}
+	for _, g := range scoped {
+		for _, t := range g.DeviceTags {
+			if t == tag {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// TagScope returns the device tags the user holds perm for, for handlers
+// that work on many devices at once. all is true when a grant covers every
+// device.
+func (a *Authorizer) TagScope(ctx context.Context, username, perm string) (all bool, tags map[string]bool, err error) {
+	grants, err := a.Grants(ctx, username)
+	if err != nil {
+		return false, nil, err
+	}
+
+	tags = make(map[string]bool)
+	for _, g := range grants {
+		if g.Permission != perm && g.Permission != PermAll {
+			continue
+		}
+		if len(g.DeviceTags) == 0 {
+			return true, nil, nil
+		}
+		if !deviceScoped(perm) {
+			continue
+		}
+		for _, t := range g.DeviceTags {
+			tags[t] = true
+		}
+	}
+	return false, tags, nil
+}
+
+// HasAdminPower reports whether any of the user's roles grants every
+// permission, or user management (which can grant everything), even if only
+// on some device tags. The admin MFA policy applies to these users.
+func (a *Authorizer) HasAdminPower(ctx context.Context, username string) (bool, error) {
+	grants, err := a.Grants(ctx, username)
+	if err != nil {
+		return false, err
+	}
+	for _, g := range grants {
+		if g.Permission == PermAll || g.Permission == PermUsersManage {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// UserAllowed is Allowed for the caller of a request. Callers using an API
+// token must also have perm in the token's scopes. Handlers that make their
+// own permission decision use this rather than Allowed.
+func (a *Authorizer) UserAllowed(ctx context.Context, user *UserInfo, perm, host string) (bool, error) {
+	if user.ViaAPIToken() && !scopeAllows(user.Scopes, perm) {
+		return false, nil
+	}
+	return a.Allowed(ctx, user.Username, perm, host)
+}
+
+// UserTagScope is TagScope for the caller of a request. An API token that is
+// not scoped for perm covers no devices.
+func (a *Authorizer) UserTagScope(ctx context.Context, user *UserInfo, perm string) (all bool, tags map[string]bool, err error) {
+	if user.ViaAPIToken() && !scopeAllows(user.Scopes, perm) {
+		return false, map[string]bool{}, nil
+	}
+	return a.TagScope(ctx, user.Username, perm)
+}
+
+// HostFilter returns a check for whether the caller holds perm on a host, for
+// handlers that list or change records of many devices at once. Hosts that
+// are not registered only pass for unscoped grants.
+func (a *Authorizer) HostFilter(ctx context.Context, user *UserInfo, perm string) (func(host string) bool, error) {
+	all, tags, err := a.UserTagScope(ctx, user, perm)
+	if err != nil {
+		return nil, err
+	}
+	if all {
+		return func(string) bool { return true }, nil
+	}
+	if len(tags) == 0 {
+		return func(string) bool { return false }, nil
+	}
+
+	devices, err := a.server.ListDeviceInventory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hostTags := make(map[string]string, len(devices))
+	for _, d := range devices {
+		hostTags[d.Ip] = d.Tag
+	}
+	return func(host string) bool {
+		tag, ok := hostTags[host]
+		return ok && tags[tag]
+	}, nil
+}
+
+// deviceTag looks up the tag of a registered device; unknown devices have no tag
+func (a *Authorizer) deviceTag(ctx context.Context, host string) (string, error) {
+	device, err := a.server.GetServerDeviceByIP(ctx, host)
+	if err == sql.ErrNoRows {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return device.Tag, nil
+}
+
+// maxPeekBody bounds how much of a request body is buffered to find its host
+const maxPeekBody = 1 << 20
+
+// peekBody reads up to maxPeekBody bytes of the request body and puts them
+// back in front of the unread rest, so the handler still gets the whole body
+func peekBody(r *http.Request) ([]byte, error) {
+	original := r.Body
+	peek, err := io.ReadAll(io.LimitReader(original, maxPeekBody))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peek), original), original}
+	return peek, err
+}
+
+// requestHost finds the device a request targets, from ?host= / ?ip= and the
+// "host" or "ip" field of a JSON body. The body is restored for the handler.
+// Handlers read whichever of these they expect, so ok is false when the
+// request names more than one device and the check could be made against a
+// different one than the handler acts on.
+func requestHost(r *http.Request) (host string, ok bool) {
+	query := r.URL.Query()
+	candidates := []string{query.Get("host"), query.Get("ip")}
+
+	if r.Body != nil && r.Method != http.MethodGet {
+		if body, err := peekBody(r); err == nil {
+			var target struct {
+				Host string `json:"host"`
+				IP   string `json:"ip"`
+			}
+			if json.Unmarshal(body, &target) == nil {
+				candidates = append(candidates, target.Host, target.IP)
+			}
+		}
+	}
+
+	for _, c := range candidates {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if host != "" && c != host {
+			return "", false
+		}
+		host = c
+	}
+	return host, true
+}
+
+// HandleMyPermissions returns the caller's effective permissions so the UI
+// can hide what they cannot use
+func HandleMyPermissions(authz *Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, ok := GetUserFromContext(r)
+		if !ok {
+			http.Error(w, "User context not found", http.StatusInternalServerError)
+			return
+		}
+
+		grants, err := authz.Grants(r.Context(), user.Username)
+		if err != nil {
+			log.Printf("❌ Failed to load permissions for %s: %v", user.Username, err)
+			http.Error(w, "Failed to load permissions", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":      "success",
+			"username":    user.Username,
+			"role":        user.Role,
+			"permissions": grants,
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScopeAllows(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		perm   string
+		want   bool
+	}{
+		{"unscoped token", nil, PermCmdExec, true},
+		{"empty scope list", []string{}, PermUsersManage, true},
+		{"exact scope", []string{PermDevicesRead, PermCmdExec}, PermCmdExec, true},
+		{"wildcard scope", []string{PermAll}, PermUsersManage, true},
+		{"missing scope", []string{PermDevicesRead}, PermCmdExec, false},
+		{"no prefix matching", []string{"devices"}, PermDevicesRead, false},
+		{"all needs the wildcard", []string{PermDevicesRead}, PermAll, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scopeAllows(tt.scopes, tt.perm); got != tt.want {
+				t.Errorf("scopeAllows(%v, %q) = %v, want %v", tt.scopes, tt.perm, got, tt.want)
+			}
+		})
+	}
+}
+
+// authorizerWith returns an Authorizer whose grant cache already holds the
+// given users, so checks that need no device lookup never reach the database
+func authorizerWith(users map[string][]Grant) *Authorizer {
+	a := &Authorizer{cache: make(map[string]cachedGrants)}
+	for username, grants := range users {
+		a.cache[username] = cachedGrants{grants: grants, expires: time.Now().Add(time.Hour)}
+	}
+	return a
+}
+
+func TestAuthorizerAllowed(t *testing.T) {
+	a := authorizerWith(map[string][]Grant{
+		"admin":  {{Permission: PermAll}},
+		"ops":    {{Permission: PermCmdExec}, {Permission: PermDevicesRead}},
+		"web":    {{Permission: PermCmdExec, DeviceTags: []string{"web"}}},
+		"webmgr": {{Permission: PermAll, DeviceTags: []string{"web"}}},
+		"nobody": nil,
+	})
+
+	tests := []struct {
+		name     string
+		username string
+		perm     string
+		host     string
+		want     bool
+	}{
+		{"wildcard grant", "admin", PermUsersManage, "", true},
+		{"wildcard grant with host", "admin", PermCmdExec, "10.0.0.1", true},
+		{"unscoped grant without host", "ops", PermCmdExec, "", true},
+		{"unscoped grant with host", "ops", PermDevicesRead, "10.0.0.1", true},
+		{"permission not granted", "ops", PermTerminal, "10.0.0.1", false},
+		{"no grants", "nobody", PermDevicesRead, "10.0.0.1", false},
+		{"scoped grant without host fails closed", "web", PermCmdExec, "", false},
+		{"scoped wildcard without host fails closed", "webmgr", PermCmdExec, "", false},
+		{"scoped wildcard never covers user management", "webmgr", PermUsersManage, "", false},
+		{"scoped wildcard never covers the MAC lists", "webmgr", PermMACManage, "", false},
+		{"scoped wildcard never covers unlisted routes", "webmgr", PermAll, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.Allowed(context.Background(), tt.username, tt.perm, tt.host)
+			if err != nil {
+				t.Fatalf("Allowed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Allowed(%q, %q, %q) = %v, want %v", tt.username, tt.perm, tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrantsAllowScoped(t *testing.T) {
+	tags := map[string]string{"10.0.0.1": "web", "10.0.0.2": "db"}
+	lookup := func(host string) (string, error) {
+		return tags[host], nil
+	}
+
+	tests := []struct {
+		name   string
+		grants []Grant
+		perm   string
+		host   string
+		want   bool
+	}{
+		{"host in scope", []Grant{{Permission: PermCmdExec, DeviceTags: []string{"web"}}}, PermCmdExec, "10.0.0.1", true},
+		{"host out of scope", []Grant{{Permission: PermCmdExec, DeviceTags: []string{"web"}}}, PermCmdExec, "10.0.0.2", false},
+		{"unregistered host", []Grant{{Permission: PermCmdExec, DeviceTags: []string{"web"}}}, PermCmdExec, "10.9.9.9", false},
+		{"one of several tags", []Grant{{Permission: PermCmdExec, DeviceTags: []string{"web", "db"}}}, PermCmdExec, "10.0.0.2", true},
+		{"scoped wildcard", []Grant{{Permission: PermAll, DeviceTags: []string{"db"}}}, PermTerminal, "10.0.0.2", true},
+		{"scope of another permission", []Grant{
+			{Permission: PermDevicesRead},
+			{Permission: PermCmdExec, DeviceTags: []string{"web"}},
+		}, PermCmdExec, "10.0.0.2", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := grantsAllow(tt.grants, tt.perm, tt.host, lookup)
+			if err != nil {
+				t.Fatalf("grantsAllow: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("grantsAllow(%q, %q) = %v, want %v", tt.perm, tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrantsAllowLookup(t *testing.T) {
+	scoped := []Grant{{Permission: PermCmdExec, DeviceTags: []string{"web"}}}
+
+	called := false
+	_, err := grantsAllow(scoped, PermCmdExec, "", func(string) (string, error) {
+		called = true
+		return "web", nil
+	})
+	if err != nil || called {
+		t.Errorf("empty host looked up the device (err %v)", err)
+	}
+
+	lookupErr := errors.New("database down")
+	allowed, err := grantsAllow(scoped, PermCmdExec, "10.0.0.1", func(string) (string, error) {
+		return "", lookupErr
+	})
+	if allowed || !errors.Is(err, lookupErr) {
+		t.Errorf("lookup failure gave %v, %v; want false, %v", allowed, err, lookupErr)
+	}
+}
+
+func TestUserAllowedScopes(t *testing.T) {
+	a := authorizerWith(map[string][]Grant{"admin": {{Permission: PermAll}}})
+
+	tests := []struct {
+		name string
+		user UserInfo
+		perm string
+		want bool
+	}{
+		{"session", UserInfo{Username: "admin"}, PermUsersManage, true},
+		{"token with scope", UserInfo{Username: "admin", APITokenID: 1, Scopes: []string{PermCmdExec}}, PermCmdExec, true},
+		{"token without scope", UserInfo{Username: "admin", APITokenID: 1, Scopes: []string{PermDevicesRead}}, PermCmdExec, false},
+		{"unscoped token", UserInfo{Username: "admin", APITokenID: 1}, PermUsersManage, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.UserAllowed(context.Background(), &tt.user, tt.perm, "")
+			if err != nil {
+				t.Fatalf("UserAllowed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("UserAllowed(%q) = %v, want %v", tt.perm, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestHost(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		wantHost string
+		wantOK   bool
+	}{
+		{"query host", http.MethodGet, "/x?host=10.0.0.1", "", "10.0.0.1", true},
+		{"query ip", http.MethodGet, "/x?ip=10.0.0.1", "", "10.0.0.1", true},
+		{"body host", http.MethodPost, "/x", `{"host":"10.0.0.1"}`, "10.0.0.1", true},
+		{"body ip", http.MethodPost, "/x", `{"ip":" 10.0.0.1 "}`, "10.0.0.1", true},
+		{"query and body agree", http.MethodPost, "/x?host=10.0.0.1", `{"host":"10.0.0.1"}`, "10.0.0.1", true},
+		{"query and body differ", http.MethodPost, "/x?host=10.0.0.1", `{"host":"10.0.0.2"}`, "", false},
+		{"query host and ip differ", http.MethodGet, "/x?host=10.0.0.1&ip=10.0.0.2", "", "", false},
+		{"body host and ip differ", http.MethodPost, "/x", `{"host":"10.0.0.1","ip":"10.0.0.2"}`, "", false},
+		{"no target", http.MethodPost, "/x", `{"command":"uptime"}`, "", true},
+		{"body ignored on GET", http.MethodGet, "/x?host=10.0.0.1", `{"host":"10.0.0.2"}`, "10.0.0.1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			host, ok := requestHost(r)
+			if host != tt.wantHost || ok != tt.wantOK {
+				t.Errorf("requestHost = %q, %v; want %q, %v", host, ok, tt.wantHost, tt.wantOK)
+			}
+			if rest, _ := io.ReadAll(r.Body); string(rest) != tt.body {
+				t.Errorf("handler would read %q, want %q", rest, tt.body)
+			}
+		})
+	}
+}
+
+func TestAuthorizeRejectsConflictingHosts(t *testing.T) {
+	a := authorizerWith(map[string][]Grant{
+		"web": {{Permission: PermCmdExec, DeviceTags: []string{"web"}}},
+	})
+	called := false
+	handler := a.Authorize(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	// The query names an in-scope device, the body the one the handler runs on
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/server/config1/cmd?host=10.0.0.1",
+		strings.NewReader(`{"host":"10.0.0.2","command":"id"}`))
+	r = r.WithContext(context.WithValue(r.Context(), UserContextKey, UserInfo{Username: "web"}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if called {
+		t.Error("handler ran for a request naming two devices")
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
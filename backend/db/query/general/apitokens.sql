@@ -0,0 +1,39 @@
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (username, name, token_prefix, token_hash, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, created_at;
+
+-- name: GetAPITokenByHash :one
+SELECT t.id, t.username, u.role, t.scopes, t.expires_at
+FROM api_tokens t
+JOIN users u ON u.name = t.username
+WHERE t.token_hash = $1;
+
+-- name: TouchAPIToken :exec
+UPDATE api_tokens
+SET last_used_at = now(), last_used_ip = sqlc.arg(ip)
+WHERE id = sqlc.arg(id)
+  AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute' OR last_used_ip <> sqlc.arg(ip));
+
+-- name: ListAPITokensByUser :many
+SELECT id, username, name, token_prefix, scopes, expires_at, last_used_at, last_used_ip, created_at
+FROM api_tokens
+WHERE username = $1
+ORDER BY created_at DESC;
+
+-- name: ListAllAPITokens :many
+SELECT id, username, name, token_prefix, scopes, expires_at, last_used_at, last_used_ip, created_at
+FROM api_tokens
+ORDER BY username, created_at DESC;
+
+-- name: DeleteUserAPIToken :execrows
+DELETE FROM api_tokens
+WHERE id = $1 AND username = $2;
+
+-- name: DeleteAPIToken :execrows
+DELETE FROM api_tokens
+WHERE id = $1;
+
+-- name: DeleteAPITokensByUser :execrows
+DELETE FROM api_tokens
+WHERE username = $1;
@@ -0,0 +1,25 @@
+-- name: CreateAuditEntry :exec
+INSERT INTO audit_log (actor, api_token_id, source_ip, method, action, target_host, payload, status_code, outcome, duration_ms)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+
+-- name: ListAuditEntries :many
+SELECT id, created_at, actor, api_token_id, source_ip, method, action, target_host, payload, status_code, outcome, duration_ms
+FROM audit_log
+WHERE (sqlc.narg(actor)::text IS NULL OR actor = sqlc.narg(actor))
+  AND (sqlc.narg(target_host)::text IS NULL OR target_host = sqlc.narg(target_host))
+  AND (sqlc.narg(action)::text IS NULL OR action LIKE sqlc.narg(action) || '%')
+  AND (sqlc.narg(outcome)::text IS NULL OR outcome = sqlc.narg(outcome))
+  AND (sqlc.narg(since)::timestamptz IS NULL OR created_at >= sqlc.narg(since))
+  AND (sqlc.narg(until)::timestamptz IS NULL OR created_at < sqlc.narg(until))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: CountAuditEntries :one
+SELECT COUNT(*)
+FROM audit_log
+WHERE (sqlc.narg(actor)::text IS NULL OR actor = sqlc.narg(actor))
+  AND (sqlc.narg(target_host)::text IS NULL OR target_host = sqlc.narg(target_host))
+  AND (sqlc.narg(action)::text IS NULL OR action LIKE sqlc.narg(action) || '%')
+  AND (sqlc.narg(outcome)::text IS NULL OR outcome = sqlc.narg(outcome))
+  AND (sqlc.narg(since)::timestamptz IS NULL OR created_at >= sqlc.narg(since))
+  AND (sqlc.narg(until)::timestamptz IS NULL OR created_at < sqlc.narg(until));
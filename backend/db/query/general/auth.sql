@@ -1,30 +1,72 @@
 -- name: GetUserByName :one
-SELECT id, name, role, email, password_hash 
+SELECT id, name, role, email, password_hash, auth_provider
 FROM users 
 WHERE name = $1;
 
--- name: SaveRefreshToken :exec
-INSERT INTO user_sessions (username, refresh_token, expires_at)
-VALUES ($1, $2, $3);
+-- name: CreateProvisionedUser :one
+INSERT INTO users (name, role, email, password_hash, auth_provider)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, name, role, email, password_hash, auth_provider;
 
--- name: GetRefreshToken :one
-SELECT username, expires_at 
+-- name: UpdateProvisionedUser :exec
+UPDATE users
+SET role = $2, email = $3
+WHERE name = $1 AND auth_provider = $4;
+
+-- name: CreateSession :one
+INSERT INTO user_sessions (username, token_hash, user_agent, ip_address, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id;
+
+-- name: GetSessionByTokenHash :one
+SELECT id, username, expires_at, last_used_at
 FROM user_sessions 
-WHERE refresh_token = $1 AND expires_at > now();
+WHERE token_hash = $1 AND expires_at > now();
 
--- name: DeleteRefreshToken :exec
-DELETE FROM user_sessions 
-WHERE refresh_token = $1;
+-- name: GetSessionByPreviousTokenHash :one
+SELECT id, username, last_used_at
+FROM user_sessions 
+WHERE previous_token_hash = $1;
 
--- name: CleanExpiredSessions :exec
-DELETE FROM user_sessions 
-WHERE expires_at < now();
+-- name: RotateSessionToken :execrows
+UPDATE user_sessions
+SET previous_token_hash = token_hash,
+    token_hash = sqlc.arg(new_token_hash),
+    ip_address = sqlc.arg(ip_address),
+    user_agent = sqlc.arg(user_agent),
+    expires_at = sqlc.arg(expires_at),
+    last_used_at = now()
+WHERE id = sqlc.arg(id) AND token_hash = sqlc.arg(old_token_hash);
 
--- name: GetValidRefreshTokenByUser :one
-SELECT refresh_token, expires_at, created_at
+-- name: ListSessionsByUser :many
+SELECT id, user_agent, ip_address, created_at, last_used_at, expires_at
 FROM user_sessions 
 WHERE username = $1 AND expires_at > now()
-ORDER BY created_at DESC
-LIMIT 1;
+ORDER BY last_used_at DESC;
 
+-- name: DeleteSessionByID :exec
+DELETE FROM user_sessions 
+WHERE id = $1;
+
+-- name: DeleteSessionByTokenHash :one
+DELETE FROM user_sessions 
+WHERE token_hash = $1
+RETURNING id, username;
 
+-- name: DeleteUserSession :execrows
+DELETE FROM user_sessions 
+WHERE id = $1 AND username = $2;
+
+-- name: DeleteSessionsByUser :many
+DELETE FROM user_sessions 
+WHERE username = $1
+RETURNING id;
+
+-- name: DeleteOtherSessionsByUser :many
+DELETE FROM user_sessions 
+WHERE username = $1 AND id <> $2
+RETURNING id;
+
+-- name: CleanExpiredSessions :exec
+DELETE FROM user_sessions 
+WHERE expires_at < now();
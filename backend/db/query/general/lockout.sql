@@ -0,0 +1,35 @@
+-- name: GetLoginLock :one
+SELECT locked_until
+FROM login_failures
+WHERE scope = $1 AND key = $2 AND locked_until > now();
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_failures (scope, key, failures, last_failure_at)
+VALUES (sqlc.arg(scope), sqlc.arg(key), 1, now())
+ON CONFLICT (scope, key) DO UPDATE
+SET failures = CASE
+        WHEN login_failures.last_failure_at < sqlc.arg(reset_before) THEN 1
+        ELSE login_failures.failures + 1
+    END,
+    last_failure_at = now()
+RETURNING failures;
+
+-- name: SetLoginLock :exec
+UPDATE login_failures
+SET locked_until = $3
+WHERE scope = $1 AND key = $2;
+
+-- name: ClearLoginFailures :execrows
+DELETE FROM login_failures
+WHERE scope = $1 AND key = $2;
+
+-- name: ListLoginLocks :many
+SELECT scope, key, failures, last_failure_at, locked_until
+FROM login_failures
+WHERE locked_until > now()
+ORDER BY locked_until DESC;
+
+-- name: DeleteExpiredLoginFailures :execrows
+DELETE FROM login_failures
+WHERE last_failure_at < sqlc.arg(reset_before)
+  AND (locked_until IS NULL OR locked_until < now());
@@ -0,0 +1,43 @@
+-- name: ListMACEntries :many
+SELECT id, mac, status, comment, block, expires_at, created_by, created_at, updated_at
+FROM mac_access_status
+ORDER BY created_at DESC;
+
+-- name: ListActiveMACEntries :many
+SELECT id, mac, status, comment, block, expires_at, created_by, created_at, updated_at
+FROM mac_access_status
+WHERE expires_at IS NULL OR expires_at > NOW();
+
+-- name: CreateMACEntry :one
+INSERT INTO mac_access_status (mac, status, comment, block, expires_at, created_by)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, mac, status, comment, block, expires_at, created_by, created_at, updated_at;
+
+-- name: UpdateMACEntry :execrows
+UPDATE mac_access_status
+SET status = $2, comment = $3, block = $4, expires_at = $5, updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteMACEntry :execrows
+DELETE FROM mac_access_status
+WHERE id = $1;
+
+-- name: UpsertMACSighting :one
+INSERT INTO mac_sightings (mac, host, ip, interface)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (mac, host) DO UPDATE
+SET ip = EXCLUDED.ip, interface = EXCLUDED.interface, last_seen = NOW()
+RETURNING alerted_at;
+
+-- name: MarkMACSightingAlerted :exec
+UPDATE mac_sightings
+SET alerted_at = NOW()
+WHERE mac = $1 AND host = $2;
+
+-- name: ListMACSightings :many
+SELECT mac, host, ip, interface, first_seen, last_seen, alerted_at
+FROM mac_sightings
+WHERE (sqlc.narg(host)::text IS NULL OR host = sqlc.narg(host))
+  AND (sqlc.narg(mac)::text IS NULL OR mac = sqlc.narg(mac))
+ORDER BY last_seen DESC
+LIMIT 1000;
@@ -0,0 +1,45 @@
+-- name: GetUserMFA :one
+SELECT username, totp_secret, enabled, last_used_step, enabled_at
+FROM user_mfa
+WHERE username = $1;
+
+-- name: UpsertUserMFASecret :exec
+INSERT INTO user_mfa (username, totp_secret)
+VALUES ($1, $2)
+ON CONFLICT (username) DO UPDATE
+SET totp_secret = EXCLUDED.totp_secret,
+    enabled = FALSE,
+    last_used_step = 0,
+    enabled_at = NULL;
+
+-- name: EnableUserMFA :exec
+UPDATE user_mfa
+SET enabled = TRUE, enabled_at = now(), last_used_step = $2
+WHERE username = $1;
+
+-- name: UpdateMFALastUsedStep :execrows
+UPDATE user_mfa
+SET last_used_step = $2
+WHERE username = $1 AND last_used_step < $2;
+
+-- name: DeleteUserMFA :exec
+DELETE FROM user_mfa
+WHERE username = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO user_recovery_codes (username, code_hash)
+VALUES ($1, $2);
+
+-- name: UseRecoveryCode :execrows
+UPDATE user_recovery_codes
+SET used_at = now()
+WHERE username = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*)
+FROM user_recovery_codes
+WHERE username = $1 AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM user_recovery_codes
+WHERE username = $1;
@@ -0,0 +1,33 @@
+-- name: GetUserByEmail :one
+SELECT id, name, role, email, password_hash, auth_provider
+FROM users
+WHERE email = $1;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET password_hash = $2
+WHERE name = $1 AND auth_provider = 'local';
+
+-- name: CreatePasswordReset :exec
+INSERT INTO password_resets (username, token_hash, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: CountRecentPasswordResets :one
+SELECT COUNT(*)
+FROM password_resets
+WHERE username = $1 AND created_at > $2;
+
+-- name: GetPasswordReset :one
+SELECT username
+FROM password_resets
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now();
+
+-- name: ConsumePasswordReset :one
+UPDATE password_resets
+SET used_at = now()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING username;
+
+-- name: DeletePasswordResetsByUser :exec
+DELETE FROM password_resets
+WHERE username = $1;
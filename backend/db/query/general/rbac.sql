@@ -0,0 +1,70 @@
+-- name: UpsertRole :exec
+INSERT INTO roles (name, description, builtin)
+VALUES ($1, $2, $3)
+ON CONFLICT (name) DO UPDATE
+SET description = EXCLUDED.description, builtin = EXCLUDED.builtin;
+
+-- name: GetRole :one
+SELECT name, description, builtin, created_at
+FROM roles
+WHERE name = $1;
+
+-- name: ListRoles :many
+SELECT name, description, builtin, created_at
+FROM roles
+ORDER BY builtin DESC, name;
+
+-- name: DeleteRole :execrows
+DELETE FROM roles
+WHERE name = $1 AND builtin = FALSE;
+
+-- name: CountUsersWithPrimaryRole :one
+SELECT COUNT(*)
+FROM users
+WHERE role = $1;
+
+-- name: ListAllRolePermissions :many
+SELECT role, permission
+FROM role_permissions
+ORDER BY role, permission;
+
+-- name: AddRolePermission :exec
+INSERT INTO role_permissions (role, permission)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteRolePermissions :exec
+DELETE FROM role_permissions
+WHERE role = $1;
+
+-- name: AssignUserRole :exec
+INSERT INTO user_roles (username, role, device_tags)
+VALUES ($1, $2, $3)
+ON CONFLICT (username, role) DO UPDATE
+SET device_tags = EXCLUDED.device_tags;
+
+-- name: UnassignUserRole :execrows
+DELETE FROM user_roles
+WHERE username = $1 AND role = $2;
+
+-- name: ListUserRoleAssignments :many
+SELECT id, username, role, device_tags, created_at
+FROM user_roles
+WHERE username = $1
+ORDER BY role;
+
+-- name: GetUserGrants :many
+SELECT rp.permission, '{}'::text[] AS device_tags
+FROM users u
+JOIN role_permissions rp ON rp.role = u.role
+WHERE u.name = $1
+UNION ALL
+SELECT rp.permission, ur.device_tags
+FROM user_roles ur
+JOIN role_permissions rp ON rp.role = ur.role
+WHERE ur.username = $1;
+
+-- name: UpdateUserRole :execrows
+UPDATE users
+SET role = $2
+WHERE name = $1;
@@ -0,0 +1,42 @@
+-- name: RevokeToken :exec
+INSERT INTO revoked_tokens (jti, username, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: ListRevokedTokens :many
+SELECT jti, expires_at
+FROM revoked_tokens
+WHERE expires_at > now();
+
+-- name: DeleteExpiredRevokedTokens :execrows
+DELETE FROM revoked_tokens
+WHERE expires_at < now();
+
+-- name: RevokeUserTokens :exec
+INSERT INTO user_token_revocations (username, revoked_before)
+VALUES ($1, $2)
+ON CONFLICT (username) DO UPDATE
+SET revoked_before = GREATEST(user_token_revocations.revoked_before, EXCLUDED.revoked_before);
+
+-- name: ListUserTokenRevocations :many
+SELECT username, revoked_before
+FROM user_token_revocations
+WHERE revoked_before > $1;
+
+-- name: DeleteStaleUserTokenRevocations :execrows
+DELETE FROM user_token_revocations
+WHERE revoked_before < $1;
+
+-- name: RevokeSessions :exec
+INSERT INTO revoked_sessions (session_id, username)
+SELECT unnest(sqlc.arg(session_ids)::int[]), sqlc.arg(username)
+ON CONFLICT (session_id) DO NOTHING;
+
+-- name: ListRevokedSessions :many
+SELECT session_id, revoked_at
+FROM revoked_sessions
+WHERE revoked_at > $1;
+
+-- name: DeleteStaleRevokedSessions :execrows
+DELETE FROM revoked_sessions
+WHERE revoked_at < $1;
@@ -9,6 +9,19 @@ WHERE name = $1;
 
 
 -- name: ListUsers :many
-SELECT id, name, role, email
-FROM users
-ORDER BY name;
+SELECT u.id, u.name, u.role, u.email, COALESCE(m.enabled, FALSE)::boolean AS mfa_enabled
+FROM users u
+LEFT JOIN user_mfa m ON m.username = u.name
+ORDER BY u.name;
+
+
+-- name: GetAppSetting :one
+SELECT value
+FROM app_settings
+WHERE key = $1;
+
+-- name: UpsertAppSetting :exec
+INSERT INTO app_settings (key, value, updated_at)
+VALUES ($1, $2, now())
+ON CONFLICT (key) DO UPDATE
+SET value = EXCLUDED.value, updated_at = now();
@@ -28,6 +28,11 @@ FROM alerts
 WHERE host = $1 AND status = 'notseen'
 ORDER BY time DESC;
 
+-- name: GetAlertHostsByIDs :many
+SELECT id, host
+FROM alerts
+WHERE id = ANY(sqlc.arg(ids)::int[]);
+
 -- name: MarkAlertAsSeen :exec
 UPDATE alerts 
 SET status = 'seen'
@@ -1,6 +1,6 @@
 -- name: CreateServerDevice :one
-INSERT INTO server_devices (ip, tag, os, access_token)
-VALUES ($1, $2, $3, $4)
+INSERT INTO server_devices (ip, tag, os, access_token, agent_port)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING id, ip, tag, os, created_at;
 
 -- name: DeleteServerDevice :exec
@@ -8,12 +8,47 @@ DELETE FROM server_devices
 WHERE ip = $1;
 
 -- name: GetAllServerDevices :many
-SELECT id, ip, tag, os, created_at , access_token
+SELECT id, ip, tag, os, created_at , access_token, agent_port, agent_scheme, agent_base_path,
+       status, status_changed_at, status_checked_at, last_seen_at, last_error, last_error_at
 FROM server_devices 
+WHERE revoked_at IS NULL
 ORDER BY created_at ASC;
 
 -- name: GetServerDeviceByIP :one
 SELECT id, ip, tag, os, access_token
 FROM server_devices 
-WHERE ip = $1;
+WHERE ip = $1 AND revoked_at IS NULL;
+
+-- name: GetServerDeviceEndpoint :one
+SELECT ip, tag, os, agent_port, agent_scheme, agent_base_path, agent_ca_cert, agent_fingerprint, tls_fingerprint
+FROM server_devices
+WHERE ip = $1 AND revoked_at IS NULL;
+
+-- name: UpdateServerDevice :execrows
+UPDATE server_devices
+SET tag = $2, os = $3, agent_port = $4, agent_scheme = $5, agent_base_path = $6,
+    agent_ca_cert = $7, agent_fingerprint = $8, updated_at = now()
+WHERE ip = $1 AND revoked_at IS NULL;
+
+-- name: ListServerDeviceIPs :many
+SELECT ip
+FROM server_devices;
+
+-- name: ListDeviceInventory :many
+SELECT id, ip, tag, os, access_token, agent_port, agent_scheme, agent_base_path, agent_ca_cert,
+       agent_fingerprint, tls_fingerprint, reverse_connect, status, status_checked_at, last_seen_at,
+       revoked_at, created_at, updated_at
+FROM server_devices
+ORDER BY created_at ASC;
 
+-- name: UpsertServerDevice :one
+INSERT INTO server_devices (ip, tag, os, access_token, agent_port, agent_scheme, agent_base_path,
+                            agent_ca_cert, agent_fingerprint)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (ip) DO UPDATE
+SET tag = EXCLUDED.tag, os = EXCLUDED.os, agent_port = EXCLUDED.agent_port,
+    agent_scheme = EXCLUDED.agent_scheme, agent_base_path = EXCLUDED.agent_base_path,
+    agent_ca_cert = EXCLUDED.agent_ca_cert, agent_fingerprint = EXCLUDED.agent_fingerprint,
+    updated_at = now()
+WHERE server_devices.revoked_at IS NULL
+RETURNING id, (xmax = 0)::boolean AS inserted;
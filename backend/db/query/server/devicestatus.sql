@@ -0,0 +1,31 @@
+-- name: RecordDeviceStatus :one
+WITH prev AS (
+    SELECT ip, status FROM server_devices
+    WHERE ip = sqlc.arg(ip) AND revoked_at IS NULL
+    FOR UPDATE
+)
+UPDATE server_devices d
+SET status = sqlc.arg(status),
+    status_changed_at = CASE WHEN prev.status = sqlc.arg(status) THEN d.status_changed_at ELSE now() END,
+    status_checked_at = now(),
+    last_seen_at = CASE WHEN sqlc.arg(status) <> 'offline' THEN now() ELSE d.last_seen_at END,
+    last_error = CASE WHEN sqlc.arg(reason)::text <> '' THEN sqlc.arg(reason)::text ELSE d.last_error END,
+    last_error_at = CASE WHEN sqlc.arg(reason)::text <> '' THEN now() ELSE d.last_error_at END
+FROM prev
+WHERE d.ip = prev.ip
+RETURNING prev.status AS previous_status;
+
+-- name: CreateDeviceStatusEvent :exec
+INSERT INTO device_status_events (host, status, previous_status, reason)
+VALUES ($1, $2, $3, $4);
+
+-- name: ListDeviceStatusEvents :many
+SELECT id, host, status, previous_status, reason, created_at
+FROM device_status_events
+WHERE (sqlc.narg(host)::text IS NULL OR host = sqlc.narg(host)::text)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: DeleteOldDeviceStatusEvents :execrows
+DELETE FROM device_status_events
+WHERE created_at < $1;
@@ -0,0 +1,18 @@
+-- name: SetDeviceTLS :execrows
+UPDATE server_devices
+SET tls_fingerprint = $2, tls_expires_at = $3, updated_at = now()
+WHERE ip = $1 AND revoked_at IS NULL;
+
+-- name: ListAgentRoutes :many
+SELECT ip, tls_fingerprint, reverse_connect, agent_port, agent_scheme, agent_base_path, agent_ca_cert, agent_fingerprint
+FROM server_devices
+WHERE revoked_at IS NULL AND (tls_fingerprint <> '' OR reverse_connect OR agent_port <> 0 OR agent_scheme <> ''
+    OR agent_base_path <> '' OR agent_ca_cert <> '' OR agent_fingerprint <> '');
+
+-- name: ListDevicesForTLSProvisioning :many
+SELECT ip, access_token, tls_fingerprint
+FROM server_devices
+WHERE revoked_at IS NULL AND (tls_fingerprint = '' OR tls_expires_at < $1)
+    -- Devices with a custom scheme, path, CA or pin are reached through something we don't manage
+    AND agent_scheme = '' AND agent_base_path = '' AND agent_ca_cert = '' AND agent_fingerprint = ''
+ORDER BY ip;
@@ -0,0 +1,36 @@
+-- name: GetDeviceTokenState :one
+SELECT ip, access_token, pending_token, token_rotated_at, revoked_at
+FROM server_devices
+WHERE ip = $1;
+
+-- name: SetDevicePendingToken :execrows
+UPDATE server_devices
+SET pending_token = $2, updated_at = now()
+WHERE ip = $1 AND revoked_at IS NULL;
+
+-- name: PromoteDeviceToken :execrows
+UPDATE server_devices
+SET access_token = pending_token, pending_token = NULL, token_rotated_at = now(), updated_at = now()
+WHERE ip = $1 AND pending_token = $2 AND revoked_at IS NULL;
+
+-- name: ClearDevicePendingToken :exec
+UPDATE server_devices
+SET pending_token = NULL, updated_at = now()
+WHERE ip = $1 AND pending_token = $2;
+
+-- name: ListDevicesForRotation :many
+SELECT ip, access_token, pending_token, token_rotated_at
+FROM server_devices
+WHERE revoked_at IS NULL AND (pending_token IS NOT NULL OR token_rotated_at < $1)
+ORDER BY token_rotated_at ASC;
+
+-- name: RevokeServerDevice :execrows
+UPDATE server_devices
+SET revoked_at = now(), access_token = '', pending_token = NULL, updated_at = now()
+WHERE ip = $1 AND revoked_at IS NULL;
+
+-- name: ListRevokedServerDevices :many
+SELECT id, ip, tag, os, revoked_at, created_at
+FROM server_devices
+WHERE revoked_at IS NOT NULL
+ORDER BY revoked_at DESC;
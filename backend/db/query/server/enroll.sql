@@ -0,0 +1,47 @@
+-- name: CreateEnrollmentCode :one
+INSERT INTO enrollment_codes (code_hash, tag, auto_approve, created_by, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, tag, auto_approve, created_by, expires_at, created_at;
+
+-- name: ListEnrollmentCodes :many
+SELECT id, tag, auto_approve, created_by, expires_at, used_at, created_at
+FROM enrollment_codes
+WHERE used_at IS NULL AND expires_at > now()
+ORDER BY created_at DESC;
+
+-- name: DeleteEnrollmentCode :execrows
+DELETE FROM enrollment_codes
+WHERE id = $1 AND used_at IS NULL;
+
+-- name: ConsumeEnrollmentCode :one
+UPDATE enrollment_codes
+SET used_at = now()
+WHERE code_hash = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING id, tag, auto_approve;
+
+-- name: CreateDeviceEnrollment :one
+INSERT INTO device_enrollments (code_id, ip, source_ip, hostname, os, tag, access_token, tls_fingerprint, tls_expires_at, reverse_connect)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, ip, source_ip, hostname, os, tag, status, created_at;
+
+-- name: ListDeviceEnrollments :many
+SELECT id, ip, source_ip, hostname, os, tag, reverse_connect, status, decided_by, decided_at, created_at
+FROM device_enrollments
+WHERE sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status)
+ORDER BY created_at DESC
+LIMIT 500;
+
+-- name: GetDeviceEnrollment :one
+SELECT id, code_id, ip, source_ip, hostname, os, tag, access_token, tls_fingerprint, tls_expires_at, reverse_connect, status, decided_by, decided_at, created_at
+FROM device_enrollments
+WHERE id = $1;
+
+-- name: DecideDeviceEnrollment :execrows
+UPDATE device_enrollments
+SET status = $2, decided_by = $3, decided_at = now()
+WHERE id = $1 AND status = 'pending';
+
+-- name: ReopenDeviceEnrollment :exec
+UPDATE device_enrollments
+SET status = 'pending', decided_by = '', decided_at = NULL
+WHERE id = $1;
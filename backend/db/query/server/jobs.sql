@@ -0,0 +1,74 @@
+-- name: CreateAgentJob :one
+INSERT INTO agent_jobs (host, operation, params, created_by)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetAgentJob :one
+SELECT * FROM agent_jobs
+WHERE id = $1;
+
+-- name: ListAgentJobs :many
+SELECT id, host, operation, params, status, progress, exit_code, error, cancel_requested,
+       created_by, created_at, started_at, finished_at, updated_at
+FROM agent_jobs
+WHERE (sqlc.narg(host)::text IS NULL OR host = sqlc.narg(host)::text)
+  AND (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status)::text)
+ORDER BY created_at DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ClaimAgentJob :one
+-- Takes the oldest queued job, or a running job whose worker stopped renewing its lease
+UPDATE agent_jobs
+SET status = 'running',
+    worker = sqlc.arg(worker),
+    lease_until = sqlc.arg(lease_until)::timestamptz,
+    started_at = COALESCE(started_at, now()),
+    updated_at = now()
+WHERE id = (
+    SELECT j.id FROM agent_jobs j
+    WHERE j.status = 'queued'
+       OR (j.status = 'running' AND j.lease_until < now())
+    ORDER BY j.created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: UpdateAgentJobProgress :one
+UPDATE agent_jobs
+SET stdout = right(stdout || sqlc.arg(stdout)::text, sqlc.arg(max_output)::int),
+    stderr = right(stderr || sqlc.arg(stderr)::text, sqlc.arg(max_output)::int),
+    stdout_offset = sqlc.arg(stdout_offset),
+    stderr_offset = sqlc.arg(stderr_offset),
+    progress = sqlc.arg(progress),
+    lease_until = sqlc.arg(lease_until)::timestamptz,
+    updated_at = now()
+WHERE id = sqlc.arg(id) AND worker = sqlc.arg(worker) AND status = 'running'
+RETURNING cancel_requested;
+
+-- name: FinishAgentJob :execrows
+UPDATE agent_jobs
+SET status = sqlc.arg(status),
+    stdout = right(stdout || sqlc.arg(stdout)::text, sqlc.arg(max_output)::int),
+    stderr = right(stderr || sqlc.arg(stderr)::text, sqlc.arg(max_output)::int),
+    exit_code = sqlc.narg(exit_code),
+    error = sqlc.arg(error),
+    progress = sqlc.arg(progress),
+    lease_until = NULL,
+    finished_at = now(),
+    updated_at = now()
+WHERE id = sqlc.arg(id) AND worker = sqlc.arg(worker) AND status = 'running';
+
+-- name: RequestAgentJobCancel :one
+-- Queued jobs are cancelled at once, running jobs by their worker
+UPDATE agent_jobs
+SET cancel_requested = true,
+    status = CASE WHEN status = 'queued' THEN 'cancelled' ELSE status END,
+    finished_at = CASE WHEN status = 'queued' THEN now() ELSE finished_at END,
+    updated_at = now()
+WHERE id = $1 AND status IN ('queued', 'running')
+RETURNING status;
+
+-- name: DeleteOldAgentJobs :execrows
+DELETE FROM agent_jobs
+WHERE finished_at < $1;
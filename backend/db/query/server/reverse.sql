@@ -0,0 +1,10 @@
+-- name: SetDeviceReverseConnect :execrows
+UPDATE server_devices
+SET reverse_connect = $2, updated_at = now()
+WHERE ip = $1 AND revoked_at IS NULL;
+
+-- name: ListReverseDevices :many
+SELECT ip, tag, os, access_token, pending_token, tls_fingerprint
+FROM server_devices
+WHERE reverse_connect AND revoked_at IS NULL
+ORDER BY ip;
@@ -0,0 +1,86 @@
+-- name: LockUserTerminalSessions :exec
+-- Serializes session creation per user until the transaction ends
+SELECT pg_advisory_xact_lock(hashtext(sqlc.arg(username)::text));
+
+-- name: CreateTerminalSession :one
+-- Returns no rows when the user already has max_sessions live sessions. Run
+-- it after LockUserTerminalSessions in the same transaction, or concurrent
+-- requests can all pass the count.
+INSERT INTO terminal_sessions (username, host, ticket_hash, lease_until)
+SELECT sqlc.arg(username), sqlc.arg(host), sqlc.arg(ticket_hash), sqlc.arg(lease_until)::timestamptz
+WHERE (
+    SELECT count(*) FROM terminal_sessions
+    WHERE username = sqlc.arg(username)
+      AND status IN ('pending', 'open')
+      AND lease_until > now()
+) < sqlc.arg(max_sessions)::int
+RETURNING *;
+
+-- name: OpenTerminalSession :one
+-- Uses up the ticket, which is good until the pending session's lease runs out
+UPDATE terminal_sessions
+SET status = 'open',
+    ticket_hash = NULL,
+    worker = sqlc.arg(worker),
+    client_ip = sqlc.arg(client_ip),
+    lease_until = sqlc.arg(lease_until)::timestamptz,
+    opened_at = now(),
+    last_input_at = now()
+WHERE ticket_hash = sqlc.arg(ticket_hash)
+  AND status = 'pending'
+  AND lease_until > now()
+RETURNING *;
+
+-- name: RenewTerminalSession :one
+UPDATE terminal_sessions
+SET lease_until = sqlc.arg(lease_until)::timestamptz,
+    last_input_at = sqlc.arg(last_input_at)
+WHERE id = sqlc.arg(id) AND worker = sqlc.arg(worker) AND status = 'open'
+RETURNING close_requested_by;
+
+-- name: CloseTerminalSession :exec
+UPDATE terminal_sessions
+SET status = 'closed',
+    ticket_hash = NULL,
+    close_reason = sqlc.arg(close_reason),
+    exit_code = sqlc.narg(exit_code),
+    error = sqlc.arg(error),
+    lease_until = now(),
+    closed_at = now()
+WHERE id = sqlc.arg(id) AND status <> 'closed';
+
+-- name: ListLiveTerminalSessions :many
+SELECT id, username, host, status, client_ip, worker, close_requested_by,
+       created_at, opened_at, last_input_at
+FROM terminal_sessions
+WHERE status IN ('pending', 'open')
+  AND lease_until > now()
+  AND (sqlc.narg(username)::text IS NULL OR username = sqlc.narg(username)::text)
+  AND (sqlc.narg(host)::text IS NULL OR host = sqlc.narg(host)::text)
+ORDER BY created_at DESC;
+
+-- name: RequestTerminalSessionClose :one
+-- Pending sessions are closed at once, open ones by the worker proxying them
+UPDATE terminal_sessions
+SET close_requested_by = sqlc.arg(closed_by),
+    status = CASE WHEN status = 'pending' THEN 'closed' ELSE status END,
+    ticket_hash = NULL,
+    close_reason = CASE WHEN status = 'pending' THEN 'forced' ELSE close_reason END,
+    closed_at = CASE WHEN status = 'pending' THEN now() ELSE closed_at END
+WHERE id = sqlc.arg(id)
+  AND status IN ('pending', 'open')
+  AND lease_until > now()
+RETURNING status;
+
+-- name: CloseStaleTerminalSessions :execrows
+-- Tickets never used, and sessions whose backend instance went away
+UPDATE terminal_sessions
+SET status = 'closed',
+    ticket_hash = NULL,
+    close_reason = CASE WHEN status = 'pending' THEN 'expired' ELSE 'lost' END,
+    closed_at = now()
+WHERE status IN ('pending', 'open') AND lease_until < now();
+
+-- name: DeleteOldTerminalSessions :execrows
+DELETE FROM terminal_sessions
+WHERE closed_at < $1;
@@ -0,0 +1,14 @@
+CREATE TABLE api_tokens (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(255) NOT NULL REFERENCES users(name) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,                -- Label chosen by the owner, e.g. "nightly backup"
+    token_prefix VARCHAR(16) NOT NULL,         -- First characters of the token, shown in listings
+    token_hash TEXT NOT NULL UNIQUE,           -- SHA-256 of the full token
+    scopes TEXT[] NOT NULL DEFAULT '{}',       -- Permissions the token may use, empty means all of the owner's
+    expires_at TIMESTAMPTZ,                    -- NULL means no expiry
+    last_used_at TIMESTAMPTZ,
+    last_used_ip VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_api_tokens_username ON api_tokens(username);
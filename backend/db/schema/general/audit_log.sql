@@ -0,0 +1,18 @@
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    actor VARCHAR(255) NOT NULL,               -- Username from the access token or API token
+    api_token_id INT,                          -- Set when the call used a personal API token
+    source_ip VARCHAR(45) NOT NULL DEFAULT '',
+    method VARCHAR(10) NOT NULL,
+    action VARCHAR(255) NOT NULL,              -- Route below /api/admin/, e.g. server/config1/cmd
+    target_host VARCHAR(255) NOT NULL DEFAULT '',
+    payload TEXT NOT NULL DEFAULT '',          -- Request body with secrets redacted
+    status_code INT NOT NULL,
+    outcome VARCHAR(10) NOT NULL CHECK (outcome IN ('success', 'failure', 'denied')),
+    duration_ms INT NOT NULL
+);
+
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
+CREATE INDEX idx_audit_log_actor ON audit_log(actor, created_at);
+CREATE INDEX idx_audit_log_target_host ON audit_log(target_host, created_at);
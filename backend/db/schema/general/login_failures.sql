@@ -0,0 +1,8 @@
+CREATE TABLE login_failures (
+    scope VARCHAR(10) NOT NULL CHECK (scope IN ('user', 'ip')),
+    key VARCHAR(255) NOT NULL,                 -- Username or source IP, depending on scope
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    locked_until TIMESTAMPTZ,                  -- Backoff or lockout end, NULL when not locked
+    PRIMARY KEY (scope, key)
+);
@@ -1,8 +1,23 @@
 CREATE TABLE IF NOT EXISTS mac_access_status (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-    mac VARCHAR(17) NOT NULL,
+    mac VARCHAR(17) NOT NULL UNIQUE,           -- Lower-case, colon separated
     status VARCHAR(20) NOT NULL CHECK (status IN ('BLACKLISTED', 'WHITELISTED')),
+    comment TEXT NOT NULL DEFAULT '',
+    block BOOLEAN NOT NULL DEFAULT FALSE,      -- Blacklisted entries only: agents drop this MAC's traffic
+    expires_at TIMESTAMP,                      -- NULL means the entry never expires
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMP NOT NULL DEFAULT NOW()
 );
 
+-- MACs reported from the ARP/neighbor tables of registered devices
+CREATE TABLE IF NOT EXISTS mac_sightings (
+    mac VARCHAR(17) NOT NULL,
+    host VARCHAR(255) NOT NULL,                -- Device whose neighbor table listed the MAC
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    interface VARCHAR(64) NOT NULL DEFAULT '',
+    first_seen TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_seen TIMESTAMP NOT NULL DEFAULT NOW(),
+    alerted_at TIMESTAMP,                      -- Last alert raised for this MAC on this device
+    PRIMARY KEY (mac, host)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE password_resets (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(255) NOT NULL REFERENCES users(name) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,           -- SHA-256 of the emailed token
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,                       -- Set when the token is redeemed, tokens are single-use
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_password_resets_username ON password_resets(username);
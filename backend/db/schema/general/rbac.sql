@@ -0,0 +1,22 @@
+CREATE TABLE roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    builtin BOOLEAN NOT NULL DEFAULT FALSE,  -- admin and viewer, recreated at startup and not editable
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE role_permissions (
+    role VARCHAR(50) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL,  -- e.g. cmd.exec, firewall.write, or * for everything
+    PRIMARY KEY (role, permission)
+);
+
+-- Extra roles on top of users.role, optionally limited to devices with given tags
+CREATE TABLE user_roles (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(255) NOT NULL REFERENCES users(name) ON DELETE CASCADE,
+    role VARCHAR(50) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    device_tags TEXT[] NOT NULL DEFAULT '{}',  -- Empty means every device
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (username, role)
+);
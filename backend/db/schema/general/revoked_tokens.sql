@@ -0,0 +1,19 @@
+CREATE TABLE revoked_tokens (
+    jti TEXT PRIMARY KEY,                      -- jti claim of a revoked access token
+    username VARCHAR(255) NOT NULL,            -- No foreign key, rows outlive deleted users
+    expires_at TIMESTAMPTZ NOT NULL,           -- Token expiry, the row is pruned after it
+    revoked_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
+
+CREATE TABLE user_token_revocations (
+    username VARCHAR(255) PRIMARY KEY,         -- Set when a user is deleted or their role changes
+    revoked_before TIMESTAMPTZ NOT NULL        -- Access tokens issued before this are rejected
+);
+
+CREATE TABLE revoked_sessions (
+    session_id INT PRIMARY KEY,                -- sid claim of the access tokens of an ended refresh session
+    username VARCHAR(255) NOT NULL,
+    revoked_at TIMESTAMPTZ NOT NULL DEFAULT now() -- Pruned once every token of the session has expired
+);
@@ -1,7 +1,13 @@
 CREATE TABLE user_sessions (
     id SERIAL PRIMARY KEY,
-    username TEXT NOT NULL UNIQUE REFERENCES users(name) ON DELETE CASCADE,  -- ✅ Now works!
-    refresh_token TEXT NOT NULL UNIQUE,
+    username TEXT NOT NULL REFERENCES users(name) ON DELETE CASCADE,  -- One row per login, a user can have many
+    token_hash TEXT NOT NULL UNIQUE,           -- SHA-256 of the current refresh token
+    previous_token_hash TEXT UNIQUE,           -- Token replaced by the last rotation, used for reuse detection
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
     expires_at TIMESTAMPTZ NOT NULL,
-    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ NOT NULL DEFAULT now()
 );
+
+CREATE INDEX idx_user_sessions_username ON user_sessions(username);
@@ -0,0 +1,16 @@
+CREATE TABLE user_mfa (
+    username VARCHAR(255) PRIMARY KEY REFERENCES users(name) ON DELETE CASCADE,
+    totp_secret TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    last_used_step BIGINT NOT NULL DEFAULT 0,  -- Last accepted TOTP step, blocks code replay
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    enabled_at TIMESTAMPTZ
+);
+
+CREATE TABLE user_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(255) NOT NULL REFERENCES users(name) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,  -- SHA-256 of the normalized code
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
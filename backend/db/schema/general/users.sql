@@ -1,7 +1,8 @@
 CREATE TABLE users (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     name VARCHAR(255) NOT NULL UNIQUE,
-    role VARCHAR(50) NOT NULL CHECK (role IN ('admin', 'viewer')),  -- ✅ Only two roles allowed
+    role VARCHAR(50) NOT NULL,  -- Primary role, must name a row in roles (checked by the API)
     email VARCHAR(255) UNIQUE NOT NULL,
-    password_hash VARCHAR(100) NOT NULL
+    password_hash VARCHAR(100) NOT NULL,
+    auth_provider VARCHAR(20) NOT NULL DEFAULT 'local'  -- local, or the SSO provider that created the account
 );
@@ -0,0 +1,25 @@
+CREATE TABLE agent_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    host VARCHAR(45) NOT NULL,
+    operation VARCHAR(50) NOT NULL,
+    params JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(10) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'succeeded', 'failed', 'cancelled')),
+    progress INT NOT NULL DEFAULT 0,           -- Percent done
+    stdout TEXT NOT NULL DEFAULT '',           -- Tail of the output, older output is dropped
+    stderr TEXT NOT NULL DEFAULT '',
+    stdout_offset BIGINT NOT NULL DEFAULT 0,   -- Output read from the agent so far, to resume after a restart
+    stderr_offset BIGINT NOT NULL DEFAULT 0,
+    exit_code INT,
+    error TEXT NOT NULL DEFAULT '',
+    cancel_requested BOOLEAN NOT NULL DEFAULT false,
+    created_by VARCHAR(255) NOT NULL,
+    worker VARCHAR(100) NOT NULL DEFAULT '',   -- Backend instance running the job
+    lease_until TIMESTAMPTZ,                   -- Another worker takes over a running job after this
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_agent_jobs_status ON agent_jobs(status, created_at);
+CREATE INDEX idx_agent_jobs_host ON agent_jobs(host, created_at);
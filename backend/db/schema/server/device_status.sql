@@ -0,0 +1,11 @@
+CREATE TABLE device_status_events (
+    id BIGSERIAL PRIMARY KEY,
+    host VARCHAR(45) NOT NULL,
+    status VARCHAR(10) NOT NULL,
+    previous_status VARCHAR(10) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',           -- Error or threshold that caused the change
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_device_status_events_host ON device_status_events(host, created_at);
+CREATE INDEX idx_device_status_events_created_at ON device_status_events(created_at);
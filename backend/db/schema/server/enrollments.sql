@@ -0,0 +1,30 @@
+CREATE TABLE enrollment_codes (
+    id SERIAL PRIMARY KEY,
+    code_hash TEXT NOT NULL UNIQUE,            -- SHA-256 of the code, shown to the admin once
+    tag VARCHAR(100) NOT NULL DEFAULT '',      -- Tag given to devices enrolled with this code
+    auto_approve BOOLEAN NOT NULL DEFAULT false,
+    created_by VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,                       -- Set when an agent redeems the code, codes are single-use
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE device_enrollments (
+    id SERIAL PRIMARY KEY,
+    code_id INT REFERENCES enrollment_codes(id) ON DELETE SET NULL,
+    ip VARCHAR(45) NOT NULL,                   -- Address reported by the agent
+    source_ip VARCHAR(45) NOT NULL,            -- Address the request came from
+    hostname VARCHAR(255) NOT NULL DEFAULT '',
+    os VARCHAR(100) NOT NULL DEFAULT '',
+    tag VARCHAR(100) NOT NULL DEFAULT '',
+    access_token VARCHAR(255) NOT NULL,        -- Becomes the device's access_token on approval
+    tls_fingerprint VARCHAR(64) NOT NULL DEFAULT '', -- Certificate issued from the agent's CSR, pinned on approval
+    tls_expires_at TIMESTAMPTZ,
+    reverse_connect BOOLEAN NOT NULL DEFAULT false, -- Agent runs with --connect, copied to the device on approval
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'denied')),
+    decided_by VARCHAR(255) NOT NULL DEFAULT '',
+    decided_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_device_enrollments_status ON device_enrollments(status, created_at);
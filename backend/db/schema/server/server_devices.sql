@@ -5,6 +5,23 @@ CREATE TABLE IF NOT EXISTS server_devices (
     tag VARCHAR(100) NOT NULL DEFAULT '',  -- NOT NULL with default
     os VARCHAR(100) NOT NULL DEFAULT '',   -- NOT NULL with default
     access_token VARCHAR(255) NOT NULL,
+    pending_token VARCHAR(255),                -- Pushed to the agent, becomes access_token once confirmed
+    token_rotated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMPTZ,                    -- Emergency revoke, the backend no longer talks to the device
+    tls_fingerprint VARCHAR(64) NOT NULL DEFAULT '', -- SHA-256 of the agent certificate, empty while the agent serves plain HTTP
+    tls_expires_at TIMESTAMPTZ,
+    reverse_connect BOOLEAN NOT NULL DEFAULT false, -- Reached through the tunnel the agent opens to the backend
+    agent_port INTEGER NOT NULL DEFAULT 0,           -- 0 uses CLIENT_PORT
+    agent_scheme VARCHAR(5) NOT NULL DEFAULT '',     -- http or https, empty uses CLIENT_PROTOCOL
+    agent_base_path VARCHAR(255) NOT NULL DEFAULT '', -- Prefix when the agent sits behind a reverse proxy
+    agent_ca_cert TEXT NOT NULL DEFAULT '',          -- PEM CA the endpoint certificate must chain to
+    agent_fingerprint VARCHAR(64) NOT NULL DEFAULT '', -- SHA-256 the endpoint certificate is pinned to
+    status VARCHAR(10) NOT NULL DEFAULT 'unknown' CHECK (status IN ('online', 'degraded', 'offline', 'unknown')),
+    status_changed_at TIMESTAMPTZ,
+    status_checked_at TIMESTAMPTZ,                   -- Last health check, stale status is reported as unknown
+    last_seen_at TIMESTAMPTZ,                        -- Last time the agent answered
+    last_error TEXT NOT NULL DEFAULT '',
+    last_error_at TIMESTAMPTZ,
     created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
     updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
 );
@@ -0,0 +1,21 @@
+CREATE TABLE terminal_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    username VARCHAR(255) NOT NULL,
+    host VARCHAR(45) NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'open', 'closed')),
+    ticket_hash VARCHAR(64) UNIQUE,            -- SHA-256 of the one-time ticket the browser connects with, cleared once used
+    client_ip VARCHAR(45) NOT NULL DEFAULT '',
+    worker VARCHAR(100) NOT NULL DEFAULT '',   -- Backend instance proxying the session
+    lease_until TIMESTAMPTZ NOT NULL,          -- The session is gone once its worker stops renewing this
+    close_requested_by VARCHAR(255) NOT NULL DEFAULT '',
+    close_reason VARCHAR(20) NOT NULL DEFAULT '',
+    exit_code INT,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    opened_at TIMESTAMPTZ,
+    last_input_at TIMESTAMPTZ,
+    closed_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_terminal_sessions_live ON terminal_sessions(status, username);
+CREATE INDEX idx_terminal_sessions_closed ON terminal_sessions(closed_at);
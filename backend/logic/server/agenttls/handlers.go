@@ -0,0 +1,100 @@
+package agenttls
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/pki"
+)
+
+// Standard response structures
+type ErrorResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// HandleProvision issues or renews a device's TLS certificate right away
+// instead of waiting for the provisioner
+func HandleProvision(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			Host string `json:"host"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Host == "" {
+			sendError(w, "Host is required", http.StatusBadRequest)
+			return
+		}
+		if !pki.Enabled() {
+			sendError(w, "Agent TLS is disabled (AGENT_TLS=off)", http.StatusConflict)
+			return
+		}
+
+		device, err := queries.GetServerDeviceByIP(r.Context(), req.Host)
+		if err == sql.ErrNoRows {
+			sendError(w, "Device not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		err = Provision(r.Context(), queries, device.Ip, device.AccessToken)
+		if errors.Is(err, ErrUnsupported) {
+			sendError(w, "The agent on this device does not support TLS, upgrade it first", http.StatusConflict)
+			return
+		} else if err != nil {
+			sendError(w, "Failed to provision certificate: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":  "success",
+			"message": "Certificate issued, the device is now reached over HTTPS",
+			"host":    device.Ip,
+		})
+	}
+}
+
+// HandleCACertificate returns the agent CA certificate in PEM form
+func HandleCACertificate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !pki.Enabled() {
+			sendError(w, "Agent TLS is disabled (AGENT_TLS=off)", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-pem-file")
+		w.Write([]byte(pki.CACertificate()))
+	}
+}
+
+// Standard response functions
+func sendGetSuccess(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+func sendError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	errorResp := ErrorResponse{
+		Status:  "failed",
+		Message: message,
+	}
+	json.NewEncoder(w).Encode(errorResp)
+}
@@ -0,0 +1,157 @@
+package agenttls
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/pki"
+)
+
+// ErrUnsupported means the agent predates TLS support and stays on plain HTTP
+var ErrUnsupported = errors.New("agent does not support TLS")
+
+var client = &http.Client{
+	Transport: config.AgentTransport,
+	Timeout:   10 * time.Second,
+}
+
+// One provisioning per device at a time
+var deviceLocks sync.Map
+
+func lockDevice(ip string) func() {
+	value, _ := deviceLocks.LoadOrStore(ip, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// Provision issues a certificate to an agent over its current channel and
+// switches the backend to HTTPS with the new certificate pinned. The same
+// call renews the certificate of an agent that already uses TLS.
+func Provision(ctx context.Context, queries *serverdb.Queries, ip, accessToken string) error {
+	if !pki.Enabled() {
+		return errors.New("agent TLS is disabled")
+	}
+
+	unlock := lockDevice(ip)
+	defer unlock()
+
+	csr, err := requestCSR(ip, accessToken)
+	if err != nil {
+		return err
+	}
+
+	certPEM, fingerprint, notAfter, err := pki.SignAgentCSR(csr, ip)
+	if err != nil {
+		return fmt.Errorf("failed to sign certificate request: %v", err)
+	}
+
+	if err := installCertificate(ip, accessToken, certPEM); err != nil {
+		return err
+	}
+
+	// The agent restarts its listener with the new certificate once it has
+	// replied, so the pin must change now
+	updated, err := queries.SetDeviceTLS(ctx, serverdb.SetDeviceTLSParams{
+		Ip:             ip,
+		TlsFingerprint: fingerprint,
+		TlsExpiresAt:   sql.NullTime{Time: notAfter, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return errors.New("device was removed or revoked")
+	}
+	config.SetAgentPin(ip, fingerprint)
+
+	log.Printf("🔐 TLS certificate issued to %s (valid until %s)", ip, notAfter.Format("2006-01-02"))
+	return nil
+}
+
+// Pin records the certificate issued to an agent during enrollment
+func Pin(ctx context.Context, queries *serverdb.Queries, ip, fingerprint string, expiresAt sql.NullTime) error {
+	if fingerprint == "" {
+		return nil
+	}
+
+	_, err := queries.SetDeviceTLS(ctx, serverdb.SetDeviceTLSParams{
+		Ip:             ip,
+		TlsFingerprint: fingerprint,
+		TlsExpiresAt:   expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+	config.SetAgentPin(ip, fingerprint)
+	return nil
+}
+
+func requestCSR(ip, accessToken string) (string, error) {
+	resp, err := agentRequest("GET", ip, "/client/tls/csr", accessToken, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", ErrUnsupported
+	default:
+		return "", fmt.Errorf("agent returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		CSR string `json:"csr"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to parse JSON response: %v", err)
+	}
+	return result.CSR, nil
+}
+
+func installCertificate(ip, accessToken, certPEM string) error {
+	body, _ := json.Marshal(map[string]string{
+		"certificate": certPEM,
+		"ca":          pki.CACertificate(),
+	})
+
+	resp, err := agentRequest("POST", ip, "/client/tls/install", accessToken, body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("agent rejected the certificate with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func agentRequest(method, ip, path, accessToken string, body []byte) (*http.Response, error) {
+	url := config.GetClientURL(ip, path)
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("network error: %v", err)
+	}
+	return resp, nil
+}
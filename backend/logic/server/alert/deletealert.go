@@ -25,7 +25,7 @@ type ErrorResponse struct {
 }
 
 // HandleDeleteAlerts - Delete/resolve alerts
-func HandleDeleteAlerts(queries *serverdb.Queries) http.HandlerFunc {
+func HandleDeleteAlerts(queries *serverdb.Queries, authz *config.Authorizer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Only allow DELETE
 		if r.Method != http.MethodDelete {
@@ -33,18 +33,8 @@ func HandleDeleteAlerts(queries *serverdb.Queries) http.HandlerFunc {
 			return
 		}
 
-		// Check user authorization
-		user, ok := config.GetUserFromContext(r)
-		if !ok {
-			sendError(w, "User context not found", http.StatusInternalServerError)
-			return
-		}
-
-		// Only admin can delete alerts
-		if user.Role != "admin" {
-			sendError(w, "Admin access required", http.StatusForbidden)
-			return
-		}
+		// Deleting alerts needs alerts.delete, checked by the route middleware
+		// and, for tag-scoped grants, on each alert's host below
 
 		// Parse JSON request body
 		var req DeleteAlertsRequest
@@ -58,6 +48,10 @@ func HandleDeleteAlerts(queries *serverdb.Queries) http.HandlerFunc {
 			return
 		}
 
+		if !alertsPermitted(w, r, queries, authz, config.PermAlertsDelete, req.AlertIDs) {
+			return
+		}
+
 		// Delete alerts
 		err := queries.DeleteMultipleAlerts(r.Context(), req.AlertIDs)
 		if err != nil {
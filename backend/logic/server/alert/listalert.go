@@ -7,7 +7,7 @@ import (
 	"net/http"
 )
 
-func HandleListAlerts(queries *serverdb.Queries) http.HandlerFunc {
+func HandleListAlerts(queries *serverdb.Queries, authz *config.Authorizer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Only allow POST
 		if r.Method != http.MethodPost {
@@ -16,7 +16,8 @@ func HandleListAlerts(queries *serverdb.Queries) http.HandlerFunc {
 		}
 
 		// Check user context
-		if _, ok := config.GetUserFromContext(r); !ok {
+		user, ok := config.GetUserFromContext(r)
+		if !ok {
 			sendError(w, "User context not found", http.StatusInternalServerError)
 			return
 		}
@@ -59,6 +60,20 @@ func HandleListAlerts(queries *serverdb.Queries) http.HandlerFunc {
 			return
 		}
 
+		// Tag-scoped users only see alerts of their own devices
+		permitted, err := authz.HostFilter(r.Context(), user, config.PermAlertsRead)
+		if err != nil {
+			sendError(w, "Failed to check permissions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		visible := alerts[:0]
+		for _, a := range alerts {
+			if permitted(a.Host) {
+				visible = append(visible, a)
+			}
+		}
+		alerts = visible
+
 		// Send successful response
 		response := map[string]interface{}{
 			"status": "success",
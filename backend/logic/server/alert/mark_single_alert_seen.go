@@ -9,7 +9,7 @@ import (
 )
 
 // HandleMarkSingleAlertAsSeen - Mark single alert as seen (for convenience)
-func HandleMarkSingleAlertAsSeen(queries *serverdb.Queries) http.HandlerFunc {
+func HandleMarkSingleAlertAsSeen(queries *serverdb.Queries, authz *config.Authorizer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Only allow PUT
 		if r.Method != http.MethodPut {
@@ -37,6 +37,10 @@ func HandleMarkSingleAlertAsSeen(queries *serverdb.Queries) http.HandlerFunc {
 			return
 		}
 
+		if !alertsPermitted(w, r, queries, authz, config.PermAlertsAck, []int32{int32(alertID)}) {
+			return
+		}
+
 		// Mark alert as seen
 		err = queries.MarkAlertAsSeen(r.Context(), int32(alertID))
 		if err != nil {
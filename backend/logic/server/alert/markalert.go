@@ -13,7 +13,7 @@ type MarkSeenRequest struct {
 }
 
 // HandleMarkAlertsAsSeen - Mark alerts as seen
-func HandleMarkAlertsAsSeen(queries *serverdb.Queries) http.HandlerFunc {
+func HandleMarkAlertsAsSeen(queries *serverdb.Queries, authz *config.Authorizer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Only allow POST
 		if r.Method != http.MethodPost {
@@ -40,6 +40,10 @@ func HandleMarkAlertsAsSeen(queries *serverdb.Queries) http.HandlerFunc {
 			return
 		}
 
+		if !alertsPermitted(w, r, queries, authz, config.PermAlertsAck, req.AlertIDs) {
+			return
+		}
+
 		// Mark alerts as seen
 		err := queries.MarkMultipleAlertsAsSeen(r.Context(), req.AlertIDs)
 		if err != nil {
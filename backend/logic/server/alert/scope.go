@@ -0,0 +1,32 @@
+package alert
+
+import (
+	"net/http"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+// alertsPermitted checks that every alert in ids is on a host the caller
+// holds perm for. It has already answered the request when it returns false.
+func alertsPermitted(w http.ResponseWriter, r *http.Request, queries *serverdb.Queries, authz *config.Authorizer, perm string, ids []int32) bool {
+	user, _ := config.GetUserFromContext(r)
+	permitted, err := authz.HostFilter(r.Context(), user, perm)
+	if err != nil {
+		sendError(w, "Failed to check permissions: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+
+	alerts, err := queries.GetAlertHostsByIDs(r.Context(), ids)
+	if err != nil {
+		sendError(w, "Failed to fetch alerts: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	for _, a := range alerts {
+		if !permitted(a.Host) {
+			sendError(w, "Permission denied: "+perm+" required for "+a.Host, http.StatusForbidden)
+			return false
+		}
+	}
+	return true
+}
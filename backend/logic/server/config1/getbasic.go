@@ -1,15 +1,11 @@
 package config1
 
 import (
-	"github.com/kishore-001/ServerManagementSuite/backend/config"
-	"context"
-	"database/sql"
 	"encoding/json"
-	"fmt"
-	"io"
 	"net/http"
 	"strings"
 
+	"github.com/kishore-001/ServerManagementSuite/backend/agentclient"
 	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
 )
 
@@ -18,12 +14,6 @@ type basicRequest struct {
 	Host string `json:"host"`
 }
 
-// Response from client (remote server)
-type clientBasicResponse struct {
-	Hostname string `json:"hostname"`
-	Timezone string `json:"timezone"`
-}
-
 // Standard response structures
 type ErrorResponse struct {
 	Status  string `json:"status"`
@@ -46,59 +36,20 @@ func HandleBasic(queries *serverdb.Queries) http.HandlerFunc {
 			return
 		}
 
-		// Lookup device and get access token
-		device, err := queries.GetServerDeviceByIP(context.Background(), req.Host)
-		if err == sql.ErrNoRows {
-			sendError(w, "Device not registered", http.StatusNotFound)
-			return
-		} else if err != nil {
-			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		// Use config for client URL (reads from .env file)
-		clientURL := config.GetClientURL(req.Host, "/client/config1/basic")
-
-		clientReq, err := http.NewRequest("GET", clientURL, nil)
+		agent, err := agentclient.Open(r.Context(), queries, req.Host)
 		if err != nil {
-			sendError(w, "Failed to create request: "+err.Error(), http.StatusInternalServerError)
+			sendAgentError(w, err)
 			return
 		}
-		clientReq.Header.Set("Authorization", "Bearer "+device.AccessToken)
-		clientReq.Header.Set("Content-Type", "application/json")
 
-		// Send request to client
-		httpClient := &http.Client{}
-		resp, err := httpClient.Do(clientReq)
+		info, err := agent.BasicInfo(r.Context())
 		if err != nil {
-			sendError(w, "Failed to reach client: "+err.Error(), http.StatusBadGateway)
-			return
-		}
-		defer resp.Body.Close()
-
-		// Handle client error responses
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-
-			// Try to parse client error response (standardized format)
-			var clientError ErrorResponse
-			if json.Unmarshal(body, &clientError) == nil && clientError.Status == "failed" {
-				sendError(w, "Client error: "+clientError.Message, http.StatusBadGateway)
-			} else {
-				sendError(w, fmt.Sprintf("Client error: %s", string(body)), http.StatusBadGateway)
-			}
-			return
-		}
-
-		// Parse client response
-		var clientResp clientBasicResponse
-		if err := json.NewDecoder(resp.Body).Decode(&clientResp); err != nil {
-			sendError(w, "Invalid client response: "+err.Error(), http.StatusBadGateway)
+			sendAgentError(w, err)
 			return
 		}
 
 		// Process response based on OS
-		processedResp := processBasicResponse(clientResp, device.Os)
+		processedResp := processBasicResponse(info, agent.OS)
 
 		// Send successful response
 		sendGetSuccess(w, processedResp)
@@ -122,8 +73,14 @@ func sendError(w http.ResponseWriter, message string, statusCode int) {
 	json.NewEncoder(w).Encode(errorResp)
 }
 
+// sendAgentError answers with what went wrong calling the agent
+func sendAgentError(w http.ResponseWriter, err error) {
+	message, statusCode := agentclient.Describe(err)
+	sendError(w, message, statusCode)
+}
+
 // Process response based on OS from database
-func processBasicResponse(resp clientBasicResponse, osType string) interface{} {
+func processBasicResponse(resp agentclient.BasicInfo, osType string) interface{} {
 	// Clean data
 	hostname := strings.TrimSpace(resp.Hostname)
 	timezone := strings.TrimSpace(resp.Timezone)
@@ -134,7 +91,7 @@ func processBasicResponse(resp clientBasicResponse, osType string) interface{} {
 	}
 
 	// Default to Linux behavior
-	return clientBasicResponse{
+	return agentclient.BasicInfo{
 		Hostname: hostname,
 		Timezone: timezone,
 	}
@@ -144,7 +101,7 @@ func processBasicResponse(resp clientBasicResponse, osType string) interface{} {
 func processWindowsBasicResponse(hostname, timezone string) interface{} {
 	// For now, return same format as Linux
 	// Add Windows-specific fields when needed
-	return clientBasicResponse{
+	return agentclient.BasicInfo{
 		Hostname: hostname,
 		Timezone: timezone,
 	}
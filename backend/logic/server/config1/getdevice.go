@@ -1,14 +1,17 @@
 package config1
 
 import (
+	"database/sql"
 	"encoding/json"
 	"net/http"
 
 	"github.com/kishore-001/ServerManagementSuite/backend/config"
 	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/devicestatus"
 )
 
-func HandleGetAllServers(queries *serverdb.Queries) http.HandlerFunc {
+// HandleGetAllServers lists the registered devices the caller can read
+func HandleGetAllServers(queries *serverdb.Queries, authz *config.Authorizer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Check if it's a GET request
 		if r.Method != http.MethodGet {
@@ -30,15 +33,33 @@ func HandleGetAllServers(queries *serverdb.Queries) http.HandlerFunc {
 			return
 		}
 
+		permitted, err := authz.HostFilter(r.Context(), user, config.PermDevicesRead)
+		if err != nil {
+			http.Error(w, "Permission check failed", http.StatusInternalServerError)
+			return
+		}
+
 		// Prepare response (exclude access tokens for security)
 		var deviceList []map[string]interface{}
 		for _, device := range devices {
+			if !permitted(device.Ip) {
+				continue
+			}
 			deviceList = append(deviceList, map[string]interface{}{
-				"id":         device.ID,
-				"ip":         device.Ip,
-				"tag":        device.Tag,
-				"os":         device.Os, // Handle sql.NullString
-				"created_at": device.CreatedAt,
+				"id":                device.ID,
+				"ip":                device.Ip,
+				"tag":               device.Tag,
+				"os":                device.Os, // Handle sql.NullString
+				"port":              device.AgentPort,
+				"scheme":            device.AgentScheme,
+				"base_path":         device.AgentBasePath,
+				"status":            devicestatus.Current(device.Status, device.StatusCheckedAt),
+				"status_changed_at": nullTime(device.StatusChangedAt),
+				"last_checked_at":   nullTime(device.StatusCheckedAt),
+				"last_seen_at":      nullTime(device.LastSeenAt),
+				"last_error":        device.LastError,
+				"last_error_at":     nullTime(device.LastErrorAt),
+				"created_at":        device.CreatedAt,
 			})
 		}
 
@@ -56,3 +77,11 @@ func HandleGetAllServers(queries *serverdb.Queries) http.HandlerFunc {
 		json.NewEncoder(w).Encode(response)
 	}
 }
+
+// nullTime renders a nullable timestamp as null in JSON
+func nullTime(t sql.NullTime) interface{} {
+	if t.Valid {
+		return t.Time
+	}
+	return nil
+}
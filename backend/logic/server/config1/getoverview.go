@@ -1,16 +1,14 @@
 package config1
 
 import (
-	"github.com/kishore-001/ServerManagementSuite/backend/config"
 	"context"
-	"database/sql"
 	"encoding/json"
-	"io"
 	"log"
 	"net/http"
 	"strings"
-	"time"
 
+	"github.com/kishore-001/ServerManagementSuite/backend/agentclient"
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
 	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
 )
 
@@ -18,10 +16,6 @@ type ServerOverviewRequest struct {
 	Host string `json:"host"`
 }
 
-type ClientUptimeResponse struct {
-	Uptime string `json:"uptime"`
-}
-
 type ServerOverviewResponse struct {
 	Status string `json:"status"`
 	Uptime string `json:"uptime"`
@@ -56,9 +50,8 @@ func HandleServerOverview(queries *serverdb.Queries) http.HandlerFunc {
 			return
 		}
 
-		// Lookup device and get access token
-		device, err := queries.GetServerDeviceByIP(context.Background(), req.Host)
-		if err == sql.ErrNoRows {
+		agent, err := agentclient.Open(r.Context(), queries, req.Host)
+		if err == agentclient.ErrDeviceNotFound {
 			log.Printf("Host %s not found in database", req.Host)
 			// Host not registered, return offline status
 			response := ServerOverviewResponse{
@@ -69,15 +62,15 @@ func HandleServerOverview(queries *serverdb.Queries) http.HandlerFunc {
 			return
 		} else if err != nil {
 			log.Printf("Database error for host %s: %v", req.Host, err)
-			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+			sendAgentError(w, err)
 			return
 		}
 
 		// Get uptime from client
-		uptime, isOnline := getClientUptime(req.Host, device.AccessToken)
+		uptime, isOnline := getClientUptime(r.Context(), agent)
 
 		// Process response based on OS and availability
-		response := processOverviewResponse(uptime, isOnline, device.Os)
+		response := processOverviewResponse(uptime, isOnline, agent.OS)
 
 		// Send successful response
 		sendGetSuccess(w, response)
@@ -85,61 +78,19 @@ func HandleServerOverview(queries *serverdb.Queries) http.HandlerFunc {
 }
 
 // getClientUptime fetches uptime from the client and returns uptime string and online status
-func getClientUptime(host, accessToken string) (string, bool) {
-	clientURL := config.GetClientURL(host, "/client/config1/uptime")
-
-	req, err := http.NewRequest("GET", clientURL, nil)
+func getClientUptime(ctx context.Context, agent *agentclient.Agent) (string, bool) {
+	uptime, err := agent.Uptime(ctx)
 	if err != nil {
-		log.Printf("Failed to create request for %s: %v", host, err)
-		return "", false
-	}
-
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("Network error connecting to %s: %v", host, err)
-		return "", false
-	}
-	defer resp.Body.Close()
-
-	// Handle client error responses
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-
-		// Try to parse client error response (standardized format)
-		var clientError ErrorResponse
-		if json.Unmarshal(body, &clientError) == nil && clientError.Status == "failed" {
-			log.Printf("Client %s error: %s", host, clientError.Message)
-		} else {
-			log.Printf("Client %s returned status %d: %s", host, resp.StatusCode, string(body))
-		}
-		return "", false
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("Failed to read response from %s: %v", host, err)
-		return "", false
-	}
-
-	var clientResp ClientUptimeResponse
-	if err := json.Unmarshal(body, &clientResp); err != nil {
-		log.Printf("Invalid JSON response from %s: %v", host, err)
+		log.Printf("Failed to get uptime of %s: %v", agent.Host, err)
 		return "", false
 	}
 
-	if clientResp.Uptime == "" {
-		log.Printf("Empty uptime received from %s", host)
+	if uptime == "" {
+		log.Printf("Empty uptime received from %s", agent.Host)
 		return "Unknown", true
 	}
 
-	return clientResp.Uptime, true
+	return uptime, true
 }
 
 // Process overview response based on OS
@@ -10,7 +10,9 @@ import (
 	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
 )
 
-func HandleCreateServer(queries *serverdb.Queries) http.HandlerFunc {
+// HandleCreateServer registers a device. Tag-scoped users can only create
+// devices with one of their own tags.
+func HandleCreateServer(queries *serverdb.Queries, authz *config.Authorizer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Check if it's a POST request
 		if r.Method != http.MethodPost {
@@ -23,9 +25,10 @@ func HandleCreateServer(queries *serverdb.Queries) http.HandlerFunc {
 
 		// Parse request body
 		var req struct {
-			IP  string `json:"ip"`
-			Tag string `json:"tag"`
-			OS  string `json:"os"`
+			IP   string `json:"ip"`
+			Tag  string `json:"tag"`
+			OS   string `json:"os"`
+			Port int    `json:"port"` // Agent port, 0 uses CLIENT_PORT
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -38,6 +41,21 @@ func HandleCreateServer(queries *serverdb.Queries) http.HandlerFunc {
 			http.Error(w, "IP address is required", http.StatusBadRequest)
 			return
 		}
+		allTags, tags, err := authz.UserTagScope(r.Context(), user, config.PermDevicesManage)
+		if err != nil {
+			http.Error(w, "Permission check failed", http.StatusInternalServerError)
+			return
+		}
+		if !allTags && !tags[req.Tag] {
+			http.Error(w, "Permission denied: "+config.PermDevicesManage+" required for tag "+req.Tag, http.StatusForbidden)
+			return
+		}
+
+		endpoint, err := config.NormalizeAgentEndpoint(config.AgentEndpoint{Port: req.Port}, false)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		// Generate access token for the device
 		accessToken, err := generateDeviceToken()
@@ -52,6 +70,7 @@ func HandleCreateServer(queries *serverdb.Queries) http.HandlerFunc {
 			Tag:         req.Tag,
 			Os:          req.OS,
 			AccessToken: accessToken,
+			AgentPort:   int32(endpoint.Port),
 		})
 		if err != nil {
 			// Check for duplicate IP error
@@ -62,6 +81,9 @@ func HandleCreateServer(queries *serverdb.Queries) http.HandlerFunc {
 			http.Error(w, "Failed to create device", http.StatusInternalServerError)
 			return
 		}
+		if endpoint.Port != 0 {
+			config.SetAgentEndpoint(device.Ip, endpoint)
+		}
 
 		// Success response
 		response := map[string]interface{}{
@@ -72,6 +94,7 @@ func HandleCreateServer(queries *serverdb.Queries) http.HandlerFunc {
 				"ip":         device.Ip,
 				"tag":        device.Tag,
 				"os":         device.Os,
+				"port":       endpoint.Port,
 				"created_at": device.CreatedAt,
 			},
 			"access_token": accessToken, // Return token for device setup
@@ -34,8 +34,8 @@ func HandleDeleteServer(queries *serverdb.Queries) http.HandlerFunc {
 			return
 		}
 
-		// Check if device exists before deletion
-		_, err := queries.GetServerDeviceByIP(r.Context(), req.IP)
+		// Check if device exists before deletion (revoked devices included)
+		_, err := queries.GetDeviceTokenState(r.Context(), req.IP)
 		if err != nil {
 			http.Error(w, "Device not found", http.StatusNotFound)
 			return
@@ -47,6 +47,7 @@ func HandleDeleteServer(queries *serverdb.Queries) http.HandlerFunc {
 			http.Error(w, "Failed to delete device", http.StatusInternalServerError)
 			return
 		}
+		config.RemoveAgentRoute(req.IP)
 
 		// Success response
 		response := map[string]interface{}{
@@ -1,16 +1,11 @@
 package config1
 
 import (
-	"bytes"
-	"context"
-	"database/sql"
 	"encoding/json"
-	"io"
 	"net/http"
 	"strings"
-	"time"
 
-	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	"github.com/kishore-001/ServerManagementSuite/backend/agentclient"
 	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
 )
 
@@ -20,15 +15,6 @@ type PasswordChangeRequest struct {
 	Password string `json:"password"`
 }
 
-type ClientPasswordRequest struct {
-	Username string `json:"username"`
-	New      string `json:"new"`
-}
-
-type ClientResponsePassword struct {
-	Status string `json:"status"`
-}
-
 func HandlePasswordChange(queries *serverdb.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Only allow POST
@@ -50,80 +36,23 @@ func HandlePasswordChange(queries *serverdb.Queries) http.HandlerFunc {
 			return
 		}
 
-		// Lookup device and get access token
-		device, err := queries.GetServerDeviceByIP(context.Background(), req.Host)
-		if err == sql.ErrNoRows {
-			sendError(w, "Device not registered", http.StatusNotFound)
-			return
-		} else if err != nil {
-			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		// Process password change request based on OS
-		clientPayload := processPasswordChangeRequest(req, device.Os)
-
-		jsonPayload, err := json.Marshal(clientPayload)
-		if err != nil {
-			sendError(w, "Failed to prepare request: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		// Use config for client URL (reads from .env file)
-		clientURL := config.GetClientURL(req.Host, "/client/config1/pass")
-
-		clientReq, err := http.NewRequest("POST", clientURL, bytes.NewBuffer(jsonPayload))
+		agent, err := agentclient.Open(r.Context(), queries, req.Host)
 		if err != nil {
-			sendError(w, "Failed to create request: "+err.Error(), http.StatusInternalServerError)
+			sendAgentError(w, err)
 			return
 		}
 
-		// Set headers with authorization token
-		clientReq.Header.Set("Authorization", "Bearer "+device.AccessToken)
-		clientReq.Header.Set("Content-Type", "application/json")
-
-		// Send request to client with timeout
-		httpClient := &http.Client{
-			Timeout: 30 * time.Second,
-		}
-
-		resp, err := httpClient.Do(clientReq)
-		if err != nil {
-			sendError(w, "Failed to reach client: "+err.Error(), http.StatusBadGateway)
-			return
-		}
-		defer resp.Body.Close()
-
-		// Handle client error responses
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-
-			// Try to parse client error response (standardized format)
-			var clientError ErrorResponse
-			if json.Unmarshal(body, &clientError) == nil && clientError.Status == "failed" {
-				sendError(w, "Client error: "+clientError.Message, http.StatusBadGateway)
-			} else {
-				sendError(w, "Client error: "+string(body), http.StatusBadGateway)
-			}
-			return
-		}
+		// Process password change request based on OS
+		clientPayload := processPasswordChangeRequest(req, agent.OS)
 
-		// Read client response
-		body, err := io.ReadAll(resp.Body)
+		clientResp, err := agent.ChangePassword(r.Context(), clientPayload)
 		if err != nil {
-			sendError(w, "Failed to read client response: "+err.Error(), http.StatusBadGateway)
-			return
-		}
-
-		// Parse client response
-		var clientResp ClientResponsePassword
-		if err := json.Unmarshal(body, &clientResp); err != nil {
-			sendError(w, "Invalid client response: "+err.Error(), http.StatusBadGateway)
+			sendAgentError(w, err)
 			return
 		}
 
 		// Process response based on OS
-		processedResp := processPasswordChangeResponse(clientResp, device.Os)
+		processedResp := processPasswordChangeResponse(clientResp, agent.OS)
 
 		// Send successful response
 		sendGetSuccess(w, processedResp)
@@ -131,20 +60,20 @@ func HandlePasswordChange(queries *serverdb.Queries) http.HandlerFunc {
 }
 
 // Process password change request based on OS
-func processPasswordChangeRequest(req PasswordChangeRequest, osType string) ClientPasswordRequest {
+func processPasswordChangeRequest(req PasswordChangeRequest, osType string) agentclient.PasswordChange {
 	if strings.ToLower(osType) == "windows" {
 		return processWindowsPasswordChangeRequest(req)
 	}
 
 	// Default Linux behavior
-	return ClientPasswordRequest{
+	return agentclient.PasswordChange{
 		Username: strings.TrimSpace(req.Username),
 		New:      req.Password, // Don't trim password to preserve spaces
 	}
 }
 
 // Process password change response based on OS
-func processPasswordChangeResponse(resp ClientResponsePassword, osType string) interface{} {
+func processPasswordChangeResponse(resp agentclient.StatusResponse, osType string) interface{} {
 	if strings.ToLower(osType) == "windows" {
 		return processWindowsPasswordChangeResponse(resp)
 	}
@@ -154,17 +83,17 @@ func processPasswordChangeResponse(resp ClientResponsePassword, osType string) i
 }
 
 // Windows-specific password change request processing (placeholder for future differences)
-func processWindowsPasswordChangeRequest(req PasswordChangeRequest) ClientPasswordRequest {
+func processWindowsPasswordChangeRequest(req PasswordChangeRequest) agentclient.PasswordChange {
 	// For now, return same format as Linux
 	// Future: might need different user management for Windows
-	return ClientPasswordRequest{
+	return agentclient.PasswordChange{
 		Username: strings.TrimSpace(req.Username),
 		New:      req.Password,
 	}
 }
 
 // Windows-specific password change response processing (placeholder for future differences)
-func processWindowsPasswordChangeResponse(resp ClientResponsePassword) interface{} {
+func processWindowsPasswordChangeResponse(resp agentclient.StatusResponse) interface{} {
 	// For now, return same format as Linux
 	// Future: might need different response handling for Windows
 	return resp
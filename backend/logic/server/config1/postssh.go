@@ -1,16 +1,11 @@
 package config1
 
 import (
-	"bytes"
-	"context"
-	"database/sql"
 	"encoding/json"
-	"io"
 	"net/http"
 	"strings"
-	"time"
 
-	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	"github.com/kishore-001/ServerManagementSuite/backend/agentclient"
 	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
 )
 
@@ -19,11 +14,6 @@ type SSHKeyManagementRequest struct {
 	Host string `json:"host"`
 }
 
-type SSHKeyManagementResponse struct {
-	Status  string `json:"status"`
-	Message string `json:"message,omitempty"`
-}
-
 func HandleSSHKeyManagement(queries *serverdb.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Only allow POST
@@ -45,80 +35,23 @@ func HandleSSHKeyManagement(queries *serverdb.Queries) http.HandlerFunc {
 			return
 		}
 
-		// Lookup device and get access token
-		device, err := queries.GetServerDeviceByIP(context.Background(), req.Host)
-		if err == sql.ErrNoRows {
-			sendError(w, "Device not registered", http.StatusNotFound)
-			return
-		} else if err != nil {
-			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		// Process SSH key request based on OS
-		clientPayload := processSSHKeyRequest(req, device.Os)
-
-		jsonPayload, err := json.Marshal(clientPayload)
-		if err != nil {
-			sendError(w, "Failed to prepare request: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		// Use config for client URL (reads from .env file)
-		clientURL := config.GetClientURL(req.Host, "/client/config1/ssh")
-
-		clientReq, err := http.NewRequest("POST", clientURL, bytes.NewBuffer(jsonPayload))
+		agent, err := agentclient.Open(r.Context(), queries, req.Host)
 		if err != nil {
-			sendError(w, "Failed to create request: "+err.Error(), http.StatusInternalServerError)
+			sendAgentError(w, err)
 			return
 		}
 
-		// Set headers with authorization token
-		clientReq.Header.Set("Authorization", "Bearer "+device.AccessToken)
-		clientReq.Header.Set("Content-Type", "application/json")
-
-		// Send request to client with timeout
-		httpClient := &http.Client{
-			Timeout: 30 * time.Second,
-		}
-
-		resp, err := httpClient.Do(clientReq)
-		if err != nil {
-			sendError(w, "Failed to reach client: "+err.Error(), http.StatusBadGateway)
-			return
-		}
-		defer resp.Body.Close()
-
-		// Handle client error responses
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-
-			// Try to parse client error response (standardized format)
-			var clientError ErrorResponse
-			if json.Unmarshal(body, &clientError) == nil && clientError.Status == "failed" {
-				sendError(w, "Client error: "+clientError.Message, http.StatusBadGateway)
-			} else {
-				sendError(w, "Client error: "+string(body), http.StatusBadGateway)
-			}
-			return
-		}
+		// Process SSH key request based on OS
+		key := processSSHKeyRequest(req, agent.OS)
 
-		// Read client response
-		body, err := io.ReadAll(resp.Body)
+		clientResp, err := agent.AddSSHKey(r.Context(), key)
 		if err != nil {
-			sendError(w, "Failed to read client response: "+err.Error(), http.StatusBadGateway)
-			return
-		}
-
-		// Parse client response
-		var clientResp SSHKeyManagementResponse
-		if err := json.Unmarshal(body, &clientResp); err != nil {
-			sendError(w, "Invalid client response: "+err.Error(), http.StatusBadGateway)
+			sendAgentError(w, err)
 			return
 		}
 
 		// Process response based on OS
-		processedResp := processSSHKeyResponse(clientResp, device.Os)
+		processedResp := processSSHKeyResponse(clientResp, agent.OS)
 
 		// Send successful response
 		sendGetSuccess(w, processedResp)
@@ -126,19 +59,17 @@ func HandleSSHKeyManagement(queries *serverdb.Queries) http.HandlerFunc {
 }
 
 // Process SSH key request based on OS
-func processSSHKeyRequest(req SSHKeyManagementRequest, osType string) map[string]string {
+func processSSHKeyRequest(req SSHKeyManagementRequest, osType string) string {
 	if strings.ToLower(osType) == "windows" {
 		return processWindowsSSHKeyRequest(req)
 	}
 
 	// Default Linux behavior
-	return map[string]string{
-		"key": strings.TrimSpace(req.Key),
-	}
+	return strings.TrimSpace(req.Key)
 }
 
 // Process SSH key response based on OS
-func processSSHKeyResponse(resp SSHKeyManagementResponse, osType string) interface{} {
+func processSSHKeyResponse(resp agentclient.MessageResponse, osType string) interface{} {
 	if strings.ToLower(osType) == "windows" {
 		return processWindowsSSHKeyResponse(resp)
 	}
@@ -148,16 +79,14 @@ func processSSHKeyResponse(resp SSHKeyManagementResponse, osType string) interfa
 }
 
 // Windows-specific SSH key request processing (placeholder for future differences)
-func processWindowsSSHKeyRequest(req SSHKeyManagementRequest) map[string]string {
+func processWindowsSSHKeyRequest(req SSHKeyManagementRequest) string {
 	// For now, return same format as Linux
 	// Future: Windows might use different SSH key management (OpenSSH for Windows)
-	return map[string]string{
-		"key": strings.TrimSpace(req.Key),
-	}
+	return strings.TrimSpace(req.Key)
 }
 
 // Windows-specific SSH key response processing (placeholder for future differences)
-func processWindowsSSHKeyResponse(resp SSHKeyManagementResponse) interface{} {
+func processWindowsSSHKeyResponse(resp agentclient.MessageResponse) interface{} {
 	// For now, return same format as Linux
 	// Future: might need different response handling for Windows SSH
 	return resp
@@ -0,0 +1,143 @@
+package config1
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/agentclient"
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+// Comment lines sent while a command is quiet, so proxies keep the stream open
+const streamKeepalive = 15 * time.Second
+
+type FrontendRequestStream struct {
+	Host       string `json:"host"`
+	Command    string `json:"command"`
+	TimeoutSec int    `json:"timeout_sec"` // Default one hour
+}
+
+// HandleCommandStream runs a command and relays its output as Server-Sent
+// Events while it runs. "stdout" and "stderr" events carry chunks of output
+// with the time they were written, then one "exit" event carries the exit
+// code. An "error" event ends the stream if the agent is lost. Closing the
+// connection kills the command on the agent.
+func HandleCommandStream(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req FrontendRequestStream
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Validate required fields
+		if req.Host == "" || req.Command == "" {
+			sendError(w, "Host and command are required", http.StatusBadRequest)
+			return
+		}
+		if req.TimeoutSec < 0 {
+			sendError(w, "timeout_sec cannot be negative", http.StatusBadRequest)
+			return
+		}
+
+		agent, err := agentclient.Open(r.Context(), queries, req.Host)
+		if err != nil {
+			sendAgentError(w, err)
+			return
+		}
+
+		command := processCommandRequest(FrontendRequestCmd{Command: req.Command, Host: req.Host}, agent.OS)
+		stream, err := agent.StreamCommand(r.Context(), command, req.TimeoutSec)
+		if err != nil {
+			sendAgentError(w, err)
+			return
+		}
+		defer stream.Close()
+
+		user, _ := config.GetUserFromContext(r)
+		log.Printf("📡 %s started a streamed command on %s", user.Username, req.Host)
+
+		rc := http.NewResponseController(w)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no") // Stop nginx from buffering the stream
+		w.WriteHeader(http.StatusOK)
+		rc.Flush()
+
+		type next struct {
+			event agentclient.CommandEvent
+			err   error
+		}
+		events := make(chan next)
+		go func() {
+			for {
+				ev, err := stream.Next()
+				select {
+				case events <- next{ev, err}:
+				case <-r.Context().Done():
+					return
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+
+		keepalive := time.NewTicker(streamKeepalive)
+		defer keepalive.Stop()
+
+		for {
+			select {
+			case n := <-events:
+				switch {
+				case n.err == io.EOF:
+					writeEvent(w, "error", map[string]string{"message": "The client ended the stream without an exit code"})
+				case n.err != nil:
+					message, _ := agentclient.Describe(n.err)
+					writeEvent(w, "error", map[string]string{"message": message})
+				default:
+					if writeEvent(w, n.event.Type, n.event) != nil {
+						return
+					}
+					rc.Flush()
+					if n.event.Type != "exit" {
+						continue
+					}
+				}
+				rc.Flush()
+				return
+
+			case <-keepalive.C:
+				if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+					return
+				}
+				rc.Flush()
+
+			case <-r.Context().Done():
+				log.Printf("⛔ Streamed command on %s closed by %s, command killed", req.Host, user.Username)
+				return
+			}
+		}
+	}
+}
+
+// writeEvent writes one Server-Sent Event with data as its JSON payload
+func writeEvent(w io.Writer, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
@@ -0,0 +1,166 @@
+package config1
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/agentclient"
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+// HandleUpdateDevice edits a device's tag, OS and the endpoint its agent is
+// reached at. Fields left out of the request keep their current value.
+// Tag-scoped users must hold devices.manage for both the current and the new
+// tag. The endpoint and its pinning decide where the device's access token is
+// sent, so changing them needs a grant covering every device.
+func HandleUpdateDevice(queries *serverdb.Queries, authz *config.Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Check if it's a POST or PUT request
+		if r.Method != http.MethodPost && r.Method != http.MethodPut {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, _ := config.GetUserFromContext(r)
+
+		var req struct {
+			IP          string  `json:"ip"`
+			Tag         *string `json:"tag"`
+			OS          *string `json:"os"`
+			Port        *int    `json:"port"`        // 0 uses CLIENT_PORT
+			Scheme      *string `json:"scheme"`      // http or https, empty uses CLIENT_PROTOCOL
+			BasePath    *string `json:"base_path"`   // e.g. /agents/web01 behind a reverse proxy
+			CACert      *string `json:"ca_cert"`     // PEM CA the endpoint certificate must chain to
+			Fingerprint *string `json:"fingerprint"` // SHA-256 of the endpoint certificate
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		// Validate required fields
+		if req.IP == "" {
+			http.Error(w, "IP address is required", http.StatusBadRequest)
+			return
+		}
+
+		device, err := queries.GetServerDeviceEndpoint(r.Context(), req.IP)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Device not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, "Failed to fetch device", http.StatusInternalServerError)
+			return
+		}
+
+		tag, osName := device.Tag, device.Os
+		if req.Tag != nil {
+			tag = strings.TrimSpace(*req.Tag)
+		}
+		if req.OS != nil {
+			osName = strings.TrimSpace(*req.OS)
+		}
+		if len(tag) > 100 || len(osName) > 100 {
+			http.Error(w, "Tag and OS must be at most 100 characters", http.StatusBadRequest)
+			return
+		}
+
+		allTags, tags, err := authz.UserTagScope(r.Context(), user, config.PermDevicesManage)
+		if err != nil {
+			http.Error(w, "Permission check failed", http.StatusInternalServerError)
+			return
+		}
+		if !allTags && (!tags[device.Tag] || !tags[tag]) {
+			http.Error(w, "Permission denied: "+config.PermDevicesManage+" required for the current and new tag", http.StatusForbidden)
+			return
+		}
+		endpoint := config.AgentEndpoint{
+			Scheme:      device.AgentScheme,
+			Port:        int(device.AgentPort),
+			BasePath:    device.AgentBasePath,
+			CACert:      device.AgentCaCert,
+			Fingerprint: device.AgentFingerprint,
+		}
+		if req.Port != nil {
+			endpoint.Port = *req.Port
+		}
+		if req.Scheme != nil {
+			endpoint.Scheme = *req.Scheme
+		}
+		if req.BasePath != nil {
+			endpoint.BasePath = *req.BasePath
+		}
+		if req.CACert != nil {
+			endpoint.CACert = *req.CACert
+		}
+		if req.Fingerprint != nil {
+			endpoint.Fingerprint = *req.Fingerprint
+		}
+
+		endpoint, err = config.NormalizeAgentEndpoint(endpoint, device.TlsFingerprint != "")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		endpointChanged := endpoint.Scheme != device.AgentScheme || endpoint.Port != int(device.AgentPort) ||
+			endpoint.BasePath != device.AgentBasePath || endpoint.CACert != device.AgentCaCert ||
+			endpoint.Fingerprint != device.AgentFingerprint
+		if endpointChanged && !allTags {
+			http.Error(w, "Permission denied: changing the agent endpoint needs "+config.PermDevicesManage+" on every device", http.StatusForbidden)
+			return
+		}
+
+		updated, err := queries.UpdateServerDevice(r.Context(), serverdb.UpdateServerDeviceParams{
+			Ip:               req.IP,
+			Tag:              tag,
+			Os:               osName,
+			AgentPort:        int32(endpoint.Port),
+			AgentScheme:      endpoint.Scheme,
+			AgentBasePath:    endpoint.BasePath,
+			AgentCaCert:      endpoint.CACert,
+			AgentFingerprint: endpoint.Fingerprint,
+		})
+		if err != nil {
+			http.Error(w, "Failed to update device", http.StatusInternalServerError)
+			return
+		}
+		if updated == 0 {
+			http.Error(w, "Device not found", http.StatusNotFound)
+			return
+		}
+		if err := config.SetAgentEndpoint(req.IP, endpoint); err != nil {
+			// Already validated, the sync picks the saved value up regardless
+			log.Printf("⚠️ Failed to apply endpoint of %s: %v", req.IP, err)
+		}
+		// Failures on the old endpoint say nothing about the new one
+		agentclient.Reset(req.IP)
+
+		// Success response
+		response := map[string]interface{}{
+			"status":  "success",
+			"message": "Server device updated successfully",
+			"device": map[string]interface{}{
+				"ip":          req.IP,
+				"tag":         tag,
+				"os":          osName,
+				"port":        endpoint.Port,
+				"scheme":      endpoint.Scheme,
+				"base_path":   endpoint.BasePath,
+				"ca_cert":     endpoint.CACert != "",
+				"fingerprint": endpoint.Fingerprint,
+				"url":         config.GetClientURL(req.IP, "/"),
+			},
+			"updated_by": user.Username,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	}
+}
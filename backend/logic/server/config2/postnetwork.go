@@ -1,15 +1,11 @@
 package config2
 
 import (
-	"bytes"
-	"context"
-	"database/sql"
 	"encoding/json"
-	"io"
 	"net/http"
 	"strings"
 
-	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	"github.com/kishore-001/ServerManagementSuite/backend/agentclient"
 	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
 )
 
@@ -22,10 +18,6 @@ type networkUpdateRequest struct {
 	DNS     string `json:"dns,omitempty"`
 }
 
-type responseJSON1 struct {
-	Status string `json:"status"`
-}
-
 func HandlePostNetwork(queries *serverdb.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Only allow POST
@@ -41,73 +33,23 @@ func HandlePostNetwork(queries *serverdb.Queries) http.HandlerFunc {
 			return
 		}
 
-		// Lookup device and get access token
-		device, err := queries.GetServerDeviceByIP(context.Background(), req.Host)
-		if err == sql.ErrNoRows {
-			sendError(w, "Device not registered", http.StatusNotFound)
-			return
-		} else if err != nil {
-			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		// Process request based on OS
-		clientPayload := processNetworkUpdateRequest(req, device.Os)
-
-		jsonPayload, err := json.Marshal(clientPayload)
+		agent, err := agentclient.Open(r.Context(), queries, req.Host)
 		if err != nil {
-			sendError(w, "Failed to prepare request: "+err.Error(), http.StatusInternalServerError)
+			sendAgentError(w, err)
 			return
 		}
 
-		// Use config for client URL (reads from .env file)
-		clientURL := config.GetClientURL(req.Host, "/client/config2/updatenetwork")
-
-		clientReq, err := http.NewRequest("POST", clientURL, bytes.NewBuffer(jsonPayload))
-		if err != nil {
-			sendError(w, "Failed to create request: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		clientReq.Header.Set("Authorization", "Bearer "+device.AccessToken)
-		clientReq.Header.Set("Content-Type", "application/json")
-
-		resp, err := http.DefaultClient.Do(clientReq)
-		if err != nil {
-			sendError(w, "Failed to reach client: "+err.Error(), http.StatusBadGateway)
-			return
-		}
-		defer resp.Body.Close()
-
-		// Handle client error responses
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-
-			var clientError ErrorResponse
-			if json.Unmarshal(body, &clientError) == nil && clientError.Status == "failed" {
-				sendError(w, "Client error: "+clientError.Message, http.StatusBadGateway)
-			} else {
-				sendError(w, "Client error: "+string(body), http.StatusBadGateway)
-			}
-			return
-		}
+		// Process request based on OS
+		clientPayload := processNetworkUpdateRequest(req, agent.OS)
 
-		// Read client response
-		body, err := io.ReadAll(resp.Body)
+		clientResp, err := agent.UpdateNetwork(r.Context(), clientPayload)
 		if err != nil {
-			sendError(w, "Failed to read client response: "+err.Error(), http.StatusBadGateway)
-			return
-		}
-
-		// Parse client response
-		var clientResp responseJSON1
-		if err := json.Unmarshal(body, &clientResp); err != nil {
-			sendError(w, "Invalid client response: "+err.Error(), http.StatusBadGateway)
+			sendAgentError(w, err)
 			return
 		}
 
 		// Process response based on OS
-		processedResp := processNetworkUpdateResponse(clientResp, device.Os)
+		processedResp := processNetworkUpdateResponse(clientResp, agent.OS)
 
 		// Send successful response
 		sendGetSuccess(w, processedResp)
@@ -115,23 +57,23 @@ func HandlePostNetwork(queries *serverdb.Queries) http.HandlerFunc {
 }
 
 // Process network update request based on OS
-func processNetworkUpdateRequest(req networkUpdateRequest, osType string) map[string]string {
+func processNetworkUpdateRequest(req networkUpdateRequest, osType string) agentclient.NetworkUpdate {
 	if strings.ToLower(osType) == "windows" {
 		return processWindowsNetworkUpdateRequest(req)
 	}
 
 	// Default Linux behavior
-	return map[string]string{
-		"method":  strings.TrimSpace(req.Method),
-		"ip":      strings.TrimSpace(req.IP),
-		"subnet":  strings.TrimSpace(req.Subnet),
-		"gateway": strings.TrimSpace(req.Gateway),
-		"dns":     strings.TrimSpace(req.DNS),
+	return agentclient.NetworkUpdate{
+		Method:  strings.TrimSpace(req.Method),
+		IP:      strings.TrimSpace(req.IP),
+		Subnet:  strings.TrimSpace(req.Subnet),
+		Gateway: strings.TrimSpace(req.Gateway),
+		DNS:     strings.TrimSpace(req.DNS),
 	}
 }
 
 // Process network update response based on OS
-func processNetworkUpdateResponse(resp responseJSON1, osType string) interface{} {
+func processNetworkUpdateResponse(resp agentclient.StatusResponse, osType string) interface{} {
 	if strings.ToLower(osType) == "windows" {
 		return processWindowsNetworkUpdateResponse(resp)
 	}
@@ -141,19 +83,19 @@ func processNetworkUpdateResponse(resp responseJSON1, osType string) interface{}
 }
 
 // Windows-specific network update request processing (placeholder for future differences)
-func processWindowsNetworkUpdateRequest(req networkUpdateRequest) map[string]string {
+func processWindowsNetworkUpdateRequest(req networkUpdateRequest) agentclient.NetworkUpdate {
 	// For now, return same format as Linux
-	return map[string]string{
-		"method":  strings.TrimSpace(req.Method),
-		"ip":      strings.TrimSpace(req.IP),
-		"subnet":  strings.TrimSpace(req.Subnet),
-		"gateway": strings.TrimSpace(req.Gateway),
-		"dns":     strings.TrimSpace(req.DNS),
+	return agentclient.NetworkUpdate{
+		Method:  strings.TrimSpace(req.Method),
+		IP:      strings.TrimSpace(req.IP),
+		Subnet:  strings.TrimSpace(req.Subnet),
+		Gateway: strings.TrimSpace(req.Gateway),
+		DNS:     strings.TrimSpace(req.DNS),
 	}
 }
 
 // Windows-specific network update response processing (placeholder for future differences)
-func processWindowsNetworkUpdateResponse(resp responseJSON1) interface{} {
+func processWindowsNetworkUpdateResponse(resp agentclient.StatusResponse) interface{} {
 	// For now, return same format as Linux
 	return resp
 }
@@ -1,18 +1,14 @@
 package config2
 
 import (
-	"bytes"
-	"context"
-	"database/sql"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	"github.com/kishore-001/ServerManagementSuite/backend/agentclient"
 	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
 )
 
@@ -91,102 +87,33 @@ func HandlePostUpdateFirewall(queries *serverdb.Queries) http.HandlerFunc {
 		logWithRequestID(requestID, "INFO", "FIREWALL", "Processing firewall %s for host: %s", req.Action, req.Host)
 
 		// Lookup device and get access token
-		device, err := queries.GetServerDeviceByIP(context.Background(), req.Host)
-		if err == sql.ErrNoRows {
-			logWithRequestID(requestID, "ERROR", "FIREWALL", "Device not found: %s", req.Host)
-			sendError(w, "Device not registered", http.StatusNotFound)
-			return
-		} else if err != nil {
-			logWithRequestID(requestID, "ERROR", "FIREWALL", "Database error: %v", err)
-			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		logWithRequestID(requestID, "DEBUG", "FIREWALL", "Found device: ID=%s, Tag=%s, OS=%s", device.ID, device.Tag, device.Os)
-
-		// Process firewall request based on OS
-		clientPayload := processFirewallUpdateRequest(req, device.Os, requestID)
-
-		// Convert to JSON for client
-		clientBody, err := json.Marshal(clientPayload)
+		agent, err := agentclient.Open(r.Context(), queries, req.Host)
 		if err != nil {
-			logWithRequestID(requestID, "ERROR", "FIREWALL", "Failed to marshal client payload: %v", err)
-			sendError(w, "Failed to prepare client request: "+err.Error(), http.StatusInternalServerError)
+			logWithRequestID(requestID, "ERROR", "FIREWALL", "Device lookup failed for %s: %v", req.Host, err)
+			sendAgentError(w, err)
 			return
 		}
 
-		logWithRequestID(requestID, "DEBUG", "FIREWALL", "Client payload: %s", string(clientBody))
-
-		// Use config for client URL
-		clientURL := config.GetClientURL(req.Host, "/client/config2/updatefirewall")
-		logWithRequestID(requestID, "INFO", "FIREWALL", "Sending request to: %s", clientURL)
-
-		clientReq, err := http.NewRequest("POST", clientURL, bytes.NewReader(clientBody))
-		if err != nil {
-			logWithRequestID(requestID, "ERROR", "FIREWALL", "Failed to create client request: %v", err)
-			sendError(w, "Failed to create client request: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		clientReq.Header.Set("Authorization", "Bearer "+device.AccessToken)
-		clientReq.Header.Set("Content-Type", "application/json")
-		clientReq.Header.Set("X-Request-ID", requestID)
-
-		// Add timeout to prevent hanging
-		httpClient := &http.Client{
-			Timeout: 30 * time.Second,
-		}
+		// Process firewall request based on OS
+		clientPayload := processFirewallUpdateRequest(req, agent.OS, requestID)
+		logWithRequestID(requestID, "DEBUG", "FIREWALL", "Client payload: %+v", clientPayload)
 
 		// Log timing information
 		startTime := time.Now()
-		logWithRequestID(requestID, "INFO", "FIREWALL", "Sending HTTP request to client...")
+		logWithRequestID(requestID, "INFO", "FIREWALL", "Sending request to client...")
 
-		resp, err := httpClient.Do(clientReq)
+		clientResp, err := agent.UpdateFirewall(r.Context(), clientPayload)
 		duration := time.Since(startTime)
-
-		if err != nil {
-			logWithRequestID(requestID, "ERROR", "FIREWALL", "Failed to reach client after %v: %v", duration, err)
-			sendError(w, "Failed to reach client: "+err.Error(), http.StatusBadGateway)
-			return
-		}
-		defer resp.Body.Close()
-
-		logWithRequestID(requestID, "INFO", "FIREWALL", "Client responded in %v with status: %d", duration, resp.StatusCode)
-
-		// Handle client error responses
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			logWithRequestID(requestID, "ERROR", "FIREWALL", "Client returned non-200 status: %d", resp.StatusCode)
-
-			var clientError ErrorResponse
-			if json.Unmarshal(body, &clientError) == nil && clientError.Status == "failed" {
-				sendError(w, "Client error: "+clientError.Message, http.StatusBadGateway)
-			} else {
-				sendError(w, "Client error: "+string(body), http.StatusBadGateway)
-			}
-			return
-		}
-
-		// Read client response
-		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			logWithRequestID(requestID, "ERROR", "FIREWALL", "Failed to read response body: %v", err)
-			sendError(w, "Failed to read client response: "+err.Error(), http.StatusBadGateway)
+			logWithRequestID(requestID, "ERROR", "FIREWALL", "Client request failed after %v: %v", duration, err)
+			sendAgentError(w, err)
 			return
 		}
 
-		logWithRequestID(requestID, "DEBUG", "FIREWALL", "Client response body: %s", string(body))
-
-		// Parse client response
-		var clientResp interface{}
-		if err := json.Unmarshal(body, &clientResp); err != nil {
-			logWithRequestID(requestID, "ERROR", "FIREWALL", "Failed to parse client response: %v", err)
-			sendError(w, "Invalid client response: "+err.Error(), http.StatusBadGateway)
-			return
-		}
+		logWithRequestID(requestID, "INFO", "FIREWALL", "Client responded in %v", duration)
 
 		// Process response based on OS
-		processedResp := processFirewallUpdateResponse(clientResp, device.Os)
+		processedResp := processFirewallUpdateResponse(clientResp, agent.OS)
 
 		// Send successful response
 		sendGetSuccess(w, processedResp)
@@ -196,9 +123,9 @@ func HandlePostUpdateFirewall(queries *serverdb.Queries) http.HandlerFunc {
 }
 
 // Process firewall update request based on OS
-func processFirewallUpdateRequest(req firewallUpdateRequest, osType string, requestID string) map[string]interface{} {
-	clientPayload := map[string]interface{}{
-		"action": req.Action,
+func processFirewallUpdateRequest(req firewallUpdateRequest, osType string, requestID string) agentclient.FirewallUpdate {
+	clientPayload := agentclient.FirewallUpdate{
+		Action: req.Action,
 	}
 
 	if strings.ToLower(osType) == "windows" {
@@ -212,33 +139,33 @@ func processFirewallUpdateRequest(req firewallUpdateRequest, osType string, requ
 }
 
 // Process Linux firewall update request
-func processLinuxFirewallUpdateRequest(req firewallUpdateRequest, clientPayload map[string]interface{}, requestID string) map[string]interface{} {
+func processLinuxFirewallUpdateRequest(req firewallUpdateRequest, clientPayload agentclient.FirewallUpdate, requestID string) agentclient.FirewallUpdate {
 	linuxFields := []string{}
 
 	// For Linux, always send the rule field for backward compatibility
 	if req.Rule != "" {
-		clientPayload["rule"] = req.Rule
+		clientPayload.Rule = req.Rule
 		linuxFields = append(linuxFields, fmt.Sprintf("rule=%s", req.Rule))
 	} else {
 		// Default rule if not provided
-		clientPayload["rule"] = "accept"
+		clientPayload.Rule = "accept"
 		linuxFields = append(linuxFields, "rule=accept(default)")
 	}
 
 	if req.Protocol != "" {
-		clientPayload["protocol"] = req.Protocol
+		clientPayload.Protocol = req.Protocol
 		linuxFields = append(linuxFields, fmt.Sprintf("protocol=%s", req.Protocol))
 	}
 	if req.Port != "" {
-		clientPayload["port"] = req.Port
+		clientPayload.Port = req.Port
 		linuxFields = append(linuxFields, fmt.Sprintf("port=%s", req.Port))
 	}
 	if req.Source != "" {
-		clientPayload["source"] = req.Source
+		clientPayload.Source = req.Source
 		linuxFields = append(linuxFields, fmt.Sprintf("source=%s", req.Source))
 	}
 	if req.Destination != "" {
-		clientPayload["destination"] = req.Destination
+		clientPayload.Destination = req.Destination
 		linuxFields = append(linuxFields, fmt.Sprintf("destination=%s", req.Destination))
 	}
 
@@ -247,59 +174,59 @@ func processLinuxFirewallUpdateRequest(req firewallUpdateRequest, clientPayload
 }
 
 // Process Windows firewall update request
-func processWindowsFirewallUpdateRequest(req firewallUpdateRequest, clientPayload map[string]interface{}, requestID string) map[string]interface{} {
+func processWindowsFirewallUpdateRequest(req firewallUpdateRequest, clientPayload agentclient.FirewallUpdate, requestID string) agentclient.FirewallUpdate {
 	windowsFields := []string{}
 
 	if req.Name != "" {
-		clientPayload["name"] = req.Name
+		clientPayload.Name = req.Name
 		windowsFields = append(windowsFields, fmt.Sprintf("name=%s", req.Name))
 	}
 	if req.DisplayName != "" {
-		clientPayload["displayName"] = req.DisplayName
+		clientPayload.DisplayName = req.DisplayName
 		windowsFields = append(windowsFields, fmt.Sprintf("displayName=%s", req.DisplayName))
 	}
 	if req.Direction != "" {
-		clientPayload["direction"] = req.Direction
+		clientPayload.Direction = req.Direction
 		windowsFields = append(windowsFields, fmt.Sprintf("direction=%s", req.Direction))
 	}
 	if req.ActionType != "" {
-		clientPayload["actionType"] = req.ActionType
+		clientPayload.ActionType = req.ActionType
 		windowsFields = append(windowsFields, fmt.Sprintf("actionType=%s", req.ActionType))
 	}
 	if req.Enabled != "" {
-		clientPayload["enabled"] = req.Enabled
+		clientPayload.Enabled = req.Enabled
 		windowsFields = append(windowsFields, fmt.Sprintf("enabled=%s", req.Enabled))
 	}
 	if req.Profile != "" {
-		clientPayload["profile"] = req.Profile
+		clientPayload.Profile = req.Profile
 		windowsFields = append(windowsFields, fmt.Sprintf("profile=%s", req.Profile))
 	}
 	if req.Protocol != "" {
-		clientPayload["protocol"] = req.Protocol
+		clientPayload.Protocol = req.Protocol
 		windowsFields = append(windowsFields, fmt.Sprintf("protocol=%s", req.Protocol))
 	}
 	if req.LocalPort != "" {
-		clientPayload["localPort"] = req.LocalPort
+		clientPayload.LocalPort = req.LocalPort
 		windowsFields = append(windowsFields, fmt.Sprintf("localPort=%s", req.LocalPort))
 	}
 	if req.RemotePort != "" {
-		clientPayload["remotePort"] = req.RemotePort
+		clientPayload.RemotePort = req.RemotePort
 		windowsFields = append(windowsFields, fmt.Sprintf("remotePort=%s", req.RemotePort))
 	}
 	if req.LocalAddress != "" {
-		clientPayload["localAddress"] = req.LocalAddress
+		clientPayload.LocalAddress = req.LocalAddress
 		windowsFields = append(windowsFields, fmt.Sprintf("localAddress=%s", req.LocalAddress))
 	}
 	if req.RemoteAddress != "" {
-		clientPayload["remoteAddress"] = req.RemoteAddress
+		clientPayload.RemoteAddress = req.RemoteAddress
 		windowsFields = append(windowsFields, fmt.Sprintf("remoteAddress=%s", req.RemoteAddress))
 	}
 	if req.Program != "" {
-		clientPayload["program"] = req.Program
+		clientPayload.Program = req.Program
 		windowsFields = append(windowsFields, fmt.Sprintf("program=%s", req.Program))
 	}
 	if req.Service != "" {
-		clientPayload["service"] = req.Service
+		clientPayload.Service = req.Service
 		windowsFields = append(windowsFields, fmt.Sprintf("service=%s", req.Service))
 	}
 
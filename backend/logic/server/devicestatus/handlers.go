@@ -0,0 +1,99 @@
+package devicestatus
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+// Standard response structures
+type ErrorResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// HandleHistory lists status changes, newest first, for ?host= or every
+// device. ?limit= caps the number of events (default 100).
+func HandleHistory(queries *serverdb.Queries, authz *config.Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		limit := defaultHistoryLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 || parsed > maxHistoryLimit {
+				sendError(w, "limit must be between 1 and 1000", http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+
+		host := r.URL.Query().Get("host")
+		events, err := queries.ListDeviceStatusEvents(r.Context(), serverdb.ListDeviceStatusEventsParams{
+			Host:     sql.NullString{String: host, Valid: host != ""},
+			RowLimit: int32(limit),
+		})
+		if err != nil {
+			sendError(w, "Failed to fetch status history: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		user, _ := config.GetUserFromContext(r)
+		permitted, err := authz.HostFilter(r.Context(), user, config.PermDevicesRead)
+		if err != nil {
+			sendError(w, "Failed to check permissions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		eventList := make([]map[string]interface{}, 0, len(events))
+		for _, e := range events {
+			if !permitted(e.Host) {
+				continue
+			}
+			eventList = append(eventList, map[string]interface{}{
+				"id":              e.ID,
+				"host":            e.Host,
+				"status":          e.Status,
+				"previous_status": e.PreviousStatus,
+				"reason":          e.Reason,
+				"created_at":      e.CreatedAt,
+			})
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status": "success",
+			"events": eventList,
+			"count":  len(eventList),
+		})
+	}
+}
+
+// Standard response functions
+func sendGetSuccess(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+func sendError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	errorResp := ErrorResponse{
+		Status:  "failed",
+		Message: message,
+	}
+	json.NewEncoder(w).Encode(errorResp)
+}
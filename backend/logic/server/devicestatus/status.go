@@ -0,0 +1,84 @@
+package devicestatus
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+// Device states recorded by the health monitor
+const (
+	StatusOnline   = "online"   // The agent answered and every metric is within its threshold
+	StatusDegraded = "degraded" // The agent answered with an error or a metric is over its threshold
+	StatusOffline  = "offline"  // The agent could not be reached
+	StatusUnknown  = "unknown"  // Never checked, or the last check is too old to trust
+)
+
+// A status not refreshed for this long is reported as unknown, so a stopped
+// monitor cannot leave devices showing online
+const staleAfter = 5 * time.Minute
+
+// Status change events are kept this long
+const historyRetention = 90 * 24 * time.Hour
+
+// Record stores the result of a health check and, when the state changed,
+// adds a status event. reason is kept as the device's last error when set.
+func Record(ctx context.Context, queries *serverdb.Queries, host, status, reason string) error {
+	previous, err := queries.RecordDeviceStatus(ctx, serverdb.RecordDeviceStatusParams{
+		Ip:     host,
+		Status: status,
+		Reason: reason,
+	})
+	if err == sql.ErrNoRows {
+		return nil // Deleted or revoked while it was being checked
+	} else if err != nil {
+		return err
+	}
+	if previous == status {
+		return nil
+	}
+
+	err = queries.CreateDeviceStatusEvent(ctx, serverdb.CreateDeviceStatusEventParams{
+		Host:           host,
+		Status:         status,
+		PreviousStatus: previous,
+		Reason:         reason,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch status {
+	case StatusOnline:
+		log.Printf("🟢 %s is online (was %s)", host, previous)
+	case StatusDegraded:
+		log.Printf("🟡 %s is degraded (was %s): %s", host, previous, reason)
+	default:
+		log.Printf("🔴 %s is %s (was %s): %s", host, status, previous, reason)
+	}
+	return nil
+}
+
+// Current returns the status to report for a device, turning stale results
+// into unknown
+func Current(status string, checkedAt sql.NullTime) string {
+	if !checkedAt.Valid || time.Since(checkedAt.Time) > staleAfter {
+		return StatusUnknown
+	}
+	return status
+}
+
+// Prune drops status events older than the retention period
+func Prune(ctx context.Context, queries *serverdb.Queries) {
+	deleted, err := queries.DeleteOldDeviceStatusEvents(ctx, time.Now().Add(-historyRetention))
+	if err != nil {
+		log.Printf("❌ Failed to prune device status events: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("🧹 Pruned %d device status events", deleted)
+	}
+}
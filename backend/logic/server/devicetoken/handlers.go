@@ -0,0 +1,181 @@
+package devicetoken
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+const maxOverlap = 24 * time.Hour
+
+// Standard response structures
+type ErrorResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// HandleRotate rotates one device's access token on demand
+func HandleRotate(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			Host           string `json:"host"`
+			OverlapSeconds int    `json:"overlap_seconds"` // Defaults to DEVICE_TOKEN_OVERLAP_MINUTES
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Host == "" {
+			sendError(w, "Host is required", http.StatusBadRequest)
+			return
+		}
+
+		overlap := time.Duration(config.AppConfig.DeviceTokenOverlapMinutes) * time.Minute
+		if req.OverlapSeconds != 0 {
+			overlap = time.Duration(req.OverlapSeconds) * time.Second
+			if overlap <= 0 || overlap > maxOverlap {
+				sendError(w, "overlap_seconds must be between 1 and 86400", http.StatusBadRequest)
+				return
+			}
+		}
+
+		err := Rotate(r.Context(), queries, req.Host, overlap)
+		switch {
+		case errors.Is(err, ErrDeviceNotFound):
+			sendError(w, "Device not found", http.StatusNotFound)
+			return
+		case errors.Is(err, ErrDeviceRevoked):
+			sendError(w, "Device access has been revoked", http.StatusConflict)
+			return
+		case err != nil:
+			sendError(w, "Failed to rotate token: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		user, _ := config.GetUserFromContext(r)
+		log.Printf("🔑 Token rotation for %s requested by %s", req.Host, user.Username)
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":          "success",
+			"message":         "Access token rotated",
+			"host":            req.Host,
+			"overlap_seconds": int(overlap / time.Second),
+		})
+	}
+}
+
+// HandleRevoke cuts off a compromised device. Its tokens are wiped and the
+// backend stops calling it until the device is deleted and registered again.
+func HandleRevoke(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			Host   string `json:"host"`
+			Reason string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Host == "" {
+			sendError(w, "Host is required", http.StatusBadRequest)
+			return
+		}
+
+		revoked, err := queries.RevokeServerDevice(r.Context(), req.Host)
+		if err != nil {
+			sendError(w, "Failed to revoke device: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if revoked == 0 {
+			sendError(w, "Device not found or already revoked", http.StatusNotFound)
+			return
+		}
+
+		config.RemoveAgentRoute(req.Host)
+
+		user, _ := config.GetUserFromContext(r)
+		content := fmt.Sprintf("Access token of %s revoked by %s", req.Host, user.Username)
+		if reason := strings.TrimSpace(req.Reason); reason != "" {
+			content += ": " + reason
+		}
+		_, err = queries.CreateAlert(context.Background(), serverdb.CreateAlertParams{
+			Host:     req.Host,
+			Severity: "critical",
+			Content:  content,
+		})
+		if err != nil {
+			log.Printf("❌ Failed to create revocation alert for %s: %v", req.Host, err)
+		}
+		log.Printf("🚫 %s", content)
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":  "success",
+			"message": "Device access revoked",
+			"host":    req.Host,
+		})
+	}
+}
+
+// HandleListRevoked lists devices whose access was revoked
+func HandleListRevoked(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		devices, err := queries.ListRevokedServerDevices(r.Context())
+		if err != nil {
+			sendError(w, "Failed to fetch revoked devices: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		deviceList := make([]map[string]interface{}, 0, len(devices))
+		for _, d := range devices {
+			deviceList = append(deviceList, map[string]interface{}{
+				"id":         d.ID,
+				"ip":         d.Ip,
+				"tag":        d.Tag,
+				"os":         d.Os,
+				"revoked_at": d.RevokedAt.Time,
+				"created_at": d.CreatedAt,
+			})
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":  "success",
+			"devices": deviceList,
+			"count":   len(deviceList),
+		})
+	}
+}
+
+// Standard response functions
+func sendGetSuccess(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+func sendError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	errorResp := ErrorResponse{
+		Status:  "failed",
+		Message: message,
+	}
+	json.NewEncoder(w).Encode(errorResp)
+}
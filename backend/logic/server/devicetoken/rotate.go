@@ -0,0 +1,211 @@
+package devicetoken
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+var (
+	ErrDeviceNotFound = errors.New("device not found")
+	ErrDeviceRevoked  = errors.New("device access has been revoked")
+)
+
+// errTokenRejected means the agent does not hold the token being confirmed
+var errTokenRejected = errors.New("agent rejected the token")
+
+var client = &http.Client{
+	Transport: config.AgentTransport,
+	Timeout:   10 * time.Second,
+}
+
+// One rotation per device at a time, whether started by an admin or the scheduler
+var deviceLocks sync.Map
+
+func lockDevice(ip string) func() {
+	value, _ := deviceLocks.LoadOrStore(ip, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// Rotate replaces a device's access token. The new token is stored as pending,
+// pushed to the agent with the current one and only becomes the device's token
+// once the agent accepts a request made with it. The agent keeps accepting the
+// old token for overlap, so calls already in flight still succeed.
+func Rotate(ctx context.Context, queries *serverdb.Queries, ip string, overlap time.Duration) error {
+	unlock := lockDevice(ip)
+	defer unlock()
+
+	state, err := queries.GetDeviceTokenState(ctx, ip)
+	if err == sql.ErrNoRows {
+		return ErrDeviceNotFound
+	} else if err != nil {
+		return err
+	}
+	if state.RevokedAt.Valid {
+		return ErrDeviceRevoked
+	}
+
+	// An earlier rotation may have reached the agent; finish it first
+	if state.PendingToken.Valid {
+		err := resume(ctx, queries, ip, state.PendingToken.String)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, errTokenRejected) {
+			return err
+		}
+	}
+
+	next, err := randomHex(32)
+	if err != nil {
+		return fmt.Errorf("failed to generate token: %v", err)
+	}
+	pending := sql.NullString{String: next, Valid: true}
+
+	// Saved before the push so the token is never lost if we stop half way
+	updated, err := queries.SetDevicePendingToken(ctx, serverdb.SetDevicePendingTokenParams{
+		Ip:           ip,
+		PendingToken: pending,
+	})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrDeviceRevoked
+	}
+
+	if err := pushToken(ip, state.AccessToken, next, overlap); err != nil {
+		clearPending(ctx, queries, ip, next)
+		return err
+	}
+
+	return resume(ctx, queries, ip, next)
+}
+
+// Resume finishes a rotation whose confirmation did not complete
+func Resume(ctx context.Context, queries *serverdb.Queries, ip, pendingToken string) error {
+	unlock := lockDevice(ip)
+	defer unlock()
+
+	err := resume(ctx, queries, ip, pendingToken)
+	if errors.Is(err, errTokenRejected) {
+		return fmt.Errorf("pending token for %s was discarded: %v", ip, err)
+	}
+	return err
+}
+
+// resume confirms the pending token with the agent and promotes it. A token
+// the agent rejects is dropped; on network errors it is kept for a retry.
+func resume(ctx context.Context, queries *serverdb.Queries, ip, pendingToken string) error {
+	if err := confirmToken(ip, pendingToken); err != nil {
+		if errors.Is(err, errTokenRejected) {
+			clearPending(ctx, queries, ip, pendingToken)
+		}
+		return err
+	}
+
+	promoted, err := queries.PromoteDeviceToken(ctx, serverdb.PromoteDeviceTokenParams{
+		Ip:           ip,
+		PendingToken: sql.NullString{String: pendingToken, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if promoted == 0 {
+		return ErrDeviceRevoked
+	}
+
+	log.Printf("🔑 Access token rotated for %s", ip)
+	return nil
+}
+
+func clearPending(ctx context.Context, queries *serverdb.Queries, ip, pendingToken string) {
+	err := queries.ClearDevicePendingToken(ctx, serverdb.ClearDevicePendingTokenParams{
+		Ip:           ip,
+		PendingToken: sql.NullString{String: pendingToken, Valid: true},
+	})
+	if err != nil {
+		log.Printf("❌ Failed to clear pending token for %s: %v", ip, err)
+	}
+}
+
+// pushToken hands the next token to the agent, authenticated with the current one
+func pushToken(ip, current, next string, overlap time.Duration) error {
+	body, _ := json.Marshal(map[string]interface{}{
+		"token":           next,
+		"overlap_seconds": int(overlap / time.Second),
+	})
+
+	resp, err := agentRequest(ip, "/client/auth/rotate", current, body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return errors.New("agent does not support token rotation")
+	default:
+		return fmt.Errorf("agent returned status %d", resp.StatusCode)
+	}
+}
+
+// confirmToken makes a request with the new token; its first use switches the
+// agent over to it
+func confirmToken(ip, token string) error {
+	resp, err := agentRequest(ip, "/client/auth/confirm", token, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized:
+		return errTokenRejected
+	default:
+		return fmt.Errorf("agent returned status %d", resp.StatusCode)
+	}
+}
+
+func agentRequest(ip, path, token string, body []byte) (*http.Response, error) {
+	url := config.GetClientURL(ip, path)
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("network error: %v", err)
+	}
+	return resp, nil
+}
+
+func randomHex(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,154 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestHostRange(t *testing.T) {
+	tests := []struct {
+		cidr      string
+		wantFirst string
+		wantTotal int
+	}{
+		{"10.0.0.0/24", "10.0.0.1", 254},
+		{"10.0.0.0/30", "10.0.0.1", 2},
+		{"10.0.0.0/31", "10.0.0.0", 2},
+		{"10.0.0.7/32", "10.0.0.7", 1},
+		{"fd00::/126", "fd00::", 4},
+	}
+
+	for _, tt := range tests {
+		prefix := netip.MustParsePrefix(tt.cidr)
+		first, total := hostRange(prefix, prefix.Addr().BitLen()-prefix.Bits())
+		if first.String() != tt.wantFirst || total != tt.wantTotal {
+			t.Errorf("hostRange(%s) = %s, %d, want %s, %d", tt.cidr, first, total, tt.wantFirst, tt.wantTotal)
+		}
+	}
+}
+
+func TestStartRejectsOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ScanOptions
+	}{
+		{"invalid CIDR", ScanOptions{CIDR: "10.0.0.1", Port: 8443}},
+		{"too large", ScanOptions{CIDR: "10.0.0.0/15", Port: 8443}},
+		{"IPv6 too large", ScanOptions{CIDR: "fd00::/111", Port: 8443}},
+		{"no port", ScanOptions{CIDR: "10.0.0.0/24"}},
+		{"port out of range", ScanOptions{CIDR: "10.0.0.0/24", Port: 70000}},
+		{"concurrency", ScanOptions{CIDR: "10.0.0.0/24", Port: 8443, Concurrency: maxConcurrency + 1}},
+		{"timeout", ScanOptions{CIDR: "10.0.0.0/24", Port: 8443, Timeout: time.Minute}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if job, err := Start(tt.opts); err == nil {
+				job.Cancel()
+				t.Error("Start accepted the options")
+			}
+		})
+	}
+}
+
+// agentServer answers /client/info like an agent named name
+func agentServer(t *testing.T, name string) (string, int) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/client/info" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"agent":"` + name + `","hostname":"web-1","os":"linux","arch":"amd64"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	host, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(portStr)
+	return host, port
+}
+
+func TestProbe(t *testing.T) {
+	p := newProber(time.Second)
+
+	host, port := agentServer(t, agentName)
+	agent, found := p.probe(context.Background(), host, port)
+	if !found {
+		t.Fatal("agent not found")
+	}
+	want := Agent{IP: host, Port: port, Scheme: "http", Hostname: "web-1", OS: "linux", Arch: "amd64"}
+	if agent != want {
+		t.Errorf("probe = %+v, want %+v", agent, want)
+	}
+
+	host, port = agentServer(t, "something-else")
+	if _, found := p.probe(context.Background(), host, port); found {
+		t.Error("a server that is not an agent was reported")
+	}
+
+	// Nothing listens on a port whose listener was closed
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	if _, found := p.probe(context.Background(), "127.0.0.1", closedPort); found {
+		t.Error("a closed port was reported")
+	}
+}
+
+func waitFinished(t *testing.T, job *Job) JobStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if status := job.Status(); status.State != StateRunning {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("scan did not finish")
+	return JobStatus{}
+}
+
+func TestScanFindsAgent(t *testing.T) {
+	host, port := agentServer(t, agentName)
+	job, err := Start(ScanOptions{CIDR: host + "/32", Port: port, StartedBy: "admin"})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	status := waitFinished(t, job)
+	if status.State != StateCompleted || status.Total != 1 || status.Scanned != 1 || status.Found != 1 {
+		t.Errorf("status = %+v", status)
+	}
+	if len(status.Agents) != 1 || status.Agents[0].IP != host || status.FinishedAt == nil {
+		t.Errorf("agents = %+v", status.Agents)
+	}
+	if got, ok := Get(job.ID); !ok || got != job {
+		t.Error("job not kept after it finished")
+	}
+}
+
+func TestCancelKeepsProgress(t *testing.T) {
+	// Nothing answers on TEST-NET-1, so every probe waits for its timeout
+	job, err := Start(ScanOptions{CIDR: "192.0.2.0/24", Port: 8443, Concurrency: 1, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	job.Cancel()
+
+	status := waitFinished(t, job)
+	if status.State != StateCancelled || status.Scanned >= status.Total {
+		t.Errorf("status after cancel = %+v", status)
+	}
+}
@@ -0,0 +1,208 @@
+package discovery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+// Standard response structures
+type ErrorResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// HandleStartScan starts scanning a CIDR for agents
+func HandleStartScan(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			CIDR        string `json:"cidr"`
+			Port        int    `json:"port"`        // Default CLIENT_PORT
+			Concurrency int    `json:"concurrency"` // Hosts probed at once, default 64
+			TimeoutMS   int    `json:"timeout_ms"`  // Per connection, default 1000
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CIDR == "" {
+			sendError(w, "cidr is required", http.StatusBadRequest)
+			return
+		}
+		if req.Port == 0 {
+			req.Port, _ = strconv.Atoi(config.AppConfig.ClientPort)
+		}
+
+		user, _ := config.GetUserFromContext(r)
+		job, err := Start(ScanOptions{
+			CIDR:        req.CIDR,
+			Port:        req.Port,
+			Concurrency: req.Concurrency,
+			Timeout:     time.Duration(req.TimeoutMS) * time.Millisecond,
+			StartedBy:   user.Username,
+		})
+		if err == errTooManyJobs {
+			sendError(w, err.Error(), http.StatusTooManyRequests)
+			return
+		} else if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		sendPostSuccess(w, map[string]interface{}{
+			"status":  "success",
+			"message": "Discovery started",
+			"job":     job.Status(),
+		})
+	}
+}
+
+// HandleListJobs lists discovery jobs without their findings
+func HandleListJobs(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		list := List()
+		jobList := make([]JobStatus, 0, len(list))
+		for _, j := range list {
+			jobList = append(jobList, j.Status())
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status": "success",
+			"jobs":   jobList,
+			"count":  len(jobList),
+		})
+	}
+}
+
+// HandleGetJob returns a job's progress and the agents found so far. Agents
+// on addresses that are not registered yet come with the body to post to
+// the device create API.
+func HandleGetJob(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		job, ok := Get(r.URL.Query().Get("id"))
+		if !ok {
+			sendError(w, "Discovery job not found", http.StatusNotFound)
+			return
+		}
+
+		registeredIPs, err := queries.ListServerDeviceIPs(r.Context())
+		if err != nil {
+			sendError(w, "Failed to fetch devices: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		isRegistered := make(map[string]bool, len(registeredIPs))
+		for _, ip := range registeredIPs {
+			isRegistered[ip] = true
+		}
+
+		status := job.Status()
+		defaultPort, _ := strconv.Atoi(config.AppConfig.ClientPort)
+
+		unregistered := make([]map[string]interface{}, 0, len(status.Agents))
+		registered := make([]Agent, 0)
+		for _, a := range status.Agents {
+			if isRegistered[a.IP] {
+				registered = append(registered, a)
+				continue
+			}
+
+			register := map[string]interface{}{
+				"ip":  a.IP,
+				"tag": a.Hostname,
+				"os":  a.OS,
+			}
+			if a.Port != defaultPort {
+				register["port"] = a.Port
+			}
+			unregistered = append(unregistered, map[string]interface{}{
+				"ip":       a.IP,
+				"port":     a.Port,
+				"scheme":   a.Scheme,
+				"hostname": a.Hostname,
+				"os":       a.OS,
+				"arch":     a.Arch,
+				"register": register, // POST to /api/admin/server/config1/create
+			})
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":     "success",
+			"job":        status,
+			"agents":     unregistered,
+			"registered": registered,
+		})
+	}
+}
+
+// HandleCancelJob stops a running job
+func HandleCancelJob(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			ID string `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+			sendError(w, "Job id is required", http.StatusBadRequest)
+			return
+		}
+
+		job, ok := Get(req.ID)
+		if !ok {
+			sendError(w, "Discovery job not found", http.StatusNotFound)
+			return
+		}
+		job.Cancel()
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":  "success",
+			"message": "Discovery cancelled",
+			"id":      req.ID,
+		})
+	}
+}
+
+// Standard response functions
+func sendGetSuccess(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+func sendPostSuccess(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(data)
+}
+
+func sendError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	errorResp := ErrorResponse{
+		Status:  "failed",
+		Message: message,
+	}
+	json.NewEncoder(w).Encode(errorResp)
+}
@@ -0,0 +1,107 @@
+package discovery
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/pki"
+)
+
+// agentName is what /client/info reports on SMS agents
+const agentName = "sms-agent"
+
+const maxInfoBody = 4 << 10
+
+type agentInfo struct {
+	Agent    string `json:"agent"`
+	Hostname string `json:"hostname"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+}
+
+type prober struct {
+	dialer *net.Dialer
+	client *http.Client
+}
+
+func newProber(timeout time.Duration) *prober {
+	// Only the agent's identity is read, nothing is sent that a fake agent
+	// could capture, so its certificate is not checked. Agents that already
+	// serve HTTPS want our client certificate before they answer.
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: true}
+	if pki.Enabled() {
+		tlsConfig.GetClientCertificate = pki.ClientCertificate
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	return &prober{
+		dialer: dialer,
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSClientConfig:     tlsConfig,
+				TLSHandshakeTimeout: timeout,
+				DisableKeepAlives:   true,
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// probe reports whether an SMS agent listens on ip:port
+func (p *prober) probe(ctx context.Context, ip string, port int) (Agent, bool) {
+	addr := net.JoinHostPort(ip, strconv.Itoa(port))
+
+	// Most addresses have nothing listening, so a bare connect weeds them out
+	conn, err := p.dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return Agent{}, false
+	}
+	conn.Close()
+
+	for _, scheme := range []string{"http", "https"} {
+		info, ok := p.fetchInfo(ctx, scheme+"://"+addr+"/client/info")
+		if ok {
+			return Agent{
+				IP:       ip,
+				Port:     port,
+				Scheme:   scheme,
+				Hostname: info.Hostname,
+				OS:       info.OS,
+				Arch:     info.Arch,
+			}, true
+		}
+	}
+	return Agent{}, false
+}
+
+func (p *prober) fetchInfo(ctx context.Context, url string) (agentInfo, bool) {
+	var info agentInfo
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return info, false
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return info, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return info, false
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxInfoBody)).Decode(&info); err != nil {
+		return info, false
+	}
+	return info, info.Agent == agentName
+}
@@ -0,0 +1,294 @@
+package discovery
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job states
+const (
+	StateRunning   = "running"
+	StateCompleted = "completed"
+	StateCancelled = "cancelled"
+)
+
+const (
+	maxHostBits        = 16 // A /16 in IPv4, a /112 in IPv6
+	defaultConcurrency = 64
+	maxConcurrency     = 256
+	defaultTimeout     = time.Second
+	maxTimeout         = 10 * time.Second
+	maxRunningJobs     = 4
+	jobRetention       = 24 * time.Hour // Finished jobs are forgotten after this long
+)
+
+var errTooManyJobs = errors.New("too many scans are running, wait for one to finish")
+
+// Agent is an SMS agent answering on a scanned address
+type Agent struct {
+	IP       string `json:"ip"`
+	Port     int    `json:"port"`
+	Scheme   string `json:"scheme"` // https when the agent already has a certificate
+	Hostname string `json:"hostname"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+}
+
+// ScanOptions describes a scan requested by an admin
+type ScanOptions struct {
+	CIDR        string
+	Port        int
+	Concurrency int
+	Timeout     time.Duration
+	StartedBy   string
+}
+
+// Job is a scan of one CIDR. Progress is kept in memory only; a restart
+// loses running and finished jobs.
+type Job struct {
+	ID          string
+	CIDR        string
+	Port        int
+	Concurrency int
+	StartedBy   string
+	StartedAt   time.Time
+
+	mu         sync.Mutex
+	state      string
+	total      int
+	scanned    int
+	agents     []Agent
+	finishedAt time.Time
+	cancel     context.CancelFunc
+}
+
+// JobStatus is a consistent copy of a job's progress
+type JobStatus struct {
+	ID          string     `json:"id"`
+	CIDR        string     `json:"cidr"`
+	Port        int        `json:"port"`
+	Concurrency int        `json:"concurrency"`
+	State       string     `json:"state"`
+	Total       int        `json:"total"`
+	Scanned     int        `json:"scanned"`
+	Found       int        `json:"found"`
+	StartedBy   string     `json:"started_by"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	Agents      []Agent    `json:"-"`
+}
+
+var jobs = struct {
+	sync.Mutex
+	byID map[string]*Job
+}{byID: make(map[string]*Job)}
+
+// Start validates the options and scans the CIDR in the background
+func Start(opts ScanOptions) (*Job, error) {
+	prefix, err := netip.ParsePrefix(opts.CIDR)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR: %v", err)
+	}
+	prefix = prefix.Masked()
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits > maxHostBits {
+		return nil, fmt.Errorf("CIDR is too large, at most %d addresses can be scanned", 1<<maxHostBits)
+	}
+	if opts.Port < 1 || opts.Port > 65535 {
+		return nil, errors.New("port must be between 1 and 65535")
+	}
+	if opts.Concurrency == 0 {
+		opts.Concurrency = defaultConcurrency
+	}
+	if opts.Concurrency < 1 || opts.Concurrency > maxConcurrency {
+		return nil, fmt.Errorf("concurrency must be between 1 and %d", maxConcurrency)
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.Timeout < 0 || opts.Timeout > maxTimeout {
+		return nil, errors.New("timeout_ms must be between 1 and 10000")
+	}
+
+	first, total := hostRange(prefix, hostBits)
+
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &Job{
+		ID:          id,
+		CIDR:        prefix.String(),
+		Port:        opts.Port,
+		Concurrency: opts.Concurrency,
+		StartedBy:   opts.StartedBy,
+		StartedAt:   time.Now(),
+		state:       StateRunning,
+		total:       total,
+		cancel:      cancel,
+	}
+
+	jobs.Lock()
+	pruneJobs()
+	running := 0
+	for _, j := range jobs.byID {
+		if j.Status().State == StateRunning {
+			running++
+		}
+	}
+	if running >= maxRunningJobs {
+		jobs.Unlock()
+		cancel()
+		return nil, errTooManyJobs
+	}
+	jobs.byID[id] = job
+	jobs.Unlock()
+
+	log.Printf("🔎 Discovery %s scanning %s port %d (%d addresses)", id, job.CIDR, job.Port, total)
+	go job.run(ctx, first, newProber(opts.Timeout))
+	return job, nil
+}
+
+// Get returns a job by id
+func Get(id string) (*Job, bool) {
+	jobs.Lock()
+	defer jobs.Unlock()
+	job, ok := jobs.byID[id]
+	return job, ok
+}
+
+// List returns every job still kept, newest first
+func List() []*Job {
+	jobs.Lock()
+	pruneJobs()
+	list := make([]*Job, 0, len(jobs.byID))
+	for _, j := range jobs.byID {
+		list = append(list, j)
+	}
+	jobs.Unlock()
+
+	sort.Slice(list, func(a, b int) bool {
+		return list[a].StartedAt.After(list[b].StartedAt)
+	})
+	return list
+}
+
+// Cancel stops a running job; the agents found so far are kept
+func (j *Job) Cancel() {
+	j.cancel()
+}
+
+// Status returns a copy of the job's progress and findings
+func (j *Job) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := JobStatus{
+		ID:          j.ID,
+		CIDR:        j.CIDR,
+		Port:        j.Port,
+		Concurrency: j.Concurrency,
+		State:       j.state,
+		Total:       j.total,
+		Scanned:     j.scanned,
+		Found:       len(j.agents),
+		StartedBy:   j.StartedBy,
+		StartedAt:   j.StartedAt,
+		Agents:      append([]Agent(nil), j.agents...),
+	}
+	if !j.finishedAt.IsZero() {
+		finishedAt := j.finishedAt
+		status.FinishedAt = &finishedAt
+	}
+	return status
+}
+
+func (j *Job) run(ctx context.Context, first netip.Addr, p *prober) {
+	addrs := make(chan netip.Addr)
+	var wg sync.WaitGroup
+	for i := 0; i < j.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for addr := range addrs {
+				agent, found := p.probe(ctx, addr.String(), j.Port)
+				j.record(agent, found)
+			}
+		}()
+	}
+
+	addr := first
+feed:
+	for i := 0; i < j.total; i++ {
+		select {
+		case addrs <- addr:
+			addr = addr.Next()
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(addrs)
+	wg.Wait()
+
+	j.mu.Lock()
+	j.state = StateCompleted
+	if ctx.Err() != nil {
+		j.state = StateCancelled
+	}
+	j.finishedAt = time.Now()
+	sort.Slice(j.agents, func(a, b int) bool {
+		return j.agents[a].IP < j.agents[b].IP
+	})
+	found := len(j.agents)
+	j.mu.Unlock()
+	j.cancel()
+
+	log.Printf("✅ Discovery %s %s: %d agents found", j.ID, j.Status().State, found)
+}
+
+func (j *Job) record(agent Agent, found bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.scanned++
+	if found {
+		j.agents = append(j.agents, agent)
+	}
+}
+
+// hostRange returns the first address to scan and how many follow it,
+// skipping the network and broadcast addresses of IPv4 subnets
+func hostRange(prefix netip.Prefix, hostBits int) (netip.Addr, int) {
+	first := prefix.Addr()
+	total := 1 << hostBits
+	if first.Is4() && hostBits >= 2 {
+		return first.Next(), total - 2
+	}
+	return first, total
+}
+
+// pruneJobs forgets finished jobs past their retention. Call with jobs locked.
+func pruneJobs() {
+	for id, j := range jobs.byID {
+		status := j.Status()
+		if status.FinishedAt != nil && time.Since(*status.FinishedAt) > jobRetention {
+			delete(jobs.byID, id)
+		}
+	}
+}
+
+func randomID() (string, error) {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,198 @@
+package enroll
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+const (
+	defaultCodeTTL = 60 * time.Minute
+	maxCodeTTL     = 7 * 24 * time.Hour
+)
+
+// Standard response structures
+type ErrorResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// HandleListCodes lists enrollment codes that are still redeemable
+func HandleListCodes(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		codes, err := queries.ListEnrollmentCodes(r.Context())
+		if err != nil {
+			sendError(w, "Failed to fetch enrollment codes: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		codeList := make([]map[string]interface{}, 0, len(codes))
+		for _, c := range codes {
+			codeList = append(codeList, map[string]interface{}{
+				"id":           c.ID,
+				"tag":          c.Tag,
+				"auto_approve": c.AutoApprove,
+				"created_by":   c.CreatedBy,
+				"expires_at":   c.ExpiresAt,
+				"created_at":   c.CreatedAt,
+			})
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status": "success",
+			"codes":  codeList,
+			"count":  len(codeList),
+		})
+	}
+}
+
+// HandleCreateCode issues a one-time enrollment code. The code is returned
+// once and only its hash is stored.
+func HandleCreateCode(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			Tag         string `json:"tag"`
+			TTLMinutes  int    `json:"ttl_minutes"`  // Default 60
+			AutoApprove bool   `json:"auto_approve"` // Register the device without review
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ttl := defaultCodeTTL
+		if req.TTLMinutes != 0 {
+			ttl = time.Duration(req.TTLMinutes) * time.Minute
+			if ttl <= 0 || ttl > maxCodeTTL {
+				sendError(w, "ttl_minutes must be between 1 and 10080", http.StatusBadRequest)
+				return
+			}
+		}
+
+		req.Tag = strings.TrimSpace(req.Tag)
+		if len(req.Tag) > 100 {
+			sendError(w, "Tag must be at most 100 characters", http.StatusBadRequest)
+			return
+		}
+
+		code, err := randomHex(16)
+		if err != nil {
+			sendError(w, "Failed to generate enrollment code", http.StatusInternalServerError)
+			return
+		}
+
+		user, _ := config.GetUserFromContext(r)
+		created, err := queries.CreateEnrollmentCode(r.Context(), serverdb.CreateEnrollmentCodeParams{
+			CodeHash:    hashCode(code),
+			Tag:         req.Tag,
+			AutoApprove: req.AutoApprove,
+			CreatedBy:   user.Username,
+			ExpiresAt:   time.Now().Add(ttl),
+		})
+		if err != nil {
+			sendError(w, "Failed to save enrollment code: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		sendPostSuccess(w, map[string]interface{}{
+			"status":       "success",
+			"message":      "Enrollment code created",
+			"id":           created.ID,
+			"code":         code, // Shown once
+			"tag":          created.Tag,
+			"auto_approve": created.AutoApprove,
+			"expires_at":   created.ExpiresAt,
+		})
+	}
+}
+
+// HandleDeleteCode revokes an unused enrollment code
+func HandleDeleteCode(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST/DELETE method
+		if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			sendError(w, "Only POST or DELETE method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			ID int32 `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == 0 {
+			sendError(w, "Code id is required", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := queries.DeleteEnrollmentCode(r.Context(), req.ID)
+		if err != nil {
+			sendError(w, "Failed to delete enrollment code: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if deleted == 0 {
+			sendError(w, "Enrollment code not found or already used", http.StatusNotFound)
+			return
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":  "success",
+			"message": "Enrollment code revoked",
+			"id":      req.ID,
+		})
+	}
+}
+
+// hashCode returns the SHA-256 hex digest stored in place of a code
+func hashCode(code string) string {
+	hash := sha256.Sum256([]byte(strings.TrimSpace(code)))
+	return hex.EncodeToString(hash[:])
+}
+
+func randomHex(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// Standard response functions
+func sendGetSuccess(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+func sendPostSuccess(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(data)
+}
+
+func sendError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	errorResp := ErrorResponse{
+		Status:  "failed",
+		Message: message,
+	}
+	json.NewEncoder(w).Encode(errorResp)
+}
@@ -0,0 +1,189 @@
+package enroll
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+// HandleListEnrollments lists enrollment requests, filtered by ?status=
+// (pending by default, "all" for every state)
+func HandleListEnrollments(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		status := sql.NullString{String: StatePending, Valid: true}
+		switch value := r.URL.Query().Get("status"); value {
+		case "", StatePending:
+		case StateApproved, StateDenied:
+			status.String = value
+		case "all":
+			status = sql.NullString{}
+		default:
+			sendError(w, "status must be pending, approved, denied or all", http.StatusBadRequest)
+			return
+		}
+
+		enrollments, err := queries.ListDeviceEnrollments(r.Context(), status)
+		if err != nil {
+			sendError(w, "Failed to fetch enrollments: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		enrollmentList := make([]map[string]interface{}, 0, len(enrollments))
+		for _, e := range enrollments {
+			enrollment := map[string]interface{}{
+				"id":              e.ID,
+				"ip":              e.Ip,
+				"source_ip":       e.SourceIp,
+				"hostname":        e.Hostname,
+				"os":              e.Os,
+				"tag":             e.Tag,
+				"status":          e.Status,
+				"reverse_connect": e.ReverseConnect,
+				"decided_by":      e.DecidedBy,
+				"decided_at":      nil,
+				"created_at":      e.CreatedAt,
+			}
+			if e.DecidedAt.Valid {
+				enrollment["decided_at"] = e.DecidedAt.Time
+			}
+			enrollmentList = append(enrollmentList, enrollment)
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":      "success",
+			"enrollments": enrollmentList,
+			"count":       len(enrollmentList),
+		})
+	}
+}
+
+// HandleApprove registers the device behind a pending enrollment. The IP and
+// tag reported by the agent can be overridden.
+func HandleApprove(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			ID  int32   `json:"id"`
+			IP  *string `json:"ip"`
+			Tag *string `json:"tag"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == 0 {
+			sendError(w, "Enrollment id is required", http.StatusBadRequest)
+			return
+		}
+
+		enrollment, ok := pendingEnrollment(w, r, queries, req.ID)
+		if !ok {
+			return
+		}
+
+		ip, tag := enrollment.Ip, enrollment.Tag
+		if req.IP != nil {
+			ip = strings.TrimSpace(*req.IP)
+			if net.ParseIP(ip) == nil {
+				sendError(w, "ip must be a valid IP address", http.StatusBadRequest)
+				return
+			}
+		}
+		if req.Tag != nil {
+			tag = strings.TrimSpace(*req.Tag)
+		}
+
+		user, _ := config.GetUserFromContext(r)
+		device, err := approve(r.Context(), queries, enrollment.ID, ip, tag, enrollment.Os, enrollment.AccessToken, user.Username)
+		if err != nil {
+			sendError(w, "Failed to approve enrollment: "+err.Error(), http.StatusConflict)
+			return
+		}
+		if err := routeDevice(r.Context(), queries, device.Ip, enrollment.TlsFingerprint, enrollment.TlsExpiresAt, enrollment.ReverseConnect); err != nil {
+			sendError(w, "Device registered but the connection to it could not be set up: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":  "success",
+			"message": "Enrollment approved, device registered",
+			"device": map[string]interface{}{
+				"id":         device.ID,
+				"ip":         device.Ip,
+				"tag":        device.Tag,
+				"os":         device.Os,
+				"created_at": device.CreatedAt,
+			},
+		})
+	}
+}
+
+// HandleDeny rejects a pending enrollment; the agent's credential is never registered
+func HandleDeny(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			ID int32 `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == 0 {
+			sendError(w, "Enrollment id is required", http.StatusBadRequest)
+			return
+		}
+
+		user, _ := config.GetUserFromContext(r)
+		decided, err := queries.DecideDeviceEnrollment(r.Context(), serverdb.DecideDeviceEnrollmentParams{
+			ID:        req.ID,
+			Status:    StateDenied,
+			DecidedBy: user.Username,
+		})
+		if err != nil {
+			sendError(w, "Failed to deny enrollment: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if decided == 0 {
+			sendError(w, "No pending enrollment with this id", http.StatusNotFound)
+			return
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":  "success",
+			"message": "Enrollment denied",
+			"id":      req.ID,
+		})
+	}
+}
+
+// pendingEnrollment loads an enrollment and writes the error response when it
+// is missing or already decided
+func pendingEnrollment(w http.ResponseWriter, r *http.Request, queries *serverdb.Queries, id int32) (serverdb.DeviceEnrollment, bool) {
+	enrollment, err := queries.GetDeviceEnrollment(r.Context(), id)
+	if err == sql.ErrNoRows {
+		sendError(w, "Enrollment not found", http.StatusNotFound)
+		return enrollment, false
+	} else if err != nil {
+		sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+		return enrollment, false
+	}
+	if enrollment.Status != StatePending {
+		sendError(w, "Enrollment was already "+enrollment.Status, http.StatusConflict)
+		return enrollment, false
+	}
+	return enrollment, true
+}
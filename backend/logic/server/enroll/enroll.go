@@ -0,0 +1,225 @@
+package enroll
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/auth"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/agenttls"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/reverse"
+	"github.com/kishore-001/ServerManagementSuite/backend/pki"
+)
+
+// Enrollment states
+const (
+	StatePending  = "pending"
+	StateApproved = "approved"
+	StateDenied   = "denied"
+)
+
+// autoApprover is recorded as decided_by for codes created with auto_approve
+const autoApprover = "auto"
+
+const maxEnrollBody = 4 << 10
+
+// enrollRequest is sent by an agent started with --enroll
+type enrollRequest struct {
+	Code     string `json:"code"`
+	IP       string `json:"ip"` // Address the backend should use to reach the agent
+	Hostname string `json:"hostname"`
+	OS       string `json:"os"`  // linux or windows
+	CSR      string `json:"csr"` // Optional PEM certificate request for the agent's TLS key
+
+	// The agent runs with --connect and is reached through its tunnel
+	ReverseConnect bool `json:"reverse_connect"`
+}
+
+// HandleEnroll redeems a one-time code for an agent and hands back the
+// device credential. Without auto-approval the device is only registered
+// once an admin approves the request.
+func HandleEnroll(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req enrollRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxEnrollBody)).Decode(&req); err != nil {
+			sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		sourceIP := auth.ClientIP(r)
+		req.IP = strings.TrimSpace(req.IP)
+		if req.IP == "" {
+			req.IP = sourceIP
+		}
+		if net.ParseIP(req.IP) == nil {
+			sendError(w, "ip must be a valid IP address", http.StatusBadRequest)
+			return
+		}
+		req.Hostname = strings.TrimSpace(req.Hostname)
+		req.OS = strings.ToLower(strings.TrimSpace(req.OS))
+		if len(req.Hostname) > 255 || len(req.OS) > 100 {
+			sendError(w, "hostname or os is too long", http.StatusBadRequest)
+			return
+		}
+		if req.Code == "" {
+			sendError(w, "Enrollment code is required", http.StatusBadRequest)
+			return
+		}
+
+		// Redeeming marks the code used, so a leaked code works at most once
+		code, err := queries.ConsumeEnrollmentCode(r.Context(), hashCode(req.Code))
+		if err == sql.ErrNoRows {
+			log.Printf("⚠️ Rejected enrollment from %s: invalid or expired code", sourceIP)
+			sendError(w, "Invalid or expired enrollment code", http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			sendError(w, "Failed to check enrollment code", http.StatusInternalServerError)
+			return
+		}
+
+		accessToken, err := randomHex(32)
+		if err != nil {
+			sendError(w, "Failed to generate access token", http.StatusInternalServerError)
+			return
+		}
+
+		// Agents that send a CSR get their certificate now and serve HTTPS
+		// from the start; it is pinned once the device is approved
+		var certPEM, fingerprint string
+		var certExpiresAt sql.NullTime
+		if req.CSR != "" && pki.Enabled() {
+			var notAfter time.Time
+			certPEM, fingerprint, notAfter, err = pki.SignAgentCSR(req.CSR, req.IP)
+			if err != nil {
+				sendError(w, "Invalid certificate request: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			certExpiresAt = sql.NullTime{Time: notAfter, Valid: true}
+		}
+
+		enrollment, err := queries.CreateDeviceEnrollment(r.Context(), serverdb.CreateDeviceEnrollmentParams{
+			CodeID:         sql.NullInt32{Int32: code.ID, Valid: true},
+			Ip:             req.IP,
+			SourceIp:       sourceIP,
+			Hostname:       req.Hostname,
+			Os:             req.OS,
+			Tag:            code.Tag,
+			AccessToken:    accessToken,
+			TlsFingerprint: fingerprint,
+			TlsExpiresAt:   certExpiresAt,
+			ReverseConnect: req.ReverseConnect,
+		})
+		if err != nil {
+			sendError(w, "Failed to save enrollment: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		state := StatePending
+		if code.AutoApprove {
+			_, err := approve(r.Context(), queries, enrollment.ID, req.IP, code.Tag, req.OS, accessToken, autoApprover)
+			if err != nil {
+				// Left pending so an admin can sort out the conflict
+				log.Printf("⚠️ Auto-approval of enrollment %d (%s) failed: %v", enrollment.ID, req.IP, err)
+			} else {
+				state = StateApproved
+				if err := routeDevice(r.Context(), queries, req.IP, fingerprint, certExpiresAt, req.ReverseConnect); err != nil {
+					log.Printf("❌ Failed to set up connection to %s: %v", req.IP, err)
+				}
+			}
+		}
+
+		if state == StatePending {
+			notifyPending(queries, req.IP, req.Hostname, sourceIP)
+		}
+		log.Printf("🆕 Enrollment %d from %s (%s, %s): %s", enrollment.ID, req.IP, req.Hostname, req.OS, state)
+
+		response := map[string]interface{}{
+			"status":        "success",
+			"enrollment_id": enrollment.ID,
+			"state":         state,
+			"ip":            req.IP,
+			"access_token":  accessToken, // Stored hashed by the agent
+		}
+		if certPEM != "" {
+			response["certificate"] = certPEM
+			response["ca"] = pki.CACertificate()
+		}
+		sendPostSuccess(w, response)
+	}
+}
+
+// approve claims a pending enrollment and registers its device. The claim
+// comes first so a concurrent deny cannot leave a registered device behind.
+func approve(ctx context.Context, queries *serverdb.Queries, id int32, ip, tag, osName, accessToken, decidedBy string) (serverdb.CreateServerDeviceRow, error) {
+	var device serverdb.CreateServerDeviceRow
+
+	decided, err := queries.DecideDeviceEnrollment(ctx, serverdb.DecideDeviceEnrollmentParams{
+		ID:        id,
+		Status:    StateApproved,
+		DecidedBy: decidedBy,
+	})
+	if err != nil {
+		return device, err
+	}
+	if decided == 0 {
+		return device, errors.New("enrollment is no longer pending")
+	}
+
+	device, err = queries.CreateServerDevice(ctx, serverdb.CreateServerDeviceParams{
+		Ip:          ip,
+		Tag:         tag,
+		Os:          osName,
+		AccessToken: accessToken,
+	})
+	if err != nil {
+		if reopenErr := queries.ReopenDeviceEnrollment(ctx, id); reopenErr != nil {
+			log.Printf("❌ Failed to reopen enrollment %d: %v", id, reopenErr)
+		}
+		if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+			return device, fmt.Errorf("a device with IP %s is already registered", ip)
+		}
+		return device, err
+	}
+	return device, nil
+}
+
+// routeDevice records how the backend reaches a newly approved device.
+// Reverse-connected devices are pinned too: their certificate signs the
+// tunnel requests.
+func routeDevice(ctx context.Context, queries *serverdb.Queries, ip, fingerprint string, certExpiresAt sql.NullTime, reverseConnect bool) error {
+	if err := agenttls.Pin(ctx, queries, ip, fingerprint, certExpiresAt); err != nil {
+		return err
+	}
+	if reverseConnect {
+		_, err := reverse.Mark(ctx, queries, ip, true)
+		return err
+	}
+	return nil
+}
+
+// notifyPending raises an info alert so admins see the request on the dashboard
+func notifyPending(queries *serverdb.Queries, ip, hostname, sourceIP string) {
+	content := fmt.Sprintf("Enrollment request from %s (%s, sent from %s) is awaiting approval", hostname, ip, sourceIP)
+	_, err := queries.CreateAlert(context.Background(), serverdb.CreateAlertParams{
+		Host:     ip,
+		Severity: "info",
+		Content:  content,
+	})
+	if err != nil {
+		log.Printf("❌ Failed to create enrollment alert for %s: %v", ip, err)
+	}
+}
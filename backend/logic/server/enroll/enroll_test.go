@@ -0,0 +1,97 @@
+package enroll
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHashCode(t *testing.T) {
+	code, err := randomHex(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) != 32 {
+		t.Errorf("code has %d characters, want 32", len(code))
+	}
+
+	hash := hashCode(code)
+	if hash == code || len(hash) != 64 {
+		t.Errorf("hashCode(%q) = %q", code, hash)
+	}
+	// Codes are often pasted with a trailing newline
+	if hashCode(" "+code+"\n") != hash {
+		t.Error("surrounding whitespace changed the hash")
+	}
+	if other, _ := randomHex(16); hashCode(other) == hash {
+		t.Error("two codes have the same hash")
+	}
+}
+
+func TestEnrollRejectedBeforeTheDatabase(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		remoteAddr string
+		want       int
+	}{
+		{"needs POST", http.MethodGet, "", "", http.StatusMethodNotAllowed},
+		{"invalid body", http.MethodPost, `{"code":`, "", http.StatusBadRequest},
+		{"body too large", http.MethodPost, `{"code":"` + strings.Repeat("a", maxEnrollBody) + `"}`, "", http.StatusBadRequest},
+		{"invalid ip", http.MethodPost, `{"code":"abc","ip":"agent.local"}`, "", http.StatusBadRequest},
+		{"no usable source ip", http.MethodPost, `{"code":"abc"}`, "pipe", http.StatusBadRequest},
+		{"hostname too long", http.MethodPost, `{"code":"abc","ip":"10.0.0.5","hostname":"` + strings.Repeat("h", 256) + `"}`, "", http.StatusBadRequest},
+		{"no code", http.MethodPost, `{"ip":"10.0.0.5"}`, "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/x", strings.NewReader(tt.body))
+			if tt.remoteAddr != "" {
+				r.RemoteAddr = tt.remoteAddr
+			}
+			w := httptest.NewRecorder()
+			HandleEnroll(nil)(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminRequestsRejectedBeforeTheDatabase(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		request *http.Request
+		want    int
+	}{
+		{"create code ttl too long", HandleCreateCode(nil),
+			httptest.NewRequest(http.MethodPost, "/x", strings.NewReader(`{"ttl_minutes":10081}`)), http.StatusBadRequest},
+		{"create code negative ttl", HandleCreateCode(nil),
+			httptest.NewRequest(http.MethodPost, "/x", strings.NewReader(`{"ttl_minutes":-1}`)), http.StatusBadRequest},
+		{"create code tag too long", HandleCreateCode(nil),
+			httptest.NewRequest(http.MethodPost, "/x", strings.NewReader(`{"tag":"`+strings.Repeat("t", 101)+`"}`)), http.StatusBadRequest},
+		{"delete code needs an id", HandleDeleteCode(nil),
+			httptest.NewRequest(http.MethodDelete, "/x", strings.NewReader(`{}`)), http.StatusBadRequest},
+		{"list unknown status", HandleListEnrollments(nil),
+			httptest.NewRequest(http.MethodGet, "/x?status=expired", nil), http.StatusBadRequest},
+		{"approve needs an id", HandleApprove(nil),
+			httptest.NewRequest(http.MethodPost, "/x", strings.NewReader(`{}`)), http.StatusBadRequest},
+		{"deny needs POST", HandleDeny(nil), httptest.NewRequest(http.MethodGet, "/x", nil), http.StatusMethodNotAllowed},
+		{"deny needs an id", HandleDeny(nil),
+			httptest.NewRequest(http.MethodPost, "/x", strings.NewReader(`{}`)), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handler(w, tt.request)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package fleet
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/agentclient"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+var inventory = []serverdb.ListDeviceInventoryRow{
+	{Ip: "10.0.0.1", Tag: "web"},
+	{Ip: "10.0.0.2", Tag: "web"},
+	{Ip: "10.0.0.3", Tag: "db"},
+	{Ip: "10.0.0.4", Tag: "web", RevokedAt: sql.NullTime{Time: time.Now(), Valid: true}},
+}
+
+func hostsOf(targets []target) []string {
+	hosts := make([]string, 0, len(targets))
+	for _, t := range targets {
+		hosts = append(hosts, t.host)
+	}
+	return hosts
+}
+
+func TestSelectTargets(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        runRequest
+		allTags    bool
+		tags       map[string]bool
+		wantHosts  []string
+		wantDenied []string
+	}{
+		{"all", runRequest{All: true}, true, nil, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, nil},
+		{"all within scope", runRequest{All: true}, false, map[string]bool{"db": true}, []string{"10.0.0.3"}, nil},
+		{"tag", runRequest{Tag: "web"}, true, nil, []string{"10.0.0.1", "10.0.0.2"}, nil},
+		{"tag out of scope", runRequest{Tag: "web"}, false, map[string]bool{"db": true}, []string{}, nil},
+		{"hosts keep their order", runRequest{Hosts: []string{"10.0.0.3", " 10.0.0.1 "}}, true, nil,
+			[]string{"10.0.0.3", "10.0.0.1"}, nil},
+		{"duplicate and empty hosts", runRequest{Hosts: []string{"10.0.0.1", "", "10.0.0.1"}}, true, nil,
+			[]string{"10.0.0.1"}, nil},
+		{"unregistered host", runRequest{Hosts: []string{"10.9.9.9"}}, true, nil, []string{"10.9.9.9"}, nil},
+		{"revoked host is unregistered", runRequest{Hosts: []string{"10.0.0.4"}}, true, nil, []string{"10.0.0.4"}, nil},
+		{"host out of scope", runRequest{Hosts: []string{"10.0.0.1", "10.0.0.3"}}, false, map[string]bool{"db": true},
+			[]string{"10.0.0.3"}, []string{"10.0.0.1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets, denied := selectTargets(tt.req, inventory, tt.allTags, tt.tags)
+			if got := hostsOf(targets); !reflect.DeepEqual(got, tt.wantHosts) {
+				t.Errorf("targets = %v, want %v", got, tt.wantHosts)
+			}
+			if !reflect.DeepEqual(denied, tt.wantDenied) {
+				t.Errorf("denied = %v, want %v", denied, tt.wantDenied)
+			}
+		})
+	}
+}
+
+func TestSelectTargetsUnregisteredHasNoAgent(t *testing.T) {
+	targets, _ := selectTargets(runRequest{Hosts: []string{"10.0.0.1", "10.0.0.4"}}, inventory, true, nil)
+	if targets[0].agent == nil {
+		t.Error("registered host has no agent")
+	}
+	if targets[1].agent != nil {
+		t.Error("revoked host got an agent")
+	}
+}
+
+func testTargets(hosts ...string) []target {
+	targets := make([]target, len(hosts))
+	for i, host := range hosts {
+		targets[i] = target{host: host, agent: agentclient.NewAgent(host, "linux", "token")}
+	}
+	return targets
+}
+
+func TestRunKeepsOrderAndLimitsConcurrency(t *testing.T) {
+	var running, peak atomic.Int32
+	fn := func(ctx context.Context, agent *agentclient.Agent) (interface{}, error) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+		return agent.Host, nil
+	}
+
+	targets := testTargets("a", "b", "c", "d", "e", "f")
+	results := run(context.Background(), targets, fn, 2, false)
+
+	for i, res := range results {
+		if res.Host != targets[i].host || res.Status != resultSuccess || res.Result != targets[i].host {
+			t.Errorf("result %d = %+v, want success on %s", i, res, targets[i].host)
+		}
+	}
+	if p := peak.Load(); p > 2 {
+		t.Errorf("%d calls ran at once, want at most 2", p)
+	}
+}
+
+func TestRunStopOnError(t *testing.T) {
+	fn := func(ctx context.Context, agent *agentclient.Agent) (interface{}, error) {
+		if agent.Host == "b" {
+			return nil, &agentclient.AgentError{StatusCode: 500, Message: "boom"}
+		}
+		return "ok", nil
+	}
+
+	results := run(context.Background(), testTargets("a", "b", "c", "d"), fn, 1, true)
+	want := []string{resultSuccess, resultFailed, resultSkipped, resultSkipped}
+	for i, res := range results {
+		if res.Status != want[i] {
+			t.Errorf("%s status = %s, want %s", res.Host, res.Status, want[i])
+		}
+	}
+	if results[1].Error != "Client error: boom" {
+		t.Errorf("failure message = %q", results[1].Error)
+	}
+
+	// Without stop_on_error every host runs
+	results = run(context.Background(), testTargets("a", "b", "c"), fn, 1, false)
+	if results[2].Status != resultSuccess {
+		t.Errorf("host after a failure = %s, want %s", results[2].Status, resultSuccess)
+	}
+}
+
+func TestRunUnregisteredAndCancelled(t *testing.T) {
+	called := false
+	fn := func(ctx context.Context, agent *agentclient.Agent) (interface{}, error) {
+		called = true
+		return nil, nil
+	}
+
+	results := run(context.Background(), []target{{host: "10.9.9.9"}}, fn, 1, false)
+	if called || results[0].Status != resultFailed || results[0].Error != "Device not registered" {
+		t.Errorf("unregistered host = %+v (called %v)", results[0], called)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results = run(ctx, testTargets("a", "b"), fn, 1, false)
+	for _, res := range results {
+		if res.Status != resultSkipped {
+			t.Errorf("%s status after cancel = %s, want %s", res.Host, res.Status, resultSkipped)
+		}
+	}
+}
+
+func TestPrepareValidatesParams(t *testing.T) {
+	tests := []struct {
+		operation string
+		params    string
+		wantErr   bool
+	}{
+		{"command", `{"command":"uptime"}`, false},
+		{"command", `{"command":"  "}`, true},
+		{"command", ``, true},
+		{"restart_service", `{"service":"nginx"}`, false},
+		{"restart_service", `{}`, true},
+		{"firewall", `{"action":"add","port":"80"}`, false},
+		{"firewall", `{"port":"80"}`, true},
+		{"clean", ``, false},
+		{"ssh_key", `{"key":"ssh-ed25519 AAAA"}`, false},
+		{"ssh_key", `{"key":1}`, true},
+	}
+
+	for _, tt := range tests {
+		_, err := operations[tt.operation].prepare(json.RawMessage(tt.params))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s prepare(%s) error = %v, want error %v", tt.operation, tt.params, err, tt.wantErr)
+		}
+	}
+}
+
+func TestOperationsHavePermissions(t *testing.T) {
+	for name, op := range operations {
+		if op.permission == "" {
+			t.Errorf("operation %s has no permission", name)
+		}
+	}
+}
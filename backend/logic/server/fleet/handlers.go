@@ -0,0 +1,246 @@
+package fleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/agentclient"
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+const (
+	defaultConcurrency = 10
+	maxConcurrency     = 50
+	maxHosts           = 1000
+)
+
+// Standard response structures
+type ErrorResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+type runRequest struct {
+	// Exactly one of hosts, tag and all picks the targets
+	Hosts []string `json:"hosts"`
+	Tag   string   `json:"tag"`
+	All   bool     `json:"all"`
+
+	Operation   string          `json:"operation"`
+	Params      json.RawMessage `json:"params"`
+	Concurrency int             `json:"concurrency"`
+	Mode        string          `json:"mode"` // continue (default) or stop_on_error
+}
+
+// HandleRun runs one operation on many hosts and returns every host's result
+func HandleRun(queries *serverdb.Queries, authz *config.Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req runRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		op, ok := operations[req.Operation]
+		if !ok {
+			sendError(w, "Unknown operation: "+req.Operation, http.StatusBadRequest)
+			return
+		}
+		fn, err := op.prepare(req.Params)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		selectors := 0
+		for _, set := range []bool{len(req.Hosts) > 0, req.Tag != "", req.All} {
+			if set {
+				selectors++
+			}
+		}
+		if selectors != 1 {
+			sendError(w, "Give exactly one of hosts, tag or all", http.StatusBadRequest)
+			return
+		}
+
+		var stopOnError bool
+		switch req.Mode {
+		case "", "continue":
+		case "stop_on_error":
+			stopOnError = true
+		default:
+			sendError(w, "mode must be continue or stop_on_error", http.StatusBadRequest)
+			return
+		}
+
+		concurrency := req.Concurrency
+		if concurrency <= 0 {
+			concurrency = defaultConcurrency
+		} else if concurrency > maxConcurrency {
+			concurrency = maxConcurrency
+		}
+
+		user, _ := config.GetUserFromContext(r)
+		allTags, tags, err := authz.UserTagScope(r.Context(), user, op.permission)
+		if err != nil {
+			sendError(w, "Failed to check permissions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !allTags && len(tags) == 0 {
+			sendError(w, "Permission denied: "+op.permission, http.StatusForbidden)
+			return
+		}
+
+		devices, err := queries.ListDeviceInventory(r.Context())
+		if err != nil {
+			sendError(w, "Failed to fetch devices: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		targets, denied := selectTargets(req, devices, allTags, tags)
+		if len(denied) > 0 {
+			sendError(w, fmt.Sprintf("Permission denied: %s on %s", op.permission, strings.Join(denied, ", ")), http.StatusForbidden)
+			return
+		}
+		if len(targets) == 0 {
+			sendError(w, "No devices match", http.StatusNotFound)
+			return
+		}
+		if len(targets) > maxHosts {
+			sendError(w, fmt.Sprintf("At most %d hosts per request", maxHosts), http.StatusBadRequest)
+			return
+		}
+
+		results := run(r.Context(), targets, fn, concurrency, stopOnError)
+
+		summary := map[string]int{resultSuccess: 0, resultFailed: 0, resultSkipped: 0}
+		for _, res := range results {
+			summary[res.Status]++
+		}
+		status := "success"
+		if summary[resultSuccess] == 0 {
+			status = "failed"
+		} else if summary[resultSuccess] < len(results) {
+			status = "partial"
+		}
+
+		log.Printf("🚀 %s ran %s on %d hosts: %d succeeded, %d failed, %d skipped",
+			user.Username, req.Operation, len(results), summary[resultSuccess], summary[resultFailed], summary[resultSkipped])
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":    status,
+			"operation": req.Operation,
+			"total":     len(results),
+			"summary":   summary,
+			"results":   results,
+		})
+	}
+}
+
+// selectTargets resolves the request's hosts, tag or all into targets.
+// Hosts named explicitly that the user may not touch are returned as denied;
+// a tag or all only selects devices the user may touch.
+func selectTargets(req runRequest, devices []serverdb.ListDeviceInventoryRow, allTags bool, tags map[string]bool) ([]target, []string) {
+	permitted := func(tag string) bool { return allTags || tags[tag] }
+	toTarget := func(d serverdb.ListDeviceInventoryRow) target {
+		return target{host: d.Ip, tag: d.Tag, agent: agentclient.NewAgent(d.Ip, d.Os, d.AccessToken)}
+	}
+
+	byIP := make(map[string]serverdb.ListDeviceInventoryRow, len(devices))
+	for _, d := range devices {
+		if !d.RevokedAt.Valid {
+			byIP[d.Ip] = d
+		}
+	}
+
+	var targets []target
+	var denied []string
+	if len(req.Hosts) > 0 {
+		seen := make(map[string]bool, len(req.Hosts))
+		for _, host := range req.Hosts {
+			host = strings.TrimSpace(host)
+			if host == "" || seen[host] {
+				continue
+			}
+			seen[host] = true
+
+			d, ok := byIP[host]
+			switch {
+			case !ok:
+				targets = append(targets, target{host: host}) // Reported as not registered
+			case !permitted(d.Tag):
+				denied = append(denied, host)
+			default:
+				targets = append(targets, toTarget(d))
+			}
+		}
+		return targets, denied
+	}
+
+	for _, d := range byIP {
+		if (req.All || d.Tag == req.Tag) && permitted(d.Tag) {
+			targets = append(targets, toTarget(d))
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].host < targets[j].host })
+	return targets, nil
+}
+
+// HandleListOperations lists the operations that can be fanned out
+func HandleListOperations() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		names := make([]string, 0, len(operations))
+		for name := range operations {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		list := make([]map[string]string, 0, len(names))
+		for _, name := range names {
+			list = append(list, map[string]string{
+				"operation":   name,
+				"permission":  operations[name].permission,
+				"description": operations[name].description,
+			})
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":     "success",
+			"operations": list,
+		})
+	}
+}
+
+// Standard response functions
+func sendGetSuccess(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+func sendError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	errorResp := ErrorResponse{
+		Status:  "failed",
+		Message: message,
+	}
+	json.NewEncoder(w).Encode(errorResp)
+}
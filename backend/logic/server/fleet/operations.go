@@ -0,0 +1,127 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/agentclient"
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+)
+
+// runFunc performs an operation on one agent and returns what it answered
+type runFunc func(ctx context.Context, agent *agentclient.Agent) (interface{}, error)
+
+// operation is an agent call that can be fanned out. prepare validates the
+// params once so a bad request fails before any host is touched.
+type operation struct {
+	permission  string
+	description string
+	prepare     func(params json.RawMessage) (runFunc, error)
+}
+
+var operations = map[string]operation{
+	"command": {
+		permission:  config.PermCmdExec,
+		description: `Run a shell command. Params: {"command": "..."}`,
+		prepare: func(params json.RawMessage) (runFunc, error) {
+			var p struct {
+				Command string `json:"command"`
+			}
+			if err := decodeParams(params, &p); err != nil {
+				return nil, err
+			}
+			command := strings.TrimSpace(p.Command)
+			if command == "" {
+				return nil, errors.New("command is required")
+			}
+			return func(ctx context.Context, agent *agentclient.Agent) (interface{}, error) {
+				return agent.RunCommand(ctx, command)
+			}, nil
+		},
+	},
+	"restart_service": {
+		permission:  config.PermServiceRestart,
+		description: `Restart a service. Params: {"service": "..."}`,
+		prepare: func(params json.RawMessage) (runFunc, error) {
+			var p struct {
+				Service string `json:"service"`
+			}
+			if err := decodeParams(params, &p); err != nil {
+				return nil, err
+			}
+			service := strings.TrimSpace(p.Service)
+			if service == "" {
+				return nil, errors.New("service is required")
+			}
+			return func(ctx context.Context, agent *agentclient.Agent) (interface{}, error) {
+				return agent.RestartService(ctx, service)
+			}, nil
+		},
+	},
+	"firewall": {
+		permission:  config.PermFirewallWrite,
+		description: `Add or delete a firewall rule. Params: the body of config2/postupdatefirewall without host`,
+		prepare: func(params json.RawMessage) (runFunc, error) {
+			var update agentclient.FirewallUpdate
+			if err := decodeParams(params, &update); err != nil {
+				return nil, err
+			}
+			if update.Action == "" {
+				return nil, errors.New("action is required")
+			}
+			return func(ctx context.Context, agent *agentclient.Agent) (interface{}, error) {
+				u := update
+				// Linux agents need a rule, as with the single-host API
+				if strings.ToLower(agent.OS) != "windows" && u.Rule == "" {
+					u.Rule = "accept"
+				}
+				return agent.UpdateFirewall(ctx, u)
+			}, nil
+		},
+	},
+	"clean": {
+		permission:  config.PermResourceClean,
+		description: "Remove temporary files and caches. No params",
+		prepare: func(params json.RawMessage) (runFunc, error) {
+			return func(ctx context.Context, agent *agentclient.Agent) (interface{}, error) {
+				result, err := agent.Optimize(ctx)
+				if err == nil && result.Status == "failed" {
+					return result, errors.New(result.Message)
+				}
+				return result, err
+			}, nil
+		},
+	},
+	"ssh_key": {
+		permission:  config.PermConfigWrite,
+		description: `Authorize an SSH public key. Params: {"key": "..."}`,
+		prepare: func(params json.RawMessage) (runFunc, error) {
+			var p struct {
+				Key string `json:"key"`
+			}
+			if err := decodeParams(params, &p); err != nil {
+				return nil, err
+			}
+			key := strings.TrimSpace(p.Key)
+			if key == "" {
+				return nil, errors.New("key is required")
+			}
+			return func(ctx context.Context, agent *agentclient.Agent) (interface{}, error) {
+				return agent.AddSSHKey(ctx, key)
+			}, nil
+		},
+	},
+}
+
+func decodeParams(params json.RawMessage, out interface{}) error {
+	if len(params) == 0 {
+		return errors.New("params are required")
+	}
+	if err := json.Unmarshal(params, out); err != nil {
+		return fmt.Errorf("invalid params: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package fleet
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/agentclient"
+)
+
+// Per-host outcomes
+const (
+	resultSuccess = "success"
+	resultFailed  = "failed"
+	resultSkipped = "skipped" // Not started because of stop_on_error or a cancelled request
+)
+
+// target is one host an operation runs on. agent is nil for hosts that are
+// not registered.
+type target struct {
+	host  string
+	tag   string
+	agent *agentclient.Agent
+}
+
+// HostResult is what happened on one host
+type HostResult struct {
+	Host       string      `json:"host"`
+	Tag        string      `json:"tag,omitempty"`
+	Status     string      `json:"status"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	DurationMS int64       `json:"duration_ms"`
+}
+
+// run calls fn on every target, at most concurrency at a time. With
+// stopOnError, hosts that have not started when one fails are skipped;
+// calls already in flight still finish. Results keep the order of targets.
+func run(ctx context.Context, targets []target, fn runFunc, concurrency int, stopOnError bool) []HostResult {
+	results := make([]HostResult, len(targets))
+	sem := make(chan struct{}, concurrency)
+	var stopped atomic.Bool
+	var wg sync.WaitGroup
+
+	for i, t := range targets {
+		results[i] = HostResult{Host: t.host, Tag: t.tag, Status: resultSkipped}
+
+		if stopped.Load() {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			continue
+		}
+		// Another host may have failed, or the request ended, while waiting
+		if stopped.Load() || ctx.Err() != nil {
+			<-sem
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, t target) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			result := &results[i]
+			var resp interface{}
+			err := agentclient.ErrDeviceNotFound
+			if t.agent != nil {
+				start := time.Now()
+				resp, err = fn(ctx, t.agent)
+				result.DurationMS = time.Since(start).Milliseconds()
+			}
+
+			if err != nil {
+				message, _ := agentclient.Describe(err)
+				result.Status = resultFailed
+				result.Error = message
+				if stopOnError {
+					stopped.Store(true)
+				}
+				return
+			}
+			result.Status = resultSuccess
+			result.Result = resp
+		}(i, t)
+	}
+
+	wg.Wait()
+	return results
+}
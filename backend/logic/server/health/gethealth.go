@@ -1,14 +1,11 @@
 package health
 
 import (
-	"context"
-	"database/sql"
 	"encoding/json"
-	"io"
 	"net/http"
 	"strings"
 
-	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	"github.com/kishore-001/ServerManagementSuite/backend/agentclient"
 	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
 )
 
@@ -36,56 +33,20 @@ func GetHealth(queries *serverdb.Queries) http.HandlerFunc {
 			return
 		}
 
-		device, err := queries.GetServerDeviceByIP(context.Background(), req.Host)
-		if err == sql.ErrNoRows {
-			sendError(w, "Device not registered", http.StatusNotFound)
-			return
-		} else if err != nil {
-			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		// Use config for client URL (reads from .env file)
-		clientURL := config.GetClientURL(req.Host, "/client/health")
-
-		clientReq, err := http.NewRequest("GET", clientURL, nil)
+		agent, err := agentclient.Open(r.Context(), queries, req.Host)
 		if err != nil {
-			sendError(w, "Failed to create request to client: "+err.Error(), http.StatusInternalServerError)
+			sendAgentError(w, err)
 			return
 		}
-		clientReq.Header.Set("Authorization", "Bearer "+device.AccessToken)
-		clientReq.Header.Set("Content-Type", "application/json")
 
-		resp, err := http.DefaultClient.Do(clientReq)
+		clientResp, err := agent.Health(r.Context())
 		if err != nil {
-			sendError(w, "Failed to reach client: "+err.Error(), http.StatusBadGateway)
-			return
-		}
-		defer resp.Body.Close()
-
-		// Handle client error responses
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-
-			// Try to parse client error response (standardized format)
-			var clientError ErrorResponse
-			if json.Unmarshal(body, &clientError) == nil && clientError.Status == "failed" {
-				sendError(w, "Client error: "+clientError.Message, http.StatusBadGateway)
-			} else {
-				sendError(w, "Client error: "+string(body), http.StatusBadGateway)
-			}
-			return
-		}
-
-		// Parse client response
-		var clientResp interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&clientResp); err != nil {
-			sendError(w, "Invalid client response: "+err.Error(), http.StatusBadGateway)
+			sendAgentError(w, err)
 			return
 		}
 
 		// Process response based on OS
-		processedResp := processHealthResponse(clientResp, device.Os)
+		processedResp := processHealthResponse(clientResp, agent.OS)
 
 		// Send successful response
 		sendGetSuccess(w, processedResp)
@@ -109,6 +70,12 @@ func sendError(w http.ResponseWriter, message string, statusCode int) {
 	json.NewEncoder(w).Encode(errorResp)
 }
 
+// sendAgentError answers with what went wrong calling the agent
+func sendAgentError(w http.ResponseWriter, err error) {
+	message, statusCode := agentclient.Describe(err)
+	sendError(w, message, statusCode)
+}
+
 // Process health response based on OS
 func processHealthResponse(resp interface{}, osType string) interface{} {
 	if strings.ToLower(osType) == "windows" {
@@ -0,0 +1,188 @@
+package inventory
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/auth"
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/devicestatus"
+)
+
+// HandleExport downloads the device inventory as CSV (default) or JSON.
+// Access tokens are only included with ?include_tokens=true, only for users
+// holding every permission (API tokens need the "*" scope), and only after a
+// fresh password/MFA check sent in the X-Confirm-Password and X-Confirm-Code
+// headers.
+func HandleExport(queries *serverdb.Queries, general *generaldb.Queries, authz *config.Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "csv"
+		}
+		if format != "csv" && format != "json" {
+			sendError(w, "format must be csv or json", http.StatusBadRequest)
+			return
+		}
+
+		user, _ := config.GetUserFromContext(r)
+		includeTokens := r.URL.Query().Get("include_tokens") == "true"
+		if includeTokens {
+			// An empty scope list would otherwise pass for a token
+			if user.ViaAPIToken() && !hasScope(user.Scopes, config.PermAll) {
+				sendError(w, "Exporting access tokens needs an API token with the * scope", http.StatusForbidden)
+				return
+			}
+			allowed, err := authz.UserAllowed(r.Context(), user, config.PermAll, "")
+			if err != nil {
+				sendError(w, "Failed to check permissions: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if !allowed {
+				sendError(w, "Only administrators can export access tokens", http.StatusForbidden)
+				return
+			}
+
+			err = auth.ConfirmIdentity(r.Context(), general, user.Username,
+				r.Header.Get("X-Confirm-Password"), r.Header.Get("X-Confirm-Code"), "", auth.ClientIP(r))
+			if errors.Is(err, auth.ErrReauthFailed) {
+				sendError(w, "Confirm your identity to export access tokens: "+err.Error(), http.StatusUnauthorized)
+				return
+			} else if err != nil {
+				sendError(w, "Failed to confirm identity: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		allTags, tags, err := authz.UserTagScope(r.Context(), user, config.PermDevicesRead)
+		if err != nil {
+			sendError(w, "Failed to check permissions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		devices, err := queries.ListDeviceInventory(r.Context())
+		if err != nil {
+			sendError(w, "Failed to fetch devices: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !allTags {
+			visible := devices[:0]
+			for _, d := range devices {
+				if tags[d.Tag] {
+					visible = append(visible, d)
+				}
+			}
+			devices = visible
+		}
+
+		if includeTokens {
+			log.Printf("🔑 %s exported the access tokens of %d devices", user.Username, len(devices))
+		}
+
+		filename := "devices-" + time.Now().Format("20060102-150405") + "." + format
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+		if format == "json" {
+			deviceList := make([]map[string]interface{}, 0, len(devices))
+			for _, d := range devices {
+				device := map[string]interface{}{
+					"id":              d.ID,
+					"ip":              d.Ip,
+					"tag":             d.Tag,
+					"os":              d.Os,
+					"port":            d.AgentPort,
+					"scheme":          d.AgentScheme,
+					"base_path":       d.AgentBasePath,
+					"ca_cert":         d.AgentCaCert,
+					"fingerprint":     d.AgentFingerprint,
+					"reverse_connect": d.ReverseConnect,
+					"status":          devicestatus.Current(d.Status, d.StatusCheckedAt),
+					"last_seen_at":    nullTime(d.LastSeenAt),
+					"revoked_at":      nullTime(d.RevokedAt),
+					"created_at":      d.CreatedAt,
+				}
+				if includeTokens {
+					device["access_token"] = d.AccessToken
+				}
+				deviceList = append(deviceList, device)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(deviceList)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv")
+		writer := csv.NewWriter(w)
+		header := []string{"id", "ip", "tag", "os", "port", "scheme", "base_path", "ca_cert", "fingerprint",
+			"reverse_connect", "status", "last_seen_at", "revoked_at", "created_at"}
+		if includeTokens {
+			header = append(header, "access_token")
+		}
+		writer.Write(header)
+		for _, d := range devices {
+			port := ""
+			if d.AgentPort != 0 {
+				port = strconv.Itoa(int(d.AgentPort))
+			}
+			record := []string{
+				d.ID.String(),
+				d.Ip,
+				csvSafe(d.Tag),
+				csvSafe(d.Os),
+				port,
+				d.AgentScheme,
+				d.AgentBasePath,
+				d.AgentCaCert,
+				d.AgentFingerprint,
+				strconv.FormatBool(d.ReverseConnect),
+				devicestatus.Current(d.Status, d.StatusCheckedAt),
+				csvTime(d.LastSeenAt),
+				csvTime(d.RevokedAt),
+				d.CreatedAt.UTC().Format(time.RFC3339),
+			}
+			if includeTokens {
+				record = append(record, d.AccessToken)
+			}
+			writer.Write(record)
+		}
+		writer.Flush()
+	}
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// nullTime renders a nullable timestamp as null in JSON
+func nullTime(t sql.NullTime) interface{} {
+	if t.Valid {
+		return t.Time
+	}
+	return nil
+}
+
+func csvTime(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,298 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/agentclient"
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+const maxImportBody = 5 << 20
+
+// Row outcomes. With dry_run they say what an import would do.
+const (
+	actionCreate    = "create"
+	actionUpdate    = "update"
+	actionUnchanged = "unchanged"
+	actionError     = "error"
+)
+
+// Standard response structures
+type ErrorResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+type rowResult struct {
+	Row         int      `json:"row"` // CSV line or position in the JSON array, from 1
+	IP          string   `json:"ip"`
+	Action      string   `json:"action"`
+	Errors      []string `json:"errors,omitempty"`
+	AccessToken string   `json:"access_token,omitempty"` // New devices only, shown once
+}
+
+// importer holds what every row is checked against
+type importer struct {
+	queries  *serverdb.Queries
+	existing map[string]serverdb.ListDeviceInventoryRow
+	seen     map[string]int // ip -> first row that used it
+	allTags  bool
+	tags     map[string]bool
+	dryRun   bool
+}
+
+// HandleImport adds devices from a CSV or JSON file and updates the ones
+// whose ip is already registered. Columns left out of the file keep their
+// current value. Invalid rows are reported and skipped; ?dry_run=true
+// reports without saving anything.
+func HandleImport(queries *serverdb.Queries, authz *config.Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBody))
+		if err != nil {
+			sendError(w, "Failed to read file: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "json"
+			if strings.Contains(r.Header.Get("Content-Type"), "csv") {
+				format = "csv"
+			}
+		}
+
+		var rows []deviceRow
+		switch format {
+		case "csv":
+			rows, err = parseCSV(bytes.NewReader(body))
+		case "json":
+			rows, err = parseJSON(body)
+		default:
+			sendError(w, "format must be csv or json", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			sendError(w, "Invalid "+format+" file: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		user, _ := config.GetUserFromContext(r)
+		allTags, tags, err := authz.UserTagScope(r.Context(), user, config.PermDevicesManage)
+		if err != nil {
+			sendError(w, "Failed to check permissions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		devices, err := queries.ListDeviceInventory(r.Context())
+		if err != nil {
+			sendError(w, "Failed to fetch devices: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		imp := &importer{
+			queries:  queries,
+			existing: make(map[string]serverdb.ListDeviceInventoryRow, len(devices)),
+			seen:     make(map[string]int, len(rows)),
+			allTags:  allTags,
+			tags:     tags,
+			dryRun:   r.URL.Query().Get("dry_run") == "true",
+		}
+		for _, d := range devices {
+			imp.existing[d.Ip] = d
+		}
+
+		results := make([]rowResult, 0, len(rows))
+		summary := map[string]int{actionCreate: 0, actionUpdate: 0, actionUnchanged: 0, actionError: 0}
+		for _, row := range rows {
+			result := imp.importRow(r.Context(), row)
+			summary[result.Action]++
+			results = append(results, result)
+		}
+
+		if !imp.dryRun {
+			log.Printf("📥 %s imported devices: %d created, %d updated, %d unchanged, %d rejected",
+				user.Username, summary[actionCreate], summary[actionUpdate], summary[actionUnchanged], summary[actionError])
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":  "success",
+			"dry_run": imp.dryRun,
+			"total":   len(rows),
+			"summary": summary,
+			"rows":    results,
+		})
+	}
+}
+
+func (imp *importer) importRow(ctx context.Context, row deviceRow) rowResult {
+	result := rowResult{Row: row.Row, IP: row.IP}
+	var errs []string
+	if row.parseErr != "" {
+		errs = append(errs, row.parseErr)
+	}
+
+	if net.ParseIP(row.IP) == nil {
+		errs = append(errs, "ip must be a valid IP address")
+	} else if first, dup := imp.seen[row.IP]; dup {
+		errs = append(errs, fmt.Sprintf("ip already appears in row %d", first))
+	} else {
+		imp.seen[row.IP] = row.Row
+	}
+
+	current, exists := imp.existing[row.IP]
+	if exists && current.RevokedAt.Valid {
+		errs = append(errs, "device is revoked")
+	}
+
+	tag, osName := current.Tag, current.Os
+	currentEndpoint := config.AgentEndpoint{
+		Scheme:      current.AgentScheme,
+		Port:        int(current.AgentPort),
+		BasePath:    current.AgentBasePath,
+		CACert:      current.AgentCaCert,
+		Fingerprint: current.AgentFingerprint,
+	}
+	endpoint := currentEndpoint
+	if row.Tag != nil {
+		tag = strings.TrimSpace(*row.Tag)
+	}
+	if row.OS != nil {
+		osName = strings.TrimSpace(*row.OS)
+	}
+	if row.Port != nil {
+		endpoint.Port = *row.Port
+	}
+	if row.Scheme != nil {
+		endpoint.Scheme = *row.Scheme
+	}
+	if row.BasePath != nil {
+		endpoint.BasePath = *row.BasePath
+	}
+	if row.CACert != nil {
+		endpoint.CACert = *row.CACert
+	}
+	if row.Fingerprint != nil {
+		endpoint.Fingerprint = *row.Fingerprint
+	}
+
+	if len(tag) > 100 || len(osName) > 100 {
+		errs = append(errs, "tag and os must be at most 100 characters")
+	}
+	endpoint, err := config.NormalizeAgentEndpoint(endpoint, current.TlsFingerprint != "")
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	// Tag-scoped users can only import into their own tags
+	if !imp.allTags {
+		if exists && !imp.tags[current.Tag] {
+			errs = append(errs, "not permitted to change this device")
+		} else if !imp.tags[tag] {
+			errs = append(errs, fmt.Sprintf("not permitted to use tag %q", tag))
+		}
+		// The endpoint decides where the device's access token is sent
+		if exists && endpoint != currentEndpoint {
+			errs = append(errs, "not permitted to change the agent endpoint of an existing device")
+		}
+	}
+
+	if len(errs) > 0 {
+		result.Action = actionError
+		result.Errors = errs
+		return result
+	}
+
+	switch {
+	case !exists:
+		result.Action = actionCreate
+	case tag == current.Tag && osName == current.Os && endpoint == currentEndpoint:
+		result.Action = actionUnchanged
+	default:
+		result.Action = actionUpdate
+	}
+	if imp.dryRun || result.Action == actionUnchanged {
+		return result
+	}
+
+	accessToken, err := randomHex(32)
+	if err != nil {
+		return failed(result, "failed to generate access token")
+	}
+	saved, err := imp.queries.UpsertServerDevice(ctx, serverdb.UpsertServerDeviceParams{
+		Ip:               row.IP,
+		Tag:              tag,
+		Os:               osName,
+		AccessToken:      accessToken, // Only used when the device is created
+		AgentPort:        int32(endpoint.Port),
+		AgentScheme:      endpoint.Scheme,
+		AgentBasePath:    endpoint.BasePath,
+		AgentCaCert:      endpoint.CACert,
+		AgentFingerprint: endpoint.Fingerprint,
+	})
+	if err == sql.ErrNoRows {
+		return failed(result, "device is revoked")
+	} else if err != nil {
+		return failed(result, "failed to save device: "+err.Error())
+	}
+	if err := config.SetAgentEndpoint(row.IP, endpoint); err != nil {
+		log.Printf("⚠️ Failed to apply endpoint of %s: %v", row.IP, err)
+	}
+	agentclient.Reset(row.IP)
+
+	if saved.Inserted {
+		result.Action = actionCreate
+		result.AccessToken = accessToken
+	} else {
+		result.Action = actionUpdate
+	}
+	return result
+}
+
+func failed(result rowResult, message string) rowResult {
+	result.Action = actionError
+	result.Errors = []string{message}
+	return result
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Standard response functions
+func sendGetSuccess(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+func sendError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	errorResp := ErrorResponse{
+		Status:  "failed",
+		Message: message,
+	}
+	json.NewEncoder(w).Encode(errorResp)
+}
@@ -0,0 +1,190 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+func TestParseCSV(t *testing.T) {
+	rows, err := parseCSV(strings.NewReader("IP, tag ,port,status,last_seen_at\n" +
+		"10.0.0.1,web,8080,online,2024-01-01\n" +
+		"10.0.0.2,'=cmd,,offline,\n" +
+		"10.0.0.3,db,eighty,,\n"))
+	if err != nil {
+		t.Fatalf("parseCSV: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(rows))
+	}
+
+	if rows[0].Row != 2 || rows[0].IP != "10.0.0.1" || *rows[0].Tag != "web" || *rows[0].Port != 8080 {
+		t.Errorf("row 2 = %+v", rows[0])
+	}
+	if rows[0].OS != nil || rows[0].Scheme != nil {
+		t.Error("columns missing from the file were set")
+	}
+	if *rows[1].Tag != "=cmd" || *rows[1].Port != 0 {
+		t.Errorf("row 3 tag = %q, port = %d", *rows[1].Tag, *rows[1].Port)
+	}
+	if rows[2].parseErr != "port must be a number" {
+		t.Errorf("row 4 parseErr = %q", rows[2].parseErr)
+	}
+}
+
+func TestParseCSVRejectsHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"empty", ""},
+		{"no ip", "tag,os\nweb,linux\n"},
+		{"unknown column", "ip,password\n10.0.0.1,x\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseCSV(strings.NewReader(tt.file)); err == nil {
+				t.Error("parseCSV accepted the file")
+			}
+		})
+	}
+}
+
+func TestParseCSVRowLimit(t *testing.T) {
+	file := "ip\n" + strings.Repeat("10.0.0.1\n", maxImportRows+1)
+	if _, err := parseCSV(strings.NewReader(file)); err == nil {
+		t.Errorf("parseCSV accepted %d rows", maxImportRows+1)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	for _, body := range []string{
+		`[{"ip":" 10.0.0.1 ","tag":"web"},{"ip":"10.0.0.2","port":9000}]`,
+		`{"devices":[{"ip":"10.0.0.1","tag":"web"},{"ip":"10.0.0.2","port":9000}]}`,
+	} {
+		rows, err := parseJSON([]byte(body))
+		if err != nil {
+			t.Fatalf("parseJSON(%s): %v", body, err)
+		}
+		if len(rows) != 2 || rows[0].Row != 1 || rows[0].IP != "10.0.0.1" || *rows[0].Tag != "web" ||
+			rows[1].Row != 2 || *rows[1].Port != 9000 || rows[1].Tag != nil {
+			t.Errorf("parseJSON(%s) = %+v", body, rows)
+		}
+	}
+
+	if _, err := parseJSON([]byte(`{"devices":"x"}`)); err == nil {
+		t.Error("parseJSON accepted a devices string")
+	}
+}
+
+func TestCSVSafeRoundTrip(t *testing.T) {
+	for _, value := range []string{"", "web", "=SUM(A1)", "+1", "-1", "@cmd", "'quoted", "'"} {
+		safe := csvSafe(value)
+		if value != "" && strings.ContainsAny(value[:1], "=+-@") && safe[0] != '\'' {
+			t.Errorf("csvSafe(%q) = %q, want it escaped", value, safe)
+		}
+		if got := csvUnescape(safe); got != value {
+			t.Errorf("csvUnescape(csvSafe(%q)) = %q", value, got)
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestImportRowDryRun(t *testing.T) {
+	existing := map[string]serverdb.ListDeviceInventoryRow{
+		"10.0.0.1": {Ip: "10.0.0.1", Tag: "web", Os: "linux"},
+		"10.0.0.2": {Ip: "10.0.0.2", Tag: "db", Os: "linux"},
+		"10.0.0.3": {Ip: "10.0.0.3", Tag: "web", RevokedAt: sql.NullTime{Time: time.Now(), Valid: true}},
+	}
+
+	tests := []struct {
+		name       string
+		row        deviceRow
+		allTags    bool
+		wantAction string
+		wantErrors []string
+	}{
+		{"new device", deviceRow{IP: "10.0.0.9", Tag: ptr("web")}, true, actionCreate, nil},
+		{"unchanged", deviceRow{IP: "10.0.0.1", Tag: ptr("web")}, true, actionUnchanged, nil},
+		{"changed tag", deviceRow{IP: "10.0.0.1", Tag: ptr("api")}, true, actionUpdate, nil},
+		{"invalid ip", deviceRow{IP: "server-1"}, true, actionError, []string{"ip must be a valid IP address"}},
+		{"revoked", deviceRow{IP: "10.0.0.3"}, true, actionError, []string{"device is revoked"}},
+		{"parse error", deviceRow{IP: "10.0.0.9", Port: ptr(0), parseErr: "port must be a number"}, true,
+			actionError, []string{"port must be a number"}},
+		{"bad scheme", deviceRow{IP: "10.0.0.9", Scheme: ptr("ftp")}, true, actionError, []string{"scheme must be http or https"}},
+		{"scoped new device", deviceRow{IP: "10.0.0.9", Tag: ptr("web")}, false, actionCreate, nil},
+		{"scoped foreign tag", deviceRow{IP: "10.0.0.9", Tag: ptr("db")}, false, actionError,
+			[]string{`not permitted to use tag "db"`}},
+		{"scoped foreign device", deviceRow{IP: "10.0.0.2", Tag: ptr("web")}, false, actionError,
+			[]string{"not permitted to change this device"}},
+		{"scoped endpoint change", deviceRow{IP: "10.0.0.1", Port: ptr(9000)}, false, actionError,
+			[]string{"not permitted to change the agent endpoint of an existing device"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imp := &importer{
+				existing: existing,
+				seen:     map[string]int{},
+				allTags:  tt.allTags,
+				tags:     map[string]bool{"web": true},
+				dryRun:   true,
+			}
+			result := imp.importRow(context.Background(), tt.row)
+			if result.Action != tt.wantAction || !reflect.DeepEqual(result.Errors, tt.wantErrors) {
+				t.Errorf("importRow = %s %v, want %s %v", result.Action, result.Errors, tt.wantAction, tt.wantErrors)
+			}
+			if result.AccessToken != "" {
+				t.Error("dry run returned an access token")
+			}
+		})
+	}
+}
+
+func TestImportRowDuplicateIP(t *testing.T) {
+	imp := &importer{seen: map[string]int{}, allTags: true, dryRun: true}
+	imp.importRow(context.Background(), deviceRow{Row: 2, IP: "10.0.0.1"})
+	result := imp.importRow(context.Background(), deviceRow{Row: 5, IP: "10.0.0.1"})
+	if result.Action != actionError || !reflect.DeepEqual(result.Errors, []string{"ip already appears in row 2"}) {
+		t.Errorf("duplicate row = %s %v", result.Action, result.Errors)
+	}
+}
+
+func TestExportRejectedBeforeTheDatabase(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		user  config.UserInfo
+		want  int
+	}{
+		{"unknown format", "format=xml", config.UserInfo{Username: "admin"}, http.StatusBadRequest},
+		{"token without the * scope", "include_tokens=true",
+			config.UserInfo{Username: "admin", APITokenID: 1, Scopes: []string{config.PermDevicesRead}}, http.StatusForbidden},
+		{"token with no scopes", "include_tokens=true",
+			config.UserInfo{Username: "admin", APITokenID: 1}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/x?"+tt.query, nil)
+			r = r.WithContext(context.WithValue(r.Context(), config.UserContextKey, tt.user))
+			w := httptest.NewRecorder()
+			HandleExport(nil, nil, nil)(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
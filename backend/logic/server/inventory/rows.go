@@ -0,0 +1,180 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const maxImportRows = 5000
+
+// deviceRow is one device from an import file. Nil fields were not in the
+// file and keep their current value.
+type deviceRow struct {
+	Row         int
+	IP          string
+	Tag         *string
+	OS          *string
+	Port        *int
+	Scheme      *string
+	BasePath    *string
+	CACert      *string
+	Fingerprint *string
+	parseErr    string
+}
+
+// jsonRow is the JSON form of deviceRow, with the export's keys
+type jsonRow struct {
+	IP          string  `json:"ip"`
+	Tag         *string `json:"tag"`
+	OS          *string `json:"os"`
+	Port        *int    `json:"port"`
+	Scheme      *string `json:"scheme"`
+	BasePath    *string `json:"base_path"`
+	CACert      *string `json:"ca_cert"`
+	Fingerprint *string `json:"fingerprint"`
+}
+
+// Columns written by the export that are not imported, so an exported file
+// can be edited and imported again
+var exportOnlyColumns = map[string]bool{
+	"id":              true,
+	"reverse_connect": true,
+	"status":          true,
+	"last_seen_at":    true,
+	"revoked_at":      true,
+	"created_at":      true,
+	"access_token":    true,
+}
+
+func parseCSV(body io.Reader) ([]deviceRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("the file is empty")
+	} else if err != nil {
+		return nil, err
+	}
+
+	columns := make([]string, len(header))
+	hasIP := false
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "ip":
+			hasIP = true
+		case "tag", "os", "port", "scheme", "base_path", "ca_cert", "fingerprint":
+		default:
+			if !exportOnlyColumns[name] {
+				return nil, fmt.Errorf("unknown column %q", name)
+			}
+			name = "" // Ignored
+		}
+		columns[i] = name
+	}
+	if !hasIP {
+		return nil, errors.New("the header must include an ip column")
+	}
+
+	var rows []deviceRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if len(rows) == maxImportRows {
+			return nil, fmt.Errorf("at most %d devices can be imported at once", maxImportRows)
+		}
+
+		row := deviceRow{Row: line}
+		for i, value := range record {
+			value = csvUnescape(strings.TrimSpace(value))
+			switch columns[i] {
+			case "ip":
+				row.IP = value
+			case "tag":
+				row.Tag = &value
+			case "os":
+				row.OS = &value
+			case "port":
+				port := 0
+				if value != "" {
+					if port, err = strconv.Atoi(value); err != nil {
+						row.parseErr = "port must be a number"
+					}
+				}
+				row.Port = &port
+			case "scheme":
+				row.Scheme = &value
+			case "base_path":
+				row.BasePath = &value
+			case "ca_cert":
+				row.CACert = &value
+			case "fingerprint":
+				row.Fingerprint = &value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseJSON accepts an array of devices or an object with a devices array
+func parseJSON(body []byte) ([]deviceRow, error) {
+	var list []jsonRow
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '{' {
+		var wrapper struct {
+			Devices []jsonRow `json:"devices"`
+		}
+		if err := json.Unmarshal(trimmed, &wrapper); err != nil {
+			return nil, err
+		}
+		list = wrapper.Devices
+	} else if err := json.Unmarshal(trimmed, &list); err != nil {
+		return nil, err
+	}
+	if len(list) > maxImportRows {
+		return nil, fmt.Errorf("at most %d devices can be imported at once", maxImportRows)
+	}
+
+	rows := make([]deviceRow, 0, len(list))
+	for i, d := range list {
+		rows = append(rows, deviceRow{
+			Row:         i + 1,
+			IP:          strings.TrimSpace(d.IP),
+			Tag:         d.Tag,
+			OS:          d.OS,
+			Port:        d.Port,
+			Scheme:      d.Scheme,
+			BasePath:    d.BasePath,
+			CACert:      d.CACert,
+			Fingerprint: d.Fingerprint,
+		})
+	}
+	return rows, nil
+}
+
+// csvSafe stops spreadsheet apps from treating a cell as a formula
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsAny(value[:1], "=+-@\t\r") {
+		return "'" + value
+	}
+	return value
+}
+
+// csvUnescape undoes csvSafe for files that went through an export
+func csvUnescape(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsAny(value[1:2], "=+-@\t\r") {
+		return value[1:]
+	}
+	return value
+}
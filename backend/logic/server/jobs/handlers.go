@@ -0,0 +1,444 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// Standard response structures
+type ErrorResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// HandleSubmit queues an operation on one host and returns the job right away
+func HandleSubmit(queries *serverdb.Queries, authz *config.Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			Host      string          `json:"host"`
+			Operation string          `json:"operation"`
+			Params    json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Host = strings.TrimSpace(req.Host)
+		if req.Host == "" {
+			sendError(w, "host is required", http.StatusBadRequest)
+			return
+		}
+
+		op, ok := operations[req.Operation]
+		if !ok {
+			sendError(w, "Unknown operation: "+req.Operation, http.StatusBadRequest)
+			return
+		}
+		params, err := op.prepare(req.Params)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		user, _ := config.GetUserFromContext(r)
+		allowed, err := authz.UserAllowed(r.Context(), user, op.permission, req.Host)
+		if err != nil {
+			sendError(w, "Failed to check permissions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			sendError(w, "Permission denied: "+op.permission, http.StatusForbidden)
+			return
+		}
+
+		if _, err := queries.GetServerDeviceByIP(r.Context(), req.Host); err == sql.ErrNoRows {
+			sendError(w, "Device not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		job, err := queries.CreateAgentJob(r.Context(), serverdb.CreateAgentJobParams{
+			Host:      req.Host,
+			Operation: req.Operation,
+			Params:    params,
+			CreatedBy: user.Username,
+		})
+		if err != nil {
+			sendError(w, "Failed to create job: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("📥 %s queued job %s (%s on %s)", user.Username, job.ID, job.Operation, job.Host)
+		sendPostSuccess(w, map[string]interface{}{
+			"status":  "success",
+			"message": "Job queued",
+			"job":     jobDetails(job, true),
+		})
+	}
+}
+
+// HandleList lists jobs on the devices the caller can read, newest first.
+// ?host=, ?status= and ?limit= (default 100) narrow the list.
+func HandleList(queries *serverdb.Queries, authz *config.Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		limit := defaultListLimit
+		if value := query.Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 || parsed > maxListLimit {
+				sendError(w, "limit must be between 1 and 1000", http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+		status := query.Get("status")
+		switch status {
+		case "", statusQueued, statusRunning, statusSucceeded, statusFailed, statusCancelled:
+		default:
+			sendError(w, "status must be queued, running, succeeded, failed or cancelled", http.StatusBadRequest)
+			return
+		}
+
+		user, _ := config.GetUserFromContext(r)
+		allTags, tags, err := authz.UserTagScope(r.Context(), user, config.PermDevicesRead)
+		if err != nil {
+			sendError(w, "Failed to check permissions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		deviceTags := make(map[string]string)
+		if !allTags {
+			devices, err := queries.ListDeviceInventory(r.Context())
+			if err != nil {
+				sendError(w, "Failed to fetch devices: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			for _, d := range devices {
+				deviceTags[d.Ip] = d.Tag
+			}
+		}
+
+		host := query.Get("host")
+		jobs, err := queries.ListAgentJobs(r.Context(), serverdb.ListAgentJobsParams{
+			Host:     sql.NullString{String: host, Valid: host != ""},
+			Status:   sql.NullString{String: status, Valid: status != ""},
+			RowLimit: int32(limit),
+		})
+		if err != nil {
+			sendError(w, "Failed to fetch jobs: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		canSeeDetails := detailsFilter(r.Context(), authz, user)
+		jobList := make([]map[string]interface{}, 0, len(jobs))
+		for _, j := range jobs {
+			if !allTags && !tags[deviceTags[j.Host]] {
+				continue
+			}
+			visible, err := canSeeDetails(j.Operation, j.Host)
+			if err != nil {
+				sendError(w, "Failed to check permissions: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			var params interface{}
+			if visible {
+				params = j.Params
+			}
+			jobList = append(jobList, map[string]interface{}{
+				"id":               j.ID,
+				"host":             j.Host,
+				"operation":        j.Operation,
+				"params":           params,
+				"redacted":         !visible,
+				"status":           j.Status,
+				"progress":         j.Progress,
+				"exit_code":        nullInt(j.ExitCode),
+				"error":            j.Error,
+				"cancel_requested": j.CancelRequested,
+				"created_by":       j.CreatedBy,
+				"created_at":       j.CreatedAt,
+				"started_at":       nullTime(j.StartedAt),
+				"finished_at":      nullTime(j.FinishedAt),
+				"updated_at":       j.UpdatedAt,
+			})
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status": "success",
+			"jobs":   jobList,
+			"count":  len(jobList),
+		})
+	}
+}
+
+// HandleGet returns a job with its output, for ?id=
+func HandleGet(queries *serverdb.Queries, authz *config.Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		job, ok := loadJob(w, r, queries, authz, r.URL.Query().Get("id"), config.PermDevicesRead)
+		if !ok {
+			return
+		}
+
+		user, _ := config.GetUserFromContext(r)
+		visible, err := detailsFilter(r.Context(), authz, user)(job.Operation, job.Host)
+		if err != nil {
+			sendError(w, "Failed to check permissions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status": "success",
+			"job":    jobDetails(job, visible),
+		})
+	}
+}
+
+// HandleCancel cancels a queued job, or asks the worker running it to stop
+// the command on the agent
+func HandleCancel(queries *serverdb.Queries, authz *config.Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			ID string `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		job, ok := loadJob(w, r, queries, authz, req.ID, config.PermDevicesRead)
+		if !ok {
+			return
+		}
+
+		// Cancelling needs the same permission as submitting
+		user, _ := config.GetUserFromContext(r)
+		perm := operationPermission(job.Operation)
+		allowed, err := authz.UserAllowed(r.Context(), user, perm, job.Host)
+		if err != nil {
+			sendError(w, "Failed to check permissions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			sendError(w, "Permission denied: "+perm, http.StatusForbidden)
+			return
+		}
+
+		status, err := queries.RequestAgentJobCancel(r.Context(), job.ID)
+		if err == sql.ErrNoRows {
+			sendError(w, "Job has already finished", http.StatusConflict)
+			return
+		} else if err != nil {
+			sendError(w, "Failed to cancel job: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("🛑 %s cancelled job %s", user.Username, job.ID)
+		message := "Cancellation requested"
+		if status == statusCancelled {
+			message = "Job cancelled"
+		}
+		sendGetSuccess(w, map[string]interface{}{
+			"status":     "success",
+			"message":    message,
+			"job_status": status,
+		})
+	}
+}
+
+// HandleListOperations lists the operations that can run as jobs
+func HandleListOperations() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		names := make([]string, 0, len(operations))
+		for name := range operations {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		list := make([]map[string]string, 0, len(names))
+		for _, name := range names {
+			list = append(list, map[string]string{
+				"operation":   name,
+				"permission":  operations[name].permission,
+				"description": operations[name].description,
+			})
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":     "success",
+			"operations": list,
+		})
+	}
+}
+
+// loadJob fetches a job the caller holds perm for on its host. It has already
+// answered the request when it returns false.
+func loadJob(w http.ResponseWriter, r *http.Request, queries *serverdb.Queries, authz *config.Authorizer, id, perm string) (serverdb.AgentJob, bool) {
+	jobID, err := uuid.Parse(id)
+	if err != nil {
+		sendError(w, "Invalid job id", http.StatusBadRequest)
+		return serverdb.AgentJob{}, false
+	}
+
+	job, err := queries.GetAgentJob(r.Context(), jobID)
+	if err == sql.ErrNoRows {
+		sendError(w, "Job not found", http.StatusNotFound)
+		return job, false
+	} else if err != nil {
+		sendError(w, "Failed to fetch job: "+err.Error(), http.StatusInternalServerError)
+		return job, false
+	}
+
+	user, _ := config.GetUserFromContext(r)
+	allowed, err := authz.UserAllowed(r.Context(), user, perm, job.Host)
+	if err != nil {
+		sendError(w, "Failed to check permissions: "+err.Error(), http.StatusInternalServerError)
+		return job, false
+	}
+	if !allowed {
+		// Jobs on devices outside the caller's tags are not revealed
+		sendError(w, "Job not found", http.StatusNotFound)
+		return job, false
+	}
+	return job, true
+}
+
+// operationPermission is what submitting or cancelling a job, and seeing its
+// params and output, takes. Jobs of an operation this build no longer knows
+// need every permission.
+func operationPermission(operation string) string {
+	if op, known := operations[operation]; known {
+		return op.permission
+	}
+	return config.PermAll
+}
+
+// detailsFilter reports whether the caller may see the params and output of
+// a job: commands and their output can hold secrets, so reading them takes
+// the permission that running the operation on the host takes.
+func detailsFilter(ctx context.Context, authz *config.Authorizer, user *config.UserInfo) func(operation, host string) (bool, error) {
+	filters := make(map[string]func(host string) bool)
+	return func(operation, host string) (bool, error) {
+		perm := operationPermission(operation)
+		filter, ok := filters[perm]
+		if !ok {
+			var err error
+			if filter, err = authz.HostFilter(ctx, user, perm); err != nil {
+				return false, err
+			}
+			filters[perm] = filter
+		}
+		return filter(host), nil
+	}
+}
+
+// jobDetails describes a job, leaving out its params and output unless
+// visible
+func jobDetails(j serverdb.AgentJob, visible bool) map[string]interface{} {
+	var params, stdout, stderr interface{}
+	if visible {
+		params, stdout, stderr = j.Params, j.Stdout, j.Stderr
+	}
+	return map[string]interface{}{
+		"id":               j.ID,
+		"host":             j.Host,
+		"operation":        j.Operation,
+		"params":           params,
+		"redacted":         !visible,
+		"status":           j.Status,
+		"progress":         j.Progress,
+		"stdout":           stdout,
+		"stderr":           stderr,
+		"exit_code":        nullInt(j.ExitCode),
+		"error":            j.Error,
+		"cancel_requested": j.CancelRequested,
+		"created_by":       j.CreatedBy,
+		"created_at":       j.CreatedAt,
+		"started_at":       nullTime(j.StartedAt),
+		"finished_at":      nullTime(j.FinishedAt),
+		"updated_at":       j.UpdatedAt,
+	}
+}
+
+func nullInt(v sql.NullInt32) interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return v.Int32
+}
+
+func nullTime(v sql.NullTime) interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return v.Time
+}
+
+// Standard response functions
+func sendGetSuccess(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+func sendPostSuccess(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(data)
+}
+
+func sendError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	errorResp := ErrorResponse{
+		Status:  "failed",
+		Message: message,
+	}
+	json.NewEncoder(w).Encode(errorResp)
+}
@@ -0,0 +1,122 @@
+package jobs
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/agentclient"
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+func TestJobDetailsRedaction(t *testing.T) {
+	job := serverdb.AgentJob{
+		Host:      "10.0.0.1",
+		Operation: "command",
+		Params:    json.RawMessage(`{"command":"mysql -psecret"}`),
+		Stdout:    "rows",
+		Stderr:    "warning: password on the command line",
+	}
+
+	full := jobDetails(job, true)
+	if string(full["params"].(json.RawMessage)) != string(job.Params) || full["stdout"] != job.Stdout ||
+		full["stderr"] != job.Stderr || full["redacted"] != false {
+		t.Errorf("visible job lost its details: %v", full)
+	}
+
+	redacted := jobDetails(job, false)
+	for _, field := range []string{"params", "stdout", "stderr"} {
+		if redacted[field] != nil {
+			t.Errorf("redacted job still has %s = %v", field, redacted[field])
+		}
+	}
+	if redacted["redacted"] != true {
+		t.Error("redacted job is not marked as redacted")
+	}
+	if redacted["host"] != job.Host || redacted["operation"] != job.Operation {
+		t.Error("redacted job lost its host or operation")
+	}
+}
+
+func TestOperationPermission(t *testing.T) {
+	tests := []struct {
+		operation string
+		want      string
+	}{
+		{"command", config.PermCmdExec},
+		{"clean", config.PermResourceClean},
+		{"removed-in-this-build", config.PermAll},
+		{"", config.PermAll},
+	}
+
+	for _, tt := range tests {
+		if got := operationPermission(tt.operation); got != tt.want {
+			t.Errorf("operationPermission(%q) = %q, want %q", tt.operation, got, tt.want)
+		}
+	}
+}
+
+func TestPrepareCommand(t *testing.T) {
+	prepare := operations["command"].prepare
+
+	tests := []struct {
+		name    string
+		params  string
+		want    string
+		wantErr bool
+	}{
+		{"trims the command", `{"command":"  uptime  "}`, `{"command":"uptime"}`, false},
+		{"keeps the timeout", `{"command":"sleep 5","timeout_sec":60}`, `{"command":"sleep 5","timeout_sec":60}`, false},
+		{"drops unknown fields", `{"command":"id","user":"root"}`, `{"command":"id"}`, false},
+		{"missing params", ``, "", true},
+		{"empty command", `{"command":"   "}`, "", true},
+		{"negative timeout", `{"command":"id","timeout_sec":-1}`, "", true},
+		{"timeout over the agent limit", `{"command":"id","timeout_sec":86401}`, "", true},
+		{"not an object", `"uptime"`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := prepare(json.RawMessage(tt.params))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("prepare error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("prepare = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandOutcome(t *testing.T) {
+	code := func(c int) *int { return &c }
+
+	tests := []struct {
+		name       string
+		status     agentclient.ExecStatus
+		wantStatus string
+		wantErr    string
+	}{
+		{"success", agentclient.ExecStatus{ExitCode: code(0)}, statusSucceeded, ""},
+		{"non-zero exit", agentclient.ExecStatus{ExitCode: code(2)}, statusFailed, "Command exited with code 2"},
+		{"cancelled", agentclient.ExecStatus{State: agentclient.ExecCancelled, ExitCode: code(137)}, statusCancelled, ""},
+		{"timed out", agentclient.ExecStatus{State: agentclient.ExecTimedOut}, statusFailed, "Command timed out"},
+		{"agent error", agentclient.ExecStatus{Error: "fork failed"}, statusFailed, "fork failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := commandOutcome(tt.status)
+			if got.status != tt.wantStatus || got.err != tt.wantErr {
+				t.Errorf("commandOutcome = %q/%q, want %q/%q", got.status, got.err, tt.wantStatus, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCleanOutput(t *testing.T) {
+	if got := cleanOutput("a\x00b\x00"); got != "ab" || strings.ContainsRune(got, 0) {
+		t.Errorf("cleanOutput = %q, want %q", got, "ab")
+	}
+}
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+)
+
+const maxCommandTimeout = 24 * 60 * 60 // Seconds, the agent's own limit
+
+// operation is a kind of job. prepare validates the params on submit and
+// returns them as they are stored; run carries the job out on a worker.
+type operation struct {
+	permission  string
+	description string
+	prepare     func(params json.RawMessage) (json.RawMessage, error)
+	run         func(ctx context.Context, r *runner) (outcome, error)
+}
+
+type commandParams struct {
+	Command    string `json:"command"`
+	TimeoutSec int    `json:"timeout_sec,omitempty"` // Default one hour
+}
+
+var operations = map[string]operation{
+	"command": {
+		permission:  config.PermCmdExec,
+		description: `Run a shell command on the agent. Params: {"command": "...", "timeout_sec": 3600}`,
+		prepare: func(params json.RawMessage) (json.RawMessage, error) {
+			var p commandParams
+			if err := decodeParams(params, &p); err != nil {
+				return nil, err
+			}
+			p.Command = strings.TrimSpace(p.Command)
+			if p.Command == "" {
+				return nil, errors.New("command is required")
+			}
+			if p.TimeoutSec < 0 || p.TimeoutSec > maxCommandTimeout {
+				return nil, fmt.Errorf("timeout_sec must be between 0 and %d", maxCommandTimeout)
+			}
+			return json.Marshal(p)
+		},
+		run: runCommand,
+	},
+	"clean": {
+		permission:  config.PermResourceClean,
+		description: "Remove temporary files and caches. No params",
+		prepare: func(params json.RawMessage) (json.RawMessage, error) {
+			return json.RawMessage(`{}`), nil
+		},
+		run: runClean,
+	},
+}
+
+func decodeParams(params json.RawMessage, out interface{}) error {
+	if len(params) == 0 {
+		return errors.New("params are required")
+	}
+	if err := json.Unmarshal(params, out); err != nil {
+		return fmt.Errorf("invalid params: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,319 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/agentclient"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+// Job states
+const (
+	statusQueued    = "queued"
+	statusRunning   = "running"
+	statusSucceeded = "succeeded"
+	statusFailed    = "failed"
+	statusCancelled = "cancelled"
+)
+
+const (
+	// A worker renews its lease while it runs a job. If it stops, another
+	// worker takes the job over once the lease runs out.
+	leaseDuration = time.Minute
+
+	pollInterval    = 2 * time.Second
+	maxPollFailures = 30      // Polls in a row the agent may miss before the job fails
+	maxStoredOutput = 1 << 20 // Characters kept per stream, older output is dropped
+	retention       = 30 * 24 * time.Hour
+)
+
+// errLeaseLost means another worker owns the job now
+var errLeaseLost = errors.New("job lease lost")
+
+// Commands report progress by printing a line such as "PROGRESS 40"
+var progressLine = regexp.MustCompile(`(?m)^PROGRESS (\d{1,3})%?\s*$`)
+
+// outcome is how a job ended. stdout and stderr are output not stored yet.
+type outcome struct {
+	status   string
+	exitCode sql.NullInt32
+	err      string
+	stdout   string
+	stderr   string
+}
+
+func failed(message string) outcome {
+	return outcome{status: statusFailed, err: message}
+}
+
+// runner carries one claimed job
+type runner struct {
+	queries  *serverdb.Queries
+	job      serverdb.AgentJob
+	agent    *agentclient.Agent
+	worker   string
+	progress int32
+}
+
+// RunNext claims the oldest waiting job and runs it to the end. It reports
+// false when there was nothing to run.
+func RunNext(ctx context.Context, queries *serverdb.Queries, worker string) (bool, error) {
+	job, err := queries.ClaimAgentJob(ctx, serverdb.ClaimAgentJobParams{
+		Worker:     worker,
+		LeaseUntil: time.Now().Add(leaseDuration),
+	})
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	r := &runner{queries: queries, job: job, worker: worker, progress: job.Progress}
+	if job.StdoutOffset > 0 || job.StderrOffset > 0 {
+		log.Printf("🔁 Resuming job %s (%s on %s)", job.ID, job.Operation, job.Host)
+	} else {
+		log.Printf("⚙️ Running job %s (%s on %s)", job.ID, job.Operation, job.Host)
+	}
+
+	var result outcome
+	op, ok := operations[job.Operation]
+	if !ok {
+		result = failed("Unknown operation: " + job.Operation)
+	} else if r.agent, err = agentclient.Open(ctx, queries, job.Host); err != nil {
+		message, _ := agentclient.Describe(err)
+		result = failed(message)
+	} else if result, err = op.run(ctx, r); err != nil {
+		if err == errLeaseLost {
+			log.Printf("⚠️ Job %s was taken over by another worker", job.ID)
+			return true, nil
+		}
+		return true, err
+	}
+
+	if err := r.finish(ctx, result); err != nil {
+		return true, err
+	}
+	log.Printf("🏁 Job %s %s", job.ID, result.status)
+	return true, nil
+}
+
+// update stores new output and renews the lease. It reports whether a user
+// asked for the job to be cancelled.
+func (r *runner) update(ctx context.Context, stdout, stderr string, stdoutOffset, stderrOffset int64) (bool, error) {
+	cancelRequested, err := r.queries.UpdateAgentJobProgress(ctx, serverdb.UpdateAgentJobProgressParams{
+		Stdout:       cleanOutput(stdout),
+		Stderr:       cleanOutput(stderr),
+		MaxOutput:    maxStoredOutput,
+		StdoutOffset: stdoutOffset,
+		StderrOffset: stderrOffset,
+		Progress:     r.progress,
+		LeaseUntil:   time.Now().Add(leaseDuration),
+		ID:           r.job.ID,
+		Worker:       r.worker,
+	})
+	if err == sql.ErrNoRows {
+		return false, errLeaseLost
+	}
+	return cancelRequested, err
+}
+
+func (r *runner) finish(ctx context.Context, result outcome) error {
+	if result.status == statusSucceeded {
+		r.progress = 100
+	}
+	_, err := r.queries.FinishAgentJob(ctx, serverdb.FinishAgentJobParams{
+		Status:    result.status,
+		Stdout:    cleanOutput(result.stdout),
+		Stderr:    cleanOutput(result.stderr),
+		MaxOutput: maxStoredOutput,
+		ExitCode:  result.exitCode,
+		Error:     result.err,
+		Progress:  r.progress,
+		ID:        r.job.ID,
+		Worker:    r.worker,
+	})
+	return err
+}
+
+// runCommand starts the command on the agent under the job's ID and polls it
+// until it ends. A worker that takes the job over picks up the same command
+// and reads on from the stored offsets.
+func runCommand(ctx context.Context, r *runner) (outcome, error) {
+	var p commandParams
+	if err := json.Unmarshal(r.job.Params, &p); err != nil {
+		return failed("Invalid params: " + err.Error()), nil
+	}
+	execID := r.job.ID.String()
+	stdoutOffset, stderrOffset := r.job.StdoutOffset, r.job.StderrOffset
+
+	cancelSent := false
+	if r.job.CancelRequested {
+		// Cancelled while no worker was watching: stop it without starting it again
+		_, err := r.agent.CancelExec(ctx, execID)
+		if isUnknownExec(err) {
+			return outcome{status: statusCancelled}, nil
+		}
+		cancelSent = err == nil
+	} else if _, err := r.agent.StartExec(ctx, agentclient.ExecStart{ID: execID, Command: p.Command, TimeoutSec: p.TimeoutSec}); err != nil {
+		message, _ := agentclient.Describe(err)
+		return failed("Failed to start command: " + message), nil
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		finished := false
+		status, err := r.agent.ExecStatus(ctx, execID, stdoutOffset, stderrOffset)
+		switch {
+		case isUnknownExec(err):
+			return failed("The agent no longer has this command, it may have restarted"), nil
+		case err != nil:
+			failures++
+			if failures >= maxPollFailures {
+				message, _ := agentclient.Describe(err)
+				return failed("Lost contact with the agent: " + message), nil
+			}
+		default:
+			failures = 0
+			stdoutOffset, stderrOffset = status.StdoutOffset, status.StderrOffset
+			if status.StdoutDropped > 0 || status.StderrDropped > 0 {
+				status.Stderr = fmt.Sprintf("[%d bytes of output lost]\n", status.StdoutDropped+status.StderrDropped) + status.Stderr
+			}
+			if matches := progressLine.FindAllStringSubmatch(status.Stdout, -1); len(matches) > 0 {
+				if n, _ := strconv.Atoi(matches[len(matches)-1][1]); n <= 100 {
+					r.progress = int32(n)
+				}
+			}
+
+			if status.State != agentclient.ExecRunning {
+				if status.Stdout == "" && status.Stderr == "" {
+					return commandOutcome(status), nil
+				}
+				finished = true
+			}
+		}
+
+		cancelRequested, err := r.update(ctx, status.Stdout, status.Stderr, stdoutOffset, stderrOffset)
+		if err != nil {
+			return outcome{}, err
+		}
+		if cancelRequested && !cancelSent {
+			if _, err := r.agent.CancelExec(ctx, execID); err == nil {
+				cancelSent = true
+			}
+		}
+
+		// Read the rest of a finished command's output right away
+		if finished {
+			continue
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return outcome{}, ctx.Err()
+		}
+	}
+}
+
+func commandOutcome(status agentclient.ExecStatus) outcome {
+	result := outcome{status: statusSucceeded}
+	if status.ExitCode != nil {
+		result.exitCode = sql.NullInt32{Int32: int32(*status.ExitCode), Valid: true}
+	}
+
+	switch {
+	case status.State == agentclient.ExecCancelled:
+		result.status = statusCancelled
+	case status.State == agentclient.ExecTimedOut:
+		result.status, result.err = statusFailed, "Command timed out"
+	case status.Error != "":
+		result.status, result.err = statusFailed, status.Error
+	case result.exitCode.Int32 != 0:
+		result.status, result.err = statusFailed, fmt.Sprintf("Command exited with code %d", result.exitCode.Int32)
+	}
+	return result
+}
+
+// isUnknownExec reports whether the agent does not know the command
+func isUnknownExec(err error) bool {
+	var agentErr *agentclient.AgentError
+	return errors.As(err, &agentErr) && agentErr.StatusCode == http.StatusNotFound
+}
+
+// runClean runs a cleanup. The agent cannot stop one half way, so cancelling
+// only stops waiting for it.
+func runClean(ctx context.Context, r *runner) (outcome, error) {
+	if r.job.CancelRequested {
+		return outcome{status: statusCancelled}, nil
+	}
+
+	cleanCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type cleanResult struct {
+		resp agentclient.OptimizeResult
+		err  error
+	}
+	done := make(chan cleanResult, 1)
+	go func() {
+		resp, err := r.agent.Optimize(cleanCtx)
+		done <- cleanResult{resp, err}
+	}()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case res := <-done:
+			if res.err != nil {
+				message, _ := agentclient.Describe(res.err)
+				return failed(message), nil
+			}
+			result := outcome{status: statusSucceeded, stdout: res.resp.Message}
+			if res.resp.Status == "failed" {
+				result.status, result.err = statusFailed, res.resp.Message
+			}
+			return result, nil
+		case <-ticker.C:
+			cancelRequested, err := r.update(ctx, "", "", 0, 0)
+			if err != nil {
+				return outcome{}, err
+			}
+			if cancelRequested {
+				return outcome{status: statusCancelled, err: "Cancelled, the agent may still finish the cleanup"}, nil
+			}
+		case <-ctx.Done():
+			return outcome{}, ctx.Err()
+		}
+	}
+}
+
+// cleanOutput drops NUL bytes, which Postgres text cannot hold
+func cleanOutput(s string) string {
+	return strings.ReplaceAll(s, "\x00", "")
+}
+
+// DeleteExpired removes jobs that finished more than 30 days ago
+func DeleteExpired(ctx context.Context, queries *serverdb.Queries) {
+	deleted, err := queries.DeleteOldAgentJobs(ctx, sql.NullTime{Time: time.Now().Add(-retention), Valid: true})
+	if err != nil {
+		log.Printf("❌ Failed to delete old jobs: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("🧹 Deleted %d finished jobs", deleted)
+	}
+}
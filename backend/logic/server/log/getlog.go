@@ -1,19 +1,12 @@
 package log
 
 import (
-	"bytes"
-	"context"
-	"database/sql"
 	"encoding/json"
-	"io"
 	"log"
 	"net/http"
-	"net/url"
-	"strconv"
 	"strings"
-	"time"
 
-	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	"github.com/kishore-001/ServerManagementSuite/backend/agentclient"
 	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
 )
 
@@ -25,12 +18,6 @@ type LogFilterRequest struct {
 	Lines int    `json:"lines,omitempty"` // Number of lines to fetch
 }
 
-// Client filter structure (without host)
-type ClientLogFilter struct {
-	Date string `json:"date,omitempty"`
-	Time string `json:"time,omitempty"`
-}
-
 // Standard response structures
 type ErrorResponse struct {
 	Status  string `json:"status"`
@@ -54,89 +41,25 @@ func GetLog(queries *serverdb.Queries) http.HandlerFunc {
 
 		log.Printf("🔍 [LOG] Received request: %+v", req)
 
-		device, err := queries.GetServerDeviceByIP(context.Background(), req.Host)
-		if err == sql.ErrNoRows {
-			sendError(w, "Device not registered", http.StatusNotFound)
-			return
-		} else if err != nil {
-			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		// Build client URL with query parameters
-		clientURL := config.GetClientURL(req.Host, "/client/log")
-		parsedURL, err := url.Parse(clientURL)
+		agent, err := agentclient.Open(r.Context(), queries, req.Host)
 		if err != nil {
-			sendError(w, "Failed to parse client URL: "+err.Error(), http.StatusInternalServerError)
+			sendAgentError(w, err)
 			return
 		}
 
-		// Add lines parameter to query string
-		query := parsedURL.Query()
-		if req.Lines > 0 {
-			query.Set("lines", strconv.Itoa(req.Lines))
-		} else {
-			query.Set("lines", "100") // Default
-		}
-		parsedURL.RawQuery = query.Encode()
-
-		log.Printf("🔍 [LOG] Client URL with query: %s", parsedURL.String())
-
 		// Process log request based on OS
-		requestBody, method := processLogRequest(req, device.Os)
-
-		log.Printf("🔍 [LOG] Using method: %s", method)
-
-		// Create request to client
-		clientReq, err := http.NewRequest(method, parsedURL.String(), requestBody)
-		if err != nil {
-			log.Printf("❌ [LOG] Failed to create client request: %v", err)
-			sendError(w, "Failed to create request to client: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		clientReq.Header.Set("Authorization", "Bearer "+device.AccessToken)
-		clientReq.Header.Set("Content-Type", "application/json")
-
-		// Add timeout to prevent hanging
-		httpClient := &http.Client{
-			Timeout: 30 * time.Second,
-		}
-
-		log.Printf("🔍 [LOG] Sending %s request to client: %s", method, parsedURL.String())
+		query := processLogRequest(req, agent.OS)
+		log.Printf("🔍 [LOG] Fetching logs from %s: %+v", req.Host, query)
 
-		resp, err := httpClient.Do(clientReq)
+		clientResp, err := agent.Logs(r.Context(), query)
 		if err != nil {
-			log.Printf("❌ [LOG] Failed to reach client: %v", err)
-			sendError(w, "Failed to reach client: "+err.Error(), http.StatusBadGateway)
-			return
-		}
-		defer resp.Body.Close()
-
-		log.Printf("✅ [LOG] Client response status: %d", resp.StatusCode)
-
-		// Handle client error responses
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-
-			var clientError ErrorResponse
-			if json.Unmarshal(body, &clientError) == nil && clientError.Status == "failed" {
-				sendError(w, "Client error: "+clientError.Message, http.StatusBadGateway)
-			} else {
-				sendError(w, "Client error: "+string(body), http.StatusBadGateway)
-			}
-			return
-		}
-
-		// Parse client response
-		var clientResp interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&clientResp); err != nil {
-			sendError(w, "Invalid client response: "+err.Error(), http.StatusBadGateway)
+			log.Printf("❌ [LOG] Client request failed: %v", err)
+			sendAgentError(w, err)
 			return
 		}
 
 		// Process response based on OS
-		processedResp := processLogResponse(clientResp, device.Os)
+		processedResp := processLogResponse(clientResp, agent.OS)
 
 		// Send successful response
 		sendGetSuccess(w, processedResp)
@@ -160,8 +83,14 @@ func sendError(w http.ResponseWriter, message string, statusCode int) {
 	json.NewEncoder(w).Encode(errorResp)
 }
 
+// sendAgentError answers with what went wrong calling the agent
+func sendAgentError(w http.ResponseWriter, err error) {
+	message, statusCode := agentclient.Describe(err)
+	sendError(w, message, statusCode)
+}
+
 // Process log request based on OS
-func processLogRequest(req LogFilterRequest, osType string) (io.Reader, string) {
+func processLogRequest(req LogFilterRequest, osType string) agentclient.LogQuery {
 	if strings.ToLower(osType) == "windows" {
 		return processWindowsLogRequest(req)
 	}
@@ -171,28 +100,17 @@ func processLogRequest(req LogFilterRequest, osType string) (io.Reader, string)
 }
 
 // Process Linux log request
-func processLinuxLogRequest(req LogFilterRequest) (io.Reader, string) {
-	// Prepare request body for date/time filters (excluding host and lines)
-	method := "GET"
-
-	if req.Date != "" || req.Time != "" {
-		clientFilter := ClientLogFilter{
-			Date: req.Date,
-			Time: req.Time,
-		}
-
-		bodyBytes, _ := json.Marshal(clientFilter)
-		method = "POST" // Use POST when sending date/time filters
-
-		log.Printf("🔍 [LOG] Sending Linux filter body: %s", string(bodyBytes))
-		return bytes.NewReader(bodyBytes), method
+func processLinuxLogRequest(req LogFilterRequest) agentclient.LogQuery {
+	// Date and time filters are sent in a POST body, lines in the query string
+	return agentclient.LogQuery{
+		Lines: req.Lines,
+		Date:  req.Date,
+		Time:  req.Time,
 	}
-
-	return nil, method
 }
 
 // Process Windows log request (placeholder for future differences)
-func processWindowsLogRequest(req LogFilterRequest) (io.Reader, string) {
+func processWindowsLogRequest(req LogFilterRequest) agentclient.LogQuery {
 	// For now, return same format as Linux
 	// Future: Windows might use different log filtering (Event Viewer vs journalctl)
 	return processLinuxLogRequest(req)
@@ -0,0 +1,75 @@
+package mac
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+)
+
+// mac_access_status.status values
+const (
+	StatusBlacklisted = "BLACKLISTED"
+	StatusWhitelisted = "WHITELISTED"
+)
+
+// SettingMonitoredSegments is the app_settings key holding the CIDRs (JSON
+// array) on which every MAC must be whitelisted
+const SettingMonitoredSegments = "mac_monitored_segments"
+
+// NormalizeMAC returns a 48-bit MAC in lower-case colon form, accepting the
+// colon, dash and dot notations agents report
+func NormalizeMAC(value string) (string, error) {
+	hw, err := net.ParseMAC(strings.TrimSpace(value))
+	if err != nil || len(hw) != 6 {
+		return "", fmt.Errorf("invalid MAC address %q", value)
+	}
+	return hw.String(), nil
+}
+
+// LoadMonitoredSegments reads the monitored segments; none are set by default
+func LoadMonitoredSegments(ctx context.Context, queries *generaldb.Queries) ([]*net.IPNet, error) {
+	value, err := queries.GetAppSetting(ctx, SettingMonitoredSegments)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var cidrs []string
+	if err := json.Unmarshal([]byte(value), &cidrs); err != nil {
+		return nil, fmt.Errorf("invalid stored segments: %v", err)
+	}
+	return ParseSegments(cidrs)
+}
+
+// ParseSegments parses a list of CIDRs
+func ParseSegments(cidrs []string) ([]*net.IPNet, error) {
+	segments := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, segment, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid segment %q", cidr)
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+// InSegments reports whether ip falls inside one of the segments
+func InSegments(ip string, segments []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, segment := range segments {
+		if segment.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
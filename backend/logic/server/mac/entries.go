@@ -0,0 +1,265 @@
+package mac
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+)
+
+// Standard response structures
+type ErrorResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// entryRequest is the body of create and update calls
+type entryRequest struct {
+	ID        string     `json:"id"` // Update only
+	MAC       string     `json:"mac"`
+	Status    string     `json:"status"` // BLACKLISTED or WHITELISTED
+	Comment   string     `json:"comment"`
+	Block     bool       `json:"block"`      // Drop the MAC's traffic on agents (blacklist only)
+	ExpiresAt *time.Time `json:"expires_at"` // Optional, RFC 3339
+}
+
+// validate normalizes the request and returns a user-facing error
+func (req *entryRequest) validate() string {
+	req.Status = strings.ToUpper(strings.TrimSpace(req.Status))
+	if req.Status != StatusBlacklisted && req.Status != StatusWhitelisted {
+		return "Status must be BLACKLISTED or WHITELISTED"
+	}
+	if req.Block && req.Status != StatusBlacklisted {
+		return "Only blacklisted MACs can be blocked"
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return "expires_at must be in the future"
+	}
+	req.Comment = strings.TrimSpace(req.Comment)
+	if len(req.Comment) > 500 {
+		return "Comment must be at most 500 characters"
+	}
+	return ""
+}
+
+func (req *entryRequest) expiresAt() sql.NullTime {
+	if req.ExpiresAt == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+}
+
+func entryMap(e generaldb.MacAccessStatus) map[string]interface{} {
+	entry := map[string]interface{}{
+		"id":         e.ID,
+		"mac":        e.Mac,
+		"status":     e.Status,
+		"comment":    e.Comment,
+		"block":      e.Block,
+		"expires_at": nil,
+		"expired":    e.ExpiresAt.Valid && e.ExpiresAt.Time.Before(time.Now()),
+		"created_by": e.CreatedBy,
+		"created_at": e.CreatedAt,
+		"updated_at": e.UpdatedAt,
+	}
+	if e.ExpiresAt.Valid {
+		entry["expires_at"] = e.ExpiresAt.Time
+	}
+	return entry
+}
+
+// HandleListEntries lists the whitelist and blacklist, including expired entries
+func HandleListEntries(queries *generaldb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		entries, err := queries.ListMACEntries(r.Context())
+		if err != nil {
+			sendError(w, "Failed to fetch MAC entries: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		entryList := make([]map[string]interface{}, 0, len(entries))
+		for _, e := range entries {
+			entryList = append(entryList, entryMap(e))
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":  "success",
+			"entries": entryList,
+			"count":   len(entryList),
+		})
+	}
+}
+
+// HandleCreateEntry whitelists or blacklists a MAC
+func HandleCreateEntry(queries *generaldb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req entryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mac, err := NormalizeMAC(req.MAC)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if msg := req.validate(); msg != "" {
+			sendError(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		user, _ := config.GetUserFromContext(r)
+		entry, err := queries.CreateMACEntry(r.Context(), generaldb.CreateMACEntryParams{
+			Mac:       mac,
+			Status:    req.Status,
+			Comment:   req.Comment,
+			Block:     req.Block,
+			ExpiresAt: req.expiresAt(),
+			CreatedBy: user.Username,
+		})
+		if err != nil {
+			if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+				sendError(w, "MAC "+mac+" already has an entry, update it instead", http.StatusConflict)
+				return
+			}
+			sendError(w, "Failed to save MAC entry: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		sendPostSuccess(w, map[string]interface{}{
+			"status":  "success",
+			"message": "MAC entry created",
+			"entry":   entryMap(entry),
+		})
+	}
+}
+
+// HandleUpdateEntry changes the status, comment, block flag or expiry of an entry
+func HandleUpdateEntry(queries *generaldb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req entryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		id, err := uuid.Parse(req.ID)
+		if err != nil {
+			sendError(w, "A valid entry id is required", http.StatusBadRequest)
+			return
+		}
+		if msg := req.validate(); msg != "" {
+			sendError(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		updated, err := queries.UpdateMACEntry(r.Context(), generaldb.UpdateMACEntryParams{
+			ID:        id,
+			Status:    req.Status,
+			Comment:   req.Comment,
+			Block:     req.Block,
+			ExpiresAt: req.expiresAt(),
+		})
+		if err != nil {
+			sendError(w, "Failed to update MAC entry: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if updated == 0 {
+			sendError(w, "MAC entry not found", http.StatusNotFound)
+			return
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":  "success",
+			"message": "MAC entry updated",
+			"id":      id,
+		})
+	}
+}
+
+// HandleDeleteEntry removes an entry; blocked MACs are released on the next sync
+func HandleDeleteEntry(queries *generaldb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST/DELETE method
+		if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			sendError(w, "Only POST or DELETE method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			ID string `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		id, err := uuid.Parse(req.ID)
+		if err != nil {
+			sendError(w, "A valid entry id is required", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := queries.DeleteMACEntry(r.Context(), id)
+		if err != nil {
+			sendError(w, "Failed to delete MAC entry: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if deleted == 0 {
+			sendError(w, "MAC entry not found", http.StatusNotFound)
+			return
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":  "success",
+			"message": "MAC entry deleted",
+			"id":      id,
+		})
+	}
+}
+
+// Standard response functions
+func sendGetSuccess(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+func sendPostSuccess(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(data)
+}
+
+func sendError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	errorResp := ErrorResponse{
+		Status:  "failed",
+		Message: message,
+	}
+	json.NewEncoder(w).Encode(errorResp)
+}
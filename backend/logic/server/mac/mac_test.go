@@ -0,0 +1,133 @@
+package mac
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeMAC(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"AA:BB:CC:DD:EE:FF", "aa:bb:cc:dd:ee:ff", false},
+		{" aa-bb-cc-dd-ee-ff ", "aa:bb:cc:dd:ee:ff", false},
+		{"aabb.ccdd.eeff", "aa:bb:cc:dd:ee:ff", false},
+		{"aa:bb:cc:dd:ee", "", true},
+		{"00:00:00:00:fe:80:00:00:00:00:00:00:02:00:5e:10:00:00:00:01", "", true}, // InfiniBand
+		{"not-a-mac", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeMAC(tt.value)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("NormalizeMAC(%q) = %q, %v, want %q (error %v)", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSegments(t *testing.T) {
+	segments, err := ParseSegments([]string{"10.0.0.0/24", " 192.168.1.7/16 ", "fd00::/64"})
+	if err != nil {
+		t.Fatalf("ParseSegments: %v", err)
+	}
+	if segments[1].String() != "192.168.0.0/16" {
+		t.Errorf("segment = %s, want it masked", segments[1])
+	}
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"10.0.0.42", true},
+		{"10.0.1.1", false},
+		{"192.168.200.1", true},
+		{"fd00::1", true},
+		{"fd01::1", false},
+		{"", false},
+		{"host.local", false},
+	}
+	for _, tt := range tests {
+		if got := InSegments(tt.ip, segments); got != tt.want {
+			t.Errorf("InSegments(%q) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+
+	if _, err := ParseSegments([]string{"10.0.0.0/24", "10.0.0.1"}); err == nil {
+		t.Error("ParseSegments accepted an address without a prefix length")
+	}
+	if InSegments("10.0.0.1", nil) {
+		t.Error("an address is inside no segments")
+	}
+}
+
+func TestValidateEntry(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name string
+		req  entryRequest
+		want string
+	}{
+		{"whitelist", entryRequest{Status: " whitelisted "}, ""},
+		{"blocked blacklist", entryRequest{Status: "BLACKLISTED", Block: true, ExpiresAt: &future}, ""},
+		{"unknown status", entryRequest{Status: "GREYLISTED"}, "Status must be BLACKLISTED or WHITELISTED"},
+		{"blocked whitelist", entryRequest{Status: "WHITELISTED", Block: true}, "Only blacklisted MACs can be blocked"},
+		{"expired", entryRequest{Status: "BLACKLISTED", ExpiresAt: &past}, "expires_at must be in the future"},
+		{"long comment", entryRequest{Status: "BLACKLISTED", Comment: strings.Repeat("c", 501)}, "Comment must be at most 500 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.req.validate(); got != tt.want {
+				t.Errorf("validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	req := entryRequest{Status: "whitelisted", Comment: "  printer  "}
+	req.validate()
+	if req.Status != StatusWhitelisted || req.Comment != "printer" {
+		t.Errorf("validate left status %q and comment %q", req.Status, req.Comment)
+	}
+	if req.expiresAt().Valid {
+		t.Error("an entry without expires_at expires")
+	}
+}
+
+func TestRequestsRejectedBeforeTheDatabase(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		request *http.Request
+		want    int
+	}{
+		{"create needs a valid MAC", HandleCreateEntry(nil),
+			httptest.NewRequest(http.MethodPost, "/x", strings.NewReader(`{"mac":"aa:bb","status":"BLACKLISTED"}`)), http.StatusBadRequest},
+		{"create needs a status", HandleCreateEntry(nil),
+			httptest.NewRequest(http.MethodPost, "/x", strings.NewReader(`{"mac":"aa:bb:cc:dd:ee:ff"}`)), http.StatusBadRequest},
+		{"update needs an id", HandleUpdateEntry(nil),
+			httptest.NewRequest(http.MethodPost, "/x", strings.NewReader(`{"id":"7","status":"BLACKLISTED"}`)), http.StatusBadRequest},
+		{"delete needs an id", HandleDeleteEntry(nil),
+			httptest.NewRequest(http.MethodDelete, "/x", strings.NewReader(`{}`)), http.StatusBadRequest},
+		{"segments must be CIDRs", HandleSegments(nil),
+			httptest.NewRequest(http.MethodPost, "/x", strings.NewReader(`{"segments":["10.0.0.0/33"]}`)), http.StatusBadRequest},
+		{"segments need GET or POST", HandleSegments(nil), httptest.NewRequest(http.MethodPut, "/x", nil), http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handler(w, tt.request)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
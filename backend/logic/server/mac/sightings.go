@@ -0,0 +1,136 @@
+package mac
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+)
+
+// HandleListSightings lists MACs reported by agents, filtered by ?host= or ?mac=
+func HandleListSightings(queries *generaldb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var params generaldb.ListMACSightingsParams
+		if host := strings.TrimSpace(r.URL.Query().Get("host")); host != "" {
+			params.Host = sql.NullString{String: host, Valid: true}
+		}
+		if value := r.URL.Query().Get("mac"); value != "" {
+			mac, err := NormalizeMAC(value)
+			if err != nil {
+				sendError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			params.Mac = sql.NullString{String: mac, Valid: true}
+		}
+
+		sightings, err := queries.ListMACSightings(r.Context(), params)
+		if err != nil {
+			sendError(w, "Failed to fetch sightings: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Tag each sighting with its current list status
+		entries, err := queries.ListActiveMACEntries(r.Context())
+		if err != nil {
+			sendError(w, "Failed to fetch MAC entries: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		statusByMAC := make(map[string]string, len(entries))
+		for _, e := range entries {
+			statusByMAC[e.Mac] = e.Status
+		}
+
+		sightingList := make([]map[string]interface{}, 0, len(sightings))
+		for _, s := range sightings {
+			sighting := map[string]interface{}{
+				"mac":         s.Mac,
+				"host":        s.Host,
+				"ip":          s.Ip,
+				"interface":   s.Interface,
+				"first_seen":  s.FirstSeen,
+				"last_seen":   s.LastSeen,
+				"list_status": statusByMAC[s.Mac],
+				"alerted_at":  nil,
+			}
+			if s.AlertedAt.Valid {
+				sighting["alerted_at"] = s.AlertedAt.Time
+			}
+			sightingList = append(sightingList, sighting)
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":    "success",
+			"sightings": sightingList,
+			"count":     len(sightingList),
+		})
+	}
+}
+
+// HandleSegments reads (GET) or replaces (POST) the monitored segments. On
+// these CIDRs every MAC that is not whitelisted raises an alert.
+func HandleSegments(queries *generaldb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			segments, err := LoadMonitoredSegments(r.Context(), queries)
+			if err != nil {
+				sendError(w, "Failed to read segments: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			cidrs := make([]string, 0, len(segments))
+			for _, s := range segments {
+				cidrs = append(cidrs, s.String())
+			}
+			sendGetSuccess(w, map[string]interface{}{
+				"status":   "success",
+				"segments": cidrs,
+			})
+
+		case http.MethodPost:
+			var req struct {
+				Segments []string `json:"segments"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			segments, err := ParseSegments(req.Segments)
+			if err != nil {
+				sendError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			cidrs := make([]string, 0, len(segments))
+			for _, s := range segments {
+				cidrs = append(cidrs, s.String())
+			}
+
+			value, _ := json.Marshal(cidrs)
+			err = queries.UpsertAppSetting(r.Context(), generaldb.UpsertAppSettingParams{
+				Key:   SettingMonitoredSegments,
+				Value: string(value),
+			})
+			if err != nil {
+				sendError(w, "Failed to save segments: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			sendGetSuccess(w, map[string]interface{}{
+				"status":   "success",
+				"message":  "Monitored segments updated",
+				"segments": cidrs,
+			})
+
+		default:
+			sendError(w, "Only GET or POST method allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -1,17 +1,12 @@
 package optimisation
 
 import (
-	"bytes"
-	"context"
-	"database/sql"
 	"encoding/json"
-	"io"
 	"log"
 	"net/http"
 	"strings"
-	"time"
 
-	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	"github.com/kishore-001/ServerManagementSuite/backend/agentclient"
 	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
 )
 
@@ -19,12 +14,6 @@ type cleanRequest struct {
 	Host string `json:"host"`
 }
 
-// Enhanced response structure to handle all client response types
-type ClientOptimizeResponse struct {
-	Status  string `json:"status"`  // "success", "partial", "failed"
-	Message string `json:"message"` // Detailed message from client
-}
-
 func PostClean(queries *serverdb.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Only allow POST
@@ -45,92 +34,23 @@ func PostClean(queries *serverdb.Queries) http.HandlerFunc {
 		log.Printf("🔍 [OPTIMIZE] Processing optimization request for host: %s", req.Host)
 
 		// Lookup device and get access token
-		device, err := queries.GetServerDeviceByIP(context.Background(), req.Host)
-		if err == sql.ErrNoRows {
-			log.Printf("❌ [OPTIMIZE] Device not found: %s", req.Host)
-			sendError(w, "Device not registered in system", http.StatusNotFound)
-			return
-		} else if err != nil {
-			log.Printf("❌ [OPTIMIZE] Database error: %v", err)
-			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		// Process clean request based on OS
-		clientPayload := processCleanRequest(req, device.Os)
-
-		jsonPayload, err := json.Marshal(clientPayload)
-		if err != nil {
-			log.Printf("❌ [OPTIMIZE] Failed to marshal client payload: %v", err)
-			sendError(w, "Failed to prepare client request: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		// Build client URL for optimization endpoint
-		clientURL := config.GetClientURL(req.Host, "/client/resource/optimize")
-		log.Printf("🔍 [OPTIMIZE] Sending request to client: %s", clientURL)
-
-		// Create request to client
-		clientReq, err := http.NewRequest("POST", clientURL, bytes.NewBuffer(jsonPayload))
-		if err != nil {
-			log.Printf("❌ [OPTIMIZE] Failed to create client request: %v", err)
-			sendError(w, "Failed to create request to client: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		clientReq.Header.Set("Authorization", "Bearer "+device.AccessToken)
-		clientReq.Header.Set("Content-Type", "application/json")
-
-		// Add timeout for cleanup operations
-		httpClient := &http.Client{
-			Timeout: 120 * time.Second, // 2 minutes for cleanup operations
-		}
-
-		// Send request to client
-		resp, err := httpClient.Do(clientReq)
+		agent, err := agentclient.Open(r.Context(), queries, req.Host)
 		if err != nil {
-			log.Printf("❌ [OPTIMIZE] Failed to reach client: %v", err)
-			sendError(w, "Failed to connect to client device: "+err.Error(), http.StatusBadGateway)
+			log.Printf("❌ [OPTIMIZE] Device lookup failed for %s: %v", req.Host, err)
+			sendAgentError(w, err)
 			return
 		}
-		defer resp.Body.Close()
-
-		log.Printf("🔍 [OPTIMIZE] Client response status: %d", resp.StatusCode)
-
-		// Handle client error responses
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			log.Printf("❌ [OPTIMIZE] Client returned error status: %d", resp.StatusCode)
 
-			var clientError ErrorResponse
-			if json.Unmarshal(body, &clientError) == nil && clientError.Status == "failed" {
-				sendError(w, "Client error: "+clientError.Message, http.StatusBadGateway)
-			} else {
-				sendError(w, "Client device returned an error: "+string(body), http.StatusBadGateway)
-			}
-			return
-		}
-
-		// Read client response
-		responseBody, err := io.ReadAll(resp.Body)
+		log.Printf("🔍 [OPTIMIZE] Sending optimization request to %s", req.Host)
+		clientResp, err := agent.Optimize(r.Context())
 		if err != nil {
-			log.Printf("❌ [OPTIMIZE] Failed to read client response: %v", err)
-			sendError(w, "Failed to read response from client: "+err.Error(), http.StatusBadGateway)
-			return
-		}
-
-		log.Printf("🔍 [OPTIMIZE] Client response body: %s", string(responseBody))
-
-		// Parse client response to handle different statuses
-		var clientResp ClientOptimizeResponse
-		if err := json.Unmarshal(responseBody, &clientResp); err != nil {
-			log.Printf("❌ [OPTIMIZE] Failed to parse client response: %v", err)
-			sendError(w, "Invalid client response: "+err.Error(), http.StatusBadGateway)
+			log.Printf("❌ [OPTIMIZE] Client request failed: %v", err)
+			sendAgentError(w, err)
 			return
 		}
 
 		// Process response based on OS
-		processedResp := processCleanResponse(clientResp, device.Os)
+		processedResp := processCleanResponse(clientResp, agent.OS)
 
 		// Handle different client response statuses (SPECIAL CASE: supports "partial")
 		switch processedResp.Status {
@@ -158,18 +78,8 @@ func PostClean(queries *serverdb.Queries) http.HandlerFunc {
 	}
 }
 
-// Process clean request based on OS
-func processCleanRequest(req cleanRequest, osType string) interface{} {
-	if strings.ToLower(osType) == "windows" {
-		return processWindowsCleanRequest(req)
-	}
-
-	// Default Linux behavior - no host field needed for client
-	return map[string]interface{}{}
-}
-
 // Process clean response based on OS
-func processCleanResponse(resp ClientOptimizeResponse, osType string) ClientOptimizeResponse {
+func processCleanResponse(resp agentclient.OptimizeResult, osType string) agentclient.OptimizeResult {
 	if strings.ToLower(osType) == "windows" {
 		return processWindowsCleanResponse(resp)
 	}
@@ -178,15 +88,8 @@ func processCleanResponse(resp ClientOptimizeResponse, osType string) ClientOpti
 	return resp
 }
 
-// Windows-specific clean request processing (placeholder for future differences)
-func processWindowsCleanRequest(req cleanRequest) interface{} {
-	// For now, return same format as Linux
-	// Future: Windows might need different cleanup parameters
-	return map[string]interface{}{}
-}
-
 // Windows-specific clean response processing (placeholder for future differences)
-func processWindowsCleanResponse(resp ClientOptimizeResponse) ClientOptimizeResponse {
+func processWindowsCleanResponse(resp agentclient.OptimizeResult) agentclient.OptimizeResult {
 	// For now, return same format as Linux
 	// Future: Windows might have different cleanup result structure
 	return resp
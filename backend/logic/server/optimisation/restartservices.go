@@ -1,16 +1,11 @@
 package optimisation
 
 import (
-	"bytes"
-	"context"
-	"database/sql"
 	"encoding/json"
-	"io"
 	"net/http"
 	"strings"
-	"time"
 
-	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	"github.com/kishore-001/ServerManagementSuite/backend/agentclient"
 	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
 )
 
@@ -40,77 +35,23 @@ func PostRestartService(queries *serverdb.Queries) http.HandlerFunc {
 			return
 		}
 
-		// Lookup device and get access token
-		device, err := queries.GetServerDeviceByIP(context.Background(), req.Host)
-		if err == sql.ErrNoRows {
-			sendError(w, "Device not registered", http.StatusNotFound)
-			return
-		} else if err != nil {
-			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		// Process request based on OS
-		clientPayload := processRestartServiceRequest(req, device.Os)
-
-		jsonPayload, err := json.Marshal(clientPayload)
+		agent, err := agentclient.Open(r.Context(), queries, req.Host)
 		if err != nil {
-			sendError(w, "Failed to prepare request: "+err.Error(), http.StatusInternalServerError)
+			sendAgentError(w, err)
 			return
 		}
 
-		// Use config for client URL (reads from .env file)
-		clientURL := config.GetClientURL(req.Host, "/client/resource/restartservice")
-
-		clientReq, err := http.NewRequest("POST", clientURL, bytes.NewBuffer(jsonPayload))
-		if err != nil {
-			sendError(w, "Failed to create request: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		clientReq.Header.Set("Authorization", "Bearer "+device.AccessToken)
-		clientReq.Header.Set("Content-Type", "application/json")
-
-		httpClient := &http.Client{
-			Timeout: 30 * time.Second,
-		}
-
-		resp, err := httpClient.Do(clientReq)
-		if err != nil {
-			sendError(w, "Failed to reach client: "+err.Error(), http.StatusBadGateway)
-			return
-		}
-		defer resp.Body.Close()
-
-		// Handle client error responses
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-
-			var clientError ErrorResponse
-			if json.Unmarshal(body, &clientError) == nil && clientError.Status == "failed" {
-				sendError(w, "Client error: "+clientError.Message, http.StatusBadGateway)
-			} else {
-				sendError(w, "Client error: "+string(body), http.StatusBadGateway)
-			}
-			return
-		}
+		// Process request based on OS
+		service := processRestartServiceRequest(req, agent.OS)
 
-		// Read client response
-		body, err := io.ReadAll(resp.Body)
+		clientResp, err := agent.RestartService(r.Context(), service)
 		if err != nil {
-			sendError(w, "Failed to read client response: "+err.Error(), http.StatusBadGateway)
-			return
-		}
-
-		// Parse client response
-		var clientResp interface{}
-		if err := json.Unmarshal(body, &clientResp); err != nil {
-			sendError(w, "Invalid client response: "+err.Error(), http.StatusBadGateway)
+			sendAgentError(w, err)
 			return
 		}
 
 		// Process response based on OS
-		processedResp := processRestartServiceResponse(clientResp, device.Os)
+		processedResp := processRestartServiceResponse(clientResp, agent.OS)
 
 		// Send successful response
 		sendGetSuccess(w, processedResp)
@@ -118,15 +59,13 @@ func PostRestartService(queries *serverdb.Queries) http.HandlerFunc {
 }
 
 // Process restart service request based on OS
-func processRestartServiceRequest(req restartServiceRequest, osType string) map[string]string {
+func processRestartServiceRequest(req restartServiceRequest, osType string) string {
 	if strings.ToLower(osType) == "windows" {
 		return processWindowsRestartServiceRequest(req)
 	}
 
 	// Default Linux behavior
-	return map[string]string{
-		"service": strings.TrimSpace(req.Service),
-	}
+	return strings.TrimSpace(req.Service)
 }
 
 // Process restart service response based on OS
@@ -140,12 +79,10 @@ func processRestartServiceResponse(resp interface{}, osType string) interface{}
 }
 
 // Windows-specific restart service request processing (placeholder for future differences)
-func processWindowsRestartServiceRequest(req restartServiceRequest) map[string]string {
+func processWindowsRestartServiceRequest(req restartServiceRequest) string {
 	// For now, return same format as Linux
 	// Future: Windows might have different service names or commands
-	return map[string]string{
-		"service": strings.TrimSpace(req.Service),
-	}
+	return strings.TrimSpace(req.Service)
 }
 
 // Windows-specific restart service response processing (placeholder for future differences)
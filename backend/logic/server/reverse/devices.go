@@ -0,0 +1,118 @@
+package reverse
+
+import (
+	"encoding/json"
+	"net/http"
+
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/tunnel"
+)
+
+// Standard response structures
+type ErrorResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// HandleSetReverse marks a device as reverse-connected, or back to direct calls
+func HandleSetReverse(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			Host    string `json:"host"`
+			Enabled bool   `json:"enabled"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Host == "" {
+			sendError(w, "Host is required", http.StatusBadRequest)
+			return
+		}
+
+		found, err := Mark(r.Context(), queries, req.Host, req.Enabled)
+		if err != nil {
+			sendError(w, "Failed to update device: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			sendError(w, "Device not found", http.StatusNotFound)
+			return
+		}
+
+		message := "Device is now called directly"
+		if req.Enabled {
+			message = "Device is now reached through its tunnel"
+		}
+		sendGetSuccess(w, map[string]interface{}{
+			"status":          "success",
+			"message":         message,
+			"host":            req.Host,
+			"reverse_connect": req.Enabled,
+		})
+	}
+}
+
+// HandleListTunnels lists reverse-connected devices and whether their agent
+// currently has a tunnel open
+func HandleListTunnels(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		devices, err := queries.ListReverseDevices(r.Context())
+		if err != nil {
+			sendError(w, "Failed to fetch devices: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		deviceList := make([]map[string]interface{}, 0, len(devices))
+		connected := 0
+		for _, d := range devices {
+			device := map[string]interface{}{
+				"ip":           d.Ip,
+				"tag":          d.Tag,
+				"os":           d.Os,
+				"connected":    false,
+				"connected_at": nil,
+				"remote_addr":  "",
+			}
+			if s, ok := tunnel.Get(d.Ip); ok {
+				device["connected"] = true
+				device["connected_at"] = s.ConnectedAt
+				device["remote_addr"] = s.RemoteAddr
+				connected++
+			}
+			deviceList = append(deviceList, device)
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":    "success",
+			"devices":   deviceList,
+			"count":     len(deviceList),
+			"connected": connected,
+		})
+	}
+}
+
+// Standard response functions
+func sendGetSuccess(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+func sendError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	errorResp := ErrorResponse{
+		Status:  "failed",
+		Message: message,
+	}
+	json.NewEncoder(w).Encode(errorResp)
+}
@@ -0,0 +1,143 @@
+package reverse
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/pki"
+	"github.com/kishore-001/ServerManagementSuite/backend/tunnel"
+)
+
+// Headers of the challenge a device with a pinned certificate must answer.
+// The backend puts a nonce in headerTunnelNonce of its 401 response, and the
+// agent repeats the request with the nonce, its certificate and a signature
+// over tunnelProofMessage made with the certificate's key.
+const (
+	headerAgentCertificate = "X-Agent-Certificate"
+	headerTunnelNonce      = "X-Tunnel-Nonce"
+	headerTunnelSignature  = "X-Tunnel-Signature"
+
+	tunnelNonceTTL = time.Minute
+)
+
+// nonces holds the challenges handed out and not yet answered. Each one
+// belongs to one device and is removed on its first use, so a captured
+// signature cannot open a second tunnel.
+var nonces = struct {
+	sync.Mutex
+	issued map[string]issuedNonce
+}{issued: make(map[string]issuedNonce)}
+
+type issuedNonce struct {
+	host    string
+	expires time.Time
+}
+
+// issueNonce creates a challenge for host and drops expired ones
+func issueNonce(host string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	nonce := hex.EncodeToString(buf)
+
+	now := time.Now()
+	nonces.Lock()
+	defer nonces.Unlock()
+	for n, issued := range nonces.issued {
+		if now.After(issued.expires) {
+			delete(nonces.issued, n)
+		}
+	}
+	nonces.issued[nonce] = issuedNonce{host: host, expires: now.Add(tunnelNonceTTL)}
+	return nonce, nil
+}
+
+// consumeNonce reports whether nonce was issued for host and is still valid.
+// The nonce is gone afterwards either way.
+func consumeNonce(nonce, host string) bool {
+	nonces.Lock()
+	defer nonces.Unlock()
+	issued, ok := nonces.issued[nonce]
+	if !ok {
+		return false
+	}
+	delete(nonces.issued, nonce)
+	return issued.host == host && time.Now().Before(issued.expires)
+}
+
+// sendChallenge answers an unsigned tunnel request of a pinned device
+func sendChallenge(w http.ResponseWriter, host string) {
+	nonce, err := issueNonce(host)
+	if err != nil {
+		sendError(w, "Failed to create tunnel challenge", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set(headerTunnelNonce, nonce)
+	sendError(w, "Sign the tunnel nonce with the agent certificate", http.StatusUnauthorized)
+}
+
+// verifyTunnelProof checks the agent's answer to a challenge issued for host
+func verifyTunnelProof(r *http.Request, host, credential, fingerprint string) error {
+	nonce := r.Header.Get(headerTunnelNonce)
+	if nonce == "" || !consumeNonce(nonce, host) {
+		return errors.New("unknown, used or expired nonce")
+	}
+
+	certDER, err := base64.StdEncoding.DecodeString(r.Header.Get(headerAgentCertificate))
+	if err != nil || len(certDER) == 0 {
+		return errors.New("no agent certificate presented")
+	}
+	signature, err := base64.StdEncoding.DecodeString(r.Header.Get(headerTunnelSignature))
+	if err != nil || len(signature) == 0 {
+		return errors.New("no signature presented")
+	}
+
+	return pki.VerifyAgentSignature(certDER, fingerprint, tunnelProofMessage(nonce, credential), signature)
+}
+
+// tunnelProofMessage is what the agent signs; it must match the agent's copy
+func tunnelProofMessage(nonce, credential string) []byte {
+	return []byte(tunnel.Protocol + "\n" + nonce + "\n" + credential)
+}
+
+// unpinned remembers the devices already reported for tunnelling without a
+// certificate, so a reconnecting agent raises one alert per backend start
+var unpinned = struct {
+	sync.Mutex
+	hosts map[string]bool
+}{hosts: make(map[string]bool)}
+
+// notifyUnpinned raises an alert the first time a device without a pinned
+// certificate opens a tunnel. Such a tunnel is authenticated by the token
+// hash alone, which anyone holding a copy of the agent's token file has.
+func notifyUnpinned(queries *serverdb.Queries, ip, source string) {
+	unpinned.Lock()
+	reported := unpinned.hosts[ip]
+	unpinned.hosts[ip] = true
+	unpinned.Unlock()
+
+	log.Printf("⚠️ Tunnel for %s from %s uses only its token hash; it is upgraded once the agent has a certificate", ip, source)
+	if reported {
+		return
+	}
+
+	content := fmt.Sprintf("Tunnel of %s is authenticated by its token hash only, since the device has no agent certificate", ip)
+	_, err := queries.CreateAlert(context.Background(), serverdb.CreateAlertParams{
+		Host:     ip,
+		Severity: "warning",
+		Content:  content,
+	})
+	if err != nil {
+		log.Printf("❌ Failed to create tunnel alert for %s: %v", ip, err)
+	}
+}
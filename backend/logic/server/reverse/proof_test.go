@@ -0,0 +1,175 @@
+package reverse
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/pki"
+)
+
+const testCredential = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+// testAgent is an agent key with a certificate from a throwaway CA
+type testAgent struct {
+	key         *ecdsa.PrivateKey
+	certDER     []byte
+	fingerprint string
+}
+
+func newTestAgent(t *testing.T, ip string) testAgent {
+	t.Helper()
+	if !pki.Enabled() {
+		if err := pki.Configure(t.TempDir()); err != nil {
+			t.Fatalf("pki.Configure: %v", err)
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM, fingerprint, _, err := pki.SignAgentCSR(
+		string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr})), ip)
+	if err != nil {
+		t.Fatalf("SignAgentCSR: %v", err)
+	}
+	block, _ := pem.Decode([]byte(certPEM))
+	return testAgent{key: key, certDER: block.Bytes, fingerprint: fingerprint}
+}
+
+// signedRequest builds the second tunnel request the way the agent does
+func (a testAgent) signedRequest(t *testing.T, credential, nonce string) *http.Request {
+	t.Helper()
+	digest := sha256.Sum256(tunnelProofMessage(nonce, credential))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/agent/tunnel", nil)
+	r.Header.Set(headerAgentCertificate, base64.StdEncoding.EncodeToString(a.certDER))
+	r.Header.Set(headerTunnelNonce, nonce)
+	r.Header.Set(headerTunnelSignature, base64.StdEncoding.EncodeToString(signature))
+	return r
+}
+
+func mustIssue(t *testing.T, host string) string {
+	t.Helper()
+	nonce, err := issueNonce(host)
+	if err != nil {
+		t.Fatalf("issueNonce: %v", err)
+	}
+	return nonce
+}
+
+func TestVerifyTunnelProof(t *testing.T) {
+	agent := newTestAgent(t, "10.0.0.1")
+	other := newTestAgent(t, "10.0.0.2")
+
+	tests := []struct {
+		name    string
+		request func(t *testing.T) *http.Request
+		wantErr bool
+	}{
+		{"signed nonce", func(t *testing.T) *http.Request {
+			return agent.signedRequest(t, testCredential, mustIssue(t, "10.0.0.1"))
+		}, false},
+		{"nonce never issued", func(t *testing.T) *http.Request {
+			return agent.signedRequest(t, testCredential, strings.Repeat("0", 64))
+		}, true},
+		{"nonce of another device", func(t *testing.T) *http.Request {
+			return agent.signedRequest(t, testCredential, mustIssue(t, "10.0.0.2"))
+		}, true},
+		{"signature over another credential", func(t *testing.T) *http.Request {
+			return agent.signedRequest(t, strings.Repeat("f", 64), mustIssue(t, "10.0.0.1"))
+		}, true},
+		{"certificate of another device", func(t *testing.T) *http.Request {
+			return other.signedRequest(t, testCredential, mustIssue(t, "10.0.0.1"))
+		}, true},
+		{"no signature", func(t *testing.T) *http.Request {
+			r := agent.signedRequest(t, testCredential, mustIssue(t, "10.0.0.1"))
+			r.Header.Del(headerTunnelSignature)
+			return r
+		}, true},
+		{"no certificate", func(t *testing.T) *http.Request {
+			r := agent.signedRequest(t, testCredential, mustIssue(t, "10.0.0.1"))
+			r.Header.Del(headerAgentCertificate)
+			return r
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyTunnelProof(tt.request(t), "10.0.0.1", testCredential, agent.fingerprint)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyTunnelProof error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTunnelProofReplay(t *testing.T) {
+	agent := newTestAgent(t, "10.0.0.1")
+	nonce := mustIssue(t, "10.0.0.1")
+
+	if err := verifyTunnelProof(agent.signedRequest(t, testCredential, nonce), "10.0.0.1", testCredential, agent.fingerprint); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := verifyTunnelProof(agent.signedRequest(t, testCredential, nonce), "10.0.0.1", testCredential, agent.fingerprint); err == nil {
+		t.Error("a used nonce opened a second tunnel")
+	}
+}
+
+func TestTunnelNonceExpiry(t *testing.T) {
+	nonce := mustIssue(t, "10.0.0.1")
+	nonces.Lock()
+	nonces.issued[nonce] = issuedNonce{host: "10.0.0.1", expires: time.Now().Add(-time.Second)}
+	nonces.Unlock()
+
+	if consumeNonce(nonce, "10.0.0.1") {
+		t.Error("expired nonce was accepted")
+	}
+
+	// Issuing drops expired nonces that were never answered
+	stale := mustIssue(t, "10.0.0.1")
+	nonces.Lock()
+	nonces.issued[stale] = issuedNonce{host: "10.0.0.1", expires: time.Now().Add(-time.Second)}
+	nonces.Unlock()
+	mustIssue(t, "10.0.0.1")
+	nonces.Lock()
+	_, kept := nonces.issued[stale]
+	nonces.Unlock()
+	if kept {
+		t.Error("expired nonce was not dropped")
+	}
+}
+
+func TestSendChallenge(t *testing.T) {
+	w := httptest.NewRecorder()
+	sendChallenge(w, "10.0.0.1")
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	nonce := w.Header().Get(headerTunnelNonce)
+	if len(nonce) != 64 {
+		t.Fatalf("nonce = %q, want 64 hex characters", nonce)
+	}
+	if !consumeNonce(nonce, "10.0.0.1") {
+		t.Error("challenge nonce was not recorded for the device")
+	}
+}
@@ -0,0 +1,146 @@
+package reverse
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/auth"
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/tunnel"
+)
+
+// reverseDevice is the device a tunnel credential belongs to
+type reverseDevice struct {
+	ip          string
+	fingerprint string
+}
+
+// HandleTunnel accepts the connection a reverse-connected agent keeps open.
+// Agents only store a hash of their access token, so that hash identifies
+// the device. Devices with a pinned certificate must also sign a nonce the
+// backend hands out with the certificate's key, so neither a copy of the
+// token file nor a captured request is enough to take over their tunnel.
+func HandleTunnel(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET upgrades
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !strings.EqualFold(r.Header.Get("Upgrade"), tunnel.Protocol) {
+			w.Header().Set("Upgrade", tunnel.Protocol)
+			sendError(w, "Upgrade to "+tunnel.Protocol+" required", http.StatusUpgradeRequired)
+			return
+		}
+
+		authHeader := r.Header.Get("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			sendError(w, "Missing device credential", http.StatusUnauthorized)
+			return
+		}
+		credential := strings.ToLower(strings.TrimPrefix(authHeader, "Bearer "))
+		source := auth.ClientIP(r)
+
+		device, err := deviceForCredential(r.Context(), queries, credential)
+		if err != nil {
+			sendError(w, "Failed to look up device", http.StatusInternalServerError)
+			return
+		}
+		if device.ip == "" {
+			log.Printf("⚠️ Rejected tunnel from %s: unknown device or reverse connect not enabled", source)
+			sendError(w, "Unknown device or reverse connect not enabled", http.StatusUnauthorized)
+			return
+		}
+
+		if device.fingerprint != "" {
+			// The first request gets a challenge, the agent answers it on
+			// the same connection
+			if r.Header.Get(headerTunnelSignature) == "" {
+				sendChallenge(w, device.ip)
+				return
+			}
+			if err := verifyTunnelProof(r, device.ip, credential, device.fingerprint); err != nil {
+				log.Printf("⚠️ Rejected tunnel for %s from %s: %v", device.ip, source, err)
+				sendError(w, "Device certificate check failed", http.StatusUnauthorized)
+				return
+			}
+		} else {
+			notifyUnpinned(queries, device.ip, source)
+		}
+
+		if previous, ok := tunnel.Get(device.ip); ok && previous.Source != source {
+			notifyReplaced(queries, device.ip, previous.Source, source)
+		}
+
+		if _, err := tunnel.Accept(w, device.ip, source); err != nil {
+			log.Printf("❌ Failed to open tunnel for %s: %v", device.ip, err)
+		}
+	}
+}
+
+// notifyReplaced raises an alert when a device's tunnel is taken over from a
+// different address, which is either the agent moving or someone else
+// holding its credentials
+func notifyReplaced(queries *serverdb.Queries, ip, previousSource, source string) {
+	content := fmt.Sprintf("Tunnel of %s was replaced by a connection from %s (previously %s)", ip, source, previousSource)
+	log.Printf("⚠️ %s", content)
+
+	_, err := queries.CreateAlert(context.Background(), serverdb.CreateAlertParams{
+		Host:     ip,
+		Severity: "warning",
+		Content:  content,
+	})
+	if err != nil {
+		log.Printf("❌ Failed to create tunnel alert for %s: %v", ip, err)
+	}
+}
+
+// deviceForCredential finds the reverse-connected device whose current or
+// pending access token hashes to credential. A pending token counts because
+// the agent switches to it before the backend records the rotation.
+func deviceForCredential(ctx context.Context, queries *serverdb.Queries, credential string) (reverseDevice, error) {
+	devices, err := queries.ListReverseDevices(ctx)
+	if err != nil {
+		return reverseDevice{}, err
+	}
+
+	for _, d := range devices {
+		if matchesToken(credential, d.AccessToken) ||
+			(d.PendingToken.Valid && matchesToken(credential, d.PendingToken.String)) {
+			return reverseDevice{ip: d.Ip, fingerprint: d.TlsFingerprint}, nil
+		}
+	}
+	return reverseDevice{}, nil
+}
+
+func matchesToken(credential, token string) bool {
+	if token == "" {
+		return false
+	}
+	hash := sha256.Sum256([]byte(token))
+	return subtle.ConstantTimeCompare([]byte(credential), []byte(hex.EncodeToString(hash[:]))) == 1
+}
+
+// Mark switches a device between direct calls and its tunnel
+func Mark(ctx context.Context, queries *serverdb.Queries, ip string, enabled bool) (bool, error) {
+	updated, err := queries.SetDeviceReverseConnect(ctx, serverdb.SetDeviceReverseConnectParams{
+		Ip:             ip,
+		ReverseConnect: enabled,
+	})
+	if err != nil || updated == 0 {
+		return false, err
+	}
+
+	config.SetAgentReverse(ip, enabled)
+	if !enabled {
+		tunnel.Disconnect(ip)
+	}
+	return true, nil
+}
@@ -0,0 +1,253 @@
+package terminal
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+// Standard response structures
+type ErrorResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// HandleOpen starts a terminal session on a host for the caller. It returns
+// a one-time ticket the browser opens the WebSocket with, since browsers
+// cannot send an Authorization header on a WebSocket.
+func HandleOpen(db *sql.DB, queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			Host string `json:"host"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Host = strings.TrimSpace(req.Host)
+		if req.Host == "" {
+			sendError(w, "host is required", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := queries.GetServerDeviceByIP(r.Context(), req.Host); err == sql.ErrNoRows {
+			sendError(w, "Device not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		ticket, err := randomHex(32)
+		if err != nil {
+			sendError(w, "Failed to generate ticket", http.StatusInternalServerError)
+			return
+		}
+
+		user, _ := config.GetUserFromContext(r)
+		maxSessions := config.AppConfig.TerminalMaxSessions
+		session, err := createSession(r.Context(), db, queries, serverdb.CreateTerminalSessionParams{
+			Username:    user.Username,
+			Host:        req.Host,
+			TicketHash:  sql.NullString{String: hashTicket(ticket), Valid: true},
+			LeaseUntil:  time.Now().Add(ticketTTL),
+			MaxSessions: int32(maxSessions),
+		})
+		if err == sql.ErrNoRows {
+			sendError(w, fmt.Sprintf("You already have %d terminals open", maxSessions), http.StatusTooManyRequests)
+			return
+		} else if err != nil {
+			sendError(w, "Failed to create terminal session: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("🖥️ %s requested a terminal on %s (session %s)", user.Username, req.Host, session.ID)
+		sendPostSuccess(w, map[string]interface{}{
+			"status":      "success",
+			"id":          session.ID,
+			"host":        session.Host,
+			"ticket":      ticket,
+			"expires_at":  session.LeaseUntil,
+			"connect_url": "/api/terminal/connect?ticket=" + url.QueryEscape(ticket),
+		})
+	}
+}
+
+// createSession inserts a pending session unless the user is at the limit.
+// The per-user lock keeps concurrent requests, on any backend instance, from
+// all passing the count before one of them inserts.
+func createSession(ctx context.Context, db *sql.DB, queries *serverdb.Queries, params serverdb.CreateTerminalSessionParams) (serverdb.TerminalSession, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return serverdb.TerminalSession{}, err
+	}
+	defer tx.Rollback()
+
+	qtx := queries.WithTx(tx)
+	if err := qtx.LockUserTerminalSessions(ctx, params.Username); err != nil {
+		return serverdb.TerminalSession{}, err
+	}
+	session, err := qtx.CreateTerminalSession(ctx, params)
+	if err != nil {
+		return serverdb.TerminalSession{}, err
+	}
+	return session, tx.Commit()
+}
+
+// HandleList lists live terminal sessions of every user and backend
+// instance. ?username= and ?host= narrow the list.
+func HandleList(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		username, host := query.Get("username"), query.Get("host")
+		sessions, err := queries.ListLiveTerminalSessions(r.Context(), serverdb.ListLiveTerminalSessionsParams{
+			Username: sql.NullString{String: username, Valid: username != ""},
+			Host:     sql.NullString{String: host, Valid: host != ""},
+		})
+		if err != nil {
+			sendError(w, "Failed to fetch terminal sessions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		list := make([]map[string]interface{}, 0, len(sessions))
+		for _, s := range sessions {
+			list = append(list, map[string]interface{}{
+				"id":              s.ID,
+				"username":        s.Username,
+				"host":            s.Host,
+				"status":          s.Status,
+				"client_ip":       s.ClientIp,
+				"backend":         s.Worker,
+				"close_requested": s.CloseRequestedBy != "",
+				"created_at":      s.CreatedAt,
+				"opened_at":       nullTime(s.OpenedAt),
+				"last_input_at":   nullTime(s.LastInputAt),
+			})
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":   "success",
+			"sessions": list,
+			"count":    len(list),
+		})
+	}
+}
+
+// HandleClose force-closes a live terminal session. A session proxied by
+// another backend instance is closed by that instance within its renew
+// interval.
+func HandleClose(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			ID string `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		id, err := uuid.Parse(req.ID)
+		if err != nil {
+			sendError(w, "Invalid session id", http.StatusBadRequest)
+			return
+		}
+
+		user, _ := config.GetUserFromContext(r)
+		status, err := queries.RequestTerminalSessionClose(r.Context(), serverdb.RequestTerminalSessionCloseParams{
+			ClosedBy: user.Username,
+			ID:       id,
+		})
+		if err == sql.ErrNoRows {
+			sendError(w, "Terminal session not found or already closed", http.StatusNotFound)
+			return
+		} else if err != nil {
+			sendError(w, "Failed to close terminal session: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		message := "Close requested"
+		if status == statusClosed || closeLocal(id, user.Username) {
+			message = "Terminal session closed"
+		}
+
+		log.Printf("🛑 %s force-closed terminal session %s", user.Username, id)
+		sendGetSuccess(w, map[string]interface{}{
+			"status":  "success",
+			"message": message,
+		})
+	}
+}
+
+// hashTicket returns the SHA-256 hex digest stored in place of a ticket
+func hashTicket(ticket string) string {
+	hash := sha256.Sum256([]byte(ticket))
+	return hex.EncodeToString(hash[:])
+}
+
+func randomHex(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+func nullTime(v sql.NullTime) interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return v.Time
+}
+
+// Standard response functions
+func sendGetSuccess(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(data)
+}
+
+func sendPostSuccess(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(data)
+}
+
+func sendError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	errorResp := ErrorResponse{
+		Status:  "failed",
+		Message: message,
+	}
+	json.NewEncoder(w).Encode(errorResp)
+}
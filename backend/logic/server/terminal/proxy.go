@@ -0,0 +1,381 @@
+package terminal
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kishore-001/ServerManagementSuite/backend/agentclient"
+	"github.com/kishore-001/ServerManagementSuite/backend/auth"
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"golang.org/x/net/websocket"
+)
+
+// Status of a session that has ended
+const statusClosed = "closed"
+
+// Why a session ended, stored on it and sent to the browser
+const (
+	reasonExited       = "exited"       // The shell exited
+	reasonDisconnected = "disconnected" // The browser went away
+	reasonIdle         = "idle"
+	reasonForced       = "forced" // Closed by an administrator
+	reasonError        = "error"  // The agent could not be reached or failed
+)
+
+const (
+	ticketTTL = 30 * time.Second
+
+	// The backend instance proxying a session renews its lease. Sessions
+	// whose lease ran out no longer count against the user's limit.
+	leaseDuration = time.Minute
+	renewInterval = 15 * time.Second // Also how often the browser is pinged
+
+	maxInputFrame = 64 << 10
+	retention     = 30 * 24 * time.Hour
+)
+
+// worker identifies this backend instance on the sessions it proxies
+var worker = func() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}()
+
+// Sessions proxied by this instance, so a force-close takes effect at once
+var (
+	liveMu sync.Mutex
+	live   = make(map[uuid.UUID]*proxy)
+)
+
+// closedMessage is the last text frame the browser gets
+type closedMessage struct {
+	Type     string `json:"type"` // Always "closed"
+	Reason   string `json:"reason"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// proxy relays one session between the browser and the agent
+type proxy struct {
+	queries *serverdb.Queries
+	session serverdb.TerminalSession
+	ws      *websocket.Conn
+	term    *agentclient.Terminal
+
+	lastInput atomic.Int64 // Unix nanoseconds of the last keystroke
+
+	stopOnce sync.Once
+	stopped  chan struct{}
+	reason   string
+	exitCode *int
+	message  string
+}
+
+// HandleConnect opens the WebSocket of a session with its ticket and relays
+// it to a shell on the agent. Binary frames carry keystrokes and output.
+// Text frames carry JSON: {"type":"resize","cols":120,"rows":40} from the
+// browser, and a final {"type":"closed",...} before the socket closes.
+func HandleConnect(queries *serverdb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			sendError(w, "WebSocket upgrade required", http.StatusBadRequest)
+			return
+		}
+
+		query := r.URL.Query()
+		ticket := query.Get("ticket")
+		if ticket == "" {
+			sendError(w, "ticket is required", http.StatusUnauthorized)
+			return
+		}
+		cols, rows := querySize(query.Get("cols"), 80), querySize(query.Get("rows"), 24)
+
+		session, err := queries.OpenTerminalSession(r.Context(), serverdb.OpenTerminalSessionParams{
+			Worker:     worker,
+			ClientIp:   auth.ClientIP(r),
+			LeaseUntil: time.Now().Add(leaseDuration),
+			TicketHash: sql.NullString{String: hashTicket(ticket), Valid: true},
+		})
+		if err == sql.ErrNoRows {
+			sendError(w, "Invalid or expired ticket", http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		p := &proxy{queries: queries, session: session, stopped: make(chan struct{})}
+		p.lastInput.Store(time.Now().UnixNano())
+
+		started := false
+		websocket.Server{
+			// The ticket authenticates the browser, so any origin may connect
+			Handshake: func(*websocket.Config, *http.Request) error { return nil },
+			Handler: func(ws *websocket.Conn) {
+				started = true
+				p.run(ws, cols, rows)
+			},
+		}.ServeHTTP(w, r)
+
+		if !started {
+			p.stop(reasonError, nil, "WebSocket handshake failed")
+			p.record()
+		}
+	}
+}
+
+func (p *proxy) run(ws *websocket.Conn, cols, rows int) {
+	p.ws = ws
+	ws.MaxPayloadBytes = maxInputFrame
+
+	liveMu.Lock()
+	live[p.session.ID] = p
+	liveMu.Unlock()
+	defer func() {
+		liveMu.Lock()
+		delete(live, p.session.ID)
+		liveMu.Unlock()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	agent, err := agentclient.Open(ctx, p.queries, p.session.Host)
+	if err == nil {
+		p.term, err = agent.OpenTerminal(ctx, cols, rows)
+	}
+	if err != nil {
+		message, _ := agentclient.Describe(err)
+		p.stop(reasonError, nil, message)
+	} else {
+		log.Printf("🖥️ %s opened a terminal on %s (session %s)", p.session.Username, p.session.Host, p.session.ID)
+		go p.readBrowser()
+		go p.readAgent()
+		go p.maintain()
+	}
+
+	<-p.stopped
+	if p.term != nil {
+		p.term.Close()
+	}
+	send(ws, websocket.TextFrame, closedMessage{Type: "closed", Reason: p.reason, ExitCode: p.exitCode, Message: p.message})
+	ws.Close()
+	p.record()
+}
+
+// stop ends the session. Only the first reason counts.
+func (p *proxy) stop(reason string, exitCode *int, message string) {
+	p.stopOnce.Do(func() {
+		p.reason, p.exitCode, p.message = reason, exitCode, message
+		close(p.stopped)
+	})
+}
+
+// record stores how the session ended
+func (p *proxy) record() {
+	var exitCode sql.NullInt32
+	if p.exitCode != nil {
+		exitCode = sql.NullInt32{Int32: int32(*p.exitCode), Valid: true}
+	}
+	message := ""
+	if p.reason == reasonError {
+		message = p.message
+	}
+
+	err := p.queries.CloseTerminalSession(context.Background(), serverdb.CloseTerminalSessionParams{
+		CloseReason: p.reason,
+		ExitCode:    exitCode,
+		Error:       message,
+		ID:          p.session.ID,
+	})
+	if err != nil {
+		log.Printf("❌ Failed to record the end of terminal session %s: %v", p.session.ID, err)
+	}
+	log.Printf("🔌 Terminal session %s on %s closed (%s)", p.session.ID, p.session.Host, p.reason)
+}
+
+// readBrowser forwards keystrokes and resizes to the agent
+func (p *proxy) readBrowser() {
+	for {
+		var f frame
+		if err := frameCodec.Receive(p.ws, &f); err != nil {
+			p.stop(reasonDisconnected, nil, "")
+			return
+		}
+
+		var err error
+		switch f.payloadType {
+		case websocket.BinaryFrame:
+			p.lastInput.Store(time.Now().UnixNano())
+			err = p.term.Input(f.data)
+		case websocket.TextFrame:
+			var msg struct {
+				Type string `json:"type"`
+				Cols int    `json:"cols"`
+				Rows int    `json:"rows"`
+			}
+			if json.Unmarshal(f.data, &msg) == nil && msg.Type == "resize" {
+				err = p.term.Resize(msg.Cols, msg.Rows)
+			}
+		}
+		if err != nil {
+			message, _ := agentclient.Describe(err)
+			p.stop(reasonError, nil, message)
+			return
+		}
+	}
+}
+
+// readAgent forwards output to the browser until the shell exits
+func (p *proxy) readAgent() {
+	for {
+		ev, err := p.term.Next()
+		switch {
+		case err == io.EOF:
+			p.stop(reasonError, nil, "The client closed the terminal without an exit code")
+		case err != nil:
+			message, _ := agentclient.Describe(err)
+			p.stop(reasonError, nil, message)
+		case ev.Type == "output":
+			if send(p.ws, websocket.BinaryFrame, ev.Data) != nil {
+				p.stop(reasonDisconnected, nil, "")
+				return
+			}
+			continue
+		case ev.Type == "exit":
+			p.stop(reasonExited, ev.ExitCode, ev.Error)
+		default:
+			continue
+		}
+		return
+	}
+}
+
+// maintain closes the session when it goes idle or an administrator closes
+// it from another instance, and otherwise renews its lease
+func (p *proxy) maintain() {
+	idleTimeout := time.Duration(config.AppConfig.TerminalIdleMinutes) * time.Minute
+	ticker := time.NewTicker(renewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopped:
+			return
+		case <-ticker.C:
+		}
+
+		lastInput := time.Unix(0, p.lastInput.Load())
+		if time.Since(lastInput) >= idleTimeout {
+			p.stop(reasonIdle, nil, fmt.Sprintf("No input for %d minutes", config.AppConfig.TerminalIdleMinutes))
+			return
+		}
+
+		closedBy, err := p.queries.RenewTerminalSession(context.Background(), serverdb.RenewTerminalSessionParams{
+			LeaseUntil:  time.Now().Add(leaseDuration),
+			LastInputAt: sql.NullTime{Time: lastInput, Valid: true},
+			ID:          p.session.ID,
+			Worker:      worker,
+		})
+		if err == sql.ErrNoRows {
+			p.stop(reasonForced, nil, "The session was closed")
+			return
+		} else if err != nil {
+			log.Printf("⚠️ Failed to renew terminal session %s: %v", p.session.ID, err)
+		} else if closedBy != "" {
+			p.stop(reasonForced, nil, "Closed by "+closedBy)
+			return
+		}
+
+		// Keeps proxies from dropping a quiet connection
+		send(p.ws, websocket.PingFrame, nil)
+	}
+}
+
+// closeLocal force-closes a session if this instance proxies it
+func closeLocal(id uuid.UUID, closedBy string) bool {
+	liveMu.Lock()
+	p, ok := live[id]
+	liveMu.Unlock()
+	if ok {
+		p.stop(reasonForced, nil, "Closed by "+closedBy)
+	}
+	return ok
+}
+
+// Cleanup closes sessions whose ticket was never used or whose backend
+// instance went away, and deletes sessions that ended long ago
+func Cleanup(ctx context.Context, queries *serverdb.Queries) {
+	if stale, err := queries.CloseStaleTerminalSessions(ctx); err != nil {
+		log.Printf("❌ Failed to close stale terminal sessions: %v", err)
+	} else if stale > 0 {
+		log.Printf("🧹 Closed %d stale terminal sessions", stale)
+	}
+
+	cutoff := sql.NullTime{Time: time.Now().Add(-retention), Valid: true}
+	if deleted, err := queries.DeleteOldTerminalSessions(ctx, cutoff); err != nil {
+		log.Printf("❌ Failed to delete old terminal sessions: %v", err)
+	} else if deleted > 0 {
+		log.Printf("🧹 Deleted %d terminal sessions older than 30 days", deleted)
+	}
+}
+
+// frame is one WebSocket frame with its type, which websocket.Message does
+// not report
+type frame struct {
+	payloadType byte
+	data        []byte
+}
+
+var frameCodec = websocket.Codec{
+	Marshal: func(v interface{}) ([]byte, byte, error) {
+		f := v.(frame)
+		return f.data, f.payloadType, nil
+	},
+	Unmarshal: func(data []byte, payloadType byte, v interface{}) error {
+		f := v.(*frame)
+		f.payloadType, f.data = payloadType, data
+		return nil
+	},
+}
+
+// send writes one frame; text frames carry v as JSON
+func send(ws *websocket.Conn, payloadType byte, v interface{}) error {
+	var data []byte
+	switch v := v.(type) {
+	case []byte:
+		data = v
+	case nil:
+	default:
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return err
+		}
+	}
+	return frameCodec.Send(ws, frame{payloadType: payloadType, data: data})
+}
+
+// querySize reads cols or rows from the connect URL
+func querySize(value string, fallback int) int {
+	if n, err := strconv.Atoi(value); err == nil && n > 0 {
+		return n
+	}
+	return fallback
+}
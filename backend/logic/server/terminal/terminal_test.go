@@ -0,0 +1,146 @@
+package terminal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"golang.org/x/net/websocket"
+)
+
+func TestQuerySize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"120", 120},
+		{"", 80},
+		{"0", 80},
+		{"-5", 80},
+		{"wide", 80},
+	}
+
+	for _, tt := range tests {
+		if got := querySize(tt.value, 80); got != tt.want {
+			t.Errorf("querySize(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestTicketsAreStoredHashed(t *testing.T) {
+	ticket, err := randomHex(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ticket) != 64 {
+		t.Errorf("ticket has %d characters, want 64", len(ticket))
+	}
+
+	hash := hashTicket(ticket)
+	if hash == ticket || len(hash) != 64 || hash != hashTicket(ticket) {
+		t.Errorf("hashTicket(%q) = %q", ticket, hash)
+	}
+	if other, _ := randomHex(32); other == ticket {
+		t.Error("two tickets are the same")
+	}
+}
+
+func TestStopKeepsFirstReason(t *testing.T) {
+	p := &proxy{stopped: make(chan struct{})}
+	code := 0
+	p.stop(reasonExited, &code, "")
+	p.stop(reasonDisconnected, nil, "")
+
+	select {
+	case <-p.stopped:
+	default:
+		t.Fatal("stop did not signal")
+	}
+	if p.reason != reasonExited || p.exitCode != &code {
+		t.Errorf("reason = %s, want %s", p.reason, reasonExited)
+	}
+}
+
+func TestCloseLocal(t *testing.T) {
+	id := uuid.New()
+	p := &proxy{session: serverdb.TerminalSession{ID: id}, stopped: make(chan struct{})}
+	liveMu.Lock()
+	live[id] = p
+	liveMu.Unlock()
+	t.Cleanup(func() {
+		liveMu.Lock()
+		delete(live, id)
+		liveMu.Unlock()
+	})
+
+	if closeLocal(uuid.New(), "admin") {
+		t.Error("closed a session this instance does not proxy")
+	}
+	if !closeLocal(id, "admin") {
+		t.Fatal("did not close a live session")
+	}
+	if p.reason != reasonForced || p.message != "Closed by admin" {
+		t.Errorf("session ended with %s/%q, want %s", p.reason, p.message, reasonForced)
+	}
+}
+
+func TestFramesKeepTheirType(t *testing.T) {
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		send(ws, websocket.BinaryFrame, []byte("output"))
+		send(ws, websocket.TextFrame, closedMessage{Type: "closed", Reason: reasonIdle})
+	}))
+	defer server.Close()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http"), "", server.URL)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer ws.Close()
+
+	var f frame
+	if err := frameCodec.Receive(ws, &f); err != nil || f.payloadType != websocket.BinaryFrame || string(f.data) != "output" {
+		t.Fatalf("first frame = %d %q, %v", f.payloadType, f.data, err)
+	}
+	if err := frameCodec.Receive(ws, &f); err != nil || f.payloadType != websocket.TextFrame {
+		t.Fatalf("second frame = %d %q, %v", f.payloadType, f.data, err)
+	}
+	var msg closedMessage
+	if err := json.Unmarshal(f.data, &msg); err != nil || msg.Type != "closed" || msg.Reason != reasonIdle {
+		t.Errorf("closed message = %+v, %v", msg, err)
+	}
+}
+
+func TestRequestsRejectedBeforeTheDatabase(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		request *http.Request
+		want    int
+	}{
+		{"open needs POST", HandleOpen(nil, nil), httptest.NewRequest(http.MethodGet, "/x", nil), http.StatusMethodNotAllowed},
+		{"open needs a host", HandleOpen(nil, nil),
+			httptest.NewRequest(http.MethodPost, "/x", strings.NewReader(`{"host":"  "}`)), http.StatusBadRequest},
+		{"connect needs an upgrade", HandleConnect(nil), httptest.NewRequest(http.MethodGet, "/x?ticket=abc", nil), http.StatusBadRequest},
+		{"connect needs a ticket", HandleConnect(nil), func() *http.Request {
+			r := httptest.NewRequest(http.MethodGet, "/x", nil)
+			r.Header.Set("Upgrade", "websocket")
+			return r
+		}(), http.StatusUnauthorized},
+		{"close needs a session id", HandleClose(nil),
+			httptest.NewRequest(http.MethodPost, "/x", strings.NewReader(`{"id":"not-a-uuid"}`)), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handler(w, tt.request)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package settings
 
 import (
+	"database/sql"
+	"github.com/kishore-001/ServerManagementSuite/backend/auth"
 	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
 	"encoding/json"
 	"golang.org/x/crypto/bcrypt"
@@ -42,8 +44,17 @@ func HandleAddUser(queries *generaldb.Queries) http.HandlerFunc {
 		}
 
 		// Validate role
-		if req.Role != "admin" && req.Role != "viewer" {
-			sendError(w, "Role must be 'admin' or 'viewer'", http.StatusBadRequest)
+		if _, err := queries.GetRole(r.Context(), strings.TrimSpace(req.Role)); err == sql.ErrNoRows {
+			sendError(w, "Unknown role: "+req.Role, http.StatusBadRequest)
+			return
+		} else if err != nil {
+			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Enforce the password policy
+		if err := auth.ValidatePassword(r.Context(), queries, strings.TrimSpace(req.Name), req.Password); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
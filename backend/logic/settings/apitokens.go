@@ -0,0 +1,240 @@
+package settings
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/auth"
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+)
+
+const maxAPITokenDays = 365
+
+// HandleListAPITokens lists the caller's API tokens. Holders of users.manage
+// can pass ?all=true to see every user's tokens.
+func HandleListAPITokens(queries *generaldb.Queries, authz *config.Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, ok := config.GetUserFromContext(r)
+		if !ok {
+			sendError(w, "User context not found", http.StatusInternalServerError)
+			return
+		}
+
+		var rows []generaldb.ListAPITokensByUserRow
+		if r.URL.Query().Get("all") == "true" {
+			allowed, err := authz.UserAllowed(r.Context(), user, config.PermUsersManage, "")
+			if err != nil {
+				sendError(w, "Permission check failed: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if !allowed {
+				sendError(w, "Permission denied: "+config.PermUsersManage+" required", http.StatusForbidden)
+				return
+			}
+
+			all, err := queries.ListAllAPITokens(r.Context())
+			if err != nil {
+				sendError(w, "Failed to fetch tokens: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			for _, t := range all {
+				rows = append(rows, generaldb.ListAPITokensByUserRow(t))
+			}
+		} else {
+			var err error
+			rows, err = queries.ListAPITokensByUser(r.Context(), user.Username)
+			if err != nil {
+				sendError(w, "Failed to fetch tokens: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		tokenList := make([]map[string]interface{}, 0, len(rows))
+		for _, t := range rows {
+			token := map[string]interface{}{
+				"id":           t.ID,
+				"username":     t.Username,
+				"name":         t.Name,
+				"token_prefix": t.TokenPrefix,
+				"scopes":       t.Scopes,
+				"last_used_ip": t.LastUsedIp,
+				"created_at":   t.CreatedAt,
+				"expires_at":   nil,
+				"last_used_at": nil,
+			}
+			if t.ExpiresAt.Valid {
+				token["expires_at"] = t.ExpiresAt.Time
+			}
+			if t.LastUsedAt.Valid {
+				token["last_used_at"] = t.LastUsedAt.Time
+			}
+			tokenList = append(tokenList, token)
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status": "success",
+			"tokens": tokenList,
+			"count":  len(tokenList),
+		})
+	}
+}
+
+// HandleCreateAPIToken issues a personal API token for the caller. The token
+// is returned once and only its hash is stored.
+func HandleCreateAPIToken(queries *generaldb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, ok := config.GetUserFromContext(r)
+		if !ok {
+			sendError(w, "User context not found", http.StatusInternalServerError)
+			return
+		}
+
+		// Tokens must not be able to mint more tokens
+		if user.ViaAPIToken() {
+			sendError(w, "API tokens cannot create other API tokens", http.StatusForbidden)
+			return
+		}
+
+		var req struct {
+			Name          string   `json:"name"`
+			Scopes        []string `json:"scopes"`
+			ExpiresInDays int      `json:"expires_in_days"` // 0 means no expiry
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" || len(req.Name) > 100 {
+			sendError(w, "Name is required (max 100 characters)", http.StatusBadRequest)
+			return
+		}
+		if req.ExpiresInDays < 0 || req.ExpiresInDays > maxAPITokenDays {
+			sendError(w, "expires_in_days must be between 0 and 365", http.StatusBadRequest)
+			return
+		}
+
+		scopes := make([]string, 0, len(req.Scopes))
+		for _, scope := range req.Scopes {
+			if !isKnownPermission(scope) {
+				sendError(w, "Unknown scope: "+scope, http.StatusBadRequest)
+				return
+			}
+			scopes = append(scopes, scope)
+		}
+
+		var expiresAt sql.NullTime
+		if req.ExpiresInDays > 0 {
+			expiresAt = sql.NullTime{Time: time.Now().AddDate(0, 0, req.ExpiresInDays), Valid: true}
+		}
+
+		token, prefix, err := auth.GenerateAPIToken()
+		if err != nil {
+			sendError(w, "Failed to generate token: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		created, err := queries.CreateAPIToken(r.Context(), generaldb.CreateAPITokenParams{
+			Username:    user.Username,
+			Name:        req.Name,
+			TokenPrefix: prefix,
+			TokenHash:   auth.HashToken(token),
+			Scopes:      scopes,
+			ExpiresAt:   expiresAt,
+		})
+		if err != nil {
+			sendError(w, "Failed to save token: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		response := map[string]interface{}{
+			"status":     "success",
+			"message":    "Token created. Copy it now, it will not be shown again.",
+			"id":         created.ID,
+			"name":       req.Name,
+			"token":      token,
+			"scopes":     scopes,
+			"created_at": created.CreatedAt,
+			"expires_at": nil,
+		}
+		if expiresAt.Valid {
+			response["expires_at"] = expiresAt.Time
+		}
+
+		sendPostSuccess(w, response)
+	}
+}
+
+// HandleRevokeAPIToken deletes one of the caller's tokens. Holders of
+// users.manage can revoke anyone's token.
+func HandleRevokeAPIToken(queries *generaldb.Queries, authz *config.Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST/DELETE method
+		if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			sendError(w, "Only POST or DELETE method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, ok := config.GetUserFromContext(r)
+		if !ok {
+			sendError(w, "User context not found", http.StatusInternalServerError)
+			return
+		}
+
+		var req struct {
+			ID int32 `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == 0 {
+			sendError(w, "Token id is required", http.StatusBadRequest)
+			return
+		}
+
+		canManage, err := authz.UserAllowed(r.Context(), user, config.PermUsersManage, "")
+		if err != nil {
+			sendError(w, "Permission check failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var revoked int64
+		if canManage && !user.ViaAPIToken() {
+			revoked, err = queries.DeleteAPIToken(r.Context(), req.ID)
+		} else {
+			revoked, err = queries.DeleteUserAPIToken(r.Context(), generaldb.DeleteUserAPITokenParams{
+				ID:       req.ID,
+				Username: user.Username,
+			})
+		}
+		if err != nil {
+			sendError(w, "Failed to revoke token: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if revoked == 0 {
+			sendError(w, "Token not found", http.StatusNotFound)
+			return
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":     "success",
+			"message":    "Token revoked successfully",
+			"id":         req.ID,
+			"revoked_by": user.Username,
+		})
+	}
+}
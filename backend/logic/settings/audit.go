@@ -0,0 +1,238 @@
+package settings
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+	maxAuditExportRows   = 50000
+)
+
+// auditFilter is built from ?actor=&host=&action=&outcome=&from=&to=
+func auditFilter(r *http.Request) (generaldb.CountAuditEntriesParams, error) {
+	q := r.URL.Query()
+	var filter generaldb.CountAuditEntriesParams
+
+	optional := func(key string) sql.NullString {
+		value := strings.TrimSpace(q.Get(key))
+		return sql.NullString{String: value, Valid: value != ""}
+	}
+	filter.Actor = optional("actor")
+	filter.TargetHost = optional("host")
+	filter.Action = optional("action") // Prefix match, e.g. "server/config1/"
+	filter.Outcome = optional("outcome")
+
+	if filter.Outcome.Valid {
+		switch filter.Outcome.String {
+		case config.AuditSuccess, config.AuditFailure, config.AuditDenied:
+		default:
+			return filter, fmt.Errorf("outcome must be success, failure or denied")
+		}
+	}
+
+	var err error
+	if filter.Since, err = parseAuditTime(q.Get("from")); err != nil {
+		return filter, fmt.Errorf("invalid from: %v", err)
+	}
+	if filter.Until, err = parseAuditTime(q.Get("to")); err != nil {
+		return filter, fmt.Errorf("invalid to: %v", err)
+	}
+	return filter, nil
+}
+
+// parseAuditTime accepts RFC 3339 timestamps or plain dates
+func parseAuditTime(value string) (sql.NullTime, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return sql.NullTime{Time: t, Valid: true}, nil
+		}
+	}
+	return sql.NullTime{}, fmt.Errorf("use RFC 3339 or YYYY-MM-DD")
+}
+
+func listParams(filter generaldb.CountAuditEntriesParams, limit, offset int32) generaldb.ListAuditEntriesParams {
+	return generaldb.ListAuditEntriesParams{
+		Actor:      filter.Actor,
+		TargetHost: filter.TargetHost,
+		Action:     filter.Action,
+		Outcome:    filter.Outcome,
+		Since:      filter.Since,
+		Until:      filter.Until,
+		RowLimit:   limit,
+		RowOffset:  offset,
+	}
+}
+
+func auditEntryMap(e generaldb.AuditLog) map[string]interface{} {
+	entry := map[string]interface{}{
+		"id":           e.ID,
+		"created_at":   e.CreatedAt,
+		"actor":        e.Actor,
+		"api_token_id": nil,
+		"source_ip":    e.SourceIp,
+		"method":       e.Method,
+		"action":       e.Action,
+		"target_host":  e.TargetHost,
+		"payload":      e.Payload,
+		"status_code":  e.StatusCode,
+		"outcome":      e.Outcome,
+		"duration_ms":  e.DurationMs,
+	}
+	if e.ApiTokenID.Valid {
+		entry["api_token_id"] = e.ApiTokenID.Int32
+	}
+	return entry
+}
+
+// HandleListAudit returns one page of audit entries, newest first
+func HandleListAudit(queries *generaldb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		filter, err := auditFilter(r)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		limit := defaultAuditPageSize
+		if v := r.URL.Query().Get("limit"); v != "" {
+			if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxAuditPageSize {
+				sendError(w, fmt.Sprintf("limit must be between 1 and %d", maxAuditPageSize), http.StatusBadRequest)
+				return
+			}
+		}
+		offset := 0
+		if v := r.URL.Query().Get("offset"); v != "" {
+			if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+				sendError(w, "offset must be a non-negative number", http.StatusBadRequest)
+				return
+			}
+		}
+
+		total, err := queries.CountAuditEntries(r.Context(), filter)
+		if err != nil {
+			sendError(w, "Failed to count audit entries: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		entries, err := queries.ListAuditEntries(r.Context(), listParams(filter, int32(limit), int32(offset)))
+		if err != nil {
+			sendError(w, "Failed to fetch audit entries: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		entryList := make([]map[string]interface{}, 0, len(entries))
+		for _, e := range entries {
+			entryList = append(entryList, auditEntryMap(e))
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":  "success",
+			"entries": entryList,
+			"count":   len(entryList),
+			"total":   total,
+			"limit":   limit,
+			"offset":  offset,
+		})
+	}
+}
+
+// HandleExportAudit downloads the filtered audit log as CSV (default) or JSON
+func HandleExportAudit(queries *generaldb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "csv"
+		}
+		if format != "csv" && format != "json" {
+			sendError(w, "format must be csv or json", http.StatusBadRequest)
+			return
+		}
+
+		filter, err := auditFilter(r)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		entries, err := queries.ListAuditEntries(r.Context(), listParams(filter, maxAuditExportRows, 0))
+		if err != nil {
+			sendError(w, "Failed to fetch audit entries: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		filename := "audit-" + time.Now().Format("20060102-150405") + "." + format
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+		if format == "json" {
+			entryList := make([]map[string]interface{}, 0, len(entries))
+			for _, e := range entries {
+				entryList = append(entryList, auditEntryMap(e))
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(entryList)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv")
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"id", "created_at", "actor", "api_token_id", "source_ip", "method",
+			"action", "target_host", "payload", "status_code", "outcome", "duration_ms"})
+		for _, e := range entries {
+			tokenID := ""
+			if e.ApiTokenID.Valid {
+				tokenID = strconv.Itoa(int(e.ApiTokenID.Int32))
+			}
+			writer.Write([]string{
+				strconv.FormatInt(e.ID, 10),
+				e.CreatedAt.UTC().Format(time.RFC3339),
+				csvSafe(e.Actor),
+				tokenID,
+				e.SourceIp,
+				e.Method,
+				csvSafe(e.Action),
+				csvSafe(e.TargetHost),
+				csvSafe(e.Payload),
+				strconv.Itoa(int(e.StatusCode)),
+				e.Outcome,
+				strconv.Itoa(int(e.DurationMs)),
+			})
+		}
+		writer.Flush()
+	}
+}
+
+// csvSafe stops spreadsheet apps from treating a cell as a formula
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsAny(value[:1], "=+-@\t\r") {
+		return "'" + value
+	}
+	return value
+}
@@ -1,7 +1,6 @@
 package settings
 
 import (
-	"github.com/kishore-001/ServerManagementSuite/backend/config"
 	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
 	"encoding/json"
 	"net/http"
@@ -15,18 +14,6 @@ func HandleListUsers(queries *generaldb.Queries) http.HandlerFunc {
 			return
 		}
 
-		// Check admin authorization
-		user, ok := config.GetUserFromContext(r)
-		if !ok {
-			sendError(w, "User context not found", http.StatusInternalServerError)
-			return
-		}
-
-		if user.Role != "admin" {
-			sendError(w, "Admin access required", http.StatusForbidden)
-			return
-		}
-
 		// Get all users from database
 		users, err := queries.ListUsers(r.Context())
 		if err != nil {
@@ -38,10 +25,11 @@ func HandleListUsers(queries *generaldb.Queries) http.HandlerFunc {
 		var userList []map[string]interface{}
 		for _, u := range users {
 			userList = append(userList, map[string]interface{}{
-				"id":    u.ID,
-				"name":  u.Name,
-				"role":  u.Role,
-				"email": u.Email,
+				"id":          u.ID,
+				"name":        u.Name,
+				"role":        u.Role,
+				"email":       u.Email,
+				"mfa_enabled": u.MfaEnabled,
 			})
 		}
 
@@ -0,0 +1,162 @@
+package settings
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/auth"
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+)
+
+// HandleLockoutPolicy reads (GET) or updates (POST) the login lockout thresholds
+func HandleLockoutPolicy(queries *generaldb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			policy, err := auth.LoadLockoutPolicy(r.Context(), queries)
+			if err != nil {
+				sendError(w, "Failed to read lockout policy: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			sendGetSuccess(w, map[string]interface{}{
+				"status": "success",
+				"policy": policy,
+			})
+
+		case http.MethodPost:
+			var req auth.LockoutPolicy
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			if req.Threshold < 1 || req.IPThreshold < 1 || req.DurationMinutes < 1 {
+				sendError(w, "Threshold, ip_threshold and duration_minutes must be at least 1", http.StatusBadRequest)
+				return
+			}
+
+			settings := map[string]int{
+				auth.SettingLockoutThreshold:   req.Threshold,
+				auth.SettingLockoutIPThreshold: req.IPThreshold,
+				auth.SettingLockoutDuration:    req.DurationMinutes,
+			}
+			for key, value := range settings {
+				err := queries.UpsertAppSetting(r.Context(), generaldb.UpsertAppSettingParams{
+					Key:   key,
+					Value: strconv.Itoa(value),
+				})
+				if err != nil {
+					sendError(w, "Failed to update lockout policy: "+err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+
+			user, _ := config.GetUserFromContext(r)
+			sendGetSuccess(w, map[string]interface{}{
+				"status":     "success",
+				"message":    "Lockout policy updated",
+				"policy":     req,
+				"updated_by": user.Username,
+			})
+
+		default:
+			sendError(w, "Only GET or POST method allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// HandleListLockouts lists usernames and IPs that are currently locked out
+func HandleListLockouts(queries *generaldb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		locks, err := queries.ListLoginLocks(r.Context())
+		if err != nil {
+			sendError(w, "Failed to fetch lockouts: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		lockList := make([]map[string]interface{}, 0, len(locks))
+		for _, l := range locks {
+			lockList = append(lockList, map[string]interface{}{
+				"scope":           l.Scope,
+				"key":             l.Key,
+				"failures":        l.Failures,
+				"last_failure_at": l.LastFailureAt,
+				"locked_until":    l.LockedUntil.Time,
+			})
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":   "success",
+			"lockouts": lockList,
+			"count":    len(lockList),
+		})
+	}
+}
+
+// HandleUnlock clears the failure counter of a username or a source IP
+func HandleUnlock(queries *generaldb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, ok := config.GetUserFromContext(r)
+		if !ok {
+			sendError(w, "User context not found", http.StatusInternalServerError)
+			return
+		}
+
+		var req struct {
+			Name string `json:"username,omitempty"`
+			IP   string `json:"ip,omitempty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		params := generaldb.ClearLoginFailuresParams{
+			Scope: auth.LockoutScopeUser,
+			Key:   strings.TrimSpace(req.Name),
+		}
+		if params.Key == "" {
+			params = generaldb.ClearLoginFailuresParams{
+				Scope: auth.LockoutScopeIP,
+				Key:   strings.TrimSpace(req.IP),
+			}
+		}
+		if params.Key == "" {
+			sendError(w, "Username or ip is required", http.StatusBadRequest)
+			return
+		}
+
+		cleared, err := queries.ClearLoginFailures(r.Context(), params)
+		if err != nil {
+			sendError(w, "Failed to unlock: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if cleared == 0 {
+			sendError(w, "No lockout found for "+params.Key, http.StatusNotFound)
+			return
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":      "success",
+			"message":     "Unlocked successfully",
+			"scope":       params.Scope,
+			"key":         params.Key,
+			"unlocked_by": user.Username,
+		})
+	}
+}
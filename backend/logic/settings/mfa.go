@@ -0,0 +1,123 @@
+package settings
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/auth"
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+)
+
+type mfaPolicy struct {
+	RequireAdminMFA bool `json:"require_admin_mfa"`
+}
+
+// HandleMFAPolicy reads (GET) or updates (POST) the admin MFA requirement
+func HandleMFAPolicy(queries *generaldb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			required, err := auth.AdminMFARequired(r.Context(), queries)
+			if err != nil {
+				sendError(w, "Failed to read MFA policy: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			sendGetSuccess(w, map[string]interface{}{
+				"status":            "success",
+				"require_admin_mfa": required,
+			})
+
+		case http.MethodPost:
+			var req mfaPolicy
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			value := "false"
+			if req.RequireAdminMFA {
+				value = "true"
+			}
+
+			err := queries.UpsertAppSetting(r.Context(), generaldb.UpsertAppSettingParams{
+				Key:   auth.SettingRequireAdminMFA,
+				Value: value,
+			})
+			if err != nil {
+				sendError(w, "Failed to update MFA policy: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			user, _ := config.GetUserFromContext(r)
+			sendGetSuccess(w, map[string]interface{}{
+				"status":            "success",
+				"message":           "MFA policy updated",
+				"require_admin_mfa": req.RequireAdminMFA,
+				"updated_by":        user.Username,
+			})
+
+		default:
+			sendError(w, "Only GET or POST method allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// HandleResetUserMFA removes a user's TOTP secret and recovery codes, e.g. after a lost phone
+func HandleResetUserMFA(queries *generaldb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, ok := config.GetUserFromContext(r)
+		if !ok {
+			sendError(w, "User context not found", http.StatusInternalServerError)
+			return
+		}
+
+		var req struct {
+			Name string `json:"username"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" {
+			sendError(w, "Username is required", http.StatusBadRequest)
+			return
+		}
+
+		// Check if user exists
+		_, err := queries.GetUserByName(r.Context(), req.Name)
+		if err == sql.ErrNoRows {
+			sendError(w, "User not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := queries.DeleteUserMFA(r.Context(), req.Name); err != nil {
+			sendError(w, "Failed to reset MFA: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := queries.DeleteRecoveryCodes(r.Context(), req.Name); err != nil {
+			sendError(w, "Failed to remove recovery codes: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":   "success",
+			"message":  "MFA reset successfully",
+			"username": req.Name,
+			"reset_by": user.Username,
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package settings
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/auth"
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+)
+
+// HandlePasswordPolicy reads (GET) or updates (POST) the password policy
+func HandlePasswordPolicy(queries *generaldb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			policy, err := auth.LoadPasswordPolicy(r.Context(), queries)
+			if err != nil {
+				sendError(w, "Failed to read password policy: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			sendGetSuccess(w, map[string]interface{}{
+				"status": "success",
+				"policy": policy,
+			})
+
+		case http.MethodPost:
+			var req auth.PasswordPolicy
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			if err := auth.SavePasswordPolicy(r.Context(), queries, req); err != nil {
+				sendError(w, "Failed to update password policy: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			user, _ := config.GetUserFromContext(r)
+			sendGetSuccess(w, map[string]interface{}{
+				"status":     "success",
+				"message":    "Password policy updated",
+				"policy":     req,
+				"updated_by": user.Username,
+			})
+
+		default:
+			sendError(w, "Only GET or POST method allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
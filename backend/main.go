@@ -2,8 +2,10 @@
 package main
 
 import (
+	"context"
 	"github.com/kishore-001/ServerManagementSuite/backend/api/common"
 	"github.com/kishore-001/ServerManagementSuite/backend/api/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/auth"
 	"github.com/kishore-001/ServerManagementSuite/backend/config"
 	"github.com/kishore-001/ServerManagementSuite/backend/routine"
 	"log"
@@ -18,41 +20,107 @@ func main() {
 	publicMux := http.NewServeMux()
 	protectedMux := http.NewServeMux()
 	adminMux := http.NewServeMux()
+	agentMux := http.NewServeMux()
+	terminalMux := http.NewServeMux()
 
 	// This is necessary for Database
 	generalqueries := config.GeneralQueries()
 	serverqueries := config.ServerQueries()
 
+	// Revoked access tokens are checked from memory on every request
+	if err := auth.StartRevocationSync(generalqueries); err != nil {
+		log.Fatalf("❌ Failed to load token revocations: %v", err)
+	}
+	auth.StartLockoutSweep(generalqueries)
+
+	// How each agent is reached: pinned TLS certificate or reverse tunnel
+	if err := config.StartAgentRouteSync(serverqueries); err != nil {
+		log.Fatalf("❌ Failed to load agent routes: %v", err)
+	}
+
 	// starting the go routines
 	healthMonitor := routine.NewHealthMonitor(serverqueries, generalqueries)
 	healthMonitor.Start()
 
+	// Track MACs seen by agents against the whitelist/blacklist
+	macMonitor := routine.NewMACMonitor(serverqueries, generalqueries)
+	macMonitor.Start()
+
+	// Rotate device access tokens on a schedule
+	tokenRotator := routine.NewTokenRotator(serverqueries)
+	tokenRotator.Start()
+
+	// Issue TLS certificates to agents still on plain HTTP
+	tlsProvisioner := routine.NewTLSProvisioner(serverqueries)
+	tlsProvisioner.Start()
+
+	// Run queued jobs, including those left running by a previous instance
+	jobRunner := routine.NewJobRunner(serverqueries)
+	jobRunner.Start()
+
+	// Expire terminal tickets and sessions left by stopped instances
+	terminalCleaner := routine.NewTerminalCleaner(serverqueries)
+	terminalCleaner.Start()
+
+	// Raise alerts when repeated login failures lock an account or IP
+	securityAlerter := routine.NewSecurityAlerter(serverqueries, generalqueries)
+	auth.SetLockoutNotifier(securityAlerter.HandleLockout)
+
+	// Password reset links go out through the same SMTP settings as alerts
+	auth.SetPasswordResetMailer(routine.NewEmailService(generalqueries).SendPasswordResetEmail)
+
+	// Roles and permissions for protected and admin routes
+	authz := config.NewAuthorizer(generalqueries, serverqueries)
+	if err := authz.EnsureBuiltinRoles(context.Background()); err != nil {
+		log.Fatalf("❌ Failed to create built-in roles: %v", err)
+	}
+	// The admin MFA policy follows effective permissions, not the role column
+	auth.SetAdminCheck(authz.HasAdminPower)
+
 	// 🌐 Public routes (no authentication required)
 	common.RegisterAuthRoutes(publicMux, generalqueries)
 
 	// 🔒 Protected routes (authentication required)
 	//  Server Protected Routes
 	server.RegisterHealthRoutes(protectedMux, serverqueries)
-	server.RegisterAlertRoutes(protectedMux, serverqueries)
+	server.RegisterAlertRoutes(protectedMux, serverqueries, authz)
 	server.RegisterLogRoutes(protectedMux, serverqueries)
-	common.RegisterCheckRoutes(protectedMux, serverqueries)
+	server.RegisterDeviceStatusRoutes(protectedMux, serverqueries, authz)
+	common.RegisterCheckRoutes(protectedMux, serverqueries, authz)
+	common.RegisterPermissionRoutes(protectedMux, authz)
 
-	// 👑 Admin-only routes
+	// 👑 Admin routes (per-route permissions)
 	//  Common Admin Routes
-	common.RegisterSettingsRoutes(adminMux, generalqueries)
+	common.RegisterSettingsRoutes(adminMux, generalqueries, authz)
 
 	//  Server Admin Routes
-	server.RegisterConfig1Routes(adminMux, serverqueries)
+	server.RegisterConfig1Routes(adminMux, serverqueries, authz)
 	server.RegisterConfig2Routes(adminMux, serverqueries)
 	server.RegisterOptimisation(adminMux, serverqueries)
+	server.RegisterMACRoutes(adminMux, generalqueries)
+	server.RegisterEnrollRoutes(adminMux, serverqueries)
+	server.RegisterDeviceTokenRoutes(adminMux, serverqueries)
+	server.RegisterAgentTLSRoutes(adminMux, serverqueries)
+	server.RegisterReverseRoutes(adminMux, serverqueries)
+	server.RegisterDiscoveryRoutes(adminMux, serverqueries)
+	server.RegisterInventoryRoutes(adminMux, serverqueries, generalqueries, authz)
+	server.RegisterFleetRoutes(adminMux, serverqueries, authz)
+	server.RegisterJobRoutes(adminMux, serverqueries, authz)
+	server.RegisterTerminalRoutes(adminMux, terminalMux, config.ServerDB(), serverqueries)
+
+	// 🤖 Agent routes (enrollment code or device credential, no user login)
+	server.RegisterAgentRoutes(agentMux, serverqueries)
 
 	// Create main mux and apply appropriate middlewares
 	mainMux := http.NewServeMux()
 
 	// Mount with different middleware chains
 	mainMux.Handle("/api/auth/", config.ApplyPublicMiddlewares(publicMux))
-	mainMux.Handle("/api/server/", config.ApplyProtectedMiddlewares(protectedMux))
-	mainMux.Handle("/api/admin/", config.ApplyAdminMiddlewares(adminMux))
+	mainMux.Handle("/api/server/", config.ApplyProtectedMiddlewares(protectedMux, authz))
+	mainMux.Handle("/api/admin/", config.ApplyAdminMiddlewares(adminMux, authz))
+	mainMux.Handle("/api/agent/", config.ApplyPublicMiddlewares(agentMux))
+	// The terminal WebSocket authenticates with a one-time ticket instead of a JWT
+	mainMux.Handle("/api/terminal/", config.ApplyPublicMiddlewares(terminalMux))
 
 	log.Printf("✅ SNSMS backend running on port %s...", config.AppConfig.ServerPort)
 	if err := http.ListenAndServe("0.0.0.0:"+config.AppConfig.ServerPort, mainMux); err != nil {
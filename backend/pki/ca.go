@@ -0,0 +1,320 @@
+// Package pki runs the small certificate authority that secures the link to
+// the agents. Agents get a server certificate signed by the CA during
+// registration; the backend authenticates to them with a client certificate
+// from the same CA.
+package pki
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	caCertFile = "ca.crt"
+	caKeyFile  = "ca.key"
+
+	caValidity     = 10 * 365 * 24 * time.Hour
+	agentValidity  = 365 * 24 * time.Hour
+	clientValidity = 30 * 24 * time.Hour
+	clientRenewal  = 7 * 24 * time.Hour // Reissue the backend certificate when it is this close to expiry
+)
+
+type authority struct {
+	cert    *x509.Certificate
+	certPEM string
+	key     crypto.Signer
+	pool    *x509.CertPool
+
+	clientMu sync.Mutex
+	client   *tls.Certificate
+}
+
+var (
+	caMu sync.RWMutex
+	ca   *authority
+)
+
+// Configure loads the CA from dir, creating it on first start. Every backend
+// instance must share the same directory.
+func Configure(dir string) error {
+	certPath := filepath.Join(dir, caCertFile)
+	keyPath := filepath.Join(dir, caKeyFile)
+
+	if _, err := os.Stat(certPath); os.IsNotExist(err) {
+		if err := createCA(dir, certPath, keyPath); err != nil {
+			return fmt.Errorf("failed to create agent CA: %v", err)
+		}
+	}
+
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return err
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return err
+	}
+
+	cert, err := parseCertificate(certPEM)
+	if err != nil {
+		return fmt.Errorf("%s: %v", certPath, err)
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return fmt.Errorf("%s: no PEM data", keyPath)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("%s: %v", keyPath, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return fmt.Errorf("%s: unsupported key type", keyPath)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	caMu.Lock()
+	ca = &authority{cert: cert, certPEM: string(certPEM), key: signer, pool: pool}
+	caMu.Unlock()
+	return nil
+}
+
+// Enabled reports whether Configure loaded a CA
+func Enabled() bool {
+	caMu.RLock()
+	defer caMu.RUnlock()
+	return ca != nil
+}
+
+// CACertificate returns the CA certificate agents use to verify the backend
+func CACertificate() string {
+	caMu.RLock()
+	defer caMu.RUnlock()
+	if ca == nil {
+		return ""
+	}
+	return ca.certPEM
+}
+
+// SignAgentCSR issues an agent server certificate for the key in csrPEM and
+// returns it with the fingerprint the backend pins
+func SignAgentCSR(csrPEM, ip string) (certPEM, fingerprint string, notAfter time.Time, err error) {
+	a, err := current()
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	block, _ := pem.Decode([]byte(csrPEM))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return "", "", time.Time{}, errors.New("invalid certificate request")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return "", "", time.Time{}, fmt.Errorf("certificate request signature: %v", err)
+	}
+
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: ip},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if parsed := net.ParseIP(ip); parsed != nil {
+		template.IPAddresses = []net.IP{parsed}
+	}
+
+	der, notAfter, err := a.sign(template, csr.PublicKey, agentValidity)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	certPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	return certPEM, Fingerprint(der), notAfter, nil
+}
+
+// ClientCertificate returns the certificate the backend presents to agents.
+// It is kept in memory only and reissued before it expires.
+func ClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	a, err := current()
+	if err != nil {
+		return nil, err
+	}
+
+	a.clientMu.Lock()
+	defer a.clientMu.Unlock()
+
+	if a.client != nil && time.Until(a.client.Leaf.NotAfter) > clientRenewal {
+		return a.client, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "sms-backend"},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, _, err := a.sign(template, key.Public(), clientValidity)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	a.client = &tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+	return a.client, nil
+}
+
+// VerifyAgent checks the certificate an agent presented: it must be an agent
+// certificate from this CA and match the fingerprint pinned for the device
+func VerifyAgent(rawCerts [][]byte, fingerprint string) error {
+	a, err := current()
+	if err != nil {
+		return err
+	}
+	if len(rawCerts) == 0 {
+		return errors.New("agent presented no certificate")
+	}
+	if Fingerprint(rawCerts[0]) != fingerprint {
+		return errors.New("agent certificate does not match the pinned fingerprint")
+	}
+
+	leaf, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return err
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:     a.pool,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	return err
+}
+
+// VerifyAgentSignature checks that signature over message was made with the
+// key of the agent certificate certDER, which VerifyAgent must accept
+func VerifyAgentSignature(certDER []byte, fingerprint string, message, signature []byte) error {
+	if err := VerifyAgent([][]byte{certDER}, fingerprint); err != nil {
+		return err
+	}
+	leaf, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return err
+	}
+	key, ok := leaf.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return errors.New("unsupported agent key type")
+	}
+	digest := sha256.Sum256(message)
+	if !ecdsa.VerifyASN1(key, digest[:], signature) {
+		return errors.New("invalid agent signature")
+	}
+	return nil
+}
+
+// Fingerprint is the hex SHA-256 of a DER certificate
+func Fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+func current() (*authority, error) {
+	caMu.RLock()
+	defer caMu.RUnlock()
+	if ca == nil {
+		return nil, errors.New("agent CA is not configured")
+	}
+	return ca, nil
+}
+
+func (a *authority) sign(template *x509.Certificate, pub crypto.PublicKey, validity time.Duration) ([]byte, time.Time, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	now := time.Now()
+	template.SerialNumber = serial
+	template.NotBefore = now.Add(-5 * time.Minute) // Tolerate clock skew
+	template.NotAfter = now.Add(validity)
+	if template.NotAfter.After(a.cert.NotAfter) {
+		template.NotAfter = a.cert.NotAfter
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, pub, a.key)
+	return der, template.NotAfter, err
+}
+
+func createCA(dir, certPath, keyPath string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "SMS Agent CA"},
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+}
+
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no certificate PEM data")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
@@ -0,0 +1,211 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"testing"
+	"time"
+)
+
+// useCA configures a fresh CA for the test and drops it afterwards
+func useCA(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := Configure(dir); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	t.Cleanup(func() {
+		caMu.Lock()
+		ca = nil
+		caMu.Unlock()
+	})
+	return dir
+}
+
+// agentCert signs a certificate for a new agent key
+func agentCert(t *testing.T, ip string) (*ecdsa.PrivateKey, []byte, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM, fingerprint, _, err := SignAgentCSR(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr})), ip)
+	if err != nil {
+		t.Fatalf("SignAgentCSR: %v", err)
+	}
+	block, _ := pem.Decode([]byte(certPEM))
+	return key, block.Bytes, fingerprint
+}
+
+func TestConfigureKeepsTheCA(t *testing.T) {
+	dir := useCA(t)
+	if !Enabled() {
+		t.Fatal("CA not enabled after Configure")
+	}
+	first := CACertificate()
+
+	if err := Configure(dir); err != nil {
+		t.Fatalf("second Configure: %v", err)
+	}
+	if CACertificate() != first {
+		t.Error("a second start created a new CA")
+	}
+}
+
+func TestNotConfigured(t *testing.T) {
+	caMu.Lock()
+	previous := ca
+	ca = nil
+	caMu.Unlock()
+	t.Cleanup(func() {
+		caMu.Lock()
+		ca = previous
+		caMu.Unlock()
+	})
+
+	if Enabled() || CACertificate() != "" {
+		t.Error("CA reported without Configure")
+	}
+	if _, _, _, err := SignAgentCSR("", "10.0.0.1"); err == nil {
+		t.Error("SignAgentCSR worked without a CA")
+	}
+	if err := VerifyAgent([][]byte{{1}}, ""); err == nil {
+		t.Error("VerifyAgent accepted a certificate without a CA")
+	}
+}
+
+func TestSignAgentCSR(t *testing.T) {
+	useCA(t)
+	_, der, fingerprint := agentCert(t, "10.0.0.5")
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fingerprint != Fingerprint(der) || len(fingerprint) != 64 {
+		t.Errorf("fingerprint = %q", fingerprint)
+	}
+	if len(cert.IPAddresses) != 1 || !cert.IPAddresses[0].Equal(net.ParseIP("10.0.0.5")) || cert.Subject.CommonName != "10.0.0.5" {
+		t.Errorf("certificate names %s %v", cert.Subject.CommonName, cert.IPAddresses)
+	}
+	if len(cert.ExtKeyUsage) != 1 || cert.ExtKeyUsage[0] != x509.ExtKeyUsageServerAuth {
+		t.Errorf("ext key usage = %v, want server auth only", cert.ExtKeyUsage)
+	}
+	if validity := time.Until(cert.NotAfter); validity < agentValidity-time.Hour || validity > agentValidity {
+		t.Errorf("certificate valid for %s", validity)
+	}
+}
+
+func TestSignAgentCSRRejectsRequests(t *testing.T) {
+	useCA(t)
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	csr, _ := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, key)
+	tampered := append([]byte(nil), csr...)
+	tampered[len(tampered)-1] ^= 0xff
+
+	tests := []struct {
+		name string
+		pem  string
+	}{
+		{"not PEM", "csr"},
+		{"wrong block type", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: csr}))},
+		{"bad signature", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: tampered}))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, err := SignAgentCSR(tt.pem, "10.0.0.5"); err == nil {
+				t.Error("SignAgentCSR accepted the request")
+			}
+		})
+	}
+}
+
+func TestVerifyAgent(t *testing.T) {
+	useCA(t)
+	_, der, fingerprint := agentCert(t, "10.0.0.5")
+	_, otherDER, otherFingerprint := agentCert(t, "10.0.0.6")
+
+	if err := VerifyAgent([][]byte{der}, fingerprint); err != nil {
+		t.Errorf("VerifyAgent rejected its certificate: %v", err)
+	}
+	if err := VerifyAgent(nil, fingerprint); err == nil {
+		t.Error("VerifyAgent accepted no certificate")
+	}
+	// A valid certificate of another device is not enough
+	if err := VerifyAgent([][]byte{otherDER}, fingerprint); err == nil {
+		t.Error("VerifyAgent accepted another device's certificate")
+	}
+
+	// The backend's own client certificate is from the CA but not for agents
+	client, err := ClientCertificate(nil)
+	if err != nil {
+		t.Fatalf("ClientCertificate: %v", err)
+	}
+	clientDER := client.Certificate[0]
+	if err := VerifyAgent([][]byte{clientDER}, Fingerprint(clientDER)); err == nil {
+		t.Error("VerifyAgent accepted a client certificate")
+	}
+
+	// Pinning does not help a certificate from another CA
+	useCA(t)
+	if err := VerifyAgent([][]byte{otherDER}, otherFingerprint); err == nil {
+		t.Error("VerifyAgent accepted a certificate from another CA")
+	}
+}
+
+func TestVerifyAgentSignature(t *testing.T) {
+	useCA(t)
+	key, der, fingerprint := agentCert(t, "10.0.0.5")
+	otherKey, _, otherFingerprint := agentCert(t, "10.0.0.6")
+
+	sign := func(key *ecdsa.PrivateKey, message string) []byte {
+		digest := sha256.Sum256([]byte(message))
+		signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signature
+	}
+
+	if err := VerifyAgentSignature(der, fingerprint, []byte("hello"), sign(key, "hello")); err != nil {
+		t.Errorf("valid signature rejected: %v", err)
+	}
+	if err := VerifyAgentSignature(der, fingerprint, []byte("hello"), sign(key, "goodbye")); err == nil {
+		t.Error("signature over another message accepted")
+	}
+	if err := VerifyAgentSignature(der, fingerprint, []byte("hello"), sign(otherKey, "hello")); err == nil {
+		t.Error("signature by another key accepted")
+	}
+	if err := VerifyAgentSignature(der, otherFingerprint, []byte("hello"), sign(key, "hello")); err == nil {
+		t.Error("signature accepted for another pinned fingerprint")
+	}
+}
+
+func TestClientCertificateIsReused(t *testing.T) {
+	useCA(t)
+	first, err := ClientCertificate(nil)
+	if err != nil {
+		t.Fatalf("ClientCertificate: %v", err)
+	}
+	if second, _ := ClientCertificate(nil); second != first {
+		t.Error("client certificate reissued while still valid")
+	}
+
+	// Close to expiry it is replaced
+	ca.clientMu.Lock()
+	first.Leaf.NotAfter = time.Now().Add(clientRenewal / 2)
+	ca.clientMu.Unlock()
+	if third, _ := ClientCertificate(nil); third == first {
+		t.Error("client certificate not reissued before it expires")
+	}
+}
@@ -3,18 +3,24 @@ package routine
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/kishore-001/ServerManagementSuite/backend/config"
 	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
 	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/devicestatus"
 )
 
+// errUnreachable marks health checks that never got an answer from the agent
+var errUnreachable = errors.New("network error")
+
 // ✅ Updated type definitions with float64 for better JSON compatibility
 type AlertRule struct {
 	CPUThreshold  float64
@@ -107,7 +113,8 @@ func NewHealthMonitor(queries *serverdb.Queries, generalQueries *generaldb.Queri
 			CheckInterval: 30 * time.Second,
 		},
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Transport: config.AgentTransport,
+			Timeout:   10 * time.Second,
 		},
 		stopChan:    make(chan bool),
 		lastAlerts:  make(map[string]map[string]time.Time),
@@ -172,6 +179,7 @@ func (hm *HealthMonitor) cleanupLoop() {
 		select {
 		case <-ticker.C:
 			hm.cleanupOldSuppressionData()
+			devicestatus.Prune(context.Background(), hm.queries)
 		case <-hm.stopChan:
 			return
 		}
@@ -193,6 +201,13 @@ func (hm *HealthMonitor) checkAllDevices() {
 func (hm *HealthMonitor) checkDeviceHealth(host, accessToken string) {
 	healthData, err := hm.getHealthData(host, accessToken)
 	if err != nil {
+		// An agent that answers with an error is still up
+		status := devicestatus.StatusDegraded
+		if errors.Is(err, errUnreachable) {
+			status = devicestatus.StatusOffline
+		}
+		hm.recordStatus(host, status, err.Error())
+
 		// Handle connectivity alert with suppression
 		hm.handleConnectivityAlert(host, err)
 		return
@@ -201,6 +216,12 @@ func (hm *HealthMonitor) checkDeviceHealth(host, accessToken string) {
 	// Device is reachable - reset connectivity alert count
 	hm.resetAlertCount(host, "connectivity")
 
+	if reason := hm.degradedReason(healthData); reason != "" {
+		hm.recordStatus(host, devicestatus.StatusDegraded, reason)
+	} else {
+		hm.recordStatus(host, devicestatus.StatusOnline, "")
+	}
+
 	// Check for health-based alerts
 	hm.evaluateHealthRules(host, healthData)
 }
@@ -219,7 +240,7 @@ func (hm *HealthMonitor) getHealthData(host, accessToken string) (*HealthRespons
 
 	resp, err := hm.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("network error: %v", err)
+		return nil, fmt.Errorf("%w: %v", errUnreachable, err)
 	}
 	defer resp.Body.Close()
 
@@ -241,6 +262,27 @@ func (hm *HealthMonitor) getHealthData(host, accessToken string) (*HealthRespons
 	return &healthData, nil
 }
 
+func (hm *HealthMonitor) recordStatus(host, status, reason string) {
+	if err := devicestatus.Record(context.Background(), hm.queries, host, status, reason); err != nil {
+		log.Printf("❌ Failed to record status of %s: %v", host, err)
+	}
+}
+
+// degradedReason lists the metrics over their threshold, empty when healthy
+func (hm *HealthMonitor) degradedReason(health *HealthResponse) string {
+	var reasons []string
+	if health.CPU.UsagePercent > hm.rules.CPUThreshold {
+		reasons = append(reasons, fmt.Sprintf("CPU %.2f%% over %.2f%%", health.CPU.UsagePercent, hm.rules.CPUThreshold))
+	}
+	if health.RAM.UsagePercent > hm.rules.RAMThreshold {
+		reasons = append(reasons, fmt.Sprintf("RAM %.2f%% over %.2f%%", health.RAM.UsagePercent, hm.rules.RAMThreshold))
+	}
+	if health.Disk.UsagePercent > hm.rules.DiskThreshold {
+		reasons = append(reasons, fmt.Sprintf("Disk %.2f%% over %.2f%%", health.Disk.UsagePercent, hm.rules.DiskThreshold))
+	}
+	return strings.Join(reasons, ", ")
+}
+
 func (hm *HealthMonitor) handleConnectivityAlert(host string, err error) {
 	alertType := "connectivity"
 
@@ -0,0 +1,106 @@
+// routine/job_runner.go
+package routine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/jobs"
+)
+
+// JobRunner runs queued jobs with JOB_WORKERS workers. Jobs are claimed from
+// the database, so several backend instances can share the queue.
+type JobRunner struct {
+	queries   *serverdb.Queries
+	stopChan  chan bool
+	isRunning bool
+
+	workers         int
+	name            string // Identifies this instance on the jobs it runs
+	pollInterval    time.Duration
+	cleanupInterval time.Duration
+}
+
+func NewJobRunner(queries *serverdb.Queries) *JobRunner {
+	hostname, _ := os.Hostname()
+	return &JobRunner{
+		queries:         queries,
+		stopChan:        make(chan bool),
+		workers:         config.AppConfig.JobWorkers,
+		name:            fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		pollInterval:    2 * time.Second,
+		cleanupInterval: time.Hour,
+	}
+}
+
+func (jr *JobRunner) Start() {
+	if jr.isRunning {
+		return
+	}
+
+	jr.isRunning = true
+	log.Printf("⚙️ Job Runner started (%d workers)", jr.workers)
+
+	for i := 0; i < jr.workers; i++ {
+		go jr.workLoop()
+	}
+	go jr.cleanupLoop()
+}
+
+func (jr *JobRunner) Stop() {
+	if !jr.isRunning {
+		return
+	}
+
+	// Jobs in progress are left to be taken over once their lease runs out
+	close(jr.stopChan)
+	jr.isRunning = false
+	log.Println("⏹️ Job Runner stopped")
+}
+
+func (jr *JobRunner) workLoop() {
+	ticker := time.NewTicker(jr.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			jr.runQueued()
+		case <-jr.stopChan:
+			return
+		}
+	}
+}
+
+// runQueued runs jobs until the queue is empty
+func (jr *JobRunner) runQueued() {
+	for {
+		ran, err := jobs.RunNext(context.Background(), jr.queries, jr.name)
+		if err != nil {
+			log.Printf("❌ Job worker error: %v", err)
+			return
+		}
+		if !ran {
+			return
+		}
+	}
+}
+
+func (jr *JobRunner) cleanupLoop() {
+	ticker := time.NewTicker(jr.cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			jobs.DeleteExpired(context.Background(), jr.queries)
+		case <-jr.stopChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,293 @@
+// routine/mac_monitor.go
+package routine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/mac"
+)
+
+// Neighbor is one entry of an agent's ARP / neighbor table
+type Neighbor struct {
+	IP        string `json:"ip"`
+	MAC       string `json:"mac"`
+	Interface string `json:"interface"`
+	State     string `json:"state"`
+}
+
+type neighborResponse struct {
+	Status    string     `json:"status"`
+	Neighbors []Neighbor `json:"neighbors"`
+}
+
+// MACMonitor collects the MACs each agent sees, alerts on blacklisted or
+// unknown ones and pushes the blocklist to the agents
+type MACMonitor struct {
+	queries        *serverdb.Queries
+	generalQueries *generaldb.Queries
+	client         *http.Client
+	stopChan       chan bool
+	isRunning      bool
+
+	checkInterval time.Duration
+	realertAfter  time.Duration // Repeat alerts for a MAC still present after this long
+	resyncAfter   time.Duration // Push an unchanged blocklist again after this long
+
+	// Last blocklist pushed to each host
+	synced   map[string]blocklistSync
+	syncedMu sync.Mutex
+
+	emailService *EmailService
+}
+
+type blocklistSync struct {
+	digest string
+	at     time.Time
+}
+
+func NewMACMonitor(queries *serverdb.Queries, generalQueries *generaldb.Queries) *MACMonitor {
+	return &MACMonitor{
+		queries:        queries,
+		generalQueries: generalQueries,
+		client: &http.Client{
+			Transport: config.AgentTransport,
+			Timeout:   10 * time.Second,
+		},
+		stopChan:      make(chan bool),
+		checkInterval: 60 * time.Second,
+		realertAfter:  1 * time.Hour,
+		resyncAfter:   10 * time.Minute,
+		synced:        make(map[string]blocklistSync),
+		emailService:  NewEmailService(generalQueries),
+	}
+}
+
+func (mm *MACMonitor) Start() {
+	if mm.isRunning {
+		return
+	}
+
+	mm.isRunning = true
+	log.Println("🔍 MAC Monitor started")
+
+	go mm.monitorLoop()
+}
+
+func (mm *MACMonitor) Stop() {
+	if !mm.isRunning {
+		return
+	}
+
+	mm.stopChan <- true
+	mm.isRunning = false
+	log.Println("⏹️ MAC Monitor stopped")
+}
+
+func (mm *MACMonitor) monitorLoop() {
+	ticker := time.NewTicker(mm.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			mm.checkAllDevices()
+		case <-mm.stopChan:
+			return
+		}
+	}
+}
+
+func (mm *MACMonitor) checkAllDevices() {
+	ctx := context.Background()
+
+	devices, err := mm.queries.GetAllServerDevices(ctx)
+	if err != nil {
+		log.Printf("❌ Failed to get devices: %v", err)
+		return
+	}
+
+	// The lists and segments are read once per round and shared by all devices
+	entries, err := mm.generalQueries.ListActiveMACEntries(ctx)
+	if err != nil {
+		log.Printf("❌ Failed to get MAC entries: %v", err)
+		return
+	}
+	segments, err := mac.LoadMonitoredSegments(ctx, mm.generalQueries)
+	if err != nil {
+		log.Printf("❌ Failed to load monitored segments: %v", err)
+	}
+
+	statusByMAC := make(map[string]string, len(entries))
+	blocklist := []string{}
+	for _, e := range entries {
+		statusByMAC[e.Mac] = e.Status
+		if e.Status == mac.StatusBlacklisted && e.Block {
+			blocklist = append(blocklist, e.Mac)
+		}
+	}
+	sort.Strings(blocklist)
+
+	for _, device := range devices {
+		go mm.checkDevice(device.Ip, device.AccessToken, statusByMAC, segments, blocklist)
+	}
+}
+
+func (mm *MACMonitor) checkDevice(host, accessToken string, statusByMAC map[string]string, segments []*net.IPNet, blocklist []string) {
+	mm.syncBlocklist(host, accessToken, blocklist)
+
+	neighbors, err := mm.getNeighbors(host, accessToken)
+	if err != nil {
+		// Reachability is reported by the health monitor
+		log.Printf("⚠️ MAC check skipped for %s: %v", host, err)
+		return
+	}
+
+	for _, n := range neighbors {
+		address, err := mac.NormalizeMAC(n.MAC)
+		if err != nil || address == "00:00:00:00:00:00" {
+			continue // Incomplete or failed neighbor entries
+		}
+
+		alertedAt, err := mm.generalQueries.UpsertMACSighting(context.Background(), generaldb.UpsertMACSightingParams{
+			Mac:       address,
+			Host:      host,
+			Ip:        n.IP,
+			Interface: n.Interface,
+		})
+		if err != nil {
+			log.Printf("❌ Failed to record MAC sighting %s on %s: %v", address, host, err)
+			continue
+		}
+
+		var severity, content string
+		switch {
+		case statusByMAC[address] == mac.StatusBlacklisted:
+			severity = "critical"
+			content = fmt.Sprintf("Blacklisted MAC %s seen at %s on interface %s", address, n.IP, n.Interface)
+		case statusByMAC[address] != mac.StatusWhitelisted && mac.InSegments(n.IP, segments):
+			severity = "warning"
+			content = fmt.Sprintf("Unknown MAC %s seen at %s on monitored segment (interface %s)", address, n.IP, n.Interface)
+		default:
+			continue
+		}
+
+		if alertedAt.Valid && time.Since(alertedAt.Time) < mm.realertAfter {
+			continue
+		}
+		mm.createAlert(host, address, severity, content)
+	}
+}
+
+func (mm *MACMonitor) createAlert(host, address, severity, content string) {
+	_, err := mm.queries.CreateAlert(context.Background(), serverdb.CreateAlertParams{
+		Host:     host,
+		Severity: severity,
+		Content:  content,
+	})
+	if err != nil {
+		log.Printf("❌ Failed to create MAC alert for %s: %v", host, err)
+		return
+	}
+
+	err = mm.generalQueries.MarkMACSightingAlerted(context.Background(), generaldb.MarkMACSightingAlertedParams{
+		Mac:  address,
+		Host: host,
+	})
+	if err != nil {
+		log.Printf("❌ Failed to mark MAC sighting %s on %s: %v", address, host, err)
+	}
+
+	log.Printf("🚨 Alert created for %s [%s]: %s", host, severity, content)
+
+	go func() {
+		if err := mm.emailService.SendAlertEmail(host, severity, content); err != nil {
+			log.Printf("❌ Failed to send email for alert: %v", err)
+		}
+	}()
+}
+
+func (mm *MACMonitor) getNeighbors(host, accessToken string) ([]Neighbor, error) {
+	url := config.GetClientURL(host, "/client/config2/neighbors")
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := mm.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("network error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("client returned status %d", resp.StatusCode)
+	}
+
+	var neighbors neighborResponse
+	if err := json.NewDecoder(resp.Body).Decode(&neighbors); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %v", err)
+	}
+	return neighbors.Neighbors, nil
+}
+
+// syncBlocklist pushes the blocked MACs to an agent when the list changed or
+// the last push is older than resyncAfter, so rebooted agents catch up
+func (mm *MACMonitor) syncBlocklist(host, accessToken string, blocklist []string) {
+	digest := strings.Join(blocklist, ",")
+
+	mm.syncedMu.Lock()
+	last, ok := mm.synced[host]
+	mm.syncedMu.Unlock()
+	if ok && last.digest == digest && time.Since(last.at) < mm.resyncAfter {
+		return
+	}
+
+	body, _ := json.Marshal(map[string][]string{"macs": blocklist})
+	url := config.GetClientURL(host, "/client/config2/macblock")
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("❌ Failed to create blocklist request for %s: %v", host, err)
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := mm.client.Do(req)
+	if err != nil {
+		log.Printf("⚠️ Failed to push MAC blocklist to %s: %v", host, err)
+		return
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if !ok || last.digest != digest {
+			log.Printf("🛡️ MAC blocklist (%d entries) pushed to %s", len(blocklist), host)
+		}
+	case http.StatusNotImplemented:
+		// The agent's OS cannot filter by MAC; remember it so we don't retry every round
+	default:
+		log.Printf("⚠️ Agent %s rejected MAC blocklist with status %d", host, resp.StatusCode)
+		return
+	}
+
+	mm.syncedMu.Lock()
+	mm.synced[host] = blocklistSync{digest: digest, at: time.Now()}
+	mm.syncedMu.Unlock()
+}
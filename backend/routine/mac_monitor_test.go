@@ -0,0 +1,95 @@
+package routine
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+)
+
+// blocklistAgent stands in for an agent's macblock endpoint, answering
+// with the status held in status
+func blocklistAgent(t *testing.T, status *atomic.Int32, pushed *[][]string) (*MACMonitor, string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			MACs []string `json:"macs"`
+		}
+		if r.URL.Path != "/client/config2/macblock" || r.Header.Get("Authorization") != "Bearer token" ||
+			json.NewDecoder(r.Body).Decode(&body) != nil {
+			t.Errorf("unexpected push to %s", r.URL.Path)
+		}
+		*pushed = append(*pushed, body.MACs)
+		w.WriteHeader(int(status.Load()))
+	}))
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := config.AppConfig
+	config.AppConfig = &config.AppConfiguration{ClientPort: port, ClientProtocol: "http"}
+	t.Cleanup(func() {
+		server.Close()
+		config.AppConfig = previous
+	})
+
+	mm := &MACMonitor{
+		client:      server.Client(),
+		resyncAfter: time.Hour,
+		synced:      make(map[string]blocklistSync),
+	}
+	return mm, host
+}
+
+func TestSyncBlocklistPushesChanges(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	var pushed [][]string
+	mm, host := blocklistAgent(t, &status, &pushed)
+
+	first := []string{"aa:bb:cc:dd:ee:ff"}
+	mm.syncBlocklist(host, "token", first)
+	mm.syncBlocklist(host, "token", first)
+	second := []string{"aa:bb:cc:dd:ee:ff", "11:22:33:44:55:66"}
+	mm.syncBlocklist(host, "token", second)
+
+	if want := [][]string{first, second}; !reflect.DeepEqual(pushed, want) {
+		t.Errorf("pushed %v, want %v", pushed, want)
+	}
+
+	// An unchanged list is pushed again once resyncAfter has passed
+	mm.synced[host] = blocklistSync{digest: mm.synced[host].digest, at: time.Now().Add(-2 * time.Hour)}
+	mm.syncBlocklist(host, "token", second)
+	if len(pushed) != 3 {
+		t.Errorf("%d pushes after resyncAfter, want 3", len(pushed))
+	}
+}
+
+func TestSyncBlocklistRetriesRejections(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	var pushed [][]string
+	mm, host := blocklistAgent(t, &status, &pushed)
+
+	list := []string{"aa:bb:cc:dd:ee:ff"}
+	mm.syncBlocklist(host, "token", list)
+	mm.syncBlocklist(host, "token", list)
+	if len(pushed) != 2 {
+		t.Errorf("%d pushes after a rejection, want 2", len(pushed))
+	}
+
+	// Agents that cannot block by MAC are not asked again every round
+	status.Store(http.StatusNotImplemented)
+	mm.syncBlocklist(host, "token", list)
+	mm.syncBlocklist(host, "token", list)
+	if len(pushed) != 3 {
+		t.Errorf("%d pushes to an agent without MAC blocking, want 3", len(pushed))
+	}
+}
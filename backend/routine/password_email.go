@@ -0,0 +1,77 @@
+// routine/password_email.go
+package routine
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/auth"
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	"gopkg.in/gomail.v2"
+)
+
+// SendPasswordResetEmail sends a reset link to the account's own address.
+// It is registered with auth.SetPasswordResetMailer.
+func (es *EmailService) SendPasswordResetEmail(mail auth.PasswordResetMail) error {
+	// Check if SMTP is configured
+	if config.AppConfig.SMTPUsername == "" || config.AppConfig.SMTPPassword == "" {
+		log.Printf("📧 SMTP not configured, cannot send password reset email to %s", mail.Username)
+		return nil
+	}
+
+	link := resetLink(mail.Token)
+	expires := mail.ExpiresAt.Format("2006-01-02 15:04:05")
+
+	message := gomail.NewMessage()
+	message.SetHeader("From", config.AppConfig.SMTPFrom)
+	message.SetHeader("To", mail.Email)
+	message.SetHeader("Subject", "[SNSMS] Password reset")
+
+	message.SetBody("text/plain", fmt.Sprintf(`
+Hello %s,
+
+A password reset was requested for your SNSMS account.
+
+Reset your password here:
+%s
+
+The link can be used once and expires at %s. Resetting signs you out of
+all sessions. If you did not request this, you can ignore this email.
+
+SNSMS - Server Network Management Suite
+`, mail.Username, link, expires))
+
+	message.AddAlternative("text/html", fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h2 style="background: #0f172a; color: white; padding: 20px; border-radius: 8px 8px 0 0; margin: 0;">Password reset</h2>
+        <div style="background: #f8f9fa; padding: 20px; border: 1px solid #dee2e6;">
+            <p>Hello %s,</p>
+            <p>A password reset was requested for your SNSMS account.</p>
+            <p><a href="%s" style="background: #0f172a; color: white; padding: 10px 16px; border-radius: 4px; text-decoration: none;">Reset password</a></p>
+            <p>The link can be used once and expires at %s. Resetting signs you out of all sessions.
+            If you did not request this, you can ignore this email.</p>
+        </div>
+    </div>
+</body>
+</html>`, mail.Username, link, expires))
+
+	if err := es.dialer.DialAndSend(message); err != nil {
+		return err
+	}
+
+	log.Printf("📧 Password reset email sent to %s", mail.Username)
+	return nil
+}
+
+// resetLink points at the frontend reset page, or is just the token when no
+// page is configured
+func resetLink(token string) string {
+	if config.AppConfig.PasswordResetURL == "" {
+		return "Reset token: " + token
+	}
+	return config.AppConfig.PasswordResetURL + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,51 @@
+// routine/security_alerts.go
+package routine
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/auth"
+	generaldb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/general"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+)
+
+// SecurityAlerter turns authentication events into alerts and admin emails
+type SecurityAlerter struct {
+	queries      *serverdb.Queries
+	emailService *EmailService
+}
+
+func NewSecurityAlerter(queries *serverdb.Queries, generalQueries *generaldb.Queries) *SecurityAlerter {
+	return &SecurityAlerter{
+		queries:      queries,
+		emailService: NewEmailService(generalQueries),
+	}
+}
+
+// HandleLockout records a lockout in the alerts table (keyed by the source
+// IP) and emails the admins
+func (sa *SecurityAlerter) HandleLockout(event auth.LockoutEvent) {
+	var content string
+	if event.Scope == auth.LockoutScopeIP {
+		content = fmt.Sprintf("Login blocked for source IP %s after %d failed attempts (last username tried: %s), locked until %s",
+			event.IP, event.Failures, event.Username, event.LockedUntil.Format("2006-01-02 15:04:05"))
+	} else {
+		content = fmt.Sprintf("Account '%s' locked after %d failed login attempts (last attempt from %s), locked until %s",
+			event.Username, event.Failures, event.IP, event.LockedUntil.Format("2006-01-02 15:04:05"))
+	}
+
+	_, err := sa.queries.CreateAlert(context.Background(), serverdb.CreateAlertParams{
+		Host:     event.IP,
+		Severity: "critical",
+		Content:  content,
+	})
+	if err != nil {
+		log.Printf("❌ Failed to create lockout alert: %v", err)
+	}
+
+	if err := sa.emailService.SendAlertEmail(event.IP, "critical", content); err != nil {
+		log.Printf("❌ Failed to send lockout email: %v", err)
+	}
+}
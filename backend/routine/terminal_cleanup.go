@@ -0,0 +1,64 @@
+// routine/terminal_cleanup.go
+package routine
+
+import (
+	"context"
+	"log"
+	"time"
+
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/terminal"
+)
+
+// TerminalCleaner closes terminal sessions left open by a backend instance
+// that stopped, expires unused tickets and deletes old sessions
+type TerminalCleaner struct {
+	queries   *serverdb.Queries
+	stopChan  chan bool
+	isRunning bool
+
+	checkInterval time.Duration
+}
+
+func NewTerminalCleaner(queries *serverdb.Queries) *TerminalCleaner {
+	return &TerminalCleaner{
+		queries:       queries,
+		stopChan:      make(chan bool),
+		checkInterval: 5 * time.Minute,
+	}
+}
+
+func (tc *TerminalCleaner) Start() {
+	if tc.isRunning {
+		return
+	}
+
+	tc.isRunning = true
+	log.Println("🖥️ Terminal Cleaner started")
+
+	go tc.cleanupLoop()
+}
+
+func (tc *TerminalCleaner) Stop() {
+	if !tc.isRunning {
+		return
+	}
+
+	tc.stopChan <- true
+	tc.isRunning = false
+	log.Println("⏹️ Terminal Cleaner stopped")
+}
+
+func (tc *TerminalCleaner) cleanupLoop() {
+	ticker := time.NewTicker(tc.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			terminal.Cleanup(context.Background(), tc.queries)
+		case <-tc.stopChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// routine/tls_provisioner.go
+package routine
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/agenttls"
+	"github.com/kishore-001/ServerManagementSuite/backend/pki"
+)
+
+// TLSProvisioner moves plain-HTTP agents to mutual TLS as soon as they run a
+// version that supports it, and renews certificates before they expire.
+// Reverse-connected agents get one through their tunnel to sign later tunnel
+// requests with.
+type TLSProvisioner struct {
+	queries   *serverdb.Queries
+	stopChan  chan bool
+	isRunning bool
+
+	checkInterval time.Duration
+	renewBefore   time.Duration
+}
+
+func NewTLSProvisioner(queries *serverdb.Queries) *TLSProvisioner {
+	return &TLSProvisioner{
+		queries:       queries,
+		stopChan:      make(chan bool),
+		checkInterval: 10 * time.Minute,
+		renewBefore:   30 * 24 * time.Hour,
+	}
+}
+
+func (tp *TLSProvisioner) Start() {
+	if tp.isRunning || !pki.Enabled() {
+		return
+	}
+
+	tp.isRunning = true
+	log.Println("🔐 TLS Provisioner started")
+
+	go tp.provisionLoop()
+}
+
+func (tp *TLSProvisioner) Stop() {
+	if !tp.isRunning {
+		return
+	}
+
+	tp.stopChan <- true
+	tp.isRunning = false
+	log.Println("⏹️ TLS Provisioner stopped")
+}
+
+func (tp *TLSProvisioner) provisionLoop() {
+	ticker := time.NewTicker(tp.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			tp.provisionDevices()
+		case <-tp.stopChan:
+			return
+		}
+	}
+}
+
+func (tp *TLSProvisioner) provisionDevices() {
+	ctx := context.Background()
+
+	devices, err := tp.queries.ListDevicesForTLSProvisioning(ctx, sql.NullTime{
+		Time:  time.Now().Add(tp.renewBefore),
+		Valid: true,
+	})
+	if err != nil {
+		log.Printf("❌ Failed to get devices for TLS provisioning: %v", err)
+		return
+	}
+
+	for _, device := range devices {
+		err := agenttls.Provision(ctx, tp.queries, device.Ip, device.AccessToken)
+		if errors.Is(err, agenttls.ErrUnsupported) {
+			continue // Older agent, it keeps working over plain HTTP
+		}
+		if err != nil {
+			log.Printf("⚠️ TLS provisioning for %s not completed: %v", device.Ip, err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// routine/token_rotation.go
+package routine
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/kishore-001/ServerManagementSuite/backend/config"
+	serverdb "github.com/kishore-001/ServerManagementSuite/backend/db/gen/server"
+	"github.com/kishore-001/ServerManagementSuite/backend/logic/server/devicetoken"
+)
+
+// TokenRotator rotates device access tokens older than
+// DEVICE_TOKEN_ROTATION_DAYS and finishes rotations left unconfirmed
+type TokenRotator struct {
+	queries   *serverdb.Queries
+	stopChan  chan bool
+	isRunning bool
+
+	checkInterval time.Duration
+	maxAge        time.Duration // 0 only finishes pending rotations
+	overlap       time.Duration
+}
+
+func NewTokenRotator(queries *serverdb.Queries) *TokenRotator {
+	return &TokenRotator{
+		queries:       queries,
+		stopChan:      make(chan bool),
+		checkInterval: 5 * time.Minute,
+		maxAge:        time.Duration(config.AppConfig.DeviceTokenRotationDays) * 24 * time.Hour,
+		overlap:       time.Duration(config.AppConfig.DeviceTokenOverlapMinutes) * time.Minute,
+	}
+}
+
+func (tr *TokenRotator) Start() {
+	if tr.isRunning {
+		return
+	}
+
+	tr.isRunning = true
+	if tr.maxAge > 0 {
+		log.Printf("🔑 Token Rotator started (every %d days)", config.AppConfig.DeviceTokenRotationDays)
+	} else {
+		log.Println("🔑 Token Rotator started (scheduled rotation disabled)")
+	}
+
+	go tr.rotationLoop()
+}
+
+func (tr *TokenRotator) Stop() {
+	if !tr.isRunning {
+		return
+	}
+
+	tr.stopChan <- true
+	tr.isRunning = false
+	log.Println("⏹️ Token Rotator stopped")
+}
+
+func (tr *TokenRotator) rotationLoop() {
+	ticker := time.NewTicker(tr.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			tr.rotateDueDevices()
+		case <-tr.stopChan:
+			return
+		}
+	}
+}
+
+func (tr *TokenRotator) rotateDueDevices() {
+	ctx := context.Background()
+
+	// The zero cutoff matches no device, leaving only pending rotations
+	var cutoff time.Time
+	if tr.maxAge > 0 {
+		cutoff = time.Now().Add(-tr.maxAge)
+	}
+
+	devices, err := tr.queries.ListDevicesForRotation(ctx, cutoff)
+	if err != nil {
+		log.Printf("❌ Failed to get devices due for token rotation: %v", err)
+		return
+	}
+
+	// Sequential on purpose: rotations are rare and each holds the device lock
+	for _, device := range devices {
+		if device.PendingToken.Valid {
+			err = devicetoken.Resume(ctx, tr.queries, device.Ip, device.PendingToken.String)
+		} else {
+			err = devicetoken.Rotate(ctx, tr.queries, device.Ip, tr.overlap)
+		}
+		if err != nil {
+			// Unreachable agents are retried on the next round
+			log.Printf("⚠️ Token rotation for %s not completed: %v", device.Ip, err)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// Minimal OpenID Connect provider for trying out single sign-on locally.
+// It logs in whichever sample user is picked on the /authorize page.
+//
+//	go run ./temp/mockidp
+//
+//	OIDC_ISSUER=http://localhost:9000
+//	OIDC_CLIENT_ID=sms
+//	OIDC_CLIENT_SECRET=sms-secret
+//	OIDC_REDIRECT_URL=http://localhost:8000/api/auth/oidc/callback
+//	OIDC_ROLE_MAP=sms-admins=admin,sms-viewers=viewer
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	keyID        = "mock-idp"
+	clientID     = "sms"
+	clientSecret = "sms-secret"
+)
+
+type sampleUser struct {
+	Username string
+	Email    string
+	Groups   []string
+}
+
+var users = []sampleUser{
+	{"alice", "alice@example.com", []string{"sms-admins"}},
+	{"bob", "bob@example.com", []string{"sms-viewers"}},
+	{"carol", "carol@example.com", []string{"marketing"}},
+}
+
+// pendingCode is an issued authorization code waiting to be exchanged
+type pendingCode struct {
+	user          sampleUser
+	nonce         string
+	redirectURI   string
+	codeChallenge string
+	expires       time.Time
+}
+
+var (
+	issuer  string
+	signKey *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes = map[string]pendingCode{}
+)
+
+var pickTemplate = template.Must(template.New("pick").Parse(`<!doctype html>
+<html><body>
+<h3>Mock IdP - sign in as</h3>
+{{range .Users}}
+<form method="POST"><input type="hidden" name="query" value="{{$.Query}}">
+<button name="user" value="{{.Username}}">{{.Username}} ({{range .Groups}}{{.}} {{end}})</button>
+</form>
+{{end}}
+</body></html>`))
+
+func main() {
+	port := os.Getenv("MOCK_IDP_PORT")
+	if port == "" {
+		port = "9000"
+	}
+	issuer = os.Getenv("MOCK_IDP_ISSUER")
+	if issuer == "" {
+		issuer = "http://localhost:" + port
+	}
+
+	var err error
+	signKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatal("❌ Failed to generate signing key:", err)
+	}
+
+	http.HandleFunc("/.well-known/openid-configuration", handleDiscovery)
+	http.HandleFunc("/authorize", handleAuthorize)
+	http.HandleFunc("/token", handleToken)
+	http.HandleFunc("/jwks", handleJWKS)
+
+	log.Printf("🚀 Mock IdP listening on :%s (issuer %s)", port, issuer)
+	log.Fatal(http.ListenAndServe(":"+port, nil))
+}
+
+func handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"jwks_uri":                              issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "profile", "email"},
+	})
+}
+
+// handleAuthorize shows the user picker (GET) and issues a code (POST)
+func handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		pickTemplate.Execute(w, map[string]interface{}{
+			"Users": users,
+			"Query": r.URL.RawQuery,
+		})
+		return
+	}
+
+	query, err := url.ParseQuery(r.FormValue("query"))
+	if err != nil {
+		http.Error(w, "bad query", http.StatusBadRequest)
+		return
+	}
+	if query.Get("client_id") != clientID || query.Get("response_type") != "code" {
+		http.Error(w, "unknown client or response type", http.StatusBadRequest)
+		return
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "PKCE S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	var picked *sampleUser
+	for i := range users {
+		if users[i].Username == r.FormValue("user") {
+			picked = &users[i]
+		}
+	}
+	if picked == nil {
+		http.Error(w, "unknown user", http.StatusBadRequest)
+		return
+	}
+
+	code := randomHex()
+	mu.Lock()
+	codes[code] = pendingCode{
+		user:          *picked,
+		nonce:         query.Get("nonce"),
+		redirectURI:   query.Get("redirect_uri"),
+		codeChallenge: query.Get("code_challenge"),
+		expires:       time.Now().Add(time.Minute),
+	}
+	mu.Unlock()
+
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "bad redirect_uri", http.StatusBadRequest)
+		return
+	}
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// handleToken exchanges a code for an ID token after checking the PKCE verifier
+func handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.FormValue("client_id"), r.FormValue("client_secret")
+	}
+	if id != clientID || secret != clientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	mu.Lock()
+	pending, found := codes[r.FormValue("code")]
+	delete(codes, r.FormValue("code"))
+	mu.Unlock()
+
+	if !found || time.Now().After(pending.expires) || pending.redirectURI != r.FormValue("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != pending.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	now := time.Now()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                issuer,
+		"sub":                pending.user.Username,
+		"aud":                clientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"nonce":              pending.nonce,
+		"preferred_username": pending.user.Username,
+		"email":              pending.user.Email,
+		"groups":             pending.user.Groups,
+	})
+	idToken.Header["kid"] = keyID
+	signed, err := idToken.SignedString(signKey)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomHex(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+	log.Printf("✅ Issued ID token for %s", pending.user.Username)
+}
+
+func handleJWKS(w http.ResponseWriter, r *http.Request) {
+	pub := signKey.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+func randomHex() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
@@ -0,0 +1,214 @@
+// Package tunnel carries agent API calls over connections the agents open to
+// the backend, for hosts the backend cannot dial (NAT, inbound firewalls).
+//
+// The agent upgrades an HTTP request to the Protocol below and then serves its
+// /client/... API on that connection. The backend speaks unencrypted HTTP/2
+// over it, so concurrent calls share the one connection.
+package tunnel
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Protocol is the Upgrade token agents send to open a tunnel
+const Protocol = "sms-tunnel"
+
+// ErrNotConnected is returned for calls to a reverse-connected device whose
+// agent has no tunnel open
+var ErrNotConnected = errors.New("reverse-connected agent is not connected")
+
+// Session is one open tunnel
+type Session struct {
+	Host        string
+	RemoteAddr  string
+	Source      string // Client address of the agent, as seen past trusted proxies
+	ConnectedAt time.Time
+
+	conn      net.Conn
+	transport *http.Transport
+	dialed    bool
+	mu        sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+var (
+	sessionsMu sync.RWMutex
+	sessions   = make(map[string]*Session)
+)
+
+// Accept takes over the connection of an upgrade request from the agent of
+// host, connecting from source. A tunnel already open for the host is replaced.
+func Accept(w http.ResponseWriter, host, source string) (*Session, error) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("connection does not support upgrades")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: " + Protocol + "\r\n\r\n")
+	if err == nil {
+		err = rw.Flush()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	s := &Session{
+		Host:        host,
+		RemoteAddr:  conn.RemoteAddr().String(),
+		Source:      source,
+		ConnectedAt: time.Now(),
+		conn:        &bufferedConn{Conn: conn, reader: rw.Reader},
+		done:        make(chan struct{}),
+	}
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	s.transport = &http.Transport{
+		Protocols:   protocols,
+		DialContext: s.dial,
+		HTTP2: &http.HTTP2Config{
+			// Pings detect tunnels silently dropped by NAT devices
+			SendPingTimeout: 30 * time.Second,
+			PingTimeout:     15 * time.Second,
+		},
+	}
+
+	sessionsMu.Lock()
+	previous := sessions[host]
+	sessions[host] = s
+	sessionsMu.Unlock()
+	if previous != nil {
+		previous.Close()
+	}
+
+	// Open the HTTP/2 connection now so a dead tunnel is noticed without
+	// waiting for the first API call
+	go func() {
+		req, _ := http.NewRequest("GET", "http://"+host+"/client/tunnel/ping", nil)
+		resp, err := s.roundTrip(req)
+		if err != nil {
+			s.Close()
+			return
+		}
+		resp.Body.Close()
+	}()
+
+	log.Printf("🔗 Tunnel opened by %s from %s", host, s.RemoteAddr)
+	return s, nil
+}
+
+// Get returns the open tunnel of host
+func Get(host string) (*Session, bool) {
+	sessionsMu.RLock()
+	defer sessionsMu.RUnlock()
+	s, ok := sessions[host]
+	return s, ok
+}
+
+// List returns every open tunnel
+func List() []*Session {
+	sessionsMu.RLock()
+	defer sessionsMu.RUnlock()
+
+	list := make([]*Session, 0, len(sessions))
+	for _, s := range sessions {
+		list = append(list, s)
+	}
+	return list
+}
+
+// Disconnect closes the tunnel of host, if any
+func Disconnect(host string) {
+	if s, ok := Get(host); ok {
+		s.Close()
+	}
+}
+
+// RoundTrip sends an agent API request through the tunnel of its host
+func RoundTrip(host string, req *http.Request) (*http.Response, error) {
+	s, ok := Get(host)
+	if !ok {
+		return nil, ErrNotConnected
+	}
+	return s.roundTrip(req)
+}
+
+// roundTrip sends req on the tunnel. The transport pools connections by
+// address, so every request is addressed to the bare host to share the one
+// HTTP/2 connection the tunnel provides.
+func (s *Session) roundTrip(req *http.Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	out.URL.Scheme = "http"
+	out.URL.Host = s.Host
+	return s.transport.RoundTrip(out)
+}
+
+// Done is closed when the tunnel goes away
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close drops the tunnel; the agent reconnects on its own
+func (s *Session) Close() {
+	s.closeOnce.Do(func() {
+		sessionsMu.Lock()
+		if sessions[s.Host] == s {
+			delete(sessions, s.Host)
+		}
+		sessionsMu.Unlock()
+
+		s.transport.CloseIdleConnections()
+		s.conn.Close()
+		close(s.done)
+		log.Printf("🔌 Tunnel of %s closed", s.Host)
+	})
+}
+
+// dial hands the tunnel connection to the HTTP/2 transport. It can only be
+// used once; when the HTTP/2 connection fails the tunnel is gone.
+func (s *Session) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dialed {
+		go s.Close()
+		return nil, ErrNotConnected
+	}
+	s.dialed = true
+	return &sessionConn{Conn: s.conn, session: s}, nil
+}
+
+// bufferedConn keeps bytes the HTTP server read past the upgrade request
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// sessionConn ends the session when the HTTP/2 transport gives up on it
+type sessionConn struct {
+	net.Conn
+	session *Session
+}
+
+func (c *sessionConn) Close() error {
+	err := c.Conn.Close()
+	go c.session.Close()
+	return err
+}
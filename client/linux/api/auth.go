@@ -0,0 +1,16 @@
+package api
+
+import (
+	"github.com/kishore-001/ServerManagementSuite/linux/auth"
+	"net/http"
+)
+
+// Device credentials, managed by the backend with the current token
+func RegisterAuthRoutes(mux *http.ServeMux) {
+	mux.Handle("/client/auth/rotate", auth.TokenAuthMiddleware(http.HandlerFunc(auth.HandleRotateToken)))
+	mux.Handle("/client/auth/confirm", auth.TokenAuthMiddleware(http.HandlerFunc(auth.HandleConfirmToken)))
+
+	// TLS certificate issued by the backend's CA
+	mux.Handle("/client/tls/csr", auth.TokenAuthMiddleware(http.HandlerFunc(auth.HandleCertificateRequest)))
+	mux.Handle("/client/tls/install", auth.TokenAuthMiddleware(http.HandlerFunc(auth.HandleInstallCertificate)))
+}
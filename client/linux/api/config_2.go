@@ -15,4 +15,6 @@ func RegisterConfig2Routes(mux *http.ServeMux) {
 	mux.Handle("/client/config2/restartinterface", auth.TokenAuthMiddleware(http.HandlerFunc(config_2.HandleRestartInterfaces)))
 	mux.Handle("/client/config2/updateroute", auth.TokenAuthMiddleware(http.HandlerFunc(config_2.HandleUpdateRoute)))
 	mux.Handle("/client/config2/updatefirewall", auth.TokenAuthMiddleware(http.HandlerFunc(config_2.HandleUpdateFirewall)))
+	mux.Handle("/client/config2/neighbors", auth.TokenAuthMiddleware(http.HandlerFunc(config_2.HandleNeighbors)))
+	mux.Handle("/client/config2/macblock", auth.TokenAuthMiddleware(http.HandlerFunc(config_2.HandleMACBlock)))
 }
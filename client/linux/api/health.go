@@ -11,4 +11,7 @@ func RegisterHealthRoutes(mux *http.ServeMux) {
 		"/client/health",
 		auth.TokenAuthMiddleware(http.HandlerFunc(health.HandleHealthConfig)),
 	)
+
+	// Unauthenticated, used by the backend's subnet discovery
+	mux.HandleFunc("/client/info", health.HandleInfo)
 }
@@ -1,10 +1,8 @@
 package auth
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"log"
 	"net/http"
-	"os"
 	"strings"
 )
 
@@ -19,16 +17,14 @@ func TokenAuthMiddleware(next http.Handler) http.Handler {
 		}
 
 		token := strings.TrimPrefix(authHeader, "Bearer ")
-		tokenHash := sha256.Sum256([]byte(token))
-		tokenHex := hex.EncodeToString(tokenHash[:])
-
-		storedHash, err := os.ReadFile(tokenFilePath)
+		ok, err := authenticate(token)
 		if err != nil {
+			log.Printf("Token check failed: %v", err)
 			http.Error(w, "Server error: token file not found", http.StatusInternalServerError)
 			return
 		}
 
-		if strings.TrimSpace(tokenHex) != strings.TrimSpace(string(storedHash)) {
+		if !ok {
 			http.Error(w, "Unauthorized Access", http.StatusUnauthorized)
 			return
 		}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// HandleCertificateRequest returns a CSR for the agent key so the backend
+// can issue its TLS certificate
+func HandleCertificateRequest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeResult(w, http.StatusMethodNotAllowed, "failed", "Only GET method allowed")
+		return
+	}
+
+	csr, err := CertificateRequest()
+	if err != nil {
+		writeResult(w, http.StatusInternalServerError, "failed", "Failed to create certificate request: "+err.Error())
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "success",
+		"csr":    csr,
+	})
+}
+
+// InstallRequest carries the certificate issued by the backend's CA
+type InstallRequest struct {
+	Certificate string `json:"certificate"`
+	CA          string `json:"ca"`
+}
+
+// HandleInstallCertificate stores the agent certificate and restarts the
+// listener with HTTPS once the response has been sent
+func HandleInstallCertificate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		writeResult(w, http.StatusMethodNotAllowed, "failed", "Only POST method allowed")
+		return
+	}
+
+	var req InstallRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResult(w, http.StatusBadRequest, "failed", "Failed to parse request body")
+		return
+	}
+
+	if err := InstallCertificate(req.Certificate, req.CA); err != nil {
+		writeResult(w, http.StatusBadRequest, "failed", err.Error())
+		return
+	}
+
+	log.Printf("🔐 TLS certificate installed")
+	writeResult(w, http.StatusOK, "success", "Certificate installed, switching to HTTPS")
+
+	select {
+	case CertificateInstalled <- struct{}{}:
+	default:
+	}
+}
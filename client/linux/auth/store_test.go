@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	oldToken = "old-token-0123456789abcdef0123456789abcdef"
+	newToken = "new-token-0123456789abcdef0123456789abcdef"
+	altToken = "alt-token-0123456789abcdef0123456789abcdef"
+)
+
+// useTempStore points the relative token file path at an empty directory
+func useTempStore(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+}
+
+func mustAuthenticate(t *testing.T, token string, want bool) {
+	t.Helper()
+	ok, err := authenticate(token)
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if ok != want {
+		t.Errorf("authenticate(%.9s) = %v, want %v", token, ok, want)
+	}
+}
+
+func storedStates(t *testing.T) map[string]tokenEntry {
+	t.Helper()
+	entries, err := readTokens()
+	if err != nil {
+		t.Fatalf("readTokens: %v", err)
+	}
+	states := make(map[string]tokenEntry, len(entries))
+	for _, e := range entries {
+		states[e.hash] = e
+	}
+	return states
+}
+
+func TestTokenRotation(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   func(t *testing.T)
+		accepts map[string]bool
+		states  map[string]string // token -> state; missing tokens are gone
+		tunnel  string            // token whose hash opens the tunnel
+	}{
+		{
+			name:    "set",
+			steps:   func(t *testing.T) {},
+			accepts: map[string]bool{oldToken: true, newToken: false},
+			states:  map[string]string{oldToken: stateActive},
+			tunnel:  oldToken,
+		},
+		{
+			name: "rotate keeps the current token until confirmed",
+			steps: func(t *testing.T) {
+				if err := AddPendingToken(newToken, time.Hour); err != nil {
+					t.Fatal(err)
+				}
+				mustAuthenticate(t, oldToken, true)
+			},
+			accepts: map[string]bool{oldToken: true},
+			states:  map[string]string{oldToken: stateActive, newToken: statePending},
+			tunnel:  oldToken,
+		},
+		{
+			name: "commit promotes the new token and retires the old one",
+			steps: func(t *testing.T) {
+				if err := AddPendingToken(newToken, time.Hour); err != nil {
+					t.Fatal(err)
+				}
+				mustAuthenticate(t, newToken, true)
+			},
+			accepts: map[string]bool{oldToken: true, newToken: true},
+			states:  map[string]string{newToken: stateActive, oldToken: stateRetire},
+			tunnel:  newToken,
+		},
+		{
+			name: "rollback replaces an unconfirmed rotation",
+			steps: func(t *testing.T) {
+				if err := AddPendingToken(newToken, time.Hour); err != nil {
+					t.Fatal(err)
+				}
+				if err := AddPendingToken(altToken, time.Hour); err != nil {
+					t.Fatal(err)
+				}
+			},
+			accepts: map[string]bool{oldToken: true, newToken: false},
+			states:  map[string]string{oldToken: stateActive, altToken: statePending},
+			tunnel:  oldToken,
+		},
+		{
+			name: "retired token expires after the overlap",
+			steps: func(t *testing.T) {
+				if err := AddPendingToken(newToken, 0); err != nil {
+					t.Fatal(err)
+				}
+				mustAuthenticate(t, newToken, true)
+				time.Sleep(10 * time.Millisecond)
+			},
+			accepts: map[string]bool{oldToken: false, newToken: true},
+			states:  map[string]string{newToken: stateActive, oldToken: stateRetire},
+			tunnel:  newToken,
+		},
+		{
+			name: "second commit drops expired retirees",
+			steps: func(t *testing.T) {
+				if err := AddPendingToken(newToken, 0); err != nil {
+					t.Fatal(err)
+				}
+				mustAuthenticate(t, newToken, true)
+				time.Sleep(10 * time.Millisecond)
+				if err := AddPendingToken(altToken, time.Hour); err != nil {
+					t.Fatal(err)
+				}
+				mustAuthenticate(t, altToken, true)
+			},
+			accepts: map[string]bool{oldToken: false, newToken: true, altToken: true},
+			states:  map[string]string{altToken: stateActive, newToken: stateRetire},
+			tunnel:  altToken,
+		},
+		{
+			name: "set clears a pending rotation",
+			steps: func(t *testing.T) {
+				if err := AddPendingToken(newToken, time.Hour); err != nil {
+					t.Fatal(err)
+				}
+				if err := SetToken(altToken); err != nil {
+					t.Fatal(err)
+				}
+			},
+			accepts: map[string]bool{oldToken: false, newToken: false, altToken: true},
+			states:  map[string]string{altToken: stateActive},
+			tunnel:  altToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempStore(t)
+			if err := SetToken(oldToken); err != nil {
+				t.Fatal(err)
+			}
+			tt.steps(t)
+
+			states := storedStates(t)
+			if len(states) != len(tt.states) {
+				t.Errorf("stored %d hashes, want %d", len(states), len(tt.states))
+			}
+			for token, state := range tt.states {
+				if got := states[hashToken(token)].state; got != state {
+					t.Errorf("state of %.9s = %q, want %q", token, got, state)
+				}
+			}
+
+			// Checked after the states, since a pending token is promoted
+			// by its first use
+			for token, want := range tt.accepts {
+				mustAuthenticate(t, token, want)
+			}
+
+			credential, err := TunnelCredential()
+			if err != nil {
+				t.Fatalf("TunnelCredential: %v", err)
+			}
+			if credential != hashToken(tt.tunnel) {
+				t.Errorf("tunnel credential is not the hash of %.9s", tt.tunnel)
+			}
+		})
+	}
+}
+
+func TestTokenRetireWindow(t *testing.T) {
+	useTempStore(t)
+	if err := SetToken(oldToken); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddPendingToken(newToken, 10*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now()
+	mustAuthenticate(t, newToken, true)
+
+	retired := storedStates(t)[hashToken(oldToken)]
+	if retired.state != stateRetire {
+		t.Fatalf("old token state = %q, want %q", retired.state, stateRetire)
+	}
+	// The file keeps whole seconds
+	if earliest := before.Add(10*time.Minute - time.Second); retired.retireAt.Before(earliest) {
+		t.Errorf("old token retires at %s, want at least %s", retired.retireAt, earliest)
+	}
+}
+
+func TestReadTokensSkipsMalformedLines(t *testing.T) {
+	useTempStore(t)
+
+	valid := hashToken(oldToken)
+	pending := hashToken(newToken)
+	lines := fmt.Sprintf("%s\n"+
+		"short\n"+
+		"%s pending 600\n"+
+		"%s unknown 1\n"+
+		"%s retire notanumber\n"+
+		"\n", valid, pending, hashToken(altToken), hashToken("x"))
+	if err := os.MkdirAll(filepath.Dir(tokenFilePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tokenFilePath, []byte(lines), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	states := storedStates(t)
+	if len(states) != 2 {
+		t.Fatalf("read %d entries, want 2", len(states))
+	}
+	if states[valid].state != stateActive {
+		t.Errorf("plain line state = %q, want %q", states[valid].state, stateActive)
+	}
+	if e := states[pending]; e.state != statePending || e.overlap != 10*time.Minute {
+		t.Errorf("pending line = %q/%s, want %q/10m", e.state, e.overlap, statePending)
+	}
+}
+
+func TestNoTokenStored(t *testing.T) {
+	useTempStore(t)
+
+	if HasToken() {
+		t.Error("HasToken reported a token in an empty store")
+	}
+	if _, err := TunnelCredential(); err == nil {
+		t.Error("TunnelCredential succeeded without a token file")
+	}
+	if _, err := authenticate(oldToken); err == nil {
+		t.Error("authenticate succeeded without a token file")
+	}
+}
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Certificate files. The key never leaves the agent; the backend signs a
+// request for it and the agent serves HTTPS once the certificate is stored.
+const (
+	tlsKeyFile  = "./auth/agent.key"
+	tlsCertFile = "./auth/agent.crt"
+	tlsCAFile   = "./auth/ca.crt"
+)
+
+// CertificateInstalled receives a value when the backend installs a new
+// certificate, so the listener can restart with it
+var CertificateInstalled = make(chan struct{}, 1)
+
+// CertificateRequest returns a PEM certificate request for the agent key,
+// creating the key on first use
+func CertificateRequest() (string, error) {
+	key, err := loadOrCreateKey()
+	if err != nil {
+		return "", err
+	}
+
+	hostname, _ := os.Hostname()
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: hostname},
+	}, key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})), nil
+}
+
+// InstallCertificate stores a certificate issued for the agent key together
+// with the CA that signs the backend's client certificate
+func InstallCertificate(certPEM, caPEM string) error {
+	keyPEM, err := os.ReadFile(tlsKeyFile)
+	if err != nil {
+		return fmt.Errorf("no key for this certificate: %v", err)
+	}
+	pair, err := tls.X509KeyPair([]byte(certPEM), keyPEM)
+	if err != nil {
+		return fmt.Errorf("certificate does not match the agent key: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(caPEM)) {
+		return errors.New("invalid CA certificate")
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return err
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:     pool,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	if err != nil {
+		return fmt.Errorf("certificate is not signed by the CA: %v", err)
+	}
+
+	// The certificate file switches the agent to HTTPS, so it goes last
+	if err := writeFileAtomic(tlsCAFile, []byte(caPEM), 0644); err != nil {
+		return err
+	}
+	return writeFileAtomic(tlsCertFile, []byte(certPEM), 0644)
+}
+
+// ServerTLSConfig returns the listener configuration, or nil while no
+// certificate is installed and the agent still serves plain HTTP. Only
+// clients with a certificate from the backend's CA are accepted.
+func ServerTLSConfig() (*tls.Config, error) {
+	if _, err := os.Stat(tlsCertFile); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	pair, err := tls.LoadX509KeyPair(tlsCertFile, tlsKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	caPEM, err := os.ReadFile(tlsCAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("%s: no certificates found", tlsCAFile)
+	}
+
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{pair},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}, nil
+}
+
+// HasTunnelCertificate reports whether the agent can answer the backend's
+// tunnel challenge
+func HasTunnelCertificate() bool {
+	_, err := os.Stat(tlsCertFile)
+	return err == nil
+}
+
+// SignTunnelRequest answers the challenge the backend sends to agents with a
+// pinned certificate: it adds the certificate and a signature with its key
+// over the backend's nonce and the tunnel credential.
+func SignTunnelRequest(req *http.Request, protocol, credential, nonce string) error {
+	pair, err := tls.LoadX509KeyPair(tlsCertFile, tlsKeyFile)
+	if err != nil {
+		return err
+	}
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return fmt.Errorf("%s: unsupported key type", tlsKeyFile)
+	}
+
+	// Must match the backend's tunnelProofMessage
+	digest := sha256.Sum256([]byte(protocol + "\n" + nonce + "\n" + credential))
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("X-Agent-Certificate", base64.StdEncoding.EncodeToString(pair.Certificate[0]))
+	req.Header.Set("X-Tunnel-Nonce", nonce)
+	req.Header.Set("X-Tunnel-Signature", base64.StdEncoding.EncodeToString(signature))
+	return nil
+}
+
+func loadOrCreateKey() (*ecdsa.PrivateKey, error) {
+	if data, err := os.ReadFile(tlsKeyFile); err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s: no PEM data", tlsKeyFile)
+		}
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		ecKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s: unsupported key type", tlsKeyFile)
+		}
+		return ecKey, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(tlsKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	// Ensure auth folder exists
+	os.MkdirAll(filepath.Dir(path), 0755)
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
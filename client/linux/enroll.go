@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/kishore-001/ServerManagementSuite/linux/auth"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// enrollResponse is returned by the backend's /api/agent/enroll
+type enrollResponse struct {
+	Status       string `json:"status"`
+	Message      string `json:"message"`
+	EnrollmentID int    `json:"enrollment_id"`
+	State        string `json:"state"` // pending or approved
+	IP           string `json:"ip"`
+	AccessToken  string `json:"access_token"`
+	Certificate  string `json:"certificate"` // Only when the backend runs its agent CA
+	CA           string `json:"ca"`
+}
+
+// enroll redeems a one-time enrollment code with the backend and saves the
+// device credential it hands back, replacing the interactive token prompt.
+// Reverse-connected agents never listen; their certificate signs the tunnel
+// requests instead.
+func enroll(backendURL, code string, reverseConnect bool) error {
+	backendURL = strings.TrimRight(backendURL, "/")
+	parsed, err := url.Parse(backendURL)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("invalid backend URL %q", backendURL)
+	}
+
+	hostname, _ := os.Hostname()
+	request := map[string]interface{}{
+		"code":            strings.TrimSpace(code),
+		"ip":              localIPFor(parsed),
+		"hostname":        hostname,
+		"os":              runtime.GOOS,
+		"reverse_connect": reverseConnect,
+	}
+	csr, err := auth.CertificateRequest()
+	if err != nil {
+		return fmt.Errorf("failed to create TLS key: %v", err)
+	}
+	request["csr"] = csr
+	body, _ := json.Marshal(request)
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Post(backendURL+"/api/agent/enroll", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("could not reach backend: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result enrollResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("unexpected response from backend (status %d)", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusCreated || result.AccessToken == "" {
+		return fmt.Errorf("backend refused enrollment: %s", result.Message)
+	}
+
+	if err := auth.SetToken(result.AccessToken); err != nil {
+		return fmt.Errorf("failed to save token hash: %v", err)
+	}
+	if result.Certificate != "" {
+		if err := auth.InstallCertificate(result.Certificate, result.CA); err != nil {
+			return fmt.Errorf("failed to install TLS certificate: %v", err)
+		}
+	}
+
+	if result.State == "approved" {
+		log.Printf("✅ Enrolled as %s (request %d), device registered", result.IP, result.EnrollmentID)
+	} else {
+		log.Printf("⏳ Enrolled as %s (request %d), waiting for an admin to approve it", result.IP, result.EnrollmentID)
+	}
+	return nil
+}
+
+// localIPFor returns the local address used to reach the backend, which is
+// the address the backend should use to reach this agent. Empty lets the
+// backend fall back to the request's source address.
+func localIPFor(backend *url.URL) string {
+	port := backend.Port()
+	if port == "" {
+		port = "80"
+		if backend.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	// UDP "connections" send nothing, they only pick the outgoing interface
+	conn, err := net.Dial("udp", net.JoinHostPort(backend.Hostname(), port))
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok && !addr.IP.IsLoopback() {
+		return addr.IP.String()
+	}
+	return ""
+}
@@ -5,6 +5,7 @@ go 1.24.3
 require (
 	github.com/golang-jwt/jwt/v5 v5.2.2
 	github.com/shirou/gopsutil/v3 v3.24.5
+	golang.org/x/sys v0.20.0
 )
 
 require (
@@ -15,5 +16,4 @@ require (
 	github.com/tklauser/go-sysconf v0.3.12 // indirect
 	github.com/tklauser/numcpus v0.6.1 // indirect
 	github.com/yusufpapurcu/wmi v1.2.4 // indirect
-	golang.org/x/sys v0.20.0 // indirect
 )
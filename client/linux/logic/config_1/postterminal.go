@@ -0,0 +1,180 @@
+package config_1
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+const (
+	maxTerminals = 16
+	maxTermSize  = 1000 // Columns or rows
+)
+
+// TerminalInput is one line of the request body: keystrokes or a new size
+type TerminalInput struct {
+	Type string `json:"type"` // input or resize
+	Data []byte `json:"data,omitempty"`
+	Cols int    `json:"cols,omitempty"`
+	Rows int    `json:"rows,omitempty"`
+}
+
+// TerminalEvent is one line of the response: terminal output, then the exit
+// code of the shell
+type TerminalEvent struct {
+	Type     string `json:"type"` // output or exit
+	Data     []byte `json:"data,omitempty"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+var (
+	terminalsMu   sync.Mutex
+	openTerminals int
+)
+
+// HandleTerminal runs an interactive shell in a pseudo-terminal. The request
+// body carries keystrokes and resizes while the response carries the
+// output, one JSON object per line each way. Closing the request body or
+// the connection hangs up the terminal.
+func HandleTerminal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	cols, rows, ok := terminalSize(r.URL.Query())
+	if !ok {
+		sendError(w, "cols and rows must be between 1 and 1000", http.StatusBadRequest)
+		return
+	}
+
+	terminalsMu.Lock()
+	if openTerminals >= maxTerminals {
+		terminalsMu.Unlock()
+		sendError(w, "Too many terminals open, try again later", http.StatusTooManyRequests)
+		return
+	}
+	openTerminals++
+	terminalsMu.Unlock()
+	defer func() {
+		terminalsMu.Lock()
+		openTerminals--
+		terminalsMu.Unlock()
+	}()
+
+	// Keystrokes keep arriving on the request body while output is written
+	rc := http.NewResponseController(w)
+	if err := rc.EnableFullDuplex(); err != nil {
+		sendError(w, "Connection does not support terminals: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	term, err := openTerminal(cols, rows)
+	if err != nil {
+		sendError(w, "Failed to open terminal: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer term.Close()
+	stop := context.AfterFunc(r.Context(), term.Close)
+	defer stop()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+	log.Printf("🖥️ Terminal opened (%dx%d)", cols, rows)
+
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	emit := func(ev TerminalEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := enc.Encode(ev); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	go func() {
+		defer term.Close()
+		dec := json.NewDecoder(r.Body)
+		for {
+			var in TerminalInput
+			if err := dec.Decode(&in); err != nil {
+				return
+			}
+			switch in.Type {
+			case "input":
+				if _, err := term.Write(in.Data); err != nil {
+					return
+				}
+			case "resize":
+				if validTermSize(in.Cols, in.Rows) {
+					term.Resize(in.Cols, in.Rows)
+				}
+			}
+		}
+	}()
+
+	type exit struct {
+		code int
+		err  error
+	}
+	exited := make(chan exit, 1)
+	go func() {
+		code, err := term.Wait()
+		term.Drain()
+		exited <- exit{code, err}
+	}()
+
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := term.Read(buf)
+		if n > 0 && emit(TerminalEvent{Type: "output", Data: buf[:n]}) != nil {
+			term.Close()
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	result := <-exited
+	if r.Context().Err() != nil {
+		log.Printf("⛔ Terminal closed by the caller, shell exited with %d", result.code)
+		return
+	}
+
+	ev := TerminalEvent{Type: "exit", ExitCode: &result.code}
+	if result.err != nil {
+		ev.Error = result.err.Error()
+	}
+	emit(ev)
+	log.Printf("🖥️ Terminal closed, shell exited with %d", result.code)
+}
+
+// terminalSize reads the starting size from the query, 80x24 by default
+func terminalSize(query url.Values) (int, int, bool) {
+	cols, rows := 80, 24
+	var err error
+	if v := query.Get("cols"); v != "" {
+		if cols, err = strconv.Atoi(v); err != nil {
+			return 0, 0, false
+		}
+	}
+	if v := query.Get("rows"); v != "" {
+		if rows, err = strconv.Atoi(v); err != nil {
+			return 0, 0, false
+		}
+	}
+	return cols, rows, validTermSize(cols, rows)
+}
+
+func validTermSize(cols, rows int) bool {
+	return cols >= 1 && cols <= maxTermSize && rows >= 1 && rows <= maxTermSize
+}
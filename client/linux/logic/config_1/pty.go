@@ -0,0 +1,160 @@
+package config_1
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	terminalKillDelay    = 5 * time.Second // After hanging up, before the session is killed
+	terminalDrainTimeout = time.Second     // Output read after the shell exits
+)
+
+// terminal is a login shell attached to a pseudo-terminal
+type terminal struct {
+	pty       *os.File // Master side; what the shell writes is read from here
+	cmd       *exec.Cmd
+	exited    chan struct{}
+	closeOnce sync.Once
+}
+
+// openTerminal starts the shell in a new session whose controlling
+// terminal is a fresh pseudo-terminal of the given size
+func openTerminal(cols, rows int) (*terminal, error) {
+	pty, tty, err := openPTY()
+	if err != nil {
+		return nil, err
+	}
+	defer tty.Close() // The shell holds its own copy
+
+	t := &terminal{pty: pty, exited: make(chan struct{})}
+	if err := t.Resize(cols, rows); err != nil {
+		pty.Close()
+		return nil, err
+	}
+
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/bash"
+	}
+	if _, err := exec.LookPath(shell); err != nil {
+		shell = "/bin/sh"
+	}
+
+	t.cmd = exec.Command(shell, "-l")
+	t.cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+	if home, err := os.UserHomeDir(); err == nil {
+		t.cmd.Dir = home
+	}
+	t.cmd.Stdin, t.cmd.Stdout, t.cmd.Stderr = tty, tty, tty
+	t.cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 0}
+
+	if err := t.cmd.Start(); err != nil {
+		pty.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// openPTY allocates a pseudo-terminal pair through /dev/ptmx
+func openPTY() (*os.File, *os.File, error) {
+	pty, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var n int
+	err = control(pty, func(fd int) error {
+		if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+			return err
+		}
+		n, err = unix.IoctlGetInt(fd, unix.TIOCGPTN)
+		return err
+	})
+	if err != nil {
+		pty.Close()
+		return nil, nil, err
+	}
+
+	tty, err := os.OpenFile("/dev/pts/"+strconv.Itoa(n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		pty.Close()
+		return nil, nil, err
+	}
+	return pty, tty, nil
+}
+
+// control runs fn on the file descriptor without taking the file out of
+// the poller, so Close still interrupts a pending Read
+func control(f *os.File, fn func(fd int) error) error {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var fnErr error
+	if err := conn.Control(func(fd uintptr) { fnErr = fn(int(fd)) }); err != nil {
+		return err
+	}
+	return fnErr
+}
+
+// Read returns the shell's output. It reports io.EOF once the shell and
+// everything it left running have closed the terminal.
+func (t *terminal) Read(p []byte) (int, error) {
+	n, err := t.pty.Read(p)
+	if errors.Is(err, syscall.EIO) || errors.Is(err, os.ErrClosed) || errors.Is(err, os.ErrDeadlineExceeded) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Write types into the terminal
+func (t *terminal) Write(p []byte) (int, error) {
+	return t.pty.Write(p)
+}
+
+func (t *terminal) Resize(cols, rows int) error {
+	return control(t.pty, func(fd int) error {
+		return unix.IoctlSetWinsize(fd, unix.TIOCSWINSZ, &unix.Winsize{Col: uint16(cols), Row: uint16(rows)})
+	})
+}
+
+// Wait waits for the shell to exit and returns its exit code
+func (t *terminal) Wait() (int, error) {
+	err := t.cmd.Wait()
+	close(t.exited)
+	if _, exited := err.(*exec.ExitError); exited {
+		err = nil
+	}
+	return t.cmd.ProcessState.ExitCode(), err
+}
+
+// Drain gives output still in the terminal a moment to be read once the
+// shell is gone, then makes Read return
+func (t *terminal) Drain() {
+	t.pty.SetReadDeadline(time.Now().Add(terminalDrainTimeout))
+}
+
+// Close hangs up the terminal, which sends SIGHUP to the shell and the job
+// in the foreground. Whatever is still running in the session after a grace
+// period is killed.
+func (t *terminal) Close() {
+	t.closeOnce.Do(func() {
+		t.pty.Close()
+		go func() {
+			select {
+			case <-t.exited:
+			case <-time.After(terminalKillDelay):
+				syscall.Kill(-t.cmd.Process.Pid, syscall.SIGKILL)
+			}
+		}()
+	})
+}
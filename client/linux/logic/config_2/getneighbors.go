@@ -0,0 +1,97 @@
+package config_2
+
+import (
+	"bufio"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Neighbor is one entry of the ARP / neighbor table
+type Neighbor struct {
+	IP        string `json:"ip"`
+	MAC       string `json:"mac"`
+	Interface string `json:"interface"`
+	State     string `json:"state"`
+}
+
+// HandleNeighbors reports the MAC addresses this host currently sees
+func HandleNeighbors(w http.ResponseWriter, r *http.Request) {
+	// Check for GET method
+	if r.Method != http.MethodGet {
+		sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	neighbors, err := getNeighbors()
+	if err != nil {
+		sendError(w, "Failed to read neighbor table: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sendGetSuccess(w, map[string]interface{}{
+		"status":    "success",
+		"neighbors": neighbors,
+	})
+}
+
+// getNeighbors reads `ip neigh`, falling back to /proc/net/arp
+func getNeighbors() ([]Neighbor, error) {
+	output, err := exec.Command("ip", "neigh", "show").Output()
+	if err != nil {
+		return getARPNeighbors()
+	}
+
+	neighbors := []Neighbor{}
+	for _, line := range strings.Split(string(output), "\n") {
+		// 192.168.1.1 dev eth0 lladdr aa:bb:cc:dd:ee:ff REACHABLE
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		n := Neighbor{IP: fields[0], State: fields[len(fields)-1]}
+		for i := 1; i+1 < len(fields); i++ {
+			switch fields[i] {
+			case "dev":
+				n.Interface = fields[i+1]
+			case "lladdr":
+				n.MAC = strings.ToLower(fields[i+1])
+			}
+		}
+		if n.MAC == "" {
+			continue // INCOMPLETE or FAILED entries have no address
+		}
+		neighbors = append(neighbors, n)
+	}
+	return neighbors, nil
+}
+
+func getARPNeighbors() ([]Neighbor, error) {
+	file, err := os.Open("/proc/net/arp")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	neighbors := []Neighbor{}
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // Skip header
+	for scanner.Scan() {
+		// IP address  HW type  Flags  HW address  Mask  Device
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || fields[3] == "00:00:00:00:00:00" {
+			continue
+		}
+		neighbors = append(neighbors, Neighbor{
+			IP:        fields[0],
+			MAC:       strings.ToLower(fields[3]),
+			Interface: fields[5],
+			State:     "ARP",
+		})
+	}
+	return neighbors, scanner.Err()
+}
@@ -0,0 +1,81 @@
+package config_2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+)
+
+// macBlockChain holds one DROP rule per blocked MAC and is jumped to from
+// INPUT and FORWARD. It is owned by the agent and rebuilt on every sync.
+const macBlockChain = "SMS_MAC_BLOCK"
+
+// MACBlockRequest is the full list of MACs to block
+type MACBlockRequest struct {
+	MACs []string `json:"macs"`
+}
+
+// HandleMACBlock replaces the blocked MAC list with the one sent by the backend
+func HandleMACBlock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	var req MACBlockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Failed to parse request body", http.StatusBadRequest)
+		return
+	}
+
+	macs := make([]string, 0, len(req.MACs))
+	for _, value := range req.MACs {
+		hw, err := net.ParseMAC(value)
+		if err != nil || len(hw) != 6 {
+			sendError(w, fmt.Sprintf("Invalid MAC address %q", value), http.StatusBadRequest)
+			return
+		}
+		macs = append(macs, hw.String())
+	}
+
+	if err := syncMACBlockChain(macs); err != nil {
+		sendError(w, "Failed to apply MAC block list: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sendGetSuccess(w, map[string]interface{}{
+		"status":  "success",
+		"blocked": len(macs),
+	})
+}
+
+func syncMACBlockChain(macs []string) error {
+	// Create the chain if needed; -N fails when it already exists
+	exec.Command("iptables", "-N", macBlockChain).Run()
+
+	if out, err := exec.Command("iptables", "-F", macBlockChain).CombinedOutput(); err != nil {
+		return fmt.Errorf("flush %s: %s", macBlockChain, strings.TrimSpace(string(out)))
+	}
+
+	for _, mac := range macs {
+		out, err := exec.Command("iptables", "-A", macBlockChain, "-m", "mac", "--mac-source", mac, "-j", "DROP").CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("block %s: %s", mac, strings.TrimSpace(string(out)))
+		}
+	}
+
+	for _, chain := range []string{"INPUT", "FORWARD"} {
+		// -C succeeds when the jump is already in place
+		if exec.Command("iptables", "-C", chain, "-j", macBlockChain).Run() == nil {
+			continue
+		}
+		if out, err := exec.Command("iptables", "-I", chain, "1", "-j", macBlockChain).CombinedOutput(); err != nil {
+			return fmt.Errorf("hook %s: %s", chain, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
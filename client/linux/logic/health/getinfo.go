@@ -0,0 +1,32 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"runtime"
+)
+
+// AgentName identifies SMS agents to the backend's subnet discovery
+const AgentName = "sms-agent"
+
+// HandleInfo answers without a token so the backend can find agents it has
+// not registered yet. It only reveals what a port scan would guess anyway.
+func HandleInfo(w http.ResponseWriter, r *http.Request) {
+	// Check for GET method
+	if r.Method != http.MethodGet {
+		sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hostname, _ := os.Hostname()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"agent":    AgentName,
+		"hostname": hostname,
+		"os":       runtime.GOOS,
+		"arch":     runtime.GOARCH,
+		"tls":      r.TLS != nil,
+	})
+}
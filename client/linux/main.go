@@ -2,20 +2,32 @@ package main
 
 import (
 	"bufio"
-	"crypto/sha256"
-	"encoding/hex"
+	"context"
+	"flag"
 	"fmt"
 	"github.com/kishore-001/ServerManagementSuite/linux/api"
+	"github.com/kishore-001/ServerManagementSuite/linux/auth"
 	"log"
 	"net/http"
 	"os"
-	"path/filepath"
 	"strings"
 )
 
-const tokenFilePath = "./auth/token.hash"
-
 func main() {
+	enrollURL := flag.String("enroll", "", "enroll with the backend at this URL using the one-time code given as the next argument")
+	connectURL := flag.String("connect", "", "keep a tunnel open to the backend at this URL instead of listening (for hosts behind NAT)")
+	listenAddr := flag.String("listen", "0.0.0.0:2210", "address the agent API listens on; the backend's port for this device must match")
+	flag.Parse()
+
+	if *enrollURL != "" {
+		if flag.NArg() != 1 {
+			log.Fatalf("❌ Usage: %s [--connect <backend-url>] --enroll <backend-url> <code>", os.Args[0])
+		}
+		if err := enroll(*enrollURL, flag.Arg(0), *connectURL != ""); err != nil {
+			log.Fatalf("❌ Enrollment failed: %v", err)
+		}
+	}
+
 	ensureTokenHashExists()
 
 	mux := http.NewServeMux()
@@ -26,43 +38,58 @@ func main() {
 	api.RegisterHealthRoutes(mux)
 	api.RegisterOptimizeRoutes(mux)
 	api.RegisterLogRoutes(mux)
+	api.RegisterAuthRoutes(mux)
 
-	log.Println("Starting client server on port 2210...")
-	if err := http.ListenAndServe("0.0.0.0:2210", mux); err != nil {
-		log.Fatalf("Server failed: %v", err)
+	if *connectURL != "" {
+		connectBackend(strings.TrimRight(*connectURL, "/"), mux)
+	}
+
+	for {
+		if err := serve(mux, *listenAddr); err != nil {
+			log.Fatalf("Server failed: %v", err)
+		}
 	}
 }
 
-// ------------------------------
-// Check and create token hash if missing
-// ------------------------------
+// serve runs the listener until the backend installs a new certificate.
+// Agents without a certificate serve plain HTTP so older backends keep working.
+func serve(handler http.Handler, addr string) error {
+	tlsConfig, err := auth.ServerTLSConfig()
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Addr: addr, Handler: handler, TLSConfig: tlsConfig}
 
-func ensureTokenHashExists() {
-	needToken := false
+	go func() {
+		<-auth.CertificateInstalled
+		log.Println("🔐 Restarting listener with the new certificate...")
+		server.Shutdown(context.Background())
+	}()
 
-	// Check if file exists
-	if _, err := os.Stat(tokenFilePath); os.IsNotExist(err) {
-		needToken = true
+	if tlsConfig != nil {
+		log.Printf("Starting client server on %s (HTTPS, client certificate required)...", addr)
+		err = server.ListenAndServeTLS("", "")
 	} else {
-		// File exists, check contents
-		data, err := os.ReadFile(tokenFilePath)
-		if err != nil || len(strings.TrimSpace(string(data))) != 64 {
-			needToken = true
-		}
+		log.Printf("Starting client server on %s...", addr)
+		err = server.ListenAndServe()
 	}
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
 
-	if needToken {
+// ------------------------------
+// Check and create token hash if missing
+// ------------------------------
+
+func ensureTokenHashExists() {
+	if !auth.HasToken() {
 		reader := bufio.NewReader(os.Stdin)
 		fmt.Print("🔐 Enter token to register this client: ")
 		token, _ := reader.ReadString('\n')
-		token = strings.TrimSpace(token)
-		hash := sha256.Sum256([]byte(token))
-		hashHex := hex.EncodeToString(hash[:])
-
-		// Ensure auth folder exists
-		os.MkdirAll(filepath.Dir(tokenFilePath), 0755)
 
-		if err := os.WriteFile(tokenFilePath, []byte(hashHex), 0644); err != nil {
+		if err := auth.SetToken(strings.TrimSpace(token)); err != nil {
 			log.Fatalf("❌ Failed to save token hash: %v", err)
 		}
 
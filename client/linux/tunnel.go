@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/kishore-001/ServerManagementSuite/linux/auth"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// tunnelProtocol must match the backend's tunnel.Protocol
+const tunnelProtocol = "sms-tunnel"
+
+const maxTunnelBackoff = time.Minute
+
+// connectBackend keeps a tunnel to the backend open for hosts the backend
+// cannot reach directly, and serves the agent API over it. It never returns.
+func connectBackend(backendURL string, handler http.Handler) {
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := serveTunnel(backendURL, handler)
+
+		// A tunnel that stayed up for a while was healthy, retry quickly
+		if time.Since(started) > maxTunnelBackoff {
+			backoff = time.Second
+		}
+		log.Printf("⚠️ Tunnel to backend closed: %v (reconnecting in %s)", err, backoff)
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if backoff > maxTunnelBackoff {
+			backoff = maxTunnelBackoff
+		}
+	}
+}
+
+// serveTunnel opens one tunnel and serves requests on it until it breaks
+func serveTunnel(backendURL string, handler http.Handler) error {
+	conn, err := dialTunnel(backendURL)
+	if err != nil {
+		return err
+	}
+	log.Printf("🔗 Tunnel to %s open", backendURL)
+
+	// The backend speaks HTTP/2 without TLS inside the tunnel; the outer
+	// connection is protected by the backend URL's own TLS
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	server := &http.Server{
+		Handler:   tunnelHandler(handler),
+		Protocols: protocols,
+		HTTP2: &http.HTTP2Config{
+			SendPingTimeout: 30 * time.Second,
+			PingTimeout:     15 * time.Second,
+		},
+	}
+
+	listener := newSingleConnListener(conn)
+	server.Serve(listener)
+	<-listener.closed
+	return errors.New("connection lost")
+}
+
+// dialTunnel connects to the backend and upgrades the connection
+func dialTunnel(backendURL string) (net.Conn, error) {
+	parsed, err := url.Parse(backendURL)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid backend URL %q", backendURL)
+	}
+
+	credential, err := auth.TunnelCredential()
+	if err != nil {
+		return nil, err
+	}
+
+	address := parsed.Host
+	if parsed.Port() == "" {
+		if parsed.Scheme == "https" {
+			address = net.JoinHostPort(parsed.Hostname(), "443")
+		} else {
+			address = net.JoinHostPort(parsed.Hostname(), "80")
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	var conn net.Conn
+	if parsed.Scheme == "https" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, &tls.Config{
+			ServerName: parsed.Hostname(),
+			NextProtos: []string{"http/1.1"}, // Upgrades need HTTP/1.1
+		})
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not reach backend: %v", err)
+	}
+
+	conn.SetDeadline(time.Now().Add(15 * time.Second))
+	reader := bufio.NewReader(conn)
+	resp, err := requestTunnel(conn, reader, backendURL, credential, "")
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// Agents with a certificate must sign the nonce the backend answers
+	// with; the signed request goes over the same connection
+	if nonce := resp.Header.Get("X-Tunnel-Nonce"); resp.StatusCode == http.StatusUnauthorized && nonce != "" && auth.HasTunnelCertificate() {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		resp, err = requestTunnel(conn, reader, backendURL, credential, nonce)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		resp.Body.Close()
+		conn.Close()
+		return nil, fmt.Errorf("backend refused tunnel with status %d", resp.StatusCode)
+	}
+	conn.SetDeadline(time.Time{})
+
+	return &bufferedConn{Conn: conn, reader: reader}, nil
+}
+
+// requestTunnel sends one upgrade request on conn and reads the answer. A
+// non-empty nonce is signed with the agent certificate.
+func requestTunnel(conn net.Conn, reader *bufio.Reader, backendURL, credential, nonce string) (*http.Response, error) {
+	req, _ := http.NewRequest("GET", backendURL+"/api/agent/tunnel", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", tunnelProtocol)
+	req.Header.Set("Authorization", "Bearer "+credential)
+	if nonce != "" {
+		if err := auth.SignTunnelRequest(req, tunnelProtocol, credential, nonce); err != nil {
+			return nil, fmt.Errorf("failed to sign tunnel request: %v", err)
+		}
+	}
+
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+	return http.ReadResponse(reader, req)
+}
+
+// tunnelHandler answers the backend's liveness probe and passes everything
+// else to the agent API
+func tunnelHandler(api http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/client/tunnel/ping" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		api.ServeHTTP(w, r)
+	})
+}
+
+// bufferedConn keeps bytes read past the upgrade response
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// singleConnListener hands one connection to http.Server and reports when
+// the server is done with it
+type singleConnListener struct {
+	conn   net.Conn
+	once   sync.Once
+	closed chan struct{}
+}
+
+func newSingleConnListener(conn net.Conn) *singleConnListener {
+	return &singleConnListener{conn: conn, closed: make(chan struct{})}
+}
+
+func (l *singleConnListener) Accept() (net.Conn, error) {
+	var conn net.Conn
+	l.once.Do(func() {
+		conn = &notifyConn{Conn: l.conn, closed: l.closed}
+	})
+	if conn != nil {
+		return conn, nil
+	}
+	<-l.closed
+	return nil, net.ErrClosed
+}
+
+func (l *singleConnListener) Close() error {
+	return nil
+}
+
+func (l *singleConnListener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
+
+// notifyConn closes the listener's channel once the server closes it
+type notifyConn struct {
+	net.Conn
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func (c *notifyConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(func() { close(c.closed) })
+	return err
+}
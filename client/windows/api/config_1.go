@@ -11,6 +11,11 @@ func RegisterConfig1Routes(mux *http.ServeMux) {
 	mux.Handle("/client/config1/pass", auth.TokenAuthMiddleware(http.HandlerFunc(config_1.HandlePasswordChange)))
 	mux.Handle("/client/config1/basic", auth.TokenAuthMiddleware(http.HandlerFunc(config_1.HandleBasicInfo)))
 	mux.Handle("/client/config1/cmd", auth.TokenAuthMiddleware(http.HandlerFunc(config_1.HandleCommandExec)))
+	mux.Handle("/client/config1/cmd/stream", auth.TokenAuthMiddleware(http.HandlerFunc(config_1.HandleCommandStream)))
 	mux.Handle("/client/config1/basic_update", auth.TokenAuthMiddleware(http.HandlerFunc(config_1.HandleBasicUpdate)))
 	mux.Handle("/client/config1/uptime", auth.TokenAuthMiddleware(http.HandlerFunc(config_1.HandleOverview)))
+	mux.Handle("/client/config1/exec/start", auth.TokenAuthMiddleware(http.HandlerFunc(config_1.HandleExecStart)))
+	mux.Handle("/client/config1/exec/status", auth.TokenAuthMiddleware(http.HandlerFunc(config_1.HandleExecStatus)))
+	mux.Handle("/client/config1/exec/cancel", auth.TokenAuthMiddleware(http.HandlerFunc(config_1.HandleExecCancel)))
+	mux.Handle("/client/config1/terminal", auth.TokenAuthMiddleware(http.HandlerFunc(config_1.HandleTerminal)))
 }
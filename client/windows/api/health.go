@@ -8,4 +8,7 @@ import (
 
 func RegisterHealthRoutes(mux *http.ServeMux) {
 	mux.Handle("/client/health", auth.TokenAuthMiddleware(http.HandlerFunc(health.HandleHealthConfig)))
+
+	// Unauthenticated, used by the backend's subnet discovery
+	mux.HandleFunc("/client/info", health.HandleInfo)
 }
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultOverlap = 10 * time.Minute
+	maxOverlap     = 24 * time.Hour
+)
+
+// RotateRequest carries the next device token, sent with the current one
+type RotateRequest struct {
+	Token          string `json:"token"`
+	OverlapSeconds int    `json:"overlap_seconds"` // How long the current token stays valid after the switch
+}
+
+// HandleRotateToken stores the token pushed by the backend as pending. The
+// current token keeps working until the backend confirms the new one.
+func HandleRotateToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		writeResult(w, http.StatusMethodNotAllowed, "failed", "Only POST method allowed")
+		return
+	}
+
+	var req RotateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResult(w, http.StatusBadRequest, "failed", "Failed to parse request body")
+		return
+	}
+	if len(req.Token) < 32 {
+		writeResult(w, http.StatusBadRequest, "failed", "Token is too short")
+		return
+	}
+
+	overlap := defaultOverlap
+	if req.OverlapSeconds > 0 {
+		overlap = time.Duration(req.OverlapSeconds) * time.Second
+	}
+	if overlap > maxOverlap {
+		overlap = maxOverlap
+	}
+
+	if err := AddPendingToken(req.Token, overlap); err != nil {
+		writeResult(w, http.StatusInternalServerError, "failed", "Failed to store token: "+err.Error())
+		return
+	}
+
+	log.Printf("🔄 New access token received, waiting for confirmation")
+	writeResult(w, http.StatusOK, "success", "Token stored, confirm it to complete the rotation")
+}
+
+// HandleConfirmToken completes a rotation. The middleware has already
+// promoted the token the request authenticated with.
+func HandleConfirmToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		writeResult(w, http.StatusMethodNotAllowed, "failed", "Only POST method allowed")
+		return
+	}
+	writeResult(w, http.StatusOK, "success", "Token confirmed")
+}
+
+func writeResult(w http.ResponseWriter, statusCode int, status, message string) {
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  status,
+		"message": message,
+	})
+}
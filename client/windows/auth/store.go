@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The token file holds one SHA-256 hash per line, optionally followed by a
+// state. Rotation adds a pending hash; the first request made with it
+// promotes it and starts the overlap window for the hashes it replaces.
+//
+//	<hash>                      active
+//	<hash> pending <seconds>    pushed by the backend, overlap to apply once confirmed
+//	<hash> retire <unix time>   replaced, still accepted until the given time
+const (
+	stateActive  = "active"
+	statePending = "pending"
+	stateRetire  = "retire"
+)
+
+type tokenEntry struct {
+	hash     string
+	state    string
+	overlap  time.Duration // pending only
+	retireAt time.Time     // retire only
+}
+
+var tokenMu sync.Mutex
+
+// HasToken reports whether a usable token hash is stored
+func HasToken() bool {
+	tokenMu.Lock()
+	defer tokenMu.Unlock()
+
+	entries, err := readTokens()
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		if e.state == stateActive {
+			return true
+		}
+	}
+	return false
+}
+
+// TunnelCredential returns the hash of the current token, which the agent
+// presents when it opens a tunnel to the backend
+func TunnelCredential() (string, error) {
+	tokenMu.Lock()
+	defer tokenMu.Unlock()
+
+	entries, err := readTokens()
+	if err != nil {
+		return "", err
+	}
+	for _, e := range entries {
+		if e.state == stateActive {
+			return e.hash, nil
+		}
+	}
+	return "", errors.New("no access token stored")
+}
+
+// SetToken replaces every stored hash with the hash of token
+func SetToken(token string) error {
+	tokenMu.Lock()
+	defer tokenMu.Unlock()
+
+	return writeTokens([]tokenEntry{{hash: hashToken(token), state: stateActive}})
+}
+
+// AddPendingToken stores a rotated token next to the current ones. It only
+// takes over once a request authenticates with it.
+func AddPendingToken(token string, overlap time.Duration) error {
+	tokenMu.Lock()
+	defer tokenMu.Unlock()
+
+	entries, err := readTokens()
+	if err != nil {
+		return err
+	}
+
+	// A newer rotation replaces one that was never confirmed
+	kept := entries[:0]
+	for _, e := range entries {
+		if e.state != statePending {
+			kept = append(kept, e)
+		}
+	}
+	kept = append(kept, tokenEntry{hash: hashToken(token), state: statePending, overlap: overlap})
+	return writeTokens(kept)
+}
+
+// authenticate checks a bearer token against the stored hashes, promoting a
+// pending token on its first use
+func authenticate(token string) (bool, error) {
+	tokenMu.Lock()
+	defer tokenMu.Unlock()
+
+	entries, err := readTokens()
+	if err != nil {
+		return false, err
+	}
+
+	hash := hashToken(token)
+	now := time.Now()
+	match := -1
+	for i, e := range entries {
+		if e.state == stateRetire && now.After(e.retireAt) {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(e.hash), []byte(hash)) == 1 {
+			match = i
+		}
+	}
+	if match < 0 {
+		return false, nil
+	}
+	if entries[match].state != statePending {
+		return true, nil
+	}
+
+	// The backend confirmed the new token: retire the others after the overlap
+	promoted := []tokenEntry{{hash: hash, state: stateActive}}
+	for i, e := range entries {
+		switch {
+		case i == match:
+		case e.state == stateActive:
+			promoted = append(promoted, tokenEntry{hash: e.hash, state: stateRetire, retireAt: now.Add(entries[match].overlap)})
+		case e.state == stateRetire && now.Before(e.retireAt):
+			promoted = append(promoted, e)
+		}
+	}
+	return true, writeTokens(promoted)
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func readTokens() ([]tokenEntry, error) {
+	file, err := os.Open(tokenFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []tokenEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || len(fields[0]) != 64 {
+			continue
+		}
+
+		entry := tokenEntry{hash: strings.ToLower(fields[0]), state: stateActive}
+		if len(fields) >= 3 {
+			value, err := strconv.ParseInt(fields[2], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch fields[1] {
+			case statePending:
+				entry.state = statePending
+				entry.overlap = time.Duration(value) * time.Second
+			case stateRetire:
+				entry.state = stateRetire
+				entry.retireAt = time.Unix(value, 0)
+			default:
+				continue
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// writeTokens replaces the token file atomically so a crash never leaves the
+// agent without a valid hash
+func writeTokens(entries []tokenEntry) error {
+	var b strings.Builder
+	for _, e := range entries {
+		switch e.state {
+		case statePending:
+			fmt.Fprintf(&b, "%s %s %d\n", e.hash, statePending, int64(e.overlap/time.Second))
+		case stateRetire:
+			fmt.Fprintf(&b, "%s %s %d\n", e.hash, stateRetire, e.retireAt.Unix())
+		default:
+			fmt.Fprintf(&b, "%s\n", e.hash)
+		}
+	}
+
+	// Ensure auth folder exists
+	os.MkdirAll(filepath.Dir(tokenFilePath), 0755)
+
+	tmp := tokenFilePath + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, tokenFilePath)
+}
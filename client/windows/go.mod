@@ -2,16 +2,18 @@ module github.com/kishore-001/ServerManagementSuite/windows
 
 go 1.24.3
 
+require (
+	github.com/golang-jwt/jwt/v5 v5.2.2
+	github.com/shirou/gopsutil/v3 v3.24.5
+	golang.org/x/sys v0.20.0
+)
+
 require (
 	github.com/go-ole/go-ole v1.2.6 // indirect
-	github.com/golang-jwt/jwt/v5 v5.2.2 // indirect
 	github.com/lufia/plan9stats v0.0.0-20211012122336-39d0f177ccd0 // indirect
 	github.com/power-devops/perfstat v0.0.0-20210106213030-5aafc221ea8c // indirect
-	github.com/shirou/gopsutil/v3 v3.24.5 // indirect
 	github.com/shoenig/go-m1cpu v0.1.6 // indirect
 	github.com/tklauser/go-sysconf v0.3.12 // indirect
 	github.com/tklauser/numcpus v0.6.1 // indirect
 	github.com/yusufpapurcu/wmi v1.2.4 // indirect
-	golang.org/x/sys v0.20.0 // indirect
-	golang.org/x/text v0.25.0 // indirect
 )
@@ -0,0 +1,361 @@
+package config_1
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Commands started through /client/config1/exec/start keep running after the
+// request ends. The backend polls their output and exit code, so a long
+// command is not cut off by an HTTP timeout.
+
+const (
+	maxExecRunning     = 16
+	maxExecOutput      = 4 << 20 // Bytes kept per stream, older output is dropped
+	maxExecChunk       = 1 << 20 // Bytes returned per stream by one status call
+	defaultExecTimeout = time.Hour
+	maxExecTimeout     = 24 * time.Hour
+	execRetention      = time.Hour // Finished commands stay readable this long
+)
+
+// Execution states
+const (
+	execRunning   = "running"
+	execExited    = "exited"
+	execCancelled = "cancelled"
+	execTimedOut  = "timeout"
+)
+
+var execIDPattern = regexp.MustCompile(`^[A-Za-z0-9-]{1,64}$`)
+
+type ExecStartRequest struct {
+	ID         string `json:"id"` // Optional, a start repeated with the same id returns the running command
+	Command    string `json:"command"`
+	TimeoutSec int    `json:"timeout_sec"`
+}
+
+type ExecCancelRequest struct {
+	ID string `json:"id"`
+}
+
+// ExecStatus is a command's state and the output after the offsets asked for
+type ExecStatus struct {
+	Status        string     `json:"status"`
+	ID            string     `json:"id"`
+	State         string     `json:"state"`
+	ExitCode      *int       `json:"exit_code,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	StartedAt     time.Time  `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+	Stdout        string     `json:"stdout"`
+	Stderr        string     `json:"stderr"`
+	StdoutOffset  int64      `json:"stdout_offset"` // Pass back to get only newer output
+	StderrOffset  int64      `json:"stderr_offset"`
+	StdoutDropped int64      `json:"stdout_dropped,omitempty"` // Bytes lost because the buffer was full
+	StderrDropped int64      `json:"stderr_dropped,omitempty"`
+}
+
+// outputBuffer keeps the tail of a stream. base is the stream offset of data[0].
+type outputBuffer struct {
+	base int64
+	data []byte
+}
+
+func (b *outputBuffer) write(p []byte) {
+	b.data = append(b.data, p...)
+	if len(b.data) > maxExecOutput {
+		// Drop a quarter at once so a chatty command is not copied on every write
+		drop := len(b.data) - maxExecOutput*3/4
+		b.data = append([]byte(nil), b.data[drop:]...)
+		b.base += int64(drop)
+	}
+}
+
+// read returns output from offset on, the offset to read from next and how
+// many bytes before it were already dropped
+func (b *outputBuffer) read(offset int64) (string, int64, int64) {
+	var dropped int64
+	if offset < b.base {
+		dropped = b.base - offset
+		offset = b.base
+	}
+	end := b.base + int64(len(b.data))
+	if offset > end {
+		offset = end
+	}
+
+	chunk := b.data[offset-b.base:]
+	if len(chunk) > maxExecChunk {
+		chunk = chunk[:completeRunes(chunk[:maxExecChunk])]
+	}
+	return string(chunk), offset + int64(len(chunk)), dropped
+}
+
+// completeRunes returns the length of p without a UTF-8 character cut off at
+// its end, so output is not split in the middle of one
+func completeRunes(p []byte) int {
+	for i := len(p) - 1; i >= 0 && i >= len(p)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(p[i]) {
+			if !utf8.FullRune(p[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(p)
+}
+
+type execution struct {
+	mu         sync.Mutex
+	id         string
+	cmd        *exec.Cmd
+	state      string
+	stopReason string // Set when the command is killed
+	exitCode   int
+	err        string
+	startedAt  time.Time
+	finishedAt time.Time
+	stdout     outputBuffer
+	stderr     outputBuffer
+}
+
+// streamWriter appends a command's output to one of its buffers
+type streamWriter struct {
+	e   *execution
+	buf *outputBuffer
+}
+
+func (s streamWriter) Write(p []byte) (int, error) {
+	s.e.mu.Lock()
+	s.buf.write(p)
+	s.e.mu.Unlock()
+	return len(p), nil
+}
+
+var (
+	execMu     sync.Mutex
+	executions = make(map[string]*execution)
+)
+
+func HandleExecStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req ExecStartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid JSON input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Command == "" {
+		sendError(w, "Command cannot be empty", http.StatusBadRequest)
+		return
+	}
+	if req.ID == "" {
+		req.ID = newExecID()
+	} else if !execIDPattern.MatchString(req.ID) {
+		sendError(w, "id may only contain letters, digits and dashes", http.StatusBadRequest)
+		return
+	}
+
+	timeout := execTimeout(req.TimeoutSec)
+
+	execMu.Lock()
+	pruneExecutions()
+	if e, ok := executions[req.ID]; ok {
+		execMu.Unlock()
+		sendGetSuccess(w, e.status(0, 0))
+		return
+	}
+	running := 0
+	for _, e := range executions {
+		e.mu.Lock()
+		if e.state == execRunning {
+			running++
+		}
+		e.mu.Unlock()
+	}
+	if running >= maxExecRunning {
+		execMu.Unlock()
+		sendError(w, "Too many commands running, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	e := &execution{id: req.ID, state: execRunning, startedAt: time.Now()}
+	e.cmd = shellCommand(req.Command)
+	e.cmd.Stdout = streamWriter{e, &e.stdout}
+	e.cmd.Stderr = streamWriter{e, &e.stderr}
+	// Background processes holding the pipes open must not keep the command running
+	e.cmd.WaitDelay = 5 * time.Second
+
+	if err := e.cmd.Start(); err != nil {
+		execMu.Unlock()
+		sendError(w, "Failed to start command: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	executions[e.id] = e
+	execMu.Unlock()
+
+	go e.wait(timeout)
+
+	sendGetSuccess(w, e.status(0, 0))
+}
+
+func HandleExecStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	stdoutOffset, _ := strconv.ParseInt(query.Get("stdout_offset"), 10, 64)
+	stderrOffset, _ := strconv.ParseInt(query.Get("stderr_offset"), 10, 64)
+
+	e, ok := getExecution(query.Get("id"))
+	if !ok {
+		sendError(w, "Command not found", http.StatusNotFound)
+		return
+	}
+	sendGetSuccess(w, e.status(stdoutOffset, stderrOffset))
+}
+
+func HandleExecCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req ExecCancelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid JSON input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	e, ok := getExecution(req.ID)
+	if !ok {
+		sendError(w, "Command not found", http.StatusNotFound)
+		return
+	}
+	e.kill(execCancelled)
+	sendGetSuccess(w, e.status(0, 0))
+}
+
+func getExecution(id string) (*execution, bool) {
+	execMu.Lock()
+	defer execMu.Unlock()
+	pruneExecutions()
+	e, ok := executions[id]
+	return e, ok
+}
+
+// pruneExecutions forgets commands that finished more than execRetention ago.
+// The caller holds execMu.
+func pruneExecutions() {
+	cutoff := time.Now().Add(-execRetention)
+	for id, e := range executions {
+		e.mu.Lock()
+		expired := e.state != execRunning && e.finishedAt.Before(cutoff)
+		e.mu.Unlock()
+		if expired {
+			delete(executions, id)
+		}
+	}
+}
+
+// wait records how the command ended, killing it once timeout has passed
+func (e *execution) wait(timeout time.Duration) {
+	timer := time.AfterFunc(timeout, func() { e.kill(execTimedOut) })
+	err := e.cmd.Wait()
+	timer.Stop()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.finishedAt = time.Now()
+	e.exitCode = e.cmd.ProcessState.ExitCode()
+	e.state = execExited
+	if e.stopReason != "" {
+		e.state = e.stopReason
+	}
+	if _, exited := err.(*exec.ExitError); err != nil && !exited {
+		e.err = err.Error()
+	}
+}
+
+// kill stops the command and everything it started
+func (e *execution) kill(reason string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.state != execRunning || e.stopReason != "" {
+		return
+	}
+	e.stopReason = reason
+	killTree(e.cmd)
+}
+
+func (e *execution) status(stdoutOffset, stderrOffset int64) ExecStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	s := ExecStatus{
+		Status:    "success",
+		ID:        e.id,
+		State:     e.state,
+		Error:     e.err,
+		StartedAt: e.startedAt,
+	}
+	if e.state != execRunning {
+		exitCode, finishedAt := e.exitCode, e.finishedAt
+		s.ExitCode = &exitCode
+		s.FinishedAt = &finishedAt
+	}
+	s.Stdout, s.StdoutOffset, s.StdoutDropped = e.stdout.read(stdoutOffset)
+	s.Stderr, s.StderrOffset, s.StderrDropped = e.stderr.read(stderrOffset)
+	return s
+}
+
+// shellCommand runs command with 'cmd /C'
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("cmd", "/C", command)
+}
+
+// killTree kills a command started by shellCommand; taskkill /T also ends
+// the processes it started
+func killTree(cmd *exec.Cmd) error {
+	pid := strconv.Itoa(cmd.Process.Pid)
+	if err := exec.Command("taskkill", "/T", "/F", "/PID", pid).Run(); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
+
+// execTimeout is how long a command may run, from the seconds asked for
+func execTimeout(seconds int) time.Duration {
+	if seconds <= 0 {
+		return defaultExecTimeout
+	}
+	if timeout := time.Duration(seconds) * time.Second; timeout < maxExecTimeout {
+		return timeout
+	}
+	return maxExecTimeout
+}
+
+func newExecID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,162 @@
+package config_1
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+type StreamRequest struct {
+	Command    string `json:"command"`
+	TimeoutSec int    `json:"timeout_sec"` // Default one hour
+}
+
+// CommandEvent is one line of a streamed command
+type CommandEvent struct {
+	Type     string    `json:"type"` // stdout, stderr, then exit
+	Data     string    `json:"data,omitempty"`
+	ExitCode *int      `json:"exit_code,omitempty"`
+	State    string    `json:"state,omitempty"` // exited or timeout, on the exit event
+	Error    string    `json:"error,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// eventStream writes events to the response as they happen. Output that
+// arrives after the exit event is dropped.
+type eventStream struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	rc     *http.ResponseController
+	cancel context.CancelFunc // Kills the command once the caller is gone
+	closed bool
+}
+
+// emit writes one event. The caller holds s.mu.
+func (s *eventStream) emit(ev CommandEvent) {
+	if s.closed {
+		return
+	}
+	ev.Time = time.Now()
+	if err := s.enc.Encode(ev); err != nil {
+		s.closed = true
+		s.cancel()
+		return
+	}
+	s.rc.Flush()
+}
+
+// eventWriter turns one output stream of the command into events. Bytes of
+// a UTF-8 character cut off by a write wait for the next one.
+type eventWriter struct {
+	stream  *eventStream
+	typ     string
+	pending []byte
+}
+
+func (w *eventWriter) Write(p []byte) (int, error) {
+	w.stream.mu.Lock()
+	defer w.stream.mu.Unlock()
+
+	data := append(w.pending, p...)
+	n := completeRunes(data)
+	w.pending = append([]byte(nil), data[n:]...)
+	if n > 0 {
+		w.stream.emit(CommandEvent{Type: w.typ, Data: string(data[:n])})
+	}
+	return len(p), nil
+}
+
+// flush sends what is left over. The caller holds the stream's lock.
+func (w *eventWriter) flush() {
+	if len(w.pending) > 0 {
+		w.stream.emit(CommandEvent{Type: w.typ, Data: string(w.pending)})
+		w.pending = nil
+	}
+}
+
+// HandleCommandStream runs a command and streams its stdout and stderr as
+// they are written, one JSON event per line, ending with the exit code. The
+// command and everything it started are killed when the caller disconnects.
+func HandleCommandStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req StreamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, "Invalid JSON input: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Command == "" {
+		sendError(w, "Command cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), execTimeout(req.TimeoutSec))
+	defer cancel()
+
+	stream := &eventStream{enc: json.NewEncoder(w), rc: http.NewResponseController(w), cancel: cancel}
+	stdout := &eventWriter{stream: stream, typ: "stdout"}
+	stderr := &eventWriter{stream: stream, typ: "stderr"}
+
+	cmd := shellCommand(req.Command)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// Background processes holding the pipes open must not keep the stream open
+	cmd.WaitDelay = 5 * time.Second
+
+	// Output waits until the response has started
+	stream.mu.Lock()
+	if err := cmd.Start(); err != nil {
+		stream.mu.Unlock()
+		sendError(w, "Failed to start command: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	stream.rc.Flush()
+	stream.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			killTree(cmd)
+		case <-done:
+		}
+	}()
+
+	err := cmd.Wait()
+	close(done)
+
+	// Nothing may be written once the handler returns
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	defer func() { stream.closed = true }()
+
+	if r.Context().Err() != nil {
+		log.Printf("⛔ Command stream closed by the caller, command killed")
+		return
+	}
+
+	exit := CommandEvent{Type: "exit", State: execExited}
+	exitCode := cmd.ProcessState.ExitCode()
+	exit.ExitCode = &exitCode
+	if ctx.Err() == context.DeadlineExceeded {
+		exit.State = execTimedOut
+	}
+	if _, exited := err.(*exec.ExitError); err != nil && !exited {
+		exit.Error = err.Error()
+	}
+
+	stdout.flush()
+	stderr.flush()
+	stream.emit(exit)
+}
@@ -0,0 +1,175 @@
+package config_1
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// After hanging up, before the shell and what it started are killed
+const terminalKillDelay = 5 * time.Second
+
+// terminal is a shell attached to a pseudo console (ConPTY)
+type terminal struct {
+	console windows.Handle
+	input   *os.File // Keystrokes for the console
+	output  *os.File // What the console draws
+	process *os.Process
+	exited  chan struct{}
+
+	closeOnce   sync.Once
+	releaseOnce sync.Once
+}
+
+// openTerminal starts PowerShell, or cmd where it is missing, in a new
+// pseudo console of the given size
+func openTerminal(cols, rows int) (*terminal, error) {
+	inRead, inWrite, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	outRead, outWrite, err := os.Pipe()
+	if err != nil {
+		inRead.Close()
+		inWrite.Close()
+		return nil, err
+	}
+
+	var console windows.Handle
+	err = windows.CreatePseudoConsole(consoleSize(cols, rows), windows.Handle(inRead.Fd()), windows.Handle(outWrite.Fd()), 0, &console)
+	// The console holds its own copies of its ends of the pipes
+	inRead.Close()
+	outWrite.Close()
+	if err != nil {
+		inWrite.Close()
+		outRead.Close()
+		return nil, err
+	}
+
+	t := &terminal{console: console, input: inWrite, output: outRead, exited: make(chan struct{})}
+
+	shell := "powershell.exe -NoLogo"
+	if _, err := exec.LookPath("powershell.exe"); err != nil {
+		shell = "cmd.exe"
+	}
+	if t.process, err = startInConsole(console, shell); err != nil {
+		t.release()
+		outRead.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// startInConsole creates a process attached to the pseudo console. os/exec
+// cannot pass the console to a new process, so this calls CreateProcess.
+func startInConsole(console windows.Handle, commandLine string) (*os.Process, error) {
+	attrs, err := windows.NewProcThreadAttributeList(1)
+	if err != nil {
+		return nil, err
+	}
+	defer attrs.Delete()
+
+	// The attribute's value is the console handle itself, not a pointer to it
+	err = attrs.Update(windows.PROC_THREAD_ATTRIBUTE_PSEUDOCONSOLE, *(*unsafe.Pointer)(unsafe.Pointer(&console)), unsafe.Sizeof(console))
+	if err != nil {
+		return nil, err
+	}
+
+	si := &windows.StartupInfoEx{ProcThreadAttributeList: attrs.List()}
+	si.Cb = uint32(unsafe.Sizeof(*si))
+	// Otherwise the shell would write to the agent's own standard handles
+	si.Flags = windows.STARTF_USESTDHANDLES
+
+	cmdLine, err := windows.UTF16PtrFromString(commandLine)
+	if err != nil {
+		return nil, err
+	}
+	var dir *uint16
+	if home, err := os.UserHomeDir(); err == nil {
+		dir, _ = windows.UTF16PtrFromString(home)
+	}
+
+	var pi windows.ProcessInformation
+	err = windows.CreateProcess(nil, cmdLine, nil, nil, false, windows.EXTENDED_STARTUPINFO_PRESENT, nil, dir, &si.StartupInfo, &pi)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(pi.Thread)
+	// Held open until os.FindProcess has its own handle, so the pid is not reused
+	defer windows.CloseHandle(pi.Process)
+	return os.FindProcess(int(pi.ProcessId))
+}
+
+func consoleSize(cols, rows int) windows.Coord {
+	return windows.Coord{X: int16(cols), Y: int16(rows)}
+}
+
+// Read returns what the console draws. It reports io.EOF once the console
+// is closed and everything it drew has been read.
+func (t *terminal) Read(p []byte) (int, error) {
+	n, err := t.output.Read(p)
+	if err != nil {
+		t.output.Close()
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Write types into the console
+func (t *terminal) Write(p []byte) (int, error) {
+	return t.input.Write(p)
+}
+
+func (t *terminal) Resize(cols, rows int) error {
+	return windows.ResizePseudoConsole(t.console, consoleSize(cols, rows))
+}
+
+// Wait waits for the shell to exit and returns its exit code
+func (t *terminal) Wait() (int, error) {
+	state, err := t.process.Wait()
+	close(t.exited)
+	if err != nil {
+		return -1, err
+	}
+	return state.ExitCode(), nil
+}
+
+// Drain closes the console once the shell is gone. The console keeps
+// running without it, and closing it flushes the last output and makes
+// Read return.
+func (t *terminal) Drain() {
+	t.release()
+}
+
+// Close closes the console, which tells the shell and the programs attached
+// to it to exit. Whatever is still running after a grace period is killed
+// along with what it started.
+func (t *terminal) Close() {
+	t.closeOnce.Do(func() {
+		t.release()
+		go func() {
+			select {
+			case <-t.exited:
+			case <-time.After(terminalKillDelay):
+				pid := strconv.Itoa(t.process.Pid)
+				if err := exec.Command("taskkill", "/T", "/F", "/PID", pid).Run(); err != nil {
+					t.process.Kill()
+				}
+			}
+		}()
+	})
+}
+
+// release closes the pseudo console and the keystroke pipe
+func (t *terminal) release() {
+	t.releaseOnce.Do(func() {
+		windows.ClosePseudoConsole(t.console)
+		t.input.Close()
+	})
+}
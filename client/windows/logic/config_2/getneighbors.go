@@ -0,0 +1,76 @@
+package config_2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+)
+
+// Neighbor is one entry of the ARP / neighbor table
+type Neighbor struct {
+	IP        string `json:"ip"`
+	MAC       string `json:"mac"`
+	Interface string `json:"interface"`
+	State     string `json:"state"`
+}
+
+// HandleNeighbors reports the MAC addresses this host currently sees
+func HandleNeighbors(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, "Only GET method allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	neighbors, err := getNeighbors()
+	if err != nil {
+		sendError(w, "Failed to read neighbor table: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "success",
+		"neighbors": neighbors,
+	})
+}
+
+func getNeighbors() ([]Neighbor, error) {
+	script := `ConvertTo-Json -Compress -InputObject @(Get-NetNeighbor -ErrorAction SilentlyContinue |
+		Where-Object { $_.State -ne 'Unreachable' -and $_.State -ne 'Incomplete' -and $_.LinkLayerAddress } |
+		Select-Object IPAddress, LinkLayerAddress, InterfaceAlias, @{Name='State';Expression={$_.State.ToString()}})`
+
+	output, err := exec.Command("powershell", "-Command", script).Output()
+	if err != nil {
+		return nil, fmt.Errorf("Get-NetNeighbor failed: %v", err)
+	}
+
+	var entries []struct {
+		IPAddress        string `json:"IPAddress"`
+		LinkLayerAddress string `json:"LinkLayerAddress"`
+		InterfaceAlias   string `json:"InterfaceAlias"`
+		State            string `json:"State"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(string(output))), &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse neighbor table: %v", err)
+	}
+
+	neighbors := []Neighbor{}
+	for _, e := range entries {
+		// Windows reports AA-BB-CC-DD-EE-FF
+		mac := strings.ToLower(strings.ReplaceAll(e.LinkLayerAddress, "-", ":"))
+		if mac == "" || mac == "00:00:00:00:00:00" || mac == "ff:ff:ff:ff:ff:ff" {
+			continue
+		}
+		neighbors = append(neighbors, Neighbor{
+			IP:        e.IPAddress,
+			MAC:       mac,
+			Interface: e.InterfaceAlias,
+			State:     e.State,
+		})
+	}
+	return neighbors, nil
+}
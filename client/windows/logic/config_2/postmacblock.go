@@ -0,0 +1,16 @@
+package config_2
+
+import (
+	"net/http"
+)
+
+// HandleMACBlock is not supported: Windows Firewall cannot filter by source
+// MAC. The backend treats 501 as "alert only" for this host.
+func HandleMACBlock(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		sendError(w, "Only POST method allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sendError(w, "MAC blocking is not supported on Windows", http.StatusNotImplemented)
+}